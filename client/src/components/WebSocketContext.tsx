@@ -8,6 +8,12 @@ import React, {
 } from "react";
 import { useAppSelector } from "../redux/hooks";
 import { RootState } from "../redux/store";
+import {
+    ClientMessage,
+    ClientRoomUpdate,
+    ServerMessage,
+    protocolVersion,
+} from "../protocol";
 
 interface WebSocketProviderProps {
     children: ReactNode;
@@ -25,7 +31,10 @@ interface WebSocketContextType {
     handleGameMessage: (
         callback: (incomingMessage: RoomMessage) => void
     ) => () => void;
+    /** true from when the connection is first opened until the session ends, including while a dropped connection is resumed */
     connectionOpen: boolean;
+    /** true while a dropped connection is being resumed */
+    reconnecting: boolean;
 }
 export interface ChatMessage {
     type: string;
@@ -40,7 +49,8 @@ export interface RoomMessage {
     timestamp: number;
 }
 
-export type Message = RoomMessage | ChatMessage;
+// messages sent to the server; see protocol.ts, which is generated from the server's types
+export type Message = ClientMessage;
 
 export interface RoomUpdate {
     type: string;
@@ -52,6 +62,7 @@ export interface RoomUpdate {
 export const RoomUpdateTypes = {
     changeDifficulty: "CHANGE_DIFFICULTY",
     changeTimeLimit: "CHANGE_TIME_LIMIT",
+    changeScoringMode: "CHANGE_SCORING_MODE",
     changeProblem: "CHANGE_PROBLEM",
     randomProblem: "RANDOM_PROBLEM",
     setUserReady: "SET_USER_READY",
@@ -62,9 +73,9 @@ export const RoomUpdateTypes = {
 
 // the types of messages that can be broadcast to other clients in a room
 export const messageTypes = {
-    chatMessage: "chat_message",
-    roomMessage: "room_message",
-    gameMessage: "game_message",
+    chatMessage: "chat",
+    roomMessage: "room_update",
+    gameMessage: "game_event",
 };
 
 const WebSocketContext = createContext<WebSocketContextType | undefined>(
@@ -77,6 +88,12 @@ const serverUrl = process.env.REACT_APP_WEBSOCKET_DOMAIN || 'code-duel-server.fl
 // set env variable to change between ssl and unsecured websocket
 const protocol = process.env.REACT_APP_WEBSOCKET_PROTOCOL || 'wss';
 
+// longest wait between attempts to resume a dropped connection, in milliseconds
+const maxReconnectDelay = 8000;
+
+// close codes the server uses when it ends a session on purpose, which aren't worth reconnecting after
+const finalCloseCodes = [1000, 1008, 1011];
+
 /**
  * Gives access to a websocket connection for a room to descendents via the useWebSocket hook
  */
@@ -86,41 +103,62 @@ export const WebSocketProvider: React.FC<WebSocketProviderProps> = ({
 }) => {
     const ws = useRef<WebSocket | null>(null);
     const [connectionOpen, setConnectionOpen] = useState(false);
+    const [reconnecting, setReconnecting] = useState(false);
     const messageQueue = useRef<Message[]>([]); // enqueue messages if they are unable to be sent
+    // message listeners are kept here rather than on the socket, so they carry over when a dropped connection is resumed
+    const listeners = useRef(new Set<(message: ServerMessage) => void>());
+    // the session the server gave us, for resuming it if the connection drops
+    const session = useRef({ token: "", grace: 0, lastSeq: 0, droppedAt: 0 });
+    const unmounting = useRef(false);
+    const reconnectAttempts = useRef(0);
+    const reconnectTimer = useRef<ReturnType<typeof setTimeout> | null>(null);
     const loggedIn = useAppSelector(
         (state: RootState) => state.userInfo.loggedIn
     );
     const idToken = useAppSelector(
         (state: RootState) => state.userInfo.idToken
     );
+    // reconnecting happens outside of rendering, so it reads the latest token from here
+    const idTokenRef = useRef(idToken);
+    idTokenRef.current = idToken;
+
+    // whether the dropped session can still be resumed
+    const canResume = () => {
+        const { token, grace, droppedAt } = session.current;
+        return token !== "" && Date.now() - droppedAt < grace * 1000;
+    };
 
-    useEffect(() => {
-        const handleUnmount = () => {
-            if (ws.current && ws.current.readyState === WebSocket.OPEN) {
-                console.log("closing websocket on unmount");
-                ws.current.close();
-            }
-        };
-        if (!loggedIn) {
-            console.warn("user not logged in; aborting websocket connection");
-            return;
-        }
-        // make sure we don't open multiple sockets for the same client
-        if (ws.current) {
-            return handleUnmount;
-        }
-        ws.current = new WebSocket(`${protocol}://${serverUrl}/ws?room=${roomID}`);
+    const connect = () => {
+        const socket = new WebSocket(`${protocol}://${serverUrl}/ws?room=${roomID}`);
+        ws.current = socket;
+        let started = false; // whether the server accepted our authorization or resume on this socket
         console.log("setting up new websocket connection");
 
-        ws.current.addEventListener("open", (event) => {
+        socket.addEventListener("open", (event) => {
             setConnectionOpen(true);
-            // send auth info
-            if (idToken) {
+            if (canResume()) {
+                // pick up the dropped session; the server replays whatever we missed after its ack
                 const message: Message = {
+                    v: protocolVersion,
+                    type: "resume",
+                    timestamp: Date.now(),
+                    payload: {
+                        token: session.current.token,
+                        after: session.current.lastSeq,
+                        versions: [protocolVersion],
+                    },
+                };
+                sendWebsocketMessage(message);
+            } else if (idTokenRef.current) {
+                // send auth info
+                const message: Message = {
+                    v: protocolVersion,
                     type: "authorization",
-                    content: idToken,
-                    sender: "websocket",
                     timestamp: Date.now(),
+                    payload: {
+                        token: idTokenRef.current,
+                        versions: [protocolVersion],
+                    },
                 };
                 sendWebsocketMessage(message);
             }
@@ -130,15 +168,87 @@ export const WebSocketProvider: React.FC<WebSocketProviderProps> = ({
             }
         });
 
-        ws.current.addEventListener("close", (event) => {
-            setConnectionOpen(false);
-            console.log("WebSocket connection closed");
+        socket.addEventListener("close", (event) => {
+            console.log("WebSocket connection closed", event.code);
+            if (ws.current !== socket) {
+                return;
+            }
+            if (started) {
+                session.current.droppedAt = Date.now();
+                reconnectAttempts.current = 0;
+            }
+            if (unmounting.current || finalCloseCodes.includes(event.code) || !canResume()) {
+                session.current.token = "";
+                setReconnecting(false);
+                setConnectionOpen(false);
+                return;
+            }
+            // the connection dropped, so try to get the session back before the server gives up on it
+            setReconnecting(true);
+            const delay = Math.min(1000 * 2 ** reconnectAttempts.current, maxReconnectDelay);
+            reconnectAttempts.current++;
+            reconnectTimer.current = setTimeout(connect, delay);
         });
 
-        ws.current.addEventListener("error", (error) => {
+        socket.addEventListener("error", (error) => {
             console.log("WebSocket error", error);
         });
 
+        socket.addEventListener("message", (event) => {
+            const receivedMessage: ServerMessage = JSON.parse(event.data);
+            if (receivedMessage.seq) {
+                session.current.lastSeq = receivedMessage.seq;
+            }
+            if (receivedMessage.type === "ack" && receivedMessage.payload.session) {
+                const { token, grace, resumed, complete } = receivedMessage.payload.session;
+                if (resumed && !complete) {
+                    // the server no longer has everything we missed, so start over from the room's current state
+                    console.warn("resumed session is missing messages; reloading the room");
+                    window.location.reload();
+                    return;
+                }
+                started = true;
+                session.current.token = token;
+                session.current.grace = grace;
+                setReconnecting(false);
+            }
+            // the server tells us about messages it couldn't accept
+            if (receivedMessage.type === "error") {
+                console.warn(
+                    `websocket message rejected (${receivedMessage.payload.code}): ${receivedMessage.payload.message}`
+                );
+                if (receivedMessage.payload.code === "resume_failed") {
+                    // the server gave up on the session and took us out of the room, so rejoin it from scratch
+                    session.current.token = "";
+                    window.location.reload();
+                    return;
+                }
+            }
+            listeners.current.forEach((listener) => listener(receivedMessage));
+        });
+    };
+
+    useEffect(() => {
+        const handleUnmount = () => {
+            unmounting.current = true;
+            if (reconnectTimer.current) {
+                clearTimeout(reconnectTimer.current);
+            }
+            if (ws.current && ws.current.readyState === WebSocket.OPEN) {
+                console.log("closing websocket on unmount");
+                ws.current.close();
+            }
+        };
+        if (!loggedIn) {
+            console.warn("user not logged in; aborting websocket connection");
+            return;
+        }
+        unmounting.current = false;
+        // make sure we don't open multiple sockets for the same client
+        if (ws.current) {
+            return handleUnmount;
+        }
+        connect();
         return handleUnmount;
     }, [loggedIn]);
 
@@ -155,12 +265,15 @@ export const WebSocketProvider: React.FC<WebSocketProviderProps> = ({
             return;
         }
         const timestamp = Date.now();
-        const message = {
-            type: messageTypes.chatMessage,
+        const message: Message = {
+            v: protocolVersion,
+            type: "chat",
             room: roomID,
-            content: msg,
-            sender: sender,
             timestamp: timestamp,
+            payload: {
+                content: msg,
+                sender: sender, // the server fills this in from our authorization
+            },
         };
         ws.current.send(JSON.stringify(message));
         console.log("sent message", message);
@@ -179,11 +292,12 @@ export const WebSocketProvider: React.FC<WebSocketProviderProps> = ({
             return;
         }
         const timestamp = Date.now();
-        const message = {
-            type: messageTypes.roomMessage,
+        const message: Message = {
+            v: protocolVersion,
+            type: "room_update",
             room: roomID,
             timestamp: timestamp,
-            roomUpdate: roomUpdate,
+            payload: roomUpdate as ClientRoomUpdate,
         };
         ws.current.send(JSON.stringify(message));
         console.log("sent message", message);
@@ -221,94 +335,79 @@ export const WebSocketProvider: React.FC<WebSocketProviderProps> = ({
     };
 
     /**
-     * function for subscribing and setting the callback behavior for when chat messages are received over websocket. returns the unsubscribe function, for cleanup.
+     * function for subscribing and setting the callback behavior for when chat messages are received over websocket. subscriptions last across reconnects. returns the unsubscribe function, for cleanup.
      * @param callback a callback function for handling when messages are received over websocket. probably for updating state in the consuming component.
      * @returns an unsubscribe function to stop listening for messages; call this function when the component unmounts to prevent memory leaks.
      */
     const handleChatMessage = (
         callback: (incomingMessage: ChatMessage) => void
     ) => {
-        const listener = (event: MessageEvent) => {
-            const receivedMessage = JSON.parse(event.data);
-            if (receivedMessage.type !== "chat_message") {
+        const listener = (receivedMessage: ServerMessage) => {
+            if (receivedMessage.type !== "chat") {
                 return;
             }
             const msg: ChatMessage = {
                 type: receivedMessage.type,
-                sender: receivedMessage.sender,
-                content: receivedMessage.content,
+                sender: receivedMessage.payload.sender,
+                content: receivedMessage.payload.content,
                 timestamp: receivedMessage.timestamp,
             };
             callback(msg);
         };
 
-        if (!ws.current) {
-            console.warn("failed to add websocket listener for chat");
-        } else {
-            ws.current.addEventListener("message", listener);
-        }
+        listeners.current.add(listener);
 
         // Return a cleanup function to unsubscribe when needed
         return () => {
-            ws.current?.removeEventListener("message", listener);
+            listeners.current.delete(listener);
         };
     };
 
     const handleRoomMessage = (
         callback: (incomingMessage: RoomMessage) => void
     ) => {
-        const listener = (event: MessageEvent) => {
-            const receivedMessage = JSON.parse(event.data);
-            if (receivedMessage.type !== "room_message") {
+        const listener = (receivedMessage: ServerMessage) => {
+            if (receivedMessage.type !== "room_update") {
                 return;
             }
             const msg: RoomMessage = {
                 type: receivedMessage.type,
                 timestamp: receivedMessage.timestamp,
-                roomupdate: receivedMessage.roomupdate,
+                roomupdate: receivedMessage.payload,
             };
             callback(msg);
         };
 
-        if (!ws.current) {
-            console.warn("failed to add websocket listener for room messages");
-        } else {
-            ws.current.addEventListener("message", listener);
-        }
+        listeners.current.add(listener);
 
         // Return a cleanup function to unsubscribe when needed
         return () => {
-            ws.current?.removeEventListener("message", listener);
+            listeners.current.delete(listener);
         };
     };
 
     const handleGameMessage = (
         callback: (incomingMessage: RoomMessage) => void
     ) => {
-        const listener = (event: MessageEvent) => {
-            const receivedMessage = JSON.parse(event.data);
-            if (receivedMessage.type !== messageTypes.gameMessage) {
+        const listener = (receivedMessage: ServerMessage) => {
+            if (receivedMessage.type !== "game_event") {
                 return;
             }
             const msg: RoomMessage = {
                 type: receivedMessage.type,
                 timestamp: receivedMessage.timestamp,
-                roomupdate: receivedMessage.roomupdate,
+                roomupdate: receivedMessage.payload,
             };
             console.log("Received game message:", receivedMessage, msg);
             callback(msg);
         };
 
-        if (!ws.current) {
-            console.warn("failed to add websocket listener for game messages");
-        } else {
-            ws.current.addEventListener("message", listener);
-            console.log("listening for game messages over websocket");
-        }
+        listeners.current.add(listener);
+        console.log("listening for game messages over websocket");
 
         // Return a cleanup function to unsubscribe when needed
         return () => {
-            ws.current?.removeEventListener("message", listener);
+            listeners.current.delete(listener);
         };
     };
 
@@ -319,6 +418,7 @@ export const WebSocketProvider: React.FC<WebSocketProviderProps> = ({
         handleRoomMessage,
         handleGameMessage,
         connectionOpen,
+        reconnecting,
     };
 
     return (
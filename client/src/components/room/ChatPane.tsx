@@ -31,7 +31,7 @@ export default function ChatPane(props: ChatPaneProps) {
         // handle local state for messages
         const timestamp = Date.now();
         addMessage({
-            type: "chat_message",
+            type: "chat",
             sender: props.username,
             content: messageInput,
             timestamp: timestamp
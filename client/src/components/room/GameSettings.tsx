@@ -18,6 +18,7 @@ interface GameSettingsProps {
     difficulty: number;
     updateSetting: Function;
     timeLimit?: number;
+    scoringMode: string;
     problem?: ProblemOverview;
     setProblem: Function;
     randomProblem: boolean;
@@ -49,6 +50,12 @@ export default function GameSettings(props: GameSettingsProps) {
         props.sendRoomUpdate(RoomUpdateTypes.changeTimeLimit, timeLimitDisp);
         props.updateSetting("timeLimit", timeLimitDisp);
     };
+    const setScoringMode = (newValue: string | null) => {
+        // clicking the selected mode again deselects it, which leaves the mode as it is
+        if (!newValue) return;
+        props.updateSetting("scoringMode", newValue);
+        props.sendRoomUpdate(RoomUpdateTypes.changeScoringMode, newValue);
+    };
     const randomProblem = props.randomProblem;
     const setRandomProblem = (newValue: boolean) => {
         props.updateSetting("randomProblem", newValue);
@@ -120,6 +127,24 @@ export default function GameSettings(props: GameSettingsProps) {
                             disabled={!props.isOwner}
                         />
                     </div>
+                    <div className="_flexRow">
+                        <Typography marginRight={1}>Scoring: </Typography>
+                        <ToggleButtonGroup
+                            disabled={!props.isOwner}
+                            color="primary"
+                            value={props.scoringMode}
+                            exclusive
+                            onChange={(_e, v) => setScoringMode(v)}
+                        >
+                            <ToggleButton value="first">
+                                First to solve
+                            </ToggleButton>
+                            <ToggleButton value="runtime">Fastest</ToggleButton>
+                            <ToggleButton value="memory">
+                                Least memory
+                            </ToggleButton>
+                        </ToggleButtonGroup>
+                    </div>
                     <div className="_flexRow">
                         {props.isOwner ? (
                             <>
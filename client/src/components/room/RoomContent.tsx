@@ -77,6 +77,9 @@ export default function RoomContent(props: RoomContentProps) {
             case "randomProblem":
                 roomData.RandomProblem = value;
                 break;
+            case "scoringMode":
+                roomData.ScoringMode = value;
+                break;
         }
         const today = new Date();
         setUpdateTimestamp(today.toLocaleTimeString());
@@ -108,6 +111,9 @@ export default function RoomContent(props: RoomContentProps) {
             case RoomUpdateTypes.changeTimeLimit:
                 roomData.TimeLimit = roomUpdate.data.value;
                 break;
+            case RoomUpdateTypes.changeScoringMode:
+                roomData.ScoringMode = roomUpdate.data.value;
+                break;
             case RoomUpdateTypes.userJoin:
                 if (!roomData.Users) {
                     roomData.Users = [];
@@ -210,6 +216,7 @@ export default function RoomContent(props: RoomContentProps) {
             <div className="room_pane">
                 <GameSettings
                     timeLimit={roomData.TimeLimit}
+                    scoringMode={roomData.ScoringMode || "first"}
                     updateSetting={handleUpdateRoomSettings}
                     problem={problemOverview}
                     setProblem={setProblemOverview}
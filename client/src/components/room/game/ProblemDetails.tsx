@@ -7,6 +7,16 @@ interface ProblemDetailsProps {
     timeLimit: number;
 }
 
+// test cases with more than one argument have an object of arguments keyed by name as their input
+function formatInput(input: any): string {
+    if (input !== null && typeof input === "object" && !Array.isArray(input)) {
+        return Object.entries(input)
+            .map(([name, value]) => `${name} = ${JSON.stringify(value)}`)
+            .join(", ");
+    }
+    return JSON.stringify(input);
+}
+
 export default function ProblemDetails(props: ProblemDetailsProps) {
     if (!props.problem) {
         return (
@@ -45,7 +55,7 @@ export default function ProblemDetails(props: ProblemDetailsProps) {
                 return (
                     <Typography
                         key={`testcase${i}`}
-                    >{`${testCase[0]}  =>  ${testCase[1]}`}</Typography>
+                    >{`${formatInput(testCase[0])}  =>  ${JSON.stringify(testCase[1])}`}</Typography>
                 );
             })}
         </div>
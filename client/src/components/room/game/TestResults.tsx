@@ -61,6 +61,21 @@ export default function TestResults(props: TestResultsProps) {
                                 {props.codeExecResult.passCount}/
                                 {props.codeExecResult.testCount}
                             </Typography>
+                            {props.codeExecResult.results.length > 0 && (
+                                <Typography
+                                    variant="body2"
+                                    fontFamily={"monospace"}
+                                    fontSize={13}
+                                >
+                                    {"> Cases: "}
+                                    {props.codeExecResult.results
+                                        .map(
+                                            (r) =>
+                                                `${r.case + 1}${r.hidden ? "*" : ""}:${r.verdict}`
+                                        )
+                                        .join(" ")}
+                                </Typography>
+                            )}
                             {props.codeExecResult.errorMessage && (
                                 <Typography
                                     variant="body2"
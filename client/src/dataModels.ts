@@ -25,6 +25,8 @@ export interface Room {
     Problem: string
     /** Whether or not this room will play a random problem */
     RandomProblem: boolean
+    /** How the winner is decided: "first" to solve, fastest "runtime" or least "memory" (first to solve if empty) */
+    ScoringMode: string
     /** Whether the room requires a password to join */
     ReqPassword: boolean
     /** Password for this room */
@@ -33,4 +35,25 @@ export interface Room {
     Status: string
     /** Users in this room */
     Users: string[]
+}
+
+/** AC=Accepted, WA=Wrong Answer, TLE=Time Limit Exceeded, MLE=Memory Limit Exceeded, RE=Runtime Error, CE=Compile Error, OLE=Output Limit Exceeded */
+export type Verdict = "AC" | "WA" | "TLE" | "MLE" | "RE" | "CE" | "OLE"
+
+export interface CaseResult {
+    /** index of the test case */
+    case: number
+    verdict: Verdict
+    /** runtime in milliseconds */
+    runtime: number
+    /** peak memory usage in kilobytes, if known */
+    memory: number
+    /** hidden cases don't include their input, expected output, output, stdout or stderr */
+    hidden: boolean
+    input?: string
+    expected?: string
+    /** the answer the solution gave */
+    output?: string
+    stdout?: string
+    stderr?: string
 }
\ No newline at end of file
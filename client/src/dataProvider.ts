@@ -1,4 +1,4 @@
-import { Problem, Room } from "./dataModels";
+import { CaseResult, Problem, Room } from "./dataModels";
 
 /** URL where our server API endpoints can be accessed */
 // set env variable to change between deployed server and locally run
@@ -118,6 +118,8 @@ export interface codeExecResponse {
     passCount: number
     testCount: number
     errorMessage: string
+    /** verdict for each test case that was run */
+    results: CaseResult[]
 }
 
 export async function launchGame(roomID: string, problemID: string, token: string): Promise<boolean> {
@@ -178,7 +180,8 @@ export async function testCode(code: string, lang: string, problemID: string, to
     const result: codeExecResponse = {
         passCount: jsonData.passCount,
         testCount: jsonData.testCount,
-        errorMessage: jsonData.errorMessage
+        errorMessage: jsonData.errorMessage,
+        results: jsonData.results || []
     };
     return result;
 }
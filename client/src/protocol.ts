@@ -0,0 +1,221 @@
+// Code generated by cmd/protocolts from server/handlers/websocket/protocol.go. DO NOT EDIT.
+
+export const protocolVersion = 1;
+
+export type MessageType =
+    | "authorization"
+    | "resume"
+    | "chat"
+    | "room_update"
+    | "game_event"
+    | "error"
+    | "ack";
+
+export type RoomUpdateType =
+    | "CHANGE_DIFFICULTY"
+    | "CHANGE_TIME_LIMIT"
+    | "CHANGE_PROBLEM"
+    | "RANDOM_PROBLEM"
+    | "CHANGE_SCORING_MODE"
+    | "SET_USER_READY"
+    | "USER_JOIN"
+    | "USER_LEAVE"
+    | "LAUNCH_GAME";
+
+export type GameEventType =
+    | "CODE_SUBMIT_RESULT"
+    | "GAME_OVER"
+    | "GAME_REVIEW";
+
+export type ErrorCode =
+    | "bad_message"
+    | "unknown_type"
+    | "invalid"
+    | "unsupported_version"
+    | "unauthorized"
+    | "auth_failed"
+    | "resume_failed"
+    | "forbidden"
+    | "internal";
+
+export type RoomUpdate =
+    | { type: "CHANGE_DIFFICULTY"; data: Difficulty }
+    | { type: "CHANGE_TIME_LIMIT"; data: TimeLimit }
+    | { type: "CHANGE_PROBLEM"; data: ProblemChoice }
+    | { type: "RANDOM_PROBLEM"; data: RandomProblem }
+    | { type: "CHANGE_SCORING_MODE"; data: ScoringMode }
+    | { type: "SET_USER_READY"; data: UserReady }
+    | { type: "USER_JOIN"; data: UserChange }
+    | { type: "USER_LEAVE"; data: UserChange }
+    | { type: "LAUNCH_GAME"; data: GameLaunch };
+
+export type ClientRoomUpdate =
+    | { type: "CHANGE_DIFFICULTY"; data: Difficulty }
+    | { type: "CHANGE_TIME_LIMIT"; data: TimeLimit }
+    | { type: "CHANGE_PROBLEM"; data: ProblemChoice }
+    | { type: "RANDOM_PROBLEM"; data: RandomProblem }
+    | { type: "CHANGE_SCORING_MODE"; data: ScoringMode }
+    | { type: "SET_USER_READY"; data: UserReady };
+
+export type GameEvent =
+    | { type: "CODE_SUBMIT_RESULT"; data: SubmitResult }
+    | { type: "GAME_OVER"; data: GameOver }
+    | { type: "GAME_REVIEW"; data: GameReview };
+
+export interface Envelope<T extends MessageType, P> {
+    v: number;
+    type: T;
+    id?: string;
+    room?: string;
+    seq?: number;
+    timestamp: number;
+    payload: P;
+}
+
+export type ClientMessage =
+    | Envelope<"authorization", Authorization>
+    | Envelope<"resume", Resume>
+    | Envelope<"chat", Chat>
+    | Envelope<"room_update", ClientRoomUpdate>;
+
+export type ServerMessage =
+    | Envelope<"chat", Chat>
+    | Envelope<"room_update", RoomUpdate>
+    | Envelope<"game_event", GameEvent>
+    | Envelope<"error", ErrorMessage>
+    | Envelope<"ack", Ack>;
+
+export interface Difficulty {
+    value: number;
+}
+
+export interface TimeLimit {
+    value: number;
+}
+
+export interface ProblemChoice {
+    value: ProblemOverview | null;
+}
+
+export interface RandomProblem {
+    value: boolean;
+}
+
+export interface ScoringMode {
+    value: string;
+}
+
+export interface UserReady {
+    value: boolean;
+    user: string;
+}
+
+export interface UserChange {
+    value: string;
+}
+
+export interface GameLaunch {
+    value: string;
+}
+
+export interface SubmitResult {
+    value: number;
+    user: string;
+    results: SubmissionCase[] | null;
+    runtime: number;
+    memory: number;
+}
+
+export interface GameOver {
+    value: string;
+    ratings: Record<string, RatingChange> | null;
+}
+
+export interface GameReview {
+    value: string;
+    review: ModelsGameReview;
+}
+
+export interface Authorization {
+    token: string;
+    versions: number[] | null;
+}
+
+export interface Resume {
+    token: string;
+    after: number;
+    versions: number[] | null;
+}
+
+export interface Chat {
+    sender: string;
+    content: string;
+}
+
+export interface ErrorMessage {
+    code: ErrorCode;
+    message: string;
+}
+
+export interface Ack {
+    version?: number;
+    session?: Session | null;
+}
+
+export interface ProblemOverview {
+    id: string;
+    name: string;
+    difficulty: number;
+    quickDesc: string;
+}
+
+export interface SubmissionCase {
+    case: number;
+    verdict: string;
+    runtime: number;
+    memory: number;
+    hidden: boolean;
+}
+
+export interface RatingChange {
+    user: string;
+    gameID: string;
+    place: number;
+    players: number;
+    before: number;
+    after: number;
+    change: number;
+    rd: number;
+    at: string;
+}
+
+export interface ModelsGameReview {
+    gameID: string;
+    roomID: string;
+    problemID: string;
+    winner: string;
+    startedAt: string;
+    endedAt: string;
+    players: PlayerReview[] | null;
+}
+
+export interface Session {
+    token: string;
+    grace: number;
+    resumed: boolean;
+    complete: boolean;
+    progress?: Record<string, number> | null;
+}
+
+export interface PlayerReview {
+    user: string;
+    submissionID?: string;
+    lang?: string;
+    code: string;
+    passCount: number;
+    testCount: number;
+    cases: SubmissionCase[] | null;
+    solved: boolean;
+    solveTime: number;
+    referenceDiff?: string;
+}
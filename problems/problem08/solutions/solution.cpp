@@ -0,0 +1,17 @@
+#include <bits/stdc++.h>
+using namespace std;
+
+vector<double> solution(vector<long long> nums, long long k) {
+	vector<double> averages;
+	long long window = 0;
+	for (long long i = 0; i < (long long)nums.size(); i++) {
+		window += nums[i];
+		if (i >= k) {
+			window -= nums[i - k];
+		}
+		if (i >= k - 1) {
+			averages.push_back((double)window / k);
+		}
+	}
+	return averages;
+}
@@ -0,0 +1,14 @@
+package main
+
+func solution(nums []int, k int) []float64 {
+	window := 0
+	for _, num := range nums[:k] {
+		window += num
+	}
+	averages := []float64{float64(window) / float64(k)}
+	for i := k; i < len(nums); i++ {
+		window += nums[i] - nums[i-k]
+		averages = append(averages, float64(window)/float64(k))
+	}
+	return averages
+}
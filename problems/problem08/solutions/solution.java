@@ -0,0 +1,17 @@
+class Solution {
+	public double[] solution(long[] nums, long k) {
+		int n = (int) k;
+		double[] averages = new double[nums.length - n + 1];
+		long window = 0;
+		for (int i = 0; i < nums.length; i++) {
+			window += nums[i];
+			if (i >= n) {
+				window -= nums[i - n];
+			}
+			if (i >= n - 1) {
+				averages[i - n + 1] = (double) window / n;
+			}
+		}
+		return averages;
+	}
+}
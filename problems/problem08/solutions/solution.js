@@ -0,0 +1,9 @@
+function solution(nums, k) {
+	let window = nums.slice(0, k).reduce((sum, num) => sum + num, 0);
+	const averages = [window / k];
+	for (let i = k; i < nums.length; i++) {
+		window += nums[i] - nums[i - k];
+		averages.push(window / k);
+	}
+	return averages;
+}
@@ -0,0 +1,7 @@
+def solution(nums: list[int], k: int) -> list[float]:
+	window = sum(nums[:k])
+	averages = [window / k]
+	for i in range(k, len(nums)):
+		window += nums[i] - nums[i - k]
+		averages.append(window / k)
+	return averages
@@ -0,0 +1,3 @@
+def solution(nums, k)
+	nums.each_cons(k).map { |window| window.sum.fdiv(k) }
+end
@@ -0,0 +1,3 @@
+fn solution(nums: Vec<i64>, k: i64) -> Vec<f64> {
+	nums.windows(k as usize).map(|window| window.iter().sum::<i64>() as f64 / k as f64).collect()
+}
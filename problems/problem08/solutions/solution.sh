@@ -0,0 +1,13 @@
+solution () {
+	nums=$1
+	k=$2
+	# bash only has integers, so awk works out the averages
+	echo "$nums" | awk -v k="$k" '{
+		window = 0
+		for (i = 1; i <= NF; i++) {
+			window += $i
+			if (i > k) window -= $(i - k)
+			if (i >= k) printf "%.10f ", window / k
+		}
+	}'
+}
@@ -0,0 +1,95 @@
+// Package auth is how the server checks who a user is. the Provider interface has one implementation for each way
+// users can log in, and the AUTH_PROVIDER setting picks which one the server uses: firebase auth, or a local
+// provider that keeps password hashes in the store and issues its own tokens, for self-hosting and tests.
+//
+// whichever provider is used, the claims of a verified token have the user's ID, name and email under the same
+// keys firebase ID tokens use: user_id, name and email.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/webbben/code-duel/config"
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+)
+
+var (
+	// ErrUnsupported is returned for things the provider leaves to its own service, such as firebase logins
+	ErrUnsupported = errors.New("not supported by this server's auth provider")
+	// ErrInvalidLogin is returned when an email and password don't match
+	ErrInvalidLogin = errors.New("incorrect email or password")
+	// ErrInvalidToken is returned when a token is malformed, expired, revoked or wasn't issued by the provider
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+// Provider checks users' logins and tokens
+type Provider interface {
+	// VerifyToken checks a token a client sent and returns its claims
+	VerifyToken(ctx context.Context, token string) (map[string]interface{}, error)
+	// CreateUser gives a user who has just been stored a login with the given password
+	CreateUser(ctx context.Context, user models.User, password string) error
+	// Login checks a user's email and password, and starts a session for them
+	Login(ctx context.Context, email string, password string) (Tokens, error)
+	// Refresh swaps a refresh token for new tokens. the old refresh token can't be used again
+	Refresh(ctx context.Context, refreshToken string) (Tokens, error)
+	// Logout ends the session a refresh token belongs to
+	Logout(ctx context.Context, refreshToken string) error
+}
+
+// the tokens for a session, and who it's for
+type Tokens struct {
+	Token        string      // access token, sent as the bearer token on requests
+	RefreshToken string      // used to get new tokens once the access token expires
+	ExpiresAt    time.Time   // when the access token expires
+	User         models.User // the user the tokens were issued to
+}
+
+var (
+	provider     Provider
+	providerOnce sync.Once
+)
+
+// Get returns the auth provider the server uses, setting up the configured one on first use
+func Get() Provider {
+	providerOnce.Do(func() {
+		provider = open(config.Get())
+	})
+	return provider
+}
+
+// Set replaces the auth provider the server uses
+func Set(p Provider) {
+	providerOnce.Do(func() {})
+	provider = p
+}
+
+func open(cfg config.Config) Provider {
+	switch cfg.AuthProvider {
+	case "firebase":
+		return &FirebaseProvider{}
+	case "local":
+		secret := []byte(cfg.AuthSecret)
+		if len(secret) == 0 {
+			log.Println("WARNING: AUTH_SECRET isn't set, so tokens are signed with a random key and users have to log in again whenever the server restarts")
+			secret = make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				log.Fatalf("failed to make a key for signing tokens: %v\n", err)
+			}
+		}
+		return &LocalProvider{
+			Store:      storage.Get(),
+			Secret:     secret,
+			TokenTTL:   cfg.AuthTokenTTL,
+			RefreshTTL: cfg.AuthRefreshTTL,
+		}
+	default:
+		log.Fatalf("unknown auth provider %q; use firebase or local\n", cfg.AuthProvider)
+		return nil
+	}
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	firebaseAuth "firebase.google.com/go/auth"
+	"github.com/webbben/code-duel/firebase"
+	"github.com/webbben/code-duel/models"
+)
+
+// FirebaseProvider checks tokens with firebase auth. clients log in with firebase directly, so it doesn't handle
+// logins or sessions itself
+type FirebaseProvider struct{}
+
+// the firebase auth client, or an error if firebase isn't set up
+func (p *FirebaseProvider) client() (*firebaseAuth.Client, error) {
+	authClient := firebase.GetAuthClient()
+	if authClient == nil {
+		return nil, errors.New("firebase auth isn't configured on this server")
+	}
+	return authClient, nil
+}
+
+func (p *FirebaseProvider) VerifyToken(ctx context.Context, token string) (map[string]interface{}, error) {
+	authClient, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+	authToken, err := authClient.VerifyIDToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return authToken.Claims, nil
+}
+
+// creates the user in firebase auth, with the same ID as their user document
+func (p *FirebaseProvider) CreateUser(ctx context.Context, user models.User, password string) error {
+	authClient, err := p.client()
+	if err != nil {
+		return err
+	}
+	params := (&firebaseAuth.UserToCreate{}).
+		Email(user.Email).
+		EmailVerified(false).
+		Password(password).
+		UID(user.ID).
+		DisplayName(user.Username)
+	_, err = authClient.CreateUser(ctx, params)
+	return err
+}
+
+func (p *FirebaseProvider) Login(ctx context.Context, email string, password string) (Tokens, error) {
+	return Tokens{}, ErrUnsupported
+}
+
+func (p *FirebaseProvider) Refresh(ctx context.Context, refreshToken string) (Tokens, error) {
+	return Tokens{}, ErrUnsupported
+}
+
+func (p *FirebaseProvider) Logout(ctx context.Context, refreshToken string) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// issuer of the local provider's tokens, so tokens signed for something else with the same key aren't accepted
+const localIssuer = "code-duel"
+
+// LocalProvider keeps bcrypt hashes of users' passwords in the store, and issues its own tokens: short lived
+// access tokens, which are JWTs signed with HS256, and refresh tokens, which are random and stored as sessions.
+// logging out ends the session, but access tokens that were already issued last until they expire
+type LocalProvider struct {
+	Store      storage.Store
+	Secret     []byte        // key tokens are signed with
+	TokenTTL   time.Duration // how long access tokens last
+	RefreshTTL time.Duration // how long refresh tokens last
+}
+
+// compared against when a login's email doesn't exist, so it takes as long as a wrong password does
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("code-duel"), bcrypt.DefaultCost)
+
+// emails are compared without case or surrounding spaces
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (p *LocalProvider) VerifyToken(ctx context.Context, token string) (map[string]interface{}, error) {
+	parsed, err := jwt.Parse(token, func(*jwt.Token) (interface{}, error) {
+		return p.Secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuer(localIssuer), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("%w; %v", ErrInvalidToken, err)
+	}
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (p *LocalProvider) CreateUser(ctx context.Context, user models.User, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password; %w", err)
+	}
+	err = p.Store.CreateCredentials(ctx, models.Credentials{
+		UserID:       user.ID,
+		Email:        normalizeEmail(user.Email),
+		PasswordHash: string(hash),
+	})
+	if errors.Is(err, storage.ErrExists) {
+		return fmt.Errorf("there's already an account for %s", user.Email)
+	}
+	return err
+}
+
+func (p *LocalProvider) Login(ctx context.Context, email string, password string) (Tokens, error) {
+	credentials, err := p.Store.GetCredentials(ctx, normalizeEmail(email))
+	if errors.Is(err, storage.ErrNotFound) {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return Tokens{}, ErrInvalidLogin
+	}
+	if err != nil {
+		return Tokens{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(credentials.PasswordHash), []byte(password)) != nil {
+		return Tokens{}, ErrInvalidLogin
+	}
+	user, err := p.Store.GetUser(ctx, credentials.UserID)
+	if err != nil {
+		return Tokens{}, fmt.Errorf("failed to get user %s; %w", credentials.UserID, err)
+	}
+	return p.issue(ctx, user)
+}
+
+func (p *LocalProvider) Refresh(ctx context.Context, refreshToken string) (Tokens, error) {
+	// each refresh token is used once, so a stolen one stops working as soon as either holder uses it. taking the
+	// session removes it in the same step, so two refreshes with the same token can't both get it
+	session, err := p.Store.TakeSession(ctx, sessionID(refreshToken))
+	if errors.Is(err, storage.ErrNotFound) {
+		return Tokens{}, ErrInvalidToken
+	}
+	if err != nil {
+		return Tokens{}, err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return Tokens{}, ErrInvalidToken
+	}
+	user, err := p.Store.GetUser(ctx, session.UserID)
+	if err != nil {
+		return Tokens{}, fmt.Errorf("failed to get user %s; %w", session.UserID, err)
+	}
+	return p.issue(ctx, user)
+}
+
+func (p *LocalProvider) Logout(ctx context.Context, refreshToken string) error {
+	return p.Store.DeleteSession(ctx, sessionID(refreshToken))
+}
+
+// starts a session for the user, and signs an access token for it
+func (p *LocalProvider) issue(ctx context.Context, user models.User) (Tokens, error) {
+	now := time.Now()
+	expiresAt := now.Add(p.TokenTTL)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":     localIssuer,
+		"sub":     user.ID,
+		"iat":     now.Unix(),
+		"exp":     expiresAt.Unix(),
+		"user_id": user.ID,
+		"name":    user.Username,
+		"email":   user.Email,
+	}).SignedString(p.Secret)
+	if err != nil {
+		return Tokens{}, fmt.Errorf("failed to sign token; %w", err)
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return Tokens{}, fmt.Errorf("failed to make refresh token; %w", err)
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(random)
+	err = p.Store.SaveSession(ctx, models.Session{
+		ID:        sessionID(refreshToken),
+		UserID:    user.ID,
+		ExpiresAt: now.Add(p.RefreshTTL),
+	})
+	if err != nil {
+		return Tokens{}, err
+	}
+	return Tokens{Token: token, RefreshToken: refreshToken, ExpiresAt: expiresAt, User: user}, nil
+}
+
+// the ID of the session a refresh token belongs to
+func sessionID(refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+)
+
+// a local provider with one user, alice, whose password is "hunter22"
+func newTestProvider(t *testing.T) (*LocalProvider, models.User) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	provider := &LocalProvider{Store: store, Secret: []byte("test secret"), TokenTTL: time.Minute, RefreshTTL: time.Hour}
+	user := models.User{Username: "alice", Email: "Alice@example.com"}
+	if err := store.CreateUser(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	if err := provider.CreateUser(ctx, user, "hunter22"); err != nil {
+		t.Fatal(err)
+	}
+	return provider, user
+}
+
+func TestLocalLogin(t *testing.T) {
+	ctx := context.Background()
+	provider, user := newTestProvider(t)
+
+	if err := provider.CreateUser(ctx, models.User{ID: "other", Email: "alice@example.com"}, "password"); err == nil {
+		t.Error("CreateUser should reject an email that already has an account")
+	}
+	if _, err := provider.Login(ctx, "alice@example.com", "wrong"); !errors.Is(err, ErrInvalidLogin) {
+		t.Errorf("Login with the wrong password: %v, expected ErrInvalidLogin", err)
+	}
+	if _, err := provider.Login(ctx, "bob@example.com", "hunter22"); !errors.Is(err, ErrInvalidLogin) {
+		t.Errorf("Login with an unknown email: %v, expected ErrInvalidLogin", err)
+	}
+
+	// emails aren't case sensitive
+	tokens, err := provider.Login(ctx, " ALICE@example.com", "hunter22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens.User != user || tokens.Token == "" || tokens.RefreshToken == "" {
+		t.Errorf("Login: %+v", tokens)
+	}
+	claims, err := provider.VerifyToken(ctx, tokens.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the claims use the same keys as firebase ID tokens
+	if claims["user_id"] != user.ID || claims["name"] != "alice" || claims["email"] != "Alice@example.com" {
+		t.Errorf("token claims: %+v", claims)
+	}
+}
+
+func TestLocalVerifyToken(t *testing.T) {
+	ctx := context.Background()
+	provider, user := newTestProvider(t)
+
+	other := &LocalProvider{Store: provider.Store, Secret: []byte("other secret"), TokenTTL: time.Minute, RefreshTTL: time.Hour}
+	tokens, err := other.issue(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.VerifyToken(ctx, tokens.Token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("token signed with another key: %v, expected ErrInvalidToken", err)
+	}
+
+	expired := &LocalProvider{Store: provider.Store, Secret: provider.Secret, TokenTTL: -time.Minute, RefreshTTL: time.Hour}
+	tokens, err = expired.issue(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.VerifyToken(ctx, tokens.Token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expired token: %v, expected ErrInvalidToken", err)
+	}
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{
+		"iss": localIssuer, "exp": time.Now().Add(time.Minute).Unix(), "user_id": user.ID, "name": "alice", "email": user.Email,
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.VerifyToken(ctx, unsigned); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("unsigned token: %v, expected ErrInvalidToken", err)
+	}
+}
+
+func TestLocalRefreshAndLogout(t *testing.T) {
+	ctx := context.Background()
+	provider, user := newTestProvider(t)
+	tokens, err := provider.Login(ctx, "alice@example.com", "hunter22")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshed, err := provider.Refresh(ctx, tokens.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.User != user || refreshed.RefreshToken == tokens.RefreshToken {
+		t.Errorf("Refresh: %+v", refreshed)
+	}
+	if _, err := provider.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("reusing a refresh token: %v, expected ErrInvalidToken", err)
+	}
+
+	if err := provider.Logout(ctx, refreshed.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Refresh(ctx, refreshed.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("refreshing after logging out: %v, expected ErrInvalidToken", err)
+	}
+
+	expiring := &LocalProvider{Store: provider.Store, Secret: provider.Secret, TokenTTL: time.Minute, RefreshTTL: -time.Minute}
+	expired, err := expiring.issue(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Refresh(ctx, expired.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expired refresh token: %v, expected ErrInvalidToken", err)
+	}
+}
+
+func TestLocalRefreshRace(t *testing.T) {
+	ctx := context.Background()
+	provider, _ := newTestProvider(t)
+	tokens, err := provider.Login(ctx, "alice@example.com", "hunter22")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// however the refreshes interleave, only one of them gets to use the token
+	var wg sync.WaitGroup
+	var refreshed atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := provider.Refresh(ctx, tokens.RefreshToken); err == nil {
+				refreshed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if refreshed.Load() != 1 {
+		t.Errorf("%d refreshes with the same token succeeded, expected 1", refreshed.Load())
+	}
+}
@@ -0,0 +1,128 @@
+// Package checkers has the built in ways of deciding whether a solution's answer is correct.
+// Answers and expected outputs are compared in the form models.Type.Encode gives them.
+package checkers
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// the answer must be exactly the expected output
+func Exact() models.Checker {
+	return models.CheckerFunc(func(args []any, expected any, output any) bool {
+		return equal(expected, output, exactScalar)
+	})
+}
+
+// strings in the answer must have the same words (runs of non-space characters) as the expected output,
+// but the spaces between them can differ
+func Tokens() models.Checker {
+	return models.CheckerFunc(func(args []any, expected any, output any) bool {
+		return equal(expected, output, func(e any, o any) bool {
+			eString, eOK := e.(string)
+			oString, oOK := o.(string)
+			if eOK && oOK {
+				return slices.Equal(strings.Fields(eString), strings.Fields(oString))
+			}
+			return exactScalar(e, o)
+		})
+	})
+}
+
+// numbers in the answer must be within absolute or relative epsilon of the expected output.
+// an epsilon of 0 isn't used
+func Float(absolute float64, relative float64) models.Checker {
+	return models.CheckerFunc(func(args []any, expected any, output any) bool {
+		return equal(expected, output, func(e any, o any) bool {
+			eNumber, eOK := e.(float64)
+			oNumber, oOK := o.(float64)
+			if !eOK || !oOK {
+				return exactScalar(e, o)
+			}
+			diff := math.Abs(eNumber - oNumber)
+			return diff == 0 ||
+				(absolute > 0 && diff <= absolute) ||
+				(relative > 0 && diff <= relative*math.Max(math.Abs(eNumber), math.Abs(oNumber)))
+		})
+	})
+}
+
+// the answer must be a list with the same elements as the expected output, in any order
+func Unordered() models.Checker {
+	return models.CheckerFunc(func(args []any, expected any, output any) bool {
+		eList, eOK := expected.([]any)
+		oList, oOK := output.([]any)
+		if !eOK || !oOK {
+			return equal(expected, output, exactScalar)
+		}
+		return slices.Equal(sortedKeys(eList), sortedKeys(oList))
+	})
+}
+
+// the answer must be a list with the same distinct elements as the expected output, in any order
+// and ignoring duplicates
+func Set() models.Checker {
+	return models.CheckerFunc(func(args []any, expected any, output any) bool {
+		eList, eOK := expected.([]any)
+		oList, oOK := output.([]any)
+		if !eOK || !oOK {
+			return equal(expected, output, exactScalar)
+		}
+		return slices.Equal(slices.Compact(sortedKeys(eList)), slices.Compact(sortedKeys(oList)))
+	})
+}
+
+// compares two values, going into lists and maps, and comparing everything else with the scalar function
+func equal(expected any, output any, scalar func(e any, o any) bool) bool {
+	switch e := expected.(type) {
+	case []any:
+		o, ok := output.([]any)
+		if !ok || len(e) != len(o) {
+			return false
+		}
+		for i := range e {
+			if !equal(e[i], o[i], scalar) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		o, ok := output.(map[string]any)
+		if !ok || len(e) != len(o) {
+			return false
+		}
+		for key, value := range e {
+			outputValue, ok := o[key]
+			if !ok || !equal(value, outputValue, scalar) {
+				return false
+			}
+		}
+		return true
+	default:
+		return scalar(expected, output)
+	}
+}
+
+func exactScalar(e any, o any) bool {
+	switch o.(type) {
+	case []any, map[string]any:
+		return false
+	}
+	return e == o
+}
+
+// the elements of a list as JSON, sorted, so lists can be compared without caring about order
+func sortedKeys(list []any) []string {
+	keys := make([]string, len(list))
+	for i, element := range list {
+		encoded, _ := json.Marshal(element)
+		keys[i] = string(encoded)
+	}
+	sort.Strings(keys)
+	return keys
+}
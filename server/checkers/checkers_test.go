@@ -0,0 +1,46 @@
+package checkers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/webbben/code-duel/models"
+)
+
+type CheckerTestCase struct {
+	Checker  models.Checker
+	Expected any
+	Output   any
+	Correct  bool
+}
+
+func TestCheckers(t *testing.T) {
+	var testCases = []CheckerTestCase{
+		{Checker: Exact(), Expected: []any{1.0, 2.0}, Output: []any{1.0, 2.0}, Correct: true},
+		{Checker: Exact(), Expected: []any{1.0, 2.0}, Output: []any{2.0, 1.0}, Correct: false},
+		{Checker: Exact(), Expected: map[string]any{"a": "x"}, Output: map[string]any{"a": "x"}, Correct: true},
+		{Checker: Exact(), Expected: map[string]any{"a": "x"}, Output: map[string]any{"b": "x"}, Correct: false},
+		{Checker: Exact(), Expected: "1", Output: 1.0, Correct: false},
+		{Checker: Exact(), Expected: nil, Output: []any{}, Correct: false},
+		{Checker: Tokens(), Expected: "hello world", Output: "  hello\n world ", Correct: true},
+		{Checker: Tokens(), Expected: []any{"a b"}, Output: []any{"a  b"}, Correct: true},
+		{Checker: Tokens(), Expected: "hello world", Output: "helloworld", Correct: false},
+		{Checker: Float(1e-6, 0), Expected: 0.1, Output: 0.1000001, Correct: true},
+		{Checker: Float(1e-6, 0), Expected: 0.1, Output: 0.101, Correct: false},
+		{Checker: Float(0, 1e-6), Expected: 1e9, Output: 1e9 + 100, Correct: true},
+		{Checker: Float(0, 1e-6), Expected: 1e9, Output: 1e9 + 10000, Correct: false},
+		{Checker: Float(1e-6, 0), Expected: []any{1.0, 2.0}, Output: []any{1.0000001, 1.9999999}, Correct: true},
+		{Checker: Unordered(), Expected: []any{1.0, 2.0, 2.0}, Output: []any{2.0, 1.0, 2.0}, Correct: true},
+		{Checker: Unordered(), Expected: []any{1.0, 2.0, 2.0}, Output: []any{2.0, 1.0, 1.0}, Correct: false},
+		{Checker: Unordered(), Expected: []any{[]any{1.0}, []any{2.0}}, Output: []any{[]any{2.0}, []any{1.0}}, Correct: true},
+		{Checker: Set(), Expected: []any{1.0, 2.0}, Output: []any{2.0, 1.0, 2.0}, Correct: true},
+		{Checker: Set(), Expected: []any{1.0, 2.0}, Output: []any{2.0, 3.0}, Correct: false},
+	}
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("Checker test %v", i), func(t *testing.T) {
+			if correct := testCase.Checker.Check(nil, testCase.Expected, testCase.Output); correct != testCase.Correct {
+				t.Errorf("Result: [%v] Expected: [%v] for %v and %v", correct, testCase.Correct, testCase.Expected, testCase.Output)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// problemcheck checks that problems can be solved through the real grading pipeline: that each problem's test
+// cases match its signature, that its code template compiles in every language, and that its reference
+// solutions pass all of its test cases. languages a problem has no reference solution for are skipped.
+//
+//	go run ./cmd/problemcheck [-problem id] [-lang lang] [-dir problems directory] [-stress count] [-seed seed]
+//
+// with -stress, the reference solutions are also run against that many random test cases from each problem's
+// generator, with expected outputs from its sample solution.
+//
+// code runs on the executor the server configuration chooses, which is this machine unless CODE_EXECUTOR=remote.
+// it exits with status 1 if any check fails.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/webbben/code-duel/config"
+	"github.com/webbben/code-duel/handlers/code"
+	"github.com/webbben/code-duel/languages"
+	"github.com/webbben/code-duel/models"
+	problemData "github.com/webbben/code-duel/problem_data"
+)
+
+func main() {
+	problemID := flag.String("problem", "", "only check the problem with this ID")
+	lang := flag.String("lang", "", "only check this language")
+	dir := flag.String("dir", config.Get().ProblemsDir, "directory of problem directories to load, alongside the built in problems")
+	stressCount := flag.Int("stress", 0, "how many generated test cases to run the reference solutions against")
+	seed := flag.Int64("seed", 1, "seed for the generated test cases")
+	flag.Parse()
+
+	failed := false
+	if _, err := os.Stat(*dir); err == nil {
+		if err := problemData.LoadProblems(*dir); err != nil {
+			fmt.Printf("FAIL loading problems from %s:\n%s\n", *dir, err)
+			failed = true
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("FAIL loading problems from %s: %s\n", *dir, err)
+		failed = true
+	}
+
+	langs := languages.IDs()
+	if *lang != "" {
+		language, ok := languages.Get(*lang)
+		if !ok {
+			fmt.Printf("FAIL language %s not supported\n", *lang)
+			os.Exit(1)
+		}
+		langs = []string{language.ID}
+	}
+	for _, problem := range problems(*problemID) {
+		for _, failure := range checkProblem(problem, langs, *stressCount, *seed) {
+			fmt.Printf("FAIL %s %s\n", problem.ID, failure)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+	fmt.Println("all problems passed")
+}
+
+// the problems to check, in order of their IDs
+func problems(problemID string) []models.Problem {
+	var problems []models.Problem
+	for _, overview := range problemData.GetProblemOverviews() {
+		if problemID == "" || overview.ID == problemID {
+			problems = append(problems, *problemData.GetProblemByID(overview.ID))
+		}
+	}
+	slices.SortFunc(problems, func(a, b models.Problem) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return problems
+}
+
+// runs every check on a problem, and describes each one that failed
+func checkProblem(problem models.Problem, langs []string, stressCount int, seed int64) []string {
+	fmt.Printf("checking %s (%s)\n", problem.ID, problem.Name)
+	if err := problemData.ValidateProblem(problem); err != nil {
+		// nothing else can be checked if the test cases don't fit the signature
+		return []string{err.Error()}
+	}
+	var stressProblem *models.Problem
+	if stressCount > 0 && problem.Generate != nil {
+		stressProblem = &models.Problem{
+			ProblemOverview: problem.ProblemOverview,
+			Signature:       problem.Signature,
+			Checker:         problem.Checker,
+			FullCases:       problem.Generate(seed, stressCount),
+			Solutions:       problem.Solutions,
+		}
+		if err := problemData.ValidateProblem(*stressProblem); err != nil {
+			return []string{fmt.Sprintf("generated %s", err)}
+		}
+	}
+	var failures []string
+	for _, lang := range langs {
+		if failure := checkTemplate(problem, lang); failure != "" {
+			failures = append(failures, fmt.Sprintf("%s template: %s", lang, failure))
+		}
+		// newer languages don't have reference solutions for every problem yet
+		if _, ok := problem.Solutions[lang]; !ok {
+			fmt.Printf("  no %s reference solution, skipping it\n", lang)
+			continue
+		}
+		if failure := checkSolution(problem, lang); failure != "" {
+			failures = append(failures, fmt.Sprintf("%s solution: %s", lang, failure))
+		}
+		if stressProblem == nil {
+			continue
+		}
+		if failure := checkSolution(*stressProblem, lang); failure != "" {
+			failures = append(failures, fmt.Sprintf("%s solution on %d generated cases (seed %d): %s", lang, stressCount, seed, failure))
+		}
+	}
+	return failures
+}
+
+// the template doesn't solve the problem, but it should run without errors
+func checkTemplate(problem models.Problem, lang string) string {
+	language, _ := languages.Get(lang)
+	template := language.ProblemTemplate(problem)
+	results := code.RunProblemTests(context.Background(), template, lang, problem, false, false)
+	if len(results.Cases) == 0 {
+		return results.ErrorMessage
+	}
+	for _, caseResult := range results.Cases {
+		if caseResult.Verdict == code.VerdictCompileError || caseResult.Verdict == code.VerdictRuntimeError {
+			return results.ErrorMessage
+		}
+	}
+	return ""
+}
+
+// the reference solution should pass every test case, hidden ones included
+func checkSolution(problem models.Problem, lang string) string {
+	solution, ok := problem.Solutions[lang]
+	if !ok {
+		return "no reference solution"
+	}
+	results := code.RunProblemTests(context.Background(), solution, lang, problem, true, false)
+	if results.PassCount != results.TestCount {
+		return fmt.Sprintf("passed %d/%d: %s", results.PassCount, results.TestCount, results.ErrorMessage)
+	}
+	return ""
+}
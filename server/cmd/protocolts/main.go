@@ -0,0 +1,30 @@
+// protocolts writes the TypeScript definitions of the websocket protocol, made from its Go types in
+// handlers/websocket. go generate runs it for the client:
+//
+//	go run ./cmd/protocolts [-o file]
+//
+// without -o, the definitions are written to stdout.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/webbben/code-duel/handlers/websocket"
+)
+
+func main() {
+	output := flag.String("o", "", "file to write the definitions to")
+	flag.Parse()
+
+	definitions := websocket.TypeScript()
+	if *output == "" {
+		fmt.Print(definitions)
+		return
+	}
+	if err := os.WriteFile(*output, []byte(definitions), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write %s: %v\n", *output, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,135 @@
+// server configuration, read from environment variables
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// code execution
+	CodeExecutor       string        // which code executor backend to use: "local" (default) or "remote"
+	CodeExecURL        string        // URL of the remote code execution service, which has to speak version 2 of its contract
+	CodeExecWorkDir    string        // directory the local executor creates its temp directories in (defaults to the OS temp dir)
+	CodeExecNamespaces bool          // whether the local executor runs code in separate linux namespaces
+	CodeExecCPUTime    time.Duration // CPU time limit for a single execution
+	CodeExecWallTime   time.Duration // wall-clock limit for a single execution
+	CodeExecMemory     int64         // memory limit for a single execution, in bytes
+	CodeExecOutput     int64         // limit on the amount of output an execution can produce, in bytes
+	CodeExecWorkers    int           // how many test cases can run at the same time (0 uses the executor's default)
+	CodeExecFailFast   bool          // stop running test cases after the first failure, instead of collecting every verdict
+	CodeExecBatchSize  int           // how many test cases to run in a single execution (0 runs them all at once)
+
+	// problems
+	ProblemsDir string // directory of problem directories to load on startup, alongside the built in problems
+
+	// storage
+	Store     string // where users, rooms, games and submissions are kept: "firestore" (default), "bolt" or "memory"
+	StorePath string // file the bolt database is kept in
+
+	// authentication
+	AuthProvider   string        // who checks logins and tokens: "firebase" (default) or "local"
+	AuthSecret     string        // key the local provider signs its tokens with
+	AuthTokenTTL   time.Duration // how long the local provider's access tokens last
+	AuthRefreshTTL time.Duration // how long the local provider's refresh tokens last
+
+	// websockets
+	WSPingInterval time.Duration // how often the server pings each connection
+	WSPongWait     time.Duration // how long a connection can go without answering a ping before it's closed as dead; should be longer than WSPingInterval
+	WSWriteWait    time.Duration // how long writing one message to a connection can take
+	WSAuthTimeout  time.Duration // how long a new connection has to send its authorization message
+	WSIdleTimeout  time.Duration // how long a connection can go without sending any messages before it's closed (0 never closes idle connections)
+	WSResumeGrace  time.Duration // how long a player whose connection dropped keeps their place in the room, so they can resume their session (0 removes them straight away)
+}
+
+var (
+	config     Config
+	configOnce sync.Once
+)
+
+// Get returns the server configuration, loading it from the environment on first use
+func Get() Config {
+	configOnce.Do(func() {
+		config = load()
+	})
+	return config
+}
+
+func load() Config {
+	return Config{
+		CodeExecutor:       getString("CODE_EXECUTOR", "local"),
+		CodeExecURL:        getString("CODE_EXEC_URL", ""),
+		CodeExecWorkDir:    getString("CODE_EXEC_WORKDIR", ""),
+		CodeExecNamespaces: getBool("CODE_EXEC_NAMESPACES", true),
+		CodeExecCPUTime:    getDuration("CODE_EXEC_CPU_TIME", 5*time.Second),
+		CodeExecWallTime:   getDuration("CODE_EXEC_WALL_TIME", 10*time.Second),
+		CodeExecMemory:     getInt("CODE_EXEC_MEMORY_MB", 256) << 20,
+		CodeExecOutput:     getInt("CODE_EXEC_OUTPUT_KB", 64) << 10,
+		CodeExecWorkers:    int(getInt("CODE_EXEC_WORKERS", 0)),
+		CodeExecFailFast:   getBool("CODE_EXEC_FAIL_FAST", false),
+		CodeExecBatchSize:  int(getInt("CODE_EXEC_BATCH_SIZE", 0)),
+		ProblemsDir:        getString("PROBLEMS_DIR", "../problems"),
+		Store:              getString("STORE", "firestore"),
+		StorePath:          getString("STORE_PATH", "code-duel.db"),
+		AuthProvider:       getString("AUTH_PROVIDER", "firebase"),
+		AuthSecret:         getString("AUTH_SECRET", ""),
+		AuthTokenTTL:       getDuration("AUTH_TOKEN_TTL", 15*time.Minute),
+		AuthRefreshTTL:     getDuration("AUTH_REFRESH_TTL", 30*24*time.Hour),
+		WSPingInterval:     getDuration("WS_PING_INTERVAL", 30*time.Second),
+		WSPongWait:         getDuration("WS_PONG_WAIT", 60*time.Second),
+		WSWriteWait:        getDuration("WS_WRITE_WAIT", 10*time.Second),
+		WSAuthTimeout:      getDuration("WS_AUTH_TIMEOUT", 15*time.Second),
+		WSIdleTimeout:      getDuration("WS_IDLE_TIMEOUT", 2*time.Hour),
+		WSResumeGrace:      getDuration("WS_RESUME_GRACE", 30*time.Second),
+	}
+}
+
+func getString(key string, fallback string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func getBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("config: invalid boolean for %s (%q); using default %v\n", key, value, fallback)
+		return fallback
+	}
+	return b
+}
+
+func getInt(key string, fallback int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("config: invalid integer for %s (%q); using default %v\n", key, value, fallback)
+		return fallback
+	}
+	return i
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("config: invalid duration for %s (%q); using default %v\n", key, value, fallback)
+		return fallback
+	}
+	return d
+}
@@ -0,0 +1,51 @@
+// Package diff compares texts line by line.
+package diff
+
+import "strings"
+
+// Lines describes how to get from one text to the other, a line at a time. each line of the result is a line of
+// either text, marked "  " if it's in both, "- " if it's only in from, and "+ " if it's only in to. blank lines at
+// the start and end of each text, and carriage returns, are ignored
+func Lines(from string, to string) string {
+	a, b := splitLines(from), splitLines(to)
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var result strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			result.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			result.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			result.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+	return result.String()
+}
+
+func splitLines(text string) []string {
+	text = strings.Trim(strings.ReplaceAll(text, "\r", ""), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,22 @@
+package diff
+
+import "testing"
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		from, to string
+		diff     string
+	}{
+		{from: "", to: "", diff: ""},
+		{from: "a\nb\n", to: "\na\nb", diff: "  a\n  b\n"},
+		{from: "a\nb\nc", to: "a\nc", diff: "  a\n- b\n  c\n"},
+		{from: "a\nc", to: "a\r\nb\r\nc\r\n", diff: "  a\n+ b\n  c\n"},
+		{from: "a\nb", to: "a\nx", diff: "  a\n- b\n+ x\n"},
+		{from: "", to: "x\ny", diff: "+ x\n+ y\n"},
+	}
+	for _, test := range tests {
+		if diff := Lines(test.from, test.to); diff != test.diff {
+			t.Errorf("Lines(%q, %q) = %q, expected %q", test.from, test.to, diff, test.diff)
+		}
+	}
+}
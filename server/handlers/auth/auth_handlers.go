@@ -2,12 +2,15 @@ package authHandlers
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
 	"strings"
 
-	"github.com/webbben/code-duel/firebase"
+	"github.com/webbben/code-duel/auth"
 	"github.com/webbben/code-duel/handlers/general"
+	"github.com/webbben/code-duel/models"
 )
 
 type Claims struct {
@@ -95,15 +98,81 @@ func GetUserClaimsFromContext(r *http.Request) (claims Claims, err error) {
 	return
 }
 
-// Verifies a given token and returns the associated claims map
+// Verifies a given token with the server's auth provider and returns the associated claims map
 func VerifyTokenAndGetClaims(token string) (claims map[string]interface{}, err error) {
-	ctx := context.Background()
-	// validate token
-	authClient := firebase.GetAuthClient()
-	authToken, err := authClient.VerifyIDToken(ctx, token)
+	claims, err = auth.Get().VerifyToken(context.Background(), token)
 	if err != nil {
-		return
+		err = errors.New("Unauthorized: " + err.Error())
 	}
-	claims = authToken.Claims
 	return
 }
+
+// logs in with an email and password, and returns the session's tokens. only the local auth provider handles
+// logins; with firebase, clients log in with firebase directly
+func LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var request models.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.Email == "" || request.Password == "" {
+		http.Error(w, "email and password are required", http.StatusBadRequest)
+		return
+	}
+	tokens, err := auth.Get().Login(r.Context(), request.Email, request.Password)
+	writeTokens(w, tokens, err)
+}
+
+// swaps a refresh token for a new access token and refresh token
+func RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	var request models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.RefreshToken == "" {
+		http.Error(w, "refreshToken is required", http.StatusBadRequest)
+		return
+	}
+	tokens, err := auth.Get().Refresh(r.Context(), request.RefreshToken)
+	writeTokens(w, tokens, err)
+}
+
+// ends the session a refresh token belongs to
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	var request models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.RefreshToken == "" {
+		http.Error(w, "refreshToken is required", http.StatusBadRequest)
+		return
+	}
+	err := auth.Get().Logout(r.Context(), request.RefreshToken)
+	if err != nil {
+		http.Error(w, err.Error(), authErrorStatus(err))
+		return
+	}
+	general.WriteResponse(w, true, nil)
+}
+
+// writes a session's tokens and user info, in the same form VerifyTokenHandler writes user info
+func writeTokens(w http.ResponseWriter, tokens auth.Tokens, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), authErrorStatus(err))
+		return
+	}
+	general.WriteResponse(w, true, map[string]interface{}{
+		"token":        tokens.Token,
+		"refreshToken": tokens.RefreshToken,
+		"expiresAt":    tokens.ExpiresAt,
+		"userID":       tokens.User.ID,
+		"username":     tokens.User.Username,
+		"email":        tokens.User.Email,
+	})
+}
+
+// the HTTP status for an error from the auth provider
+func authErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, auth.ErrUnsupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, auth.ErrInvalidLogin), errors.Is(err, auth.ErrInvalidToken):
+		return http.StatusUnauthorized
+	}
+	log.Printf("auth error: %v\n", err)
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,76 @@
+package authHandlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webbben/code-duel/auth"
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+)
+
+// sends a POST request to the handler, and decodes the response body
+func post(t *testing.T, handler http.HandlerFunc, body string, header string) (int, map[string]interface{}) {
+	t.Helper()
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	if header != "" {
+		r.Header.Set("Authorization", header)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response
+}
+
+func TestLocalSession(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	provider := &auth.LocalProvider{Store: store, Secret: []byte("test secret"), TokenTTL: time.Minute, RefreshTTL: time.Hour}
+	auth.Set(provider)
+	user := models.User{Username: "alice", Email: "alice@example.com"}
+	store.CreateUser(ctx, &user)
+	if err := provider.CreateUser(ctx, user, "hunter22"); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, _ := post(t, LoginHandler, `{"email": "alice@example.com", "password": "wrong"}`, ""); status != http.StatusUnauthorized {
+		t.Errorf("login with the wrong password: %d, expected %d", status, http.StatusUnauthorized)
+	}
+	status, login := post(t, LoginHandler, `{"email": "alice@example.com", "password": "hunter22"}`, "")
+	if status != http.StatusOK || login["userID"] != user.ID || login["username"] != "alice" {
+		t.Fatalf("login: %d %+v", status, login)
+	}
+
+	// the token works anywhere a firebase token would
+	status, verified := post(t, VerifyTokenHandler, "", "Bearer "+login["token"].(string))
+	if status != http.StatusOK || verified["userID"] != user.ID || verified["username"] != "alice" || verified["email"] != "alice@example.com" {
+		t.Errorf("verifying the token: %d %+v", status, verified)
+	}
+	if status, _ := post(t, VerifyTokenHandler, "", "Bearer nonsense"); status != http.StatusUnauthorized {
+		t.Errorf("verifying a bad token: %d, expected %d", status, http.StatusUnauthorized)
+	}
+
+	status, refreshed := post(t, RefreshHandler, `{"refreshToken": "`+login["refreshToken"].(string)+`"}`, "")
+	if status != http.StatusOK || refreshed["token"] == "" || refreshed["refreshToken"] == login["refreshToken"] {
+		t.Fatalf("refresh: %d %+v", status, refreshed)
+	}
+	if status, _ := post(t, LogoutHandler, `{"refreshToken": "`+refreshed["refreshToken"].(string)+`"}`, ""); status != http.StatusOK {
+		t.Errorf("logout: %d", status)
+	}
+	if status, _ := post(t, RefreshHandler, `{"refreshToken": "`+refreshed["refreshToken"].(string)+`"}`, ""); status != http.StatusUnauthorized {
+		t.Errorf("refresh after logging out: %d, expected %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestFirebaseLogin(t *testing.T) {
+	auth.Set(&auth.FirebaseProvider{})
+	if status, _ := post(t, LoginHandler, `{"email": "alice@example.com", "password": "hunter22"}`, ""); status != http.StatusNotImplemented {
+		t.Errorf("login with firebase auth: %d, expected %d", status, http.StatusNotImplemented)
+	}
+}
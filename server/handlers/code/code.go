@@ -1,27 +1,26 @@
 package code
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"reflect"
 	"slices"
+	"time"
 
+	"github.com/webbben/code-duel/config"
 	authHandlers "github.com/webbben/code-duel/handlers/auth"
 	"github.com/webbben/code-duel/handlers/general"
 	"github.com/webbben/code-duel/handlers/websocket"
+	"github.com/webbben/code-duel/languages"
 	"github.com/webbben/code-duel/models"
 	problemData "github.com/webbben/code-duel/problem_data"
+	"github.com/webbben/code-duel/rooms"
+	"github.com/webbben/code-duel/storage"
 )
 
-// languages we know our code execution service supports
-var supportedLangs []string = []string{"python", "go", "bash"}
-
-var codeExecURL = "https://code-exec-microservice.fly.dev/"
-
 type CodeSubmitRequest struct {
 	ProblemID string `json:"problemID"`
 	Lang      string `json:"lang"`
@@ -29,16 +28,6 @@ type CodeSubmitRequest struct {
 	RoomID    string `json:"roomID"`
 }
 
-type ExecCodeRequest struct {
-	Lang string `json:"lang"`
-	Code string `json:"code"`
-}
-
-type ExecCodeResponse struct {
-	Output string `json:"output"`
-	Error  bool   `json:"error"`
-}
-
 // Handles a code test
 func HandleTestCode(w http.ResponseWriter, r *http.Request) {
 	codeSubmission(w, r, false)
@@ -49,6 +38,21 @@ func HandleSubmitCode(w http.ResponseWriter, r *http.Request) {
 	codeSubmission(w, r, true)
 }
 
+// runs code against a problem's test cases. the basic test cases are shown to players, but the extra cases for a
+// full test are hidden
+func RunProblemTests(ctx context.Context, code string, lang string, problem models.Problem, fullTest bool, failFast bool) TestResults {
+	testCases := make([]testCaseRun, 0, len(problem.TestCases)+len(problem.FullCases))
+	for _, testCase := range problem.TestCases {
+		testCases = append(testCases, testCaseRun{testCase: testCase})
+	}
+	if fullTest {
+		for _, testCase := range problem.FullCases {
+			testCases = append(testCases, testCaseRun{testCase: testCase, hidden: true})
+		}
+	}
+	return runTests(ctx, code, lang, problem, testCases, failFast, config.Get().CodeExecBatchSize)
+}
+
 // Handles a code submission request. If a full test, will run against all tests for a problem, not just the basic cases.
 func codeSubmission(w http.ResponseWriter, r *http.Request, fullTest bool) {
 	// get the user who is sending this request
@@ -68,7 +72,8 @@ func codeSubmission(w http.ResponseWriter, r *http.Request, fullTest bool) {
 		http.Error(w, "Request missing required information", http.StatusBadRequest)
 		return
 	}
-	if !slices.Contains(supportedLangs, req.Lang) {
+	lang, ok := languages.Get(req.Lang)
+	if !ok {
 		http.Error(w, fmt.Sprintf("Language %s not supported", req.Lang), http.StatusBadRequest)
 		return
 	}
@@ -78,172 +83,86 @@ func codeSubmission(w http.ResponseWriter, r *http.Request, fullTest bool) {
 		http.Error(w, fmt.Sprintf("Testing code: problem %s not found", req.ProblemID), http.StatusBadRequest)
 		return
 	}
-	testCases := problem.TestCases
-	if fullTest {
-		testCases = append(testCases, problem.FullCases...)
-	}
-	// run the tests and report the outcome
-	passCount, testCount, errorMessage := runTests(req.Code, req.Lang, testCases)
-	response := map[string]interface{}{
-		"passCount":    passCount,
-		"testCount":    testCount,
-		"errorMessage": errorMessage,
-	}
-	websocket.UpdateGameState(claims.DisplayName, req.RoomID, "CODE_SUBMIT_RESULT", map[string]interface{}{
-		"passCount": passCount,
-	})
-	general.WriteResponse(w, true, response)
-}
-
-func runTests(code string, lang string, testCases []models.TestCase) (passCount int, testCount int, errorMessage string) {
-	testCount = len(testCases)
-	passCount = 0
-	errorMessage = ""
-
-	for _, testCase := range testCases {
-		input, expOut := testCase[0], testCase[1]
-
-		result, err := runTestCase(code, lang, input)
-		if err != nil {
-			errorMessage = fmt.Sprintf("Error during execution: %s", err.Error())
-			break
-		}
-		expOutFmt := formatInput(lang, expOut, true) // get the correctly formatted inputs and outputs
-		inputFmt := formatInput(lang, input, false)
-		log.Printf("Output: [%s] Expected: [%s]\n", result, expOutFmt)
-		if result != expOutFmt {
-			errorMessage = fmt.Sprintf("Failed test case [%s]: Result [%s] Expected [%s]", inputFmt, result, expOutFmt)
-			break
-		}
-		passCount++
-	}
-	return
-}
-
-func runTestCase(code string, lang string, input any) (string, error) {
-	inputFmt := formatInput(lang, input, false)
-	codeWithInput := fmt.Sprintf(code, inputFmt)
-	reqBody := map[string]interface{}{
-		"lang": lang,
-		"code": codeWithInput,
-	}
-	log.Printf("Running test for %s code...", lang)
-	// make request to code execution service
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", errors.New("Internal server error: failed to marshal request json")
-	}
-	response, err := http.Post(codeExecURL, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", errors.New("Internal server error: failed to communicate with code execution service")
+	// only the room's members can run code in it, and while it's in game, only the game's players
+	room, err := rooms.GetRoom(req.RoomID)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("Room %s not found", req.RoomID), http.StatusNotFound)
+		return
 	}
-	// read response
-	var execCodeResponse ExecCodeResponse
-	err = json.NewDecoder(response.Body).Decode(&execCodeResponse)
 	if err != nil {
-		return "", errors.New("Internal server error: failed to read executed code response body")
-	}
-	if execCodeResponse.Error {
-		return "", errors.New(execCodeResponse.Output)
-	}
-	log.Printf("... result: %s", execCodeResponse.Output)
-	return execCodeResponse.Output, nil
-}
-
-// formats the input value to the correct format for the given language
-func formatInput(lang string, input any, forOutput bool) string {
-	switch lang {
-	case "go":
-		// go has built in support for this!
-		return fmt.Sprintf("%#v", input)
-	case "python":
-		return formatPython(input, forOutput)
-	case "bash":
-		return formatBash(input, forOutput)
-	default:
-		// default to putting it in quotes like a string
-		return fmt.Sprintf("\"%s\"", input)
-	}
-}
-
-func formatPython(input any, forOutput bool) string {
-	inputType := reflect.TypeOf(input)
-	inputValue := reflect.ValueOf(input)
-	kind := inputType.Kind()
-
-	// input is array/slice, so format its individual elements
-	if kind == reflect.Array || kind == reflect.Slice {
-		inputFmt := "["
-		for i := 0; i < inputValue.Len(); i++ {
-			element := inputValue.Index(i).Interface()
-			inputFmt += formatPython(element, forOutput)
-			if i < inputValue.Len()-1 {
-				inputFmt += ","
-			}
-		}
-		inputFmt += "]"
-		return inputFmt
-	}
-	// input is string
-	if kind == reflect.String {
-		if forOutput {
-			return fmt.Sprintf("%s", input) // no quotes for output
-		}
-		return fmt.Sprintf("\"%s\"", input)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
-	// input is boolean
-	if kind == reflect.Bool {
-		boolVal := inputValue.Bool()
-		if boolVal {
-			return "True"
-		}
-		return "False"
+	if !slices.Contains(room.Users, claims.DisplayName) {
+		http.Error(w, fmt.Sprintf("Forbidden: user %s isn't in room %s", claims.DisplayName, req.RoomID), http.StatusForbidden)
+		return
 	}
-	// input is number
-	if kind == reflect.Int || kind == reflect.Int64 || kind == reflect.Float64 {
-		return fmt.Sprintf("%v", input)
+	// run the tests and report the outcome
+	submittedAt, gameID := time.Now(), websocket.GetGameID(req.RoomID)
+	if gameID != "" && !websocket.IsGamePlayer(req.RoomID, claims.DisplayName) {
+		http.Error(w, fmt.Sprintf("Forbidden: user %s isn't playing in room %s's game", claims.DisplayName, req.RoomID), http.StatusForbidden)
+		return
 	}
-	return fmt.Sprintf("%s", input)
+	results := RunProblemTests(r.Context(), req.Code, lang.ID, *problem, fullTest, config.Get().CodeExecFailFast)
+	submission := models.Submission{
+		User:         claims.DisplayName,
+		RoomID:       req.RoomID,
+		GameID:       gameID,
+		ProblemID:    problem.ID,
+		Lang:         lang.ID,
+		Code:         req.Code,
+		FullTest:     fullTest,
+		PassCount:    results.PassCount,
+		TestCount:    results.TestCount,
+		ErrorMessage: results.ErrorMessage,
+		Cases:        results.SubmissionCases(),
+		SubmittedAt:  submittedAt,
+		FinishedAt:   time.Now(),
+	}
+	// losing the history shouldn't lose the player their results, so this is only logged
+	if err := storage.Get().SaveSubmission(r.Context(), &submission); err != nil {
+		log.Printf("failed to save %s's submission for problem %s: %v\n", claims.DisplayName, problem.ID, err)
+	}
+	recordSolve(r.Context(), submission, results)
+	// other players only see the verdicts and resource usage, not the output
+	websocket.UpdateGameState(claims.DisplayName, req.RoomID, websocket.SubmitResult{
+		Value:   results.PassCount,
+		Results: submission.Cases,
+		Runtime: results.TotalRuntime(),
+		Memory:  results.PeakMemory(),
+	}, fullTest, submittedAt)
+	general.WriteResponse(w, true, map[string]interface{}{
+		"passCount":    results.PassCount,
+		"testCount":    results.TestCount,
+		"errorMessage": results.ErrorMessage,
+		"results":      results.Cases,
+		"submissionID": submission.ID,
+	})
 }
 
-func formatBash(input any, forOutput bool) string {
-	inputType := reflect.TypeOf(input)
-	inputValue := reflect.ValueOf(input)
-	kind := inputType.Kind()
-
-	// input is array/slice, so format its individual elements
-	if kind == reflect.Array || kind == reflect.Slice {
-		inputFmt := "["
-		for i := 0; i < inputValue.Len(); i++ {
-			element := inputValue.Index(i).Interface()
-			inputFmt += formatBash(element, forOutput)
-			if i < inputValue.Len()-1 {
-				inputFmt += " "
-			}
-		}
-		inputFmt += "]"
-		return inputFmt
-	}
-	// input is string
-	if kind == reflect.String {
-		if forOutput {
-			return fmt.Sprintf("%s", input) // no quotes for output
-		}
-		return fmt.Sprintf("\"%s\"", input)
+// saves a full submission that passed every test of its room's game for the problem leaderboards, with how long
+// into the game it was sent. only the game's players' submissions count, and only while that game is still going
+func recordSolve(ctx context.Context, submission models.Submission, results TestResults) {
+	if !submission.FullTest || submission.GameID == "" || results.TestCount == 0 || results.PassCount != results.TestCount {
+		return
 	}
-	// input is boolean
-	if kind == reflect.Bool {
-		boolVal := inputValue.Bool()
-		if boolVal {
-			return "true"
-		}
-		return "false"
+	gameID, problemID, startedAt, ok := websocket.GetPlayerGame(submission.RoomID, submission.User)
+	if !ok || gameID != submission.GameID || problemID != submission.ProblemID {
+		return
 	}
-	// input is number
-	if kind == reflect.Int || kind == reflect.Int64 || kind == reflect.Float64 {
-		return fmt.Sprintf("%v", input)
+	err := storage.Get().SaveSolve(ctx, models.Solve{
+		User:         submission.User,
+		ProblemID:    submission.ProblemID,
+		GameID:       submission.GameID,
+		SubmissionID: submission.ID,
+		Lang:         submission.Lang,
+		SolveTime:    submission.SolveTime(startedAt),
+		Runtime:      results.TotalRuntime(),
+		SolvedAt:     submission.SubmittedAt,
+	})
+	if err != nil {
+		log.Printf("failed to save %s's solve of problem %s: %v\n", submission.User, submission.ProblemID, err)
 	}
-	return fmt.Sprintf("%s", input)
 }
 
 func HandleGetCodeTemplate(w http.ResponseWriter, r *http.Request) {
@@ -273,9 +192,9 @@ func getCodeTemplate(problemID string, lang string) (string, error) {
 	if problem == nil {
 		return "", errors.New(fmt.Sprintf("Failed to get code template: Problem %s not found", problemID))
 	}
-	template := problem.GetTemplate(lang)
-	if template == "" {
-		return "", errors.New(fmt.Sprintf("Failed to get code template: %s template for problem %s not found", lang, problemID))
+	language, ok := languages.Get(lang)
+	if !ok {
+		return "", errors.New(fmt.Sprintf("Failed to get code template: language %s not supported", lang))
 	}
-	return template, nil
+	return language.ProblemTemplate(*problem), nil
 }
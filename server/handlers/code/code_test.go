@@ -2,63 +2,107 @@ package code
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
+
+	"github.com/webbben/code-duel/models"
 )
 
-type FormatInputTestCase struct {
-	Input    any
-	Lang     string
-	Expected string
+type ExpectedAnswerTestCase struct {
+	Type     models.Type
+	Expected any
+	Returned bool
+	Answer   string
+}
+
+func TestExpectedAnswer(t *testing.T) {
+	var testCases = []ExpectedAnswerTestCase{
+		{Type: models.ListOf(models.Int), Expected: []int{1, 2, 3, 4, 5}, Returned: true, Answer: "[1,2,3,4,5]"},
+		{Type: models.ListOf(models.Float), Expected: []float64{1.1, 2.2, 3.3}, Returned: true, Answer: "[1.1,2.2,3.3]"},
+		{Type: models.ListOf(models.String), Expected: []string{"hi", "100%"}, Returned: true, Answer: "[\"hi\",\"100%\"]"},
+		{Type: models.String, Expected: "IV", Returned: true, Answer: "\"IV\""},
+		{Type: models.Bool, Expected: true, Returned: true, Answer: "true"},
+		{Type: models.ListOf(models.ListOf(models.Bool)), Expected: [][]bool{{true, true}, {true, false}}, Returned: true, Answer: "[[true,true],[true,false]]"},
+		{Type: models.MapOf(models.Int), Expected: map[string]int{"b": 2, "a": 1}, Returned: true, Answer: "{\"a\":1,\"b\":2}"},
+		{Type: models.TreeOf(models.Int), Expected: []any{1, nil, 2}, Returned: true, Answer: "[1,null,2]"},
+		{Type: models.Void, Expected: "Hello world!", Returned: false, Answer: "Hello world!"},
+		{Type: models.Int, Expected: 1234567890, Returned: false, Answer: "1234567890"},
+		{Type: models.Bool, Expected: false, Returned: false, Answer: "false"},
+		{Type: models.LinkedListOf(models.Int), Expected: []int{1, 2, 3}, Returned: false, Answer: "1 2 3"},
+		{Type: models.ListOf(models.ListOf(models.Int)), Expected: [][]int{{1, 2}, {3, 4}}, Returned: false, Answer: "1 2\n3 4"},
+		{Type: models.MapOf(models.ListOf(models.Int)), Expected: map[string][]int{"y": {3}, "x": {1, 2}}, Returned: false, Answer: "x 1 2\ny 3"},
+	}
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("ExpectedAnswer test %v", i), func(t *testing.T) {
+			expected, err := testCase.Type.Encode(testCase.Expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if answer := expectedAnswer(expected, testCase.Returned); answer != testCase.Answer {
+				t.Errorf("Result: [%s] Expected: [%s]", answer, testCase.Answer)
+			}
+			if testCase.Returned {
+				return
+			}
+			// printed answers should read back as the value they were printed from
+			parsed, err := parsePrintedAnswer(testCase.Type, testCase.Answer)
+			if err != nil || !reflect.DeepEqual(parsed, expected) {
+				t.Errorf("Parsed: [%v] (%v) Expected: [%v]", parsed, err, expected)
+			}
+		})
+	}
 }
 
-func TestFormatInputPython(t *testing.T) {
-	var testCases = []FormatInputTestCase{
-		{Input: []int{1, 2, 3, 4, 5}, Lang: "python", Expected: "[1,2,3,4,5]"},
-		{Input: []float64{1.1, 2.2, 3.3, 4.4, 5.5}, Lang: "python", Expected: "[1.1,2.2,3.3,4.4,5.5]"},
-		{Input: []string{"hi", "bye", "yes", "no"}, Lang: "python", Expected: "[\"hi\",\"bye\",\"yes\",\"no\"]"},
-		{Input: true, Lang: "python", Expected: "True"},
-		{Input: false, Lang: "python", Expected: "False"},
-		{Input: []bool{true, false}, Lang: "python", Expected: "[True,False]"},
-		{Input: [][]bool{{true, true}, {true, false}}, Lang: "python", Expected: "[[True,True],[True,False]]"},
+func TestParsePrintedAnswer(t *testing.T) {
+	parsed, err := parsePrintedAnswer(models.TreeOf(models.Int), "1 null 2\n")
+	if err != nil || !reflect.DeepEqual(parsed, []any{1.0, nil, 2.0}) {
+		t.Errorf("Parsed: [%v] (%v)", parsed, err)
+	}
+	if _, err := parsePrintedAnswer(models.Int, "1.5"); err == nil {
+		t.Error("Expected an error for a float printed as an int")
+	}
+	if _, err := parsePrintedAnswer(models.ListOf(models.Bool), "true maybe"); err == nil {
+		t.Error("Expected an error for a list of booleans with something else in it")
 	}
-	runTestsFormatInput(testCases, t)
 }
 
-func TestFormatInputGo(t *testing.T) {
-	var testCases = []FormatInputTestCase{
-		{Input: []int{1, 2, 3, 4, 5}, Lang: "go", Expected: "[]int{1, 2, 3, 4, 5}"},
-		{Input: []float64{1.1, 2.2, 3.3, 4.4, 5.5}, Lang: "go", Expected: "[]float64{1.1, 2.2, 3.3, 4.4, 5.5}"},
-		{Input: []string{"hi", "bye", "yes", "no"}, Lang: "go", Expected: "[]string{\"hi\", \"bye\", \"yes\", \"no\"}"},
-		{Input: true, Lang: "go", Expected: "true"},
-		{Input: false, Lang: "go", Expected: "false"},
-		{Input: []bool{true, false}, Lang: "go", Expected: "[]bool{true, false}"},
-		{Input: [][]bool{{true, true}, {true, false}}, Lang: "go", Expected: "[][]bool{[]bool{true, true}, []bool{true, false}}"},
+func TestCanonicalJSON(t *testing.T) {
+	var testCases = map[string]string{
+		"4.0":               "4",
+		"[1, 2,\n 3]":       "[1,2,3]",
+		"\"a\"":             "\"a\"",
+		"not json":          "not json",
+		"{\"b\":1,\"a\":2}": "{\"a\":2,\"b\":1}",
+	}
+	for input, expected := range testCases {
+		if result := canonicalJSON(input); result != expected {
+			t.Errorf("Result: [%s] Expected: [%s]", result, expected)
+		}
 	}
-	runTestsFormatInput(testCases, t)
 }
 
-func TestFormatInputBash(t *testing.T) {
-	var testCases = []FormatInputTestCase{
-		{Input: []int{1, 2, 3, 4, 5}, Lang: "bash", Expected: "[1 2 3 4 5]"},
-		{Input: []float64{1.1, 2.2, 3.3, 4.4, 5.5}, Lang: "bash", Expected: "[1.1 2.2 3.3 4.4 5.5]"},
-		{Input: []string{"hi", "bye", "yes", "no"}, Lang: "bash", Expected: "[\"hi\" \"bye\" \"yes\" \"no\"]"},
-		{Input: true, Lang: "bash", Expected: "true"},
-		{Input: false, Lang: "bash", Expected: "false"},
-		{Input: []bool{true, false}, Lang: "bash", Expected: "[true false]"},
-		// apparently bash doesn't really support 2d arrays? anyway, lets try not to define problems with 2d array inputs.
-		{Input: [][]bool{{true, true}, {true, false}}, Lang: "bash", Expected: "[[true true] [true false]]"},
+func TestEncodeCaseChecksTypes(t *testing.T) {
+	signature := models.Signature{Name: "solution", Params: []models.Param{{Name: "words", Type: models.ListOf(models.String)}}, Returns: models.Int}
+	if _, err := encodeCase(signature, models.TestCase{[]string{"a"}, 1.5}); err == nil {
+		t.Error("Expected an error for a float given as an int")
+	}
+	if _, err := encodeCase(signature, models.TestCase{[]any{"a", 1}, 1}); err == nil {
+		t.Error("Expected an error for a list with a number in a list of strings")
+	}
+	encoded, err := encodeCase(signature, models.TestCase{[]string{"a"}, 1})
+	if err != nil || !reflect.DeepEqual(encoded, encodedCase{args: []any{[]any{"a"}}, expected: 1.0}) {
+		t.Errorf("Encoded: [%v] (%v)", encoded, err)
 	}
-	runTestsFormatInput(testCases, t)
 }
 
-func runTestsFormatInput(testCases []FormatInputTestCase, t *testing.T) {
-	for i, testCase := range testCases {
-		testName := fmt.Sprintf("FormatInput test %v", i)
-		t.Run(testName, func(t *testing.T) {
-			inputFmt := formatInput(testCase.Lang, testCase.Input, false)
-			if inputFmt != testCase.Expected {
-				t.Errorf("Result: [%s] Expected: [%s]", inputFmt, testCase.Expected)
-			}
-		})
+func TestFormatArgs(t *testing.T) {
+	signature := models.Signature{
+		Name:    "solution",
+		Params:  []models.Param{{Name: "nums", Type: models.ListOf(models.Int)}, {Name: "target", Type: models.Int}},
+		Returns: models.ListOf(models.Int),
+	}
+	formatted := formatArgs(signature, []any{[]int{2, 7, 11, 15}, 9})
+	if formatted != "nums = [2,7,11,15], target = 9" {
+		t.Errorf("Unexpected formatted arguments: %s", formatted)
 	}
 }
@@ -0,0 +1,154 @@
+package code
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/webbben/code-duel/config"
+	"github.com/webbben/code-duel/models"
+)
+
+// Executor runs a piece of code and reports what happened
+type Executor interface {
+	// Execute runs the code in the request. The returned error is only for problems with the executor itself
+	// (e.g. the service couldn't be reached); errors in the submitted code are reported in the ExecResult.
+	Execute(ctx context.Context, req ExecRequest) (ExecResult, error)
+	// MaxWorkers is how many programs the executor can run at the same time
+	MaxWorkers() int
+	// Measures is which resources of the programs it runs the executor can measure
+	Measures() Measurements
+}
+
+// which resources of a program an executor can measure, and so which scoring modes games can use
+type Measurements struct {
+	Runtime bool // the solution's own runtime. the harness times it, so any executor that runs harnesses can
+	Memory  bool // the program's peak memory
+}
+
+type ExecRequest struct {
+	Lang  string // language the code is written in
+	Code  string // source code to run
+	Stdin string // data to pass to the program on stdin
+	// Cases is how many test cases the code runs in one go. The executor's time and output limits are for a
+	// single case, so they're multiplied by it. 0 is treated as 1.
+	Cases int
+	// Limits are the problem's limits for a single case, which replace the executor's own where they're set
+	Limits Limits
+}
+
+type ExecResult struct {
+	Stdout         string        // what the program printed to stdout
+	Stderr         string        // what the program printed to stderr (or the compiler output, for compile errors)
+	ExitCode       int           // exit code of the program
+	Runtime        time.Duration // wall-clock time the program ran for
+	Memory         int64         // peak memory usage in bytes, if the executor can measure it
+	CompileError   bool          // the code failed to compile
+	TimedOut       bool          // the program was stopped for going over its CPU or wall-clock limit
+	MemoryExceeded bool          // the program was stopped for going over its memory limit
+	OutputExceeded bool          // the program was stopped for printing too much output
+}
+
+// whether the program ran to completion without any errors
+func (r ExecResult) OK() bool {
+	return r.ExitCode == 0 && !r.CompileError && !r.TimedOut && !r.MemoryExceeded && !r.OutputExceeded
+}
+
+// resource limits for running a single program
+type Limits struct {
+	CPUTime  time.Duration // CPU time the program may use
+	WallTime time.Duration // wall-clock time the program may run for
+	Memory   int64         // memory the program may use, in bytes
+	Output   int64         // output the program may write to stdout and stderr combined, in bytes
+}
+
+// extra time a program gets on top of its problem's time limits, to start up and load its test cases
+const startupTime = time.Second
+
+// the limits to run the request's program with, given the executor's own. the request's limits replace them, and
+// the time and output limits are multiplied by the number of cases
+func (req ExecRequest) limits(base Limits) Limits {
+	limits := base.with(req.Limits)
+	if req.Cases > 1 {
+		limits.CPUTime *= time.Duration(req.Cases)
+		limits.WallTime *= time.Duration(req.Cases)
+		limits.Output *= int64(req.Cases)
+	}
+	// a problem's time limits are for its solution alone, so the program also gets time to start up
+	if req.Limits.CPUTime > 0 {
+		limits.CPUTime += startupTime
+	}
+	if req.Limits.WallTime > 0 {
+		limits.WallTime += startupTime
+	}
+	return limits
+}
+
+// the limits, with any that are set in other replacing them
+func (l Limits) with(other Limits) Limits {
+	if other.CPUTime > 0 {
+		l.CPUTime = other.CPUTime
+	}
+	if other.WallTime > 0 {
+		l.WallTime = other.WallTime
+	}
+	if other.Memory > 0 {
+		l.Memory = other.Memory
+	}
+	if other.Output > 0 {
+		l.Output = other.Output
+	}
+	return l
+}
+
+// the executor used for running code submissions
+var executor Executor = newExecutorFromConfig()
+
+// the scoring modes games can use with the executor: ranking players by a resource needs it to be measured
+func ScoringModes() []string {
+	measures := executor.Measures()
+	modes := []string{models.ScoringFirstToSolve}
+	if measures.Runtime {
+		modes = append(modes, models.ScoringRuntime)
+	}
+	if measures.Memory {
+		modes = append(modes, models.ScoringMemory)
+	}
+	return modes
+}
+
+// SetExecutor replaces the executor used for running code submissions
+func SetExecutor(e Executor) {
+	executor = e
+}
+
+// creates the executor chosen by the server configuration
+func newExecutorFromConfig() Executor {
+	conf := config.Get()
+	limits := Limits{
+		CPUTime:  conf.CodeExecCPUTime,
+		WallTime: conf.CodeExecWallTime,
+		Memory:   conf.CodeExecMemory,
+		Output:   conf.CodeExecOutput,
+	}
+	local := &LocalExecutor{
+		WorkDir:    conf.CodeExecWorkDir,
+		Namespaces: conf.CodeExecNamespaces,
+		Workers:    conf.CodeExecWorkers,
+		Limits:     limits,
+	}
+	switch conf.CodeExecutor {
+	case "local":
+		return local
+	case "remote":
+		// there's no default service: the one code duel used to run on only speaks version 1 of the contract
+		if conf.CodeExecURL == "" {
+			log.Println("The remote code executor needs CODE_EXEC_URL; falling back to running code locally")
+			return local
+		}
+		return NewRemoteExecutor(conf.CodeExecURL, conf.CodeExecWorkers, limits)
+	default:
+		log.Printf("Unknown code executor %q; falling back to running code locally\n", conf.CodeExecutor)
+		return local
+	}
+}
@@ -0,0 +1,272 @@
+package code
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/webbben/code-duel/languages"
+)
+
+// LocalExecutor runs code on this machine, in a temporary directory and a separate process with resource limits.
+// On linux, it can also run each process in its own set of namespaces so it can't see the network or other processes,
+// with its own root filesystem that only has the system and language tool directories (read only) and its temporary
+// directory in it.
+type LocalExecutor struct {
+	WorkDir    string // directory to create temp directories in; the OS temp dir is used if empty
+	Namespaces bool   // whether to run code in separate namespaces (linux only)
+	Limits     Limits // limits applied when running code
+	Workers    int    // how many programs can run at the same time; defaults to the number of CPUs
+}
+
+// limits for compile steps. compilers need a lot more memory and time than the programs they build,
+// so memory is left unlimited here and the time limits are generous
+var compileLimits = Limits{
+	CPUTime:  30 * time.Second,
+	WallTime: time.Minute,
+	Output:   64 << 10,
+}
+
+// largest file a program is allowed to write
+const maxFileSize = 64 << 20
+
+// how many processes (or threads) a program can start, on top of the ones its user already has. compilers and
+// runtimes start a few threads of their own, and a fork bomb stops here
+const maxProcesses = 256
+
+// build cache shared between go compiles, so the standard library isn't rebuilt for every submission
+var goCacheDir = filepath.Join(os.TempDir(), "code-duel-gocache")
+
+// rustup looks for its toolchains under the home directory, which sandboxed processes don't share
+var rustupHome = findRustupHome()
+
+func findRustupHome() string {
+	if dir := os.Getenv("RUSTUP_HOME"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".rustup")
+}
+
+func (e *LocalExecutor) MaxWorkers() int {
+	if e.Workers <= 0 {
+		return runtime.NumCPU()
+	}
+	return e.Workers
+}
+
+func (e *LocalExecutor) Measures() Measurements {
+	return Measurements{Runtime: true, Memory: measuresMemory}
+}
+
+func (e *LocalExecutor) Execute(ctx context.Context, req ExecRequest) (ExecResult, error) {
+	lang, ok := languages.Get(req.Lang)
+	if !ok {
+		return ExecResult{}, fmt.Errorf("local executor: language %s not supported", req.Lang)
+	}
+	dir, err := os.MkdirTemp(e.WorkDir, "code-duel-")
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("local executor: failed to create temp directory; %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = os.WriteFile(filepath.Join(dir, lang.Source), []byte(req.Code), 0644)
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("local executor: failed to write source file; %w", err)
+	}
+
+	if lang.Compile != nil {
+		result, err := e.run(ctx, dir, lang.Compile, "", compileLimits)
+		if err != nil {
+			return result, err
+		}
+		if !result.OK() {
+			// report the compiler output as the error
+			return ExecResult{
+				Stderr:       result.Stdout + result.Stderr,
+				ExitCode:     result.ExitCode,
+				Runtime:      result.Runtime,
+				CompileError: true,
+			}, nil
+		}
+	}
+	return e.run(ctx, dir, lang.Run, req.Stdin, req.limits(e.Limits))
+}
+
+// runs a command in dir with the given limits applied
+func (e *LocalExecutor) run(ctx context.Context, dir string, command []string, stdin string, limits Limits) (ExecResult, error) {
+	runCtx := ctx
+	if limits.WallTime > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, limits.WallTime)
+		defer cancel()
+	}
+	// separate cancel so we can stop the program as soon as it goes over its output limit
+	runCtx, stop := context.WithCancel(runCtx)
+	defer stop()
+
+	// rlimits are applied by a small shell wrapper, which then execs the real command. in namespaces, it first moves
+	// into a root filesystem of its own
+	processes := 0
+	if n := userProcesses(); n >= 0 {
+		processes = n + maxProcesses
+	}
+	script := ulimitScript(limits, processes)
+	if e.Namespaces {
+		root, err := os.MkdirTemp(e.WorkDir, "code-duel-root-")
+		if err != nil {
+			return ExecResult{}, fmt.Errorf("local executor: failed to create sandbox root; %w", err)
+		}
+		defer os.RemoveAll(root)
+		isolate, err := isolateScript(root, dir)
+		if err != nil {
+			return ExecResult{}, fmt.Errorf("local executor: %w", err)
+		}
+		script = isolate + script
+	}
+	args := append([]string{"-c", script, "sandbox"}, command...)
+	cmd := exec.CommandContext(runCtx, "/bin/sh", args...)
+	cmd.Dir = dir
+	cmd.Env = sandboxEnv(dir)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.SysProcAttr = sandboxAttr(e.Namespaces)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	cmd.WaitDelay = time.Second
+
+	output := &outputLimiter{remaining: limits.Output, limited: limits.Output > 0, onExceed: stop}
+	stdout := &limitedWriter{limiter: output}
+	stderr := &limitedWriter{limiter: output}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	result := ExecResult{
+		Stdout:  stdout.buf.String(),
+		Stderr:  stderr.buf.String(),
+		Runtime: time.Since(start),
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return result, fmt.Errorf("local executor: failed to run %s; %w", command[0], err)
+		}
+	}
+	if ctx.Err() != nil {
+		// the caller gave up on this execution, so the result doesn't mean anything
+		return result, ctx.Err()
+	}
+
+	state := cmd.ProcessState
+	result.ExitCode = state.ExitCode()
+	result.Memory = peakMemory(state)
+	result.OutputExceeded = output.exceeded
+	cpuTime := state.UserTime() + state.SystemTime()
+	result.TimedOut = errors.Is(runCtx.Err(), context.DeadlineExceeded) || (limits.CPUTime > 0 && cpuTime >= limits.CPUTime)
+	result.MemoryExceeded = result.ExitCode != 0 && outOfMemory(limits.Memory, result.Memory, result.Stderr)
+	return result, nil
+}
+
+// whether a program that failed did so because it ran out of memory. processes that hit the data size limit
+// usually die with an allocation error rather than a signal, so this is a best guess from the peak usage and the
+// error output
+func outOfMemory(limit int64, peak int64, stderr string) bool {
+	if limit <= 0 {
+		return false
+	}
+	if peak >= limit*9/10 {
+		return true
+	}
+	for _, message := range []string{"MemoryError", "out of memory", "bad_alloc", "memory allocation of"} {
+		if strings.Contains(stderr, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// builds the shell script that sets the resource limits before exec-ing the real command. processes is the most
+// processes the program's user can have, or 0 for no limit
+func ulimitScript(limits Limits, processes int) string {
+	var script strings.Builder
+	if limits.CPUTime > 0 {
+		fmt.Fprintf(&script, "ulimit -t %d; ", int(math.Ceil(limits.CPUTime.Seconds())))
+	}
+	if limits.Memory > 0 {
+		fmt.Fprintf(&script, "ulimit -d %d; ", limits.Memory>>10)
+	}
+	// file size is counted in 512 byte blocks by some shells, so this is the strictest reading of it
+	fmt.Fprintf(&script, "ulimit -f %d; ", maxFileSize/512)
+	if processes > 0 {
+		// dash calls the process limit -p, which is the pipe size in bash
+		fmt.Fprintf(&script, "ulimit -u %[1]d 2>/dev/null || ulimit -p %[1]d; ", processes)
+	}
+	script.WriteString(`exec "$@"`)
+	return script.String()
+}
+
+// environment for sandboxed processes; only what's needed to find and run the language tools
+func sandboxEnv(dir string) []string {
+	return []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + dir,
+		"TMPDIR=" + dir,
+		"LANG=C.UTF-8",
+		"GOCACHE=" + goCacheDir,
+		"GOPATH=" + filepath.Join(dir, "gopath"),
+		"GOFLAGS=",
+		"GOTOOLCHAIN=local",
+		"CGO_ENABLED=0",
+		"RUSTUP_HOME=" + rustupHome,
+	}
+}
+
+// keeps track of how much output a program is still allowed to write across stdout and stderr
+type outputLimiter struct {
+	mu        sync.Mutex
+	remaining int64
+	limited   bool
+	exceeded  bool
+	onExceed  func()
+}
+
+type limitedWriter struct {
+	limiter *outputLimiter
+	buf     bytes.Buffer
+}
+
+// writes as much as the output limit allows, and discards the rest.
+// always reports success, so the program is stopped by onExceed rather than a broken pipe
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	l := w.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.limited {
+		return w.buf.Write(p)
+	}
+	if int64(len(p)) > l.remaining {
+		w.buf.Write(p[:l.remaining])
+		l.remaining = 0
+		if !l.exceeded {
+			l.exceeded = true
+			l.onExceed()
+		}
+		return len(p), nil
+	}
+	l.remaining -= int64(len(p))
+	return w.buf.Write(p)
+}
@@ -0,0 +1,180 @@
+//go:build linux
+
+package code
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// system directories a sandbox's root filesystem gets, read only, if they exist
+var sandboxSystemDirs = []string{"/bin", "/sbin", "/lib", "/lib32", "/lib64", "/libx32", "/usr", "/etc", "/opt"}
+
+// files in those that sandboxed code mustn't read. they're hidden behind /dev/null
+var sandboxHiddenFiles = []string{"/etc/shadow", "/etc/gshadow"}
+
+// devices sandboxed code can use
+var sandboxDevices = []string{"null", "zero", "random", "urandom"}
+
+// process attributes for sandboxed processes. the process gets its own process group so the whole group
+// can be killed, and optionally its own user, mount, pid, network, ipc and uts namespaces, which isolateScript
+// uses to give it its own root filesystem.
+func sandboxAttr(namespaces bool) *syscall.SysProcAttr {
+	attr := &syscall.SysProcAttr{
+		Setpgid:   true,
+		Pdeathsig: syscall.SIGKILL,
+	}
+	if namespaces {
+		attr.Cloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+		// map the current user to root inside the namespace, so the process has no more access than we do
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+		attr.GidMappingsEnableSetgroups = false
+	}
+	return attr
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// whether peakMemory measures anything on this platform
+const measuresMemory = true
+
+// peak resident memory of a finished process, in bytes
+func peakMemory(state *os.ProcessState) int64 {
+	usage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+	// linux reports maxrss in kilobytes
+	return int64(usage.Maxrss) << 10
+}
+
+// builds the start of the sandbox's shell script, which moves it into a root filesystem of its own. it's mounted on
+// root, an empty directory, and has the system directories and the language tools read only, its own /proc and
+// /tmp, and dir (where the code is) and the go build cache writable. the rest of the machine's files, like the
+// server's problems, store and /proc, are left behind once it pivots into it. it needs the mount namespace and root
+// in the user namespace that sandboxAttr gives it
+func isolateScript(root string, dir string) (string, error) {
+	for _, tool := range []string{"mount", "umount", "pivot_root"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return "", fmt.Errorf("%s is needed to isolate sandboxed code's filesystem; %w", tool, err)
+		}
+	}
+	if err := os.MkdirAll(goCacheDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create the go build cache; %w", err)
+	}
+
+	var script strings.Builder
+	q := shellQuote
+	fmt.Fprintf(&script, "set -e; mount --make-rprivate /; mount -t tmpfs -o mode=755 sandbox %s; ", q(root))
+	for _, d := range append(slices.Clone(sandboxSystemDirs), toolDirs()...) {
+		info, err := os.Lstat(d)
+		if err != nil {
+			continue
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			// like /bin on systems that keep everything in /usr
+			target, err := os.Readlink(d)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(&script, "mkdir -p %s; ln -s %s %s; ", q(root+filepath.Dir(d)), q(target), q(root+d))
+			continue
+		}
+		fmt.Fprintf(&script, "mkdir -p %[1]s; mount --rbind %[2]s %[1]s; mount -o remount,bind,ro %[1]s; ", q(root+d), q(d))
+	}
+	for _, f := range sandboxHiddenFiles {
+		if _, err := os.Stat(f); err == nil {
+			fmt.Fprintf(&script, "mount --bind /dev/null %s; ", q(root+f))
+		}
+	}
+	fmt.Fprintf(&script, "mkdir -p %[1]s/tmp %[1]s/proc %[1]s/dev; mount -t tmpfs -o mode=1777 tmp %[1]s/tmp; mount -t proc proc %[1]s/proc; ", q(root))
+	for _, device := range sandboxDevices {
+		fmt.Fprintf(&script, "touch %[1]s; mount --bind %[2]s %[1]s; ", q(root+"/dev/"+device), q("/dev/"+device))
+	}
+	fmt.Fprintf(&script, "ln -s /proc/self/fd %s; ", q(root+"/dev/fd"))
+	// the writable directories go last, as they may be inside the others
+	for _, d := range []string{goCacheDir, dir} {
+		fmt.Fprintf(&script, "mkdir -p %[1]s; mount --bind %[2]s %[1]s; ", q(root+d), q(d))
+	}
+	fmt.Fprintf(&script, "mkdir %[1]s/.old; pivot_root %[1]s %[1]s/.old; umount -l /.old; rmdir /.old; cd %[2]s; set +e; ", q(root), q(dir))
+	return script.String(), nil
+}
+
+// directories the language tools are installed in outside the system directories, like ~/.cargo or ~/.pyenv. each
+// tool directory on the PATH brings its parent with it, as tools often keep what they need next to their bin
+// directory, unless that's the root or a home directory
+func toolDirs() []string {
+	home, _ := os.UserHomeDir()
+	var dirs []string
+	for _, d := range append(filepath.SplitList(os.Getenv("PATH")), rustupHome) {
+		if !filepath.IsAbs(d) || d == "/" {
+			continue
+		}
+		d = filepath.Clean(d)
+		if parent := filepath.Dir(d); parent != "/" && parent != home {
+			d = parent
+		}
+		if !slices.ContainsFunc(append(slices.Clone(sandboxSystemDirs), dirs...), func(other string) bool { return within(d, other) }) {
+			dirs = append(dirs, d)
+		}
+	}
+	// a directory that came first may be inside one that came later
+	return slices.DeleteFunc(dirs, func(d string) bool {
+		return slices.ContainsFunc(dirs, func(other string) bool { return other != d && within(d, other) })
+	})
+}
+
+// whether path is dir or inside it
+func within(path string, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+// quotes a string for the shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// how many processes the current user has running, counting threads, which is what RLIMIT_NPROC limits.
+// returns -1 if it can't be counted
+func userProcesses() int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return -1
+	}
+	uid := strconv.Itoa(os.Getuid())
+	count := 0
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		status, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "status"))
+		if err != nil {
+			continue // it has exited since
+		}
+		owned, threads := false, 0
+		for _, line := range strings.Split(string(status), "\n") {
+			field, value, _ := strings.Cut(line, ":")
+			switch field {
+			case "Uid":
+				ids := strings.Fields(value)
+				owned = len(ids) > 0 && ids[0] == uid
+			case "Threads":
+				threads, _ = strconv.Atoi(strings.TrimSpace(value))
+			}
+		}
+		if owned {
+			count += threads
+		}
+	}
+	return count
+}
@@ -0,0 +1,40 @@
+//go:build linux
+
+package code
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalExecutorIsolatesFilesystem(t *testing.T) {
+	e := newTestLocalExecutor(t)
+	e.Namespaces = true
+	if err := exec.Command("unshare", "-Urm", "true").Run(); err != nil {
+		t.Skip("user namespaces not available")
+	}
+	secret := filepath.Join(t.TempDir(), "tests.yaml")
+	os.WriteFile(secret, []byte("hidden case"), 0644)
+
+	result, err := e.Execute(context.Background(), ExecRequest{
+		Lang: "bash",
+		Code: fmt.Sprintf("cat %s\ncat /proc/%d/environ\necho written > out.txt && cat out.txt", secret, os.Getpid()),
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if strings.Contains(result.Stdout, "hidden case") {
+		t.Error("sandboxed code shouldn't be able to read files outside its directory")
+	}
+	if strings.Contains(result.Stdout, "PATH=") {
+		t.Error("sandboxed code shouldn't be able to read the server's environment")
+	}
+	if !strings.Contains(result.Stdout, "written") {
+		t.Errorf("sandboxed code should be able to write to its directory: %+v", result)
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !linux
+
+package code
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// namespaces are only available on linux, so other platforms just get a plain process
+func sandboxAttr(namespaces bool) *syscall.SysProcAttr {
+	return nil
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// memory usage isn't measured on other platforms
+const measuresMemory = false
+
+func peakMemory(state *os.ProcessState) int64 {
+	return 0
+}
+
+// other platforms don't have the namespaces to isolate the filesystem with
+func isolateScript(root string, dir string) (string, error) {
+	return "", nil
+}
+
+// processes are only counted on linux
+func userProcesses() int {
+	return -1
+}
@@ -0,0 +1,89 @@
+package code
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLocalExecutor(t *testing.T) *LocalExecutor {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	return &LocalExecutor{
+		WorkDir: t.TempDir(),
+		Limits: Limits{
+			CPUTime:  2 * time.Second,
+			WallTime: 2 * time.Second,
+			Memory:   256 << 20,
+			Output:   1 << 10,
+		},
+	}
+}
+
+func TestLocalExecutorRunsCode(t *testing.T) {
+	e := newTestLocalExecutor(t)
+	result, err := e.Execute(context.Background(), ExecRequest{
+		Lang:  "bash",
+		Code:  "read line\necho \"hello $line\"",
+		Stdin: "world\n",
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !result.OK() || result.Stdout != "hello world\n" {
+		t.Errorf("Result: [%+v] Expected stdout: [hello world]", result)
+	}
+}
+
+func TestLocalExecutorReportsErrors(t *testing.T) {
+	e := newTestLocalExecutor(t)
+	result, err := e.Execute(context.Background(), ExecRequest{
+		Lang: "bash",
+		Code: "echo oops >&2\nexit 3",
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.ExitCode != 3 || strings.TrimSpace(result.Stderr) != "oops" {
+		t.Errorf("Result: [%+v] Expected exit code 3 and stderr [oops]", result)
+	}
+}
+
+func TestLocalExecutorWallTimeLimit(t *testing.T) {
+	e := newTestLocalExecutor(t)
+	result, err := e.Execute(context.Background(), ExecRequest{
+		Lang: "bash",
+		Code: "sleep 30",
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !result.TimedOut || result.Runtime > 5*time.Second {
+		t.Errorf("Result: [%+v] Expected a time out after ~2s", result)
+	}
+}
+
+func TestLocalExecutorOutputLimit(t *testing.T) {
+	e := newTestLocalExecutor(t)
+	result, err := e.Execute(context.Background(), ExecRequest{
+		Lang: "bash",
+		Code: "while true; do echo spam; done",
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !result.OutputExceeded || len(result.Stdout) > 1<<10 {
+		t.Errorf("Expected output limit to be exceeded; got %d bytes of output, exceeded=%v", len(result.Stdout), result.OutputExceeded)
+	}
+}
+
+func TestLocalExecutorUnsupportedLang(t *testing.T) {
+	e := newTestLocalExecutor(t)
+	_, err := e.Execute(context.Background(), ExecRequest{Lang: "cobol", Code: "DISPLAY 'HI'."})
+	if err == nil {
+		t.Error("Expected an error for an unsupported language")
+	}
+}
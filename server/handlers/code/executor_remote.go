@@ -0,0 +1,148 @@
+package code
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+/*
+ * The code execution service is sent a POST with a JSON ExecCodeRequest for each program, and answers with a JSON
+ * ExecCodeResponse. The contract is versioned: the request says which version it's written in, and the service
+ * echoes the version it answered in.
+ *
+ * Version 1 only had lang and code in the request, and output and error in the response. Version 2 adds:
+ *   - stdin: what to pass to the program on stdin. The test harnesses read their cases (and the nonce their
+ *     records carry) from it, so nothing can be judged without it.
+ *   - limits: the program's CPU and wall-clock time, memory and output limits, already scaled for the number of
+ *     cases it runs. 0 means no limit. The service stops the program when it goes over one, and says which in
+ *     timed_out, memory_exceeded or output_exceeded.
+ *   - memory_bytes: the program's peak memory, if the service can measure it.
+ *
+ * A service that doesn't echo version 2 would ignore stdin and the limits, so its responses are an error rather
+ * than a wrong verdict.
+ */
+
+// the version of the code execution service's contract this server speaks
+const RemoteProtocolVersion = 2
+
+// RemoteExecutor runs code on the code execution microservice
+type RemoteExecutor struct {
+	URL     string
+	Client  *http.Client
+	Workers int    // how many requests to send to the service at the same time
+	Limits  Limits // default limits for every program, which a problem's own limits replace
+}
+
+// number of requests sent to the service at the same time, if not configured
+const defaultRemoteWorkers = 4
+
+type ExecCodeRequest struct {
+	Version int          `json:"version"`
+	Lang    string       `json:"lang"`
+	Code    string       `json:"code"`
+	Stdin   string       `json:"stdin"`
+	Limits  RemoteLimits `json:"limits"`
+}
+
+// RemoteLimits are the limits a program runs with, as they're sent to the service
+type RemoteLimits struct {
+	CPUTimeMs  int64 `json:"cpu_time_ms"`
+	WallTimeMs int64 `json:"wall_time_ms"`
+	Memory     int64 `json:"memory_bytes"`
+	Output     int64 `json:"output_bytes"`
+}
+
+type ExecCodeResponse struct {
+	Version        int    `json:"version"`
+	Output         string `json:"output"`
+	Error          bool   `json:"error"`
+	Memory         int64  `json:"memory_bytes"`
+	TimedOut       bool   `json:"timed_out"`
+	MemoryExceeded bool   `json:"memory_exceeded"`
+	OutputExceeded bool   `json:"output_exceeded"`
+}
+
+func NewRemoteExecutor(url string, workers int, limits Limits) *RemoteExecutor {
+	return &RemoteExecutor{
+		URL:     url,
+		Client:  &http.Client{Timeout: time.Minute},
+		Workers: workers,
+		Limits:  limits,
+	}
+}
+
+func (e *RemoteExecutor) MaxWorkers() int {
+	if e.Workers <= 0 {
+		return defaultRemoteWorkers
+	}
+	return e.Workers
+}
+
+// the service runs the harness, which times the solution, but it doesn't have to report memory
+func (e *RemoteExecutor) Measures() Measurements {
+	return Measurements{Runtime: true}
+}
+
+func (e *RemoteExecutor) Execute(ctx context.Context, req ExecRequest) (ExecResult, error) {
+	limits := req.limits(e.Limits)
+	jsonData, err := json.Marshal(ExecCodeRequest{
+		Version: RemoteProtocolVersion,
+		Lang:    req.Lang,
+		Code:    req.Code,
+		Stdin:   req.Stdin,
+		Limits: RemoteLimits{
+			CPUTimeMs:  limits.CPUTime.Milliseconds(),
+			WallTimeMs: limits.WallTime.Milliseconds(),
+			Memory:     limits.Memory,
+			Output:     limits.Output,
+		},
+	})
+	if err != nil {
+		return ExecResult{}, errors.New("Internal server error: failed to marshal request json")
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return ExecResult{}, errors.New("Internal server error: failed to create code execution request")
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	response, err := e.Client.Do(httpReq)
+	if err != nil {
+		return ExecResult{}, errors.New("Internal server error: failed to communicate with code execution service")
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return ExecResult{}, fmt.Errorf("Internal server error: code execution service responded with status %d", response.StatusCode)
+	}
+
+	// read response
+	var execCodeResponse ExecCodeResponse
+	err = json.NewDecoder(response.Body).Decode(&execCodeResponse)
+	if err != nil {
+		return ExecResult{}, errors.New("Internal server error: failed to read executed code response body")
+	}
+	if execCodeResponse.Version != RemoteProtocolVersion {
+		return ExecResult{}, fmt.Errorf("Internal server error: code execution service speaks version %d of its contract, but version %d is needed for stdin and limits", max(execCodeResponse.Version, 1), RemoteProtocolVersion)
+	}
+	// the service only tells us whether there was an error, and puts the error details in the output
+	result := ExecResult{
+		Runtime:        time.Since(start),
+		Memory:         execCodeResponse.Memory,
+		TimedOut:       execCodeResponse.TimedOut,
+		MemoryExceeded: execCodeResponse.MemoryExceeded,
+		OutputExceeded: execCodeResponse.OutputExceeded,
+	}
+	if execCodeResponse.Error {
+		result.ExitCode = 1
+		result.Stderr = execCodeResponse.Output
+	} else {
+		result.Stdout = execCodeResponse.Output
+	}
+	return result, nil
+}
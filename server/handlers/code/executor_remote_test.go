@@ -0,0 +1,55 @@
+package code
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// a code execution service that answers every request with response, and keeps the last request it got
+func newTestService(t *testing.T, response map[string]any) (*RemoteExecutor, *ExecCodeRequest) {
+	var got ExecCodeRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("bad request: %v", err)
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return NewRemoteExecutor(server.URL, 1, Limits{CPUTime: 2 * time.Second, Memory: 256 << 20}), &got
+}
+
+func TestRemoteExecutorSendsStdinAndLimits(t *testing.T) {
+	e, got := newTestService(t, map[string]any{"version": RemoteProtocolVersion, "output": "ok", "memory_exceeded": true})
+	result, err := e.Execute(context.Background(), ExecRequest{
+		Lang:   "python",
+		Code:   "print(input())",
+		Stdin:  "nonce\n[\n[1]\n]\n",
+		Cases:  3,
+		Limits: Limits{Memory: 64 << 20},
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got.Version != RemoteProtocolVersion || got.Stdin != "nonce\n[\n[1]\n]\n" {
+		t.Errorf("Unexpected request: %+v", got)
+	}
+	// the executor's time limit is for each case, and the problem's memory limit replaces the executor's
+	if got.Limits.CPUTimeMs != 6000 || got.Limits.Memory != 64<<20 {
+		t.Errorf("Unexpected limits: %+v", got.Limits)
+	}
+	if result.Stdout != "ok" || !result.MemoryExceeded {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestRemoteExecutorNeedsVersion(t *testing.T) {
+	// a service from before stdin was part of the contract doesn't say which version it speaks
+	e, _ := newTestService(t, map[string]any{"output": "", "error": false})
+	if _, err := e.Execute(context.Background(), ExecRequest{Lang: "python", Code: "pass", Stdin: "nonce\n[]\n"}); err == nil {
+		t.Error("Expected an error from a service that doesn't support stdin")
+	}
+}
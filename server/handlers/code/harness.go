@@ -0,0 +1,91 @@
+package code
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/webbben/code-duel/languages"
+)
+
+// the harnesses themselves are built by the languages package, which describes the lines they print
+
+// what the harness recorded for one run of the code
+type harnessRecord struct {
+	OK       bool
+	Runtime  time.Duration
+	Stdout   string
+	Error    string
+	Result   string // the return value, as JSON
+	Returned bool   // whether the solution returned anything
+}
+
+// makes a random nonce for an execution, which the harness reads from stdin and puts in every record it prints.
+// the player's code shares stdout with the harness, so records without it are taken to be forged and ignored
+func newHarnessNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to make harness nonce; %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// reads the records printed by a harness for a batch of caseCount cases, in case order. lines without the nonce
+// are ignored, and a record for a case that was already recorded or isn't in the batch is an error, since the
+// harness prints each case's record once and in order.
+// if the harness reported a compile error, it is returned as well.
+func parseHarnessOutput(stdout string, nonce string, caseCount int) (records []harnessRecord, compileError string, hasCompileError bool, err error) {
+	// the last line is cut short if the program was stopped while writing it, so only whole lines are read
+	lines := strings.Split(stdout, "\n")
+	for _, line := range lines[:len(lines)-1] {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[1] != nonce {
+			continue
+		}
+		switch fields[0] {
+		case languages.HarnessCompileErrorMarker:
+			if len(fields) == 3 {
+				return records, decodeHarnessField(fields[2]), true, nil
+			}
+		case languages.HarnessCaseMarker:
+			if len(fields) != 8 {
+				continue
+			}
+			index, err := strconv.Atoi(fields[2])
+			if err != nil {
+				continue
+			}
+			if index != len(records) || index >= caseCount {
+				return nil, "", false, fmt.Errorf("unexpected record for case %d after %d of %d cases", index, len(records), caseCount)
+			}
+			nanos, err := strconv.ParseInt(fields[4], 10, 64)
+			if err != nil {
+				continue
+			}
+			records = append(records, harnessRecord{
+				OK:       fields[3] == "ok",
+				Runtime:  time.Duration(nanos),
+				Stdout:   decodeHarnessField(fields[5]),
+				Error:    decodeHarnessField(fields[6]),
+				Result:   decodeHarnessField(fields[7]),
+				Returned: fields[7] != "-",
+			})
+		}
+	}
+	return records, "", false, nil
+}
+
+func decodeHarnessField(field string) string {
+	if field == "-" {
+		return ""
+	}
+	decoded, err := base64.StdEncoding.DecodeString(field)
+	if err != nil {
+		return ""
+	}
+	return string(decoded)
+}
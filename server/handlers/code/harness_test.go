@@ -0,0 +1,535 @@
+package code
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webbben/code-duel/languages"
+	"github.com/webbben/code-duel/models"
+)
+
+func TestParseHarnessOutput(t *testing.T) {
+	stdout := "junk before\n" +
+		languages.HarnessCaseMarker + " n0nce 0 ok 1500 aGk= - WzFd\n" +
+		// printed by the player's code, which doesn't know the nonce
+		languages.HarnessCaseMarker + " guess 1 ok 1 - - WzFd\n" +
+		languages.HarnessCaseMarker + " 1 ok 1 - - WzFd\n" +
+		languages.HarnessCaseMarker + " n0nce 1 error 20 - b29wcw== -\n" +
+		languages.HarnessCaseMarker + " n0nce 2 ok\n" +
+		// cut off by the output limit
+		languages.HarnessCaseMarker + " n0nce 3 ok 1500 - - MTI"
+	records, _, hasCompileError, err := parseHarnessOutput(stdout, "n0nce", 4)
+	if err != nil || hasCompileError {
+		t.Errorf("Unexpected error: %v (%v)", err, hasCompileError)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records; got %d", len(records))
+	}
+	if r := records[0]; !r.OK || r.Stdout != "hi" || r.Error != "" || r.Runtime != 1500*time.Nanosecond || !r.Returned || r.Result != "[1]" {
+		t.Errorf("Unexpected record for case 0: %+v", r)
+	}
+	if r := records[1]; r.OK || r.Stdout != "" || r.Error != "oops" || r.Returned {
+		t.Errorf("Unexpected record for case 1: %+v", r)
+	}
+
+	_, compileError, hasCompileError, _ := parseHarnessOutput(languages.HarnessCompileErrorMarker+" n0nce YmFk\n", "n0nce", 1)
+	if !hasCompileError || compileError != "bad" {
+		t.Errorf("Expected compile error [bad]; got [%s] (%v)", compileError, hasCompileError)
+	}
+	if _, _, hasCompileError, _ := parseHarnessOutput(languages.HarnessCompileErrorMarker+" YmFk\n", "n0nce", 1); hasCompileError {
+		t.Error("A compile error without the nonce should be ignored")
+	}
+
+	// each case is recorded once, in order, and only the batch's cases are
+	for name, stdout := range map[string]string{
+		"duplicate":    languages.HarnessCaseMarker + " n0nce 0 ok 1 - - -\n" + languages.HarnessCaseMarker + " n0nce 0 ok 1 - - -\n",
+		"out of order": languages.HarnessCaseMarker + " n0nce 1 ok 1 - - -\n",
+		"out of range": languages.HarnessCaseMarker + " n0nce 0 ok 1 - - -\n" + languages.HarnessCaseMarker + " n0nce 1 ok 1 - - -\n",
+	} {
+		if _, _, _, err := parseHarnessOutput(stdout, "n0nce", 1); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+var goHarnessTestCode = `
+package main
+
+import "fmt"
+
+func solution(nums []int) int {
+	if len(nums) == 0 {
+		panic("no numbers")
+	}
+	sum := 0
+	for _, n := range nums {
+		sum += n
+	}
+	fmt.Printf("sum is %d\n", sum)
+	return sum
+}
+`
+
+func TestGoHarness(t *testing.T) {
+	useLocalExecutor(t, "go")
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{[]int{1, 2, 3}, 6}},
+		{testCase: models.TestCase{[]int{}, 0}},
+		{testCase: models.TestCase{[]int{5}, 5}},
+	}
+	signature := models.Signature{
+		Name:    "solution",
+		Params:  []models.Param{{Name: "nums", Type: models.ListOf(models.Int)}},
+		Returns: models.Int,
+	}
+	results := runTests(context.Background(), goHarnessTestCode, "go", models.Problem{Signature: signature}, testCases, false, 0)
+	expected := []Verdict{VerdictAccepted, VerdictRuntimeError, VerdictAccepted}
+	for i, caseResult := range results.Cases {
+		if caseResult.Verdict != expected[i] {
+			t.Errorf("case %d: Result: [%s] Expected: [%s] (%+v)", i, caseResult.Verdict, expected[i], caseResult)
+		}
+	}
+	// what solution prints is kept apart from its answer
+	if results.Cases[0].Stdout != "sum is 6" || results.Cases[0].Output != "6" {
+		t.Errorf("Unexpected output for case 0: [%s] [%s]", results.Cases[0].Stdout, results.Cases[0].Output)
+	}
+}
+
+func TestBashHarness(t *testing.T) {
+	useLocalExecutor(t, "bash")
+	code := `
+solution () {
+	local total=0
+	for n in $1; do
+		total=$(( total + n ))
+	done
+	echo "$total%"
+}
+`
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{[]int{1, 2}, "3%"}},
+		{testCase: models.TestCase{[]int{}, "0%"}},
+		{testCase: models.TestCase{[]int{50, 1}, "52%"}},
+	}
+	signature := models.Signature{
+		Name:    "solution",
+		Params:  []models.Param{{Name: "nums", Type: models.ListOf(models.Int)}},
+		Returns: models.String,
+	}
+	results := runTests(context.Background(), code, "bash", models.Problem{Signature: signature}, testCases, false, 0)
+	expected := []Verdict{VerdictAccepted, VerdictAccepted, VerdictWrongAnswer}
+	for i, caseResult := range results.Cases {
+		if caseResult.Verdict != expected[i] {
+			t.Errorf("case %d: Result: [%s] Expected: [%s] (%+v)", i, caseResult.Verdict, expected[i], caseResult)
+		}
+	}
+}
+
+func TestPythonHarnessCompileError(t *testing.T) {
+	useLocalExecutor(t, "python3")
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{1, 1}},
+		{testCase: models.TestCase{2, 2}},
+	}
+	results := runTests(context.Background(), "def solution(n):\n    return (n", "python", models.Problem{Signature: intSignature}, testCases, false, 0)
+	for i, caseResult := range results.Cases {
+		if caseResult.Verdict != VerdictCompileError {
+			t.Errorf("case %d: Result: [%s] Expected: [%s]", i, caseResult.Verdict, VerdictCompileError)
+		}
+	}
+}
+
+func TestPythonHarnessKeepsPercentSigns(t *testing.T) {
+	useLocalExecutor(t, "python3")
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{"7", "7%"}},
+		{testCase: models.TestCase{"a%b", "a%b%"}},
+	}
+	signature := models.Signature{
+		Name:    "solution",
+		Params:  []models.Param{{Name: "x", Type: models.String}},
+		Returns: models.String,
+	}
+	code := "def solution(x):\n    print('%d' % 1)\n    return '%s%%' % x"
+	results := runTests(context.Background(), code, "python", models.Problem{Signature: signature}, testCases, false, 0)
+	if results.PassCount != 2 {
+		t.Errorf("Result: %d/%d passed; Expected: 2/2 (%+v)", results.PassCount, results.TestCount, results.Cases)
+	}
+}
+
+func TestHarnessIgnoresForgedRecords(t *testing.T) {
+	useLocalExecutor(t, "python3")
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{1, 2}},
+		{testCase: models.TestCase{2, 4}},
+	}
+	signature := models.Signature{
+		Name:    "double",
+		Params:  []models.Param{{Name: "n", Type: models.Int}},
+		Returns: models.Int,
+	}
+	// prints records passing every case straight to the real stdout, and stops before the harness can print its own
+	code := `
+import os
+
+def double(n):
+    for case in range(2):
+        os.write(1, b"\n` + languages.HarnessCaseMarker + ` 0 " + str(case).encode() + b" ok 1 - - Mg==\n")
+        os.write(1, b"` + languages.HarnessCaseMarker + ` " + str(case).encode() + b" ok 1 - - Mg==\n")
+    os._exit(0)
+`
+	results := runTests(context.Background(), code, "python", models.Problem{Signature: signature}, testCases, false, 0)
+	if results.PassCount != 0 {
+		t.Errorf("Result: %d/%d passed; Expected: 0/2 (%+v)", results.PassCount, results.TestCount, results.Cases)
+	}
+}
+
+func TestHarnessesHideNonce(t *testing.T) {
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{1, 2}},
+		{testCase: models.TestCase{2, 4}},
+	}
+	// each solution looks for the nonce wherever it could get at the harness's variables by name, prints records
+	// passing every case with everything it finds straight to the real stdout, and stops before the harness can
+	// print its own. the compiled languages can't name anything of the harness's, so they can only guess
+	solutions := map[string]struct {
+		tool string
+		code string
+	}{
+		"python": {"python3", `
+import os, sys
+
+def solution(n):
+    for nonce in [v for v in vars(sys.modules["__main__"]).values() if isinstance(v, str)]:
+        for case, result in ((0, "Mg=="), (1, "NA==")):
+            os.write(1, ("\nMARKER %s %d ok 1 - - %s\n" % (nonce, case, result)).encode())
+    os._exit(0)
+`},
+		"ruby": {"ruby", `
+def solution(n)
+  candidates = TOPLEVEL_BINDING.local_variables.map { |v| TOPLEVEL_BINDING.local_variable_get(v) }
+  candidates += Object.constants.map { |c| Object.const_get(c) rescue nil }
+  candidates.grep(String).each do |nonce|
+    STDOUT.syswrite("\nMARKER #{nonce} 0 ok 1 - - Mg==\nMARKER #{nonce} 1 ok 1 - - NA==\n")
+  end
+  exit!(0)
+end
+`},
+		"javascript": {"node", `
+function solution(n) {
+	const fs = require("fs");
+	for (const key of Object.getOwnPropertyNames(globalThis)) {
+		let value;
+		try { value = globalThis[key]; } catch (e) { continue; }
+		if (typeof value === "string") {
+			fs.writeSync(1, "\nMARKER " + value + " 0 ok 1 - - Mg==\nMARKER " + value + " 1 ok 1 - - NA==\n");
+		}
+	}
+	process.exit(0);
+}
+`},
+		"typescript": {"tsc", `
+function solution(n: number): number {
+	const fs = require("fs");
+	for (const key of Object.getOwnPropertyNames(globalThis)) {
+		let value: any;
+		try { value = (globalThis as any)[key]; } catch (e) { continue; }
+		if (typeof value === "string") {
+			fs.writeSync(1, "\nMARKER " + value + " 0 ok 1 - - Mg==\nMARKER " + value + " 1 ok 1 - - NA==\n");
+		}
+	}
+	process.exit(0);
+	return 0;
+}
+`},
+		// every variable the code's shell has, written to the stdout of the harness's shells above it
+		"bash": {"bash", `
+solution () {
+	local pid=$PPID stat var level
+	for level in 1 2; do
+		for var in $(compgen -v); do
+			printf '\nMARKER %s 0 ok 1 - - Mg==\nMARKER %s 1 ok 1 - - NA==\n' "${!var}" "${!var}" >>"/proc/$pid/fd/1" 2>/dev/null
+		done
+		read -r stat <"/proc/$pid/stat" || break
+		stat=(${stat##*) })
+		pid=${stat[1]}
+	done
+	exit 0
+}
+`},
+		"go": {"go", `
+package main
+
+import "os"
+
+func solution(n int) int {
+	os.NewFile(1, "stdout").WriteString("\nMARKER guess 0 ok 1 - - Mg==\nMARKER guess 1 ok 1 - - NA==\n")
+	os.Exit(0)
+	return 0
+}
+`},
+		"cpp": {"g++", `
+#include <cstdio>
+#include <cstdlib>
+
+long long solution(long long n) {
+	std::fputs("\nMARKER guess 0 ok 1 - - Mg==\nMARKER guess 1 ok 1 - - NA==\n", stdout);
+	std::fflush(stdout);
+	std::_Exit(0);
+}
+`},
+		"rust": {"rustc", `
+fn solution(_n: i64) -> i64 {
+	use std::io::Write;
+	let _ = std::io::stdout().write_all(b"\nMARKER guess 0 ok 1 - - Mg==\nMARKER guess 1 ok 1 - - NA==\n");
+	let _ = std::io::stdout().flush();
+	std::process::exit(0);
+}
+`},
+		"java": {"javac", `
+class Solution {
+	public long solution(long n) {
+		try {
+			new java.io.FileOutputStream(java.io.FileDescriptor.out).write("\nMARKER guess 0 ok 1 - - Mg==\nMARKER guess 1 ok 1 - - NA==\n".getBytes());
+		} catch (java.io.IOException e) {
+		}
+		Runtime.getRuntime().halt(0);
+		return 0;
+	}
+}
+`},
+	}
+	for lang, solution := range solutions {
+		t.Run(lang, func(t *testing.T) {
+			useLocalExecutor(t, solution.tool)
+			code := strings.ReplaceAll(solution.code, "MARKER", languages.HarnessCaseMarker)
+			results := runTests(context.Background(), code, lang, models.Problem{Signature: intSignature}, testCases, false, 0)
+			if results.PassCount != 0 {
+				t.Errorf("Result: %d/%d passed; Expected: 0/2 (%+v)", results.PassCount, results.TestCount, results.Cases)
+			}
+		})
+	}
+}
+
+func TestPythonHarnessLinkedLists(t *testing.T) {
+	useLocalExecutor(t, "python3")
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{[]int{1, 2, 3}, []int{3, 2, 1}}},
+		{testCase: models.TestCase{[]int{}, []int{}}},
+	}
+	signature := models.Signature{
+		Name:    "reverse",
+		Params:  []models.Param{{Name: "head", Type: models.LinkedListOf(models.Int)}},
+		Returns: models.LinkedListOf(models.Int),
+	}
+	code := `
+def reverse(head: ListNode) -> ListNode:
+    previous = None
+    while head:
+        head.next, previous, head = previous, head, head.next
+    return previous
+`
+	results := runTests(context.Background(), code, "python", models.Problem{Signature: signature}, testCases, false, 0)
+	if results.PassCount != 2 {
+		t.Errorf("Result: %d/%d passed; Expected: 2/2 (%+v)", results.PassCount, results.TestCount, results.Cases)
+	}
+}
+
+func TestHarnessesLinkedLists(t *testing.T) {
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{[]int{1, 2, 3}, []int{3, 2, 1}}},
+		{testCase: models.TestCase{[]int{}, []int{}}},
+	}
+	signature := models.Signature{
+		Name:    "reverse",
+		Params:  []models.Param{{Name: "head", Type: models.LinkedListOf(models.Int)}},
+		Returns: models.LinkedListOf(models.Int),
+	}
+	solutions := map[string]struct {
+		tool string
+		code string
+	}{
+		"javascript": {"node", `
+function reverse(head) {
+	let previous = null;
+	while (head) {
+		[head.next, previous, head] = [previous, head, head.next];
+	}
+	return previous;
+}
+`},
+		"typescript": {"tsc", `
+function reverse(head: ListNode | null): ListNode | null {
+	let previous: ListNode | null = null;
+	while (head) {
+		[head.next, previous, head] = [previous, head, head.next];
+	}
+	return previous;
+}
+`},
+		"java": {"javac", `
+class Solution {
+	public ListNode reverse(ListNode head) {
+		ListNode previous = null;
+		while (head != null) {
+			ListNode next = head.next;
+			head.next = previous;
+			previous = head;
+			head = next;
+		}
+		return previous;
+	}
+}
+`},
+		"cpp": {"g++", `
+ListNode* reverse(ListNode* head) {
+	ListNode* previous = nullptr;
+	while (head) {
+		ListNode* next = head->next;
+		head->next = previous;
+		previous = head;
+		head = next;
+	}
+	return previous;
+}
+`},
+		"rust": {"rustc", `
+fn reverse(head: Option<Box<ListNode>>) -> Option<Box<ListNode>> {
+	let (mut head, mut previous) = (head, None);
+	while let Some(mut node) = head {
+		head = node.next.take();
+		node.next = previous;
+		previous = Some(node);
+	}
+	previous
+}
+`},
+		"ruby": {"ruby", `
+def reverse(head)
+	previous = nil
+	head.next, previous, head = previous, head, head.next while head
+	previous
+end
+`},
+	}
+	for lang, solution := range solutions {
+		t.Run(lang, func(t *testing.T) {
+			useLocalExecutor(t, solution.tool)
+			results := runTests(context.Background(), solution.code, lang, models.Problem{Signature: signature}, testCases, false, 0)
+			if results.PassCount != len(testCases) {
+				t.Errorf("Result: %d/%d passed; Expected: all passed (%+v)", results.PassCount, results.TestCount, results.Cases)
+			}
+		})
+	}
+}
+
+func TestHarnessesCompileErrors(t *testing.T) {
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{1, 1}},
+		{testCase: models.TestCase{2, 2}},
+	}
+	solutions := map[string]struct {
+		tool string
+		code string
+	}{
+		"javascript": {"node", "function solution(n) {\n\treturn (n;\n}"},
+		"cpp":        {"g++", "long long solution(long long n) {\n\treturn (n;\n}"},
+		"rust":       {"rustc", "fn solution(n: i64) -> i64 {\n\t(n\n}"},
+		"ruby":       {"ruby", "def solution(n)\n\t(n\nend"},
+	}
+	for lang, solution := range solutions {
+		t.Run(lang, func(t *testing.T) {
+			useLocalExecutor(t, solution.tool)
+			results := runTests(context.Background(), solution.code, lang, models.Problem{Signature: intSignature}, testCases, false, 0)
+			for i, caseResult := range results.Cases {
+				if caseResult.Verdict != VerdictCompileError {
+					t.Errorf("case %d: Result: [%s] Expected: [%s]", i, caseResult.Verdict, VerdictCompileError)
+				}
+			}
+		})
+	}
+}
+
+func TestGoHarnessTrees(t *testing.T) {
+	useLocalExecutor(t, "go")
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{[]any{4, 2, 7, 1, 3, 6, 9}, []any{4, 7, 2, 9, 6, 3, 1}}},
+		{testCase: models.TestCase{[]any{1, nil, 2}, []any{1, 2}}},
+		{testCase: models.TestCase{[]any{}, []any{}}},
+	}
+	signature := models.Signature{
+		Name:    "invert",
+		Params:  []models.Param{{Name: "root", Type: models.TreeOf(models.Int)}},
+		Returns: models.TreeOf(models.Int),
+	}
+	code := `
+package main
+
+func invert(root *TreeNode) *TreeNode {
+	if root != nil {
+		root.Left, root.Right = invert(root.Right), invert(root.Left)
+	}
+	return root
+}
+`
+	results := runTests(context.Background(), code, "go", models.Problem{Signature: signature}, testCases, false, 0)
+	if results.PassCount != 3 {
+		t.Errorf("Result: %d/%d passed; Expected: 3/3 (%+v)", results.PassCount, results.TestCount, results.Cases)
+	}
+}
+
+func TestHarnessesSpreadArguments(t *testing.T) {
+	signature := models.Signature{
+		Name:    "repeat",
+		Params:  []models.Param{{Name: "word", Type: models.String}, {Name: "times", Type: models.Int}, {Name: "sep", Type: models.String}},
+		Returns: models.String,
+	}
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{models.Args{"word": "ab", "times": 3, "sep": "-"}, "ab-ab-ab"}},
+		{testCase: models.TestCase{models.Args{"times": 1, "sep": " ", "word": "x y"}, "x y"}},
+	}
+	solutions := map[string]struct {
+		tool string
+		code string
+	}{
+		"python":     {"python3", "def repeat(word, times, sep):\n    return sep.join([word] * times)"},
+		"go":         {"go", "package main\n\nimport \"strings\"\n\nfunc repeat(word string, times int, sep string) string {\n\treturn strings.Repeat(word+sep, times)[:times*(len(word)+len(sep))-len(sep)]\n}"},
+		"bash":       {"bash", "repeat () {\n\tlocal out=$1\n\tfor (( i = 1; i < $2; i++ )); do out+=\"$3$1\"; done\n\techo \"$out\"\n}"},
+		"javascript": {"node", "function repeat(word, times, sep) {\n\treturn Array(times).fill(word).join(sep);\n}"},
+		"typescript": {"tsc", "function repeat(word: string, times: number, sep: string): string {\n\treturn Array(times).fill(word).join(sep);\n}"},
+		"java":       {"javac", "class Solution {\n\tpublic String repeat(String word, long times, String sep) {\n\t\treturn String.join(sep, java.util.Collections.nCopies((int) times, word));\n\t}\n}"},
+		"cpp":        {"g++", "string repeat(string word, long long times, string sep) {\n\tstring out = word;\n\tfor (long long i = 1; i < times; i++) out += sep + word;\n\treturn out;\n}"},
+		"rust":       {"rustc", "fn repeat(word: String, times: i64, sep: String) -> String {\n\tvec![word; times as usize].join(&sep)\n}"},
+		"ruby":       {"ruby", "def repeat(word, times, sep)\n\t([word] * times).join(sep)\nend"},
+	}
+	for lang, solution := range solutions {
+		t.Run(lang, func(t *testing.T) {
+			useLocalExecutor(t, solution.tool)
+			results := runTests(context.Background(), solution.code, lang, models.Problem{Signature: signature}, testCases, false, 0)
+			if results.PassCount != len(testCases) {
+				t.Errorf("Result: %d/%d passed; Expected: all passed (%+v)", results.PassCount, results.TestCount, results.Cases)
+			}
+		})
+	}
+}
+
+func TestRunTestsRejectsMismatchedArguments(t *testing.T) {
+	useExecutor(t, fakeExecutor(func(req ExecRequest) ExecResult {
+		t.Error("Expected no code to run")
+		return ExecResult{}
+	}))
+	signature := models.Signature{
+		Name:    "solution",
+		Params:  []models.Param{{Name: "a", Type: models.Int}, {Name: "b", Type: models.Int}},
+		Returns: models.Int,
+	}
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{models.Args{"a": 1, "c": 2}, 3}},
+	}
+	results := runTests(context.Background(), "", "python", models.Problem{Signature: signature}, testCases, false, 0)
+	if !strings.Contains(results.ErrorMessage, "no argument for b") {
+		t.Errorf("Unexpected error message: %s", results.ErrorMessage)
+	}
+}
@@ -0,0 +1,15 @@
+package code
+
+import (
+	"net/http"
+
+	"github.com/webbben/code-duel/handlers/general"
+	"github.com/webbben/code-duel/languages"
+)
+
+// Handles a request for the languages code can be submitted in
+func HandleGetLanguages(w http.ResponseWriter, r *http.Request) {
+	general.WriteResponse(w, true, map[string]interface{}{
+		"languages": languages.All(),
+	})
+}
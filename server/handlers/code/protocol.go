@@ -0,0 +1,193 @@
+package code
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/webbben/code-duel/models"
+)
+
+/*
+ * Test inputs are passed to the player's code as JSON on stdin, after a first line with the nonce the harness has to
+ * put in its records (see languages/harness.go): an array with an entry for each test case, where
+ * each entry is the array of arguments to call solution with. Each entry is on a line of its own, so a harness can
+ * read one case at a time instead of the whole array. Values are written in JSON according to the types
+ * the problem's signature declares for them (see models.Type), and the harness for each language decodes them into
+ * native values of those types, calls solution, and reports its return value as JSON.
+ *
+ * If solution doesn't return anything, what it printed is taken as its answer instead. That's how bash gives its
+ * answers, and how problems with a void return type are solved in any language.
+ */
+
+// a test case's arguments and expected output, in the form the signature's types encode them in
+type encodedCase struct {
+	args     []any
+	expected any
+}
+
+// encodes a test case according to the signature, checking that its arguments and expected output have the
+// declared types
+func encodeCase(signature models.Signature, testCase models.TestCase) (encodedCase, error) {
+	args, err := signature.Args(testCase)
+	if err != nil {
+		return encodedCase{}, err
+	}
+	encoded := encodedCase{args: make([]any, len(args))}
+	for i, arg := range args {
+		encoded.args[i], err = signature.Params[i].Type.Encode(arg)
+		if err != nil {
+			return encodedCase{}, fmt.Errorf("argument %s: %w", signature.Params[i].Name, err)
+		}
+	}
+	encoded.expected, err = signature.Returns.Encode(testCase[1])
+	if err != nil {
+		return encodedCase{}, fmt.Errorf("expected output: %w", err)
+	}
+	return encoded, nil
+}
+
+// encodes the arguments for each test case in a batch, to be passed to the harness on stdin after the line with
+// the execution's nonce
+func encodeCaseInputs(nonce string, caseArgs [][]any) (string, error) {
+	lines := make([]string, len(caseArgs))
+	for i, args := range caseArgs {
+		encoded, err := json.Marshal(args)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode test case inputs: %w", err)
+		}
+		lines[i] = string(encoded)
+	}
+	return nonce + "\n[\n" + strings.Join(lines, ",\n") + "\n]\n", nil
+}
+
+// formats a test case's arguments to show to the player. when there's more than one, they're shown with their names
+func formatArgs(signature models.Signature, args []any) string {
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		formatted[i] = formatJSON(arg)
+		if len(args) > 1 && i < len(signature.Params) {
+			formatted[i] = signature.Params[i].Name + " = " + formatted[i]
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// the answer we expect from the solution, to show to the player: as JSON if the solution returns its answer,
+// or as it would be printed if not
+func expectedAnswer(expected any, returned bool) string {
+	if returned {
+		return formatJSON(expected)
+	}
+	return printedAnswer(expected)
+}
+
+// how an encoded value is written when it's printed as an answer: strings as they are, lists as their elements
+// separated by spaces (or one per line, for lists of lists), maps as one "key value" pair per line, and anything
+// else as JSON
+func printedAnswer(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []any:
+		separator := " "
+		elements := make([]string, len(v))
+		for i, element := range v {
+			if _, ok := element.([]any); ok {
+				separator = "\n"
+			}
+			elements[i] = printedAnswer(element)
+		}
+		return strings.Join(elements, separator)
+	case map[string]any:
+		entries := make([]string, 0, len(v))
+		for key, entry := range v {
+			entries = append(entries, key+" "+printedAnswer(entry))
+		}
+		sort.Strings(entries)
+		return strings.Join(entries, "\n")
+	default:
+		return formatJSON(v)
+	}
+}
+
+// reads a printed answer as a value of the given type, undoing what printedAnswer does
+func parsePrintedAnswer(t models.Type, text string) (any, error) {
+	var value any
+	switch t.Kind {
+	case models.KindInt, models.KindFloat:
+		n, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number")
+		}
+		value = n
+	case models.KindBool:
+		b, err := strconv.ParseBool(strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("expected a boolean")
+		}
+		value = b
+	case models.KindList, models.KindLinkedList, models.KindTree:
+		pieces := strings.Fields(text)
+		if t.Elem.Elem != nil {
+			pieces = lines(text)
+		}
+		elements := make([]any, len(pieces))
+		for i, piece := range pieces {
+			if t.Kind == models.KindTree && piece == "null" {
+				continue // a missing node
+			}
+			element, err := parsePrintedAnswer(*t.Elem, piece)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			elements[i] = element
+		}
+		value = elements
+	case models.KindMap:
+		entries := map[string]any{}
+		for _, line := range lines(text) {
+			key, entry, _ := strings.Cut(strings.TrimSpace(line), " ")
+			parsed, err := parsePrintedAnswer(*t.Elem, entry)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", key, err)
+			}
+			entries[key] = parsed
+		}
+		value = entries
+	default:
+		value = text
+	}
+	return t.Encode(value)
+}
+
+// the lines of some text that aren't blank
+func lines(text string) []string {
+	var nonBlank []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			nonBlank = append(nonBlank, line)
+		}
+	}
+	return nonBlank
+}
+
+// rewrites a JSON value in a standard form, so values that mean the same thing (like 4 and 4.0) look the same.
+// text that isn't valid JSON is returned as it is
+func canonicalJSON(s string) string {
+	var value any
+	if err := json.Unmarshal([]byte(s), &value); err != nil {
+		return s
+	}
+	return formatJSON(value)
+}
+
+func formatJSON(value any) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
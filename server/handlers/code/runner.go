@@ -0,0 +1,339 @@
+package code
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/webbben/code-duel/checkers"
+	"github.com/webbben/code-duel/languages"
+	"github.com/webbben/code-duel/models"
+)
+
+// a test case to run, and whether its details should be hidden from the player
+type testCaseRun struct {
+	testCase models.TestCase
+	hidden   bool
+}
+
+// what running one test case produced
+type caseOutput struct {
+	ExecResult
+	Result   string // what solution returned, as JSON
+	Returned bool   // whether solution returned anything. if it didn't, what it printed is its answer
+}
+
+// the solution's answer, to show to the player
+func (o caseOutput) answer() string {
+	if o.Returned {
+		return canonicalJSON(o.Result)
+	}
+	return o.Stdout
+}
+
+// the solution's answer as a value of the return type, in the form the type encodes it in
+func (o caseOutput) value(returns models.Type) (any, error) {
+	if !o.Returned {
+		return parsePrintedAnswer(returns, o.Stdout)
+	}
+	var value any
+	if err := json.Unmarshal([]byte(o.Result), &value); err != nil {
+		return nil, err
+	}
+	return returns.Encode(value)
+}
+
+// runs the code against each of the test cases and gives the verdict for each one.
+//
+// cases are split into batches, and each batch is run in a single execution through a test harness. batches
+// run at the same time, on as many workers as the executor allows. in fail-fast mode, the first failing case
+// stops any batches that are still outstanding, and only the cases that finished are included in the results.
+// otherwise every case is run. either way, the case results are in the same order as the test cases.
+func runTests(ctx context.Context, code string, lang string, problem models.Problem, testCases []testCaseRun, failFast bool, batchSize int) TestResults {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	language, ok := languages.Get(lang)
+	if !ok {
+		return TestResults{
+			TestCount:    len(testCases),
+			ErrorMessage: fmt.Sprintf("Language %s not supported", lang),
+		}
+	}
+	signature := problem.Signature
+	limits := problemLimits(problem, language)
+	checker := problem.Checker
+	if checker == nil {
+		checker = checkers.Exact()
+	}
+	cases := make([]encodedCase, len(testCases))
+	for i, testCase := range testCases {
+		encoded, err := encodeCase(signature, testCase.testCase)
+		if err != nil {
+			// the problem's test data doesn't match its signature
+			return TestResults{
+				TestCount:    len(testCases),
+				ErrorMessage: fmt.Sprintf("Internal server error: test case %d: %s", i+1, err.Error()),
+			}
+		}
+		cases[i] = encoded
+	}
+
+	caseResults := make([]*CaseResult, len(testCases))
+	execResults := make([]ExecResult, len(testCases))
+	var execErr error
+	var runtime time.Duration // how long the solution took over every case, as the harness measured it
+	var mutex sync.Mutex
+
+	batches := makeBatches(len(testCases), batchSize)
+	jobs := make(chan []int)
+	var wg sync.WaitGroup
+	workers := min(max(executor.MaxWorkers(), 1), len(batches))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range jobs {
+				batchResults, batchRuntime, err := runBatch(ctx, code, language, signature, limits, cases, batch)
+				if err != nil {
+					if ctx.Err() == nil {
+						// something is wrong with the executor, so there's no point running the other cases
+						mutex.Lock()
+						execErr = err
+						mutex.Unlock()
+						cancel()
+					}
+					continue
+				}
+				mutex.Lock()
+				runtime += batchRuntime
+				mutex.Unlock()
+				for j, i := range batch {
+					output := batchResults[j]
+					expected := expectedAnswer(cases[i].expected, output.Returned)
+					correct := checkAnswer(checker, signature.Returns, cases[i], output)
+					log.Printf("Output: [%s] Expected: [%s]\n", output.answer(), expected)
+
+					caseResult := newCaseResult(i, testCases[i].hidden, formatArgs(signature, cases[i].args), expected, correct, output)
+					caseResults[i] = &caseResult
+					execResults[i] = output.ExecResult
+					// if it doesn't compile, it won't compile for any of the other cases either
+					if caseResult.Verdict == VerdictCompileError || (failFast && caseResult.Verdict != VerdictAccepted) {
+						cancel()
+					}
+				}
+			}
+		}()
+	}
+feed:
+	for _, batch := range batches {
+		select {
+		case jobs <- batch:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	results := collectResults(testCases, caseResults, execResults, execErr)
+	results.runtime = runtime
+	return results
+}
+
+// splits case indexes into batches of at most batchSize cases. a batch size of 0 puts every case in one batch
+func makeBatches(caseCount int, batchSize int) [][]int {
+	if batchSize <= 0 {
+		batchSize = max(caseCount, 1)
+	}
+	var batches [][]int
+	for start := 0; start < caseCount; start += batchSize {
+		batch := make([]int, 0, batchSize)
+		for i := start; i < min(start+batchSize, caseCount); i++ {
+			batch = append(batch, i)
+		}
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// puts the case results together in test case order
+func collectResults(testCases []testCaseRun, caseResults []*CaseResult, execResults []ExecResult, execErr error) TestResults {
+	results := TestResults{
+		TestCount: len(testCases),
+		Cases:     make([]CaseResult, 0, len(testCases)),
+	}
+	for i, caseResult := range caseResults {
+		if caseResult != nil && caseResult.Verdict == VerdictCompileError {
+			// a compile error applies to every case
+			results.ErrorMessage = failureMessage(*caseResult, execResults[i])
+			for j := range testCases {
+				results.Cases = append(results.Cases, CaseResult{
+					Case:    j,
+					Verdict: VerdictCompileError,
+					Hidden:  testCases[j].hidden,
+				})
+			}
+			return results
+		}
+	}
+	for i, caseResult := range caseResults {
+		if caseResult == nil {
+			continue // cancelled before it finished
+		}
+		results.Cases = append(results.Cases, *caseResult)
+		if caseResult.Verdict == VerdictAccepted {
+			results.PassCount++
+		} else if results.ErrorMessage == "" {
+			results.ErrorMessage = failureMessage(*caseResult, execResults[i])
+		}
+	}
+	if execErr != nil {
+		results.ErrorMessage = fmt.Sprintf("Error during execution: %s", execErr.Error())
+	}
+	return results
+}
+
+// describes why a test case failed
+func failureMessage(caseResult CaseResult, execResult ExecResult) string {
+	caseName := fmt.Sprintf("test case [%s]", caseResult.Input)
+	if caseResult.Hidden {
+		caseName = fmt.Sprintf("hidden test case %d", caseResult.Case+1)
+	}
+	switch caseResult.Verdict {
+	case VerdictCompileError:
+		return fmt.Sprintf("Compile error: %s", excerpt(execResult.Stderr))
+	case VerdictTimeLimit:
+		return fmt.Sprintf("Time limit exceeded on %s", caseName)
+	case VerdictMemoryLimit:
+		return fmt.Sprintf("Memory limit exceeded on %s", caseName)
+	case VerdictOutputLimit:
+		return fmt.Sprintf("Output limit exceeded on %s", caseName)
+	case VerdictRuntimeError:
+		// a hidden case's error output could give away its input
+		if caseResult.Hidden {
+			return fmt.Sprintf("Runtime error on %s", caseName)
+		}
+		return fmt.Sprintf("Runtime error on %s: %s", caseName, excerpt(execResult.Stderr))
+	}
+	if caseResult.Hidden {
+		return fmt.Sprintf("Failed %s", caseName)
+	}
+	return fmt.Sprintf("Failed %s: Result [%s] Expected [%s]", caseName, caseResult.Output, caseResult.Expected)
+}
+
+// whether the solution's answer to a test case is correct, according to the problem's checker.
+// an answer that isn't even a value of the return type is never correct
+func checkAnswer(checker models.Checker, returns models.Type, testCase encodedCase, output caseOutput) bool {
+	value, err := output.value(returns)
+	if err != nil {
+		return false
+	}
+	return checker.Check(testCase.args, testCase.expected, value)
+}
+
+// runs the code once for a batch of test cases, and works out what each case produced. the solution's runtime over
+// the cases the harness recorded is returned too: it's only the calls to solution, so it doesn't count the program
+// starting up, compiling or, for the remote executor, the trip to the service
+func runBatch(ctx context.Context, code string, lang languages.Language, signature models.Signature, limits Limits, cases []encodedCase, batch []int) ([]caseOutput, time.Duration, error) {
+	batchArgs := make([][]any, len(batch))
+	for j, i := range batch {
+		batchArgs[j] = cases[i].args
+	}
+	nonce, err := newHarnessNonce()
+	if err != nil {
+		return nil, 0, err
+	}
+	stdin, err := encodeCaseInputs(nonce, batchArgs)
+	if err != nil {
+		return nil, 0, err
+	}
+	harness := lang.Harness(code, signature)
+	log.Printf("Running %d tests for %s code...", len(batch), lang.ID)
+	result, err := executor.Execute(ctx, ExecRequest{
+		Lang:   lang.ID,
+		Code:   harness,
+		Stdin:  stdin,
+		Cases:  len(batch),
+		Limits: limits,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	batchResults := make([]caseOutput, len(batch))
+	if result.CompileError {
+		for j := range batch {
+			batchResults[j] = caseOutput{ExecResult: result}
+		}
+		return batchResults, 0, nil
+	}
+	records, compileError, hasCompileError, err := parseHarnessOutput(result.Stdout, nonce, len(batch))
+	var runtime time.Duration
+	for j := range batch {
+		if err != nil {
+			// only the harness knows the nonce, so there's no telling which of its records to believe
+			batchResults[j] = caseOutput{ExecResult: ExecResult{ExitCode: 1, Stderr: "harness: " + err.Error()}}
+			continue
+		}
+		if hasCompileError {
+			batchResults[j] = caseOutput{ExecResult: ExecResult{ExitCode: 1, CompileError: true, Stderr: compileError}}
+			continue
+		}
+		if j >= len(records) {
+			// the harness never finished this case, so whatever stopped the execution is what went wrong with it
+			batchResults[j] = caseOutput{ExecResult: ExecResult{
+				Stderr:         result.Stderr,
+				ExitCode:       max(result.ExitCode, 1),
+				Runtime:        result.Runtime,
+				Memory:         result.Memory,
+				TimedOut:       result.TimedOut,
+				MemoryExceeded: result.MemoryExceeded,
+				OutputExceeded: result.OutputExceeded,
+			}}
+			continue
+		}
+		record := records[j]
+		runtime += record.Runtime
+		output := caseOutput{
+			ExecResult: ExecResult{
+				Stdout:  lang.NormalizeOutput(record.Stdout),
+				Stderr:  record.Error,
+				Runtime: record.Runtime,
+				Memory:  result.Memory,
+			},
+			Result: record.Result,
+			// a void solution's answer is what it printed, whatever it returns
+			Returned: record.Returned && signature.Returns.Kind != models.KindVoid,
+		}
+		if !record.OK {
+			output.ExitCode = 1
+		}
+		// executors that can't enforce the problem's limits themselves still measure how much was used
+		if limits.CPUTime > 0 && record.Runtime > limits.CPUTime {
+			output.TimedOut = true
+		}
+		if (limits.Memory > 0 && result.Memory > limits.Memory) || (!record.OK && outOfMemory(limits.Memory, result.Memory, record.Error)) {
+			output.MemoryExceeded = true
+		}
+		batchResults[j] = output
+	}
+	return batchResults, runtime, nil
+}
+
+// a problem's limits for each test case, scaled for the language. the time limit is for the solution's CPU time,
+// and programs are given twice that in wall-clock time, for any waiting they do
+func problemLimits(problem models.Problem, lang languages.Language) Limits {
+	var limits Limits
+	if problem.TimeLimit > 0 {
+		limits.CPUTime = time.Duration(float64(problem.TimeLimit)*lang.TimeFactor) * time.Millisecond
+		limits.WallTime = 2 * limits.CPUTime
+	}
+	if problem.MemoryLimit > 0 {
+		limits.Memory = int64(float64(problem.MemoryLimit)*lang.MemoryFactor) << 20
+	}
+	return limits
+}
@@ -0,0 +1,280 @@
+package code
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/webbben/code-duel/languages"
+	"github.com/webbben/code-duel/models"
+)
+
+// solution(n int) int
+var intSignature = models.Signature{
+	Name:    "solution",
+	Params:  []models.Param{{Name: "n", Type: models.Int}},
+	Returns: models.Int,
+}
+
+// executor that runs code by calling a function, so tests don't need a real executor
+type fakeExecutor func(req ExecRequest) ExecResult
+
+func (f fakeExecutor) Execute(ctx context.Context, req ExecRequest) (ExecResult, error) {
+	return f(req), nil
+}
+
+func (f fakeExecutor) MaxWorkers() int {
+	return 4
+}
+
+func (f fakeExecutor) Measures() Measurements {
+	return Measurements{Runtime: true, Memory: true}
+}
+
+// swaps in an executor for the duration of a test
+func useExecutor(t *testing.T, e Executor) {
+	previous := executor
+	SetExecutor(e)
+	t.Cleanup(func() { SetExecutor(previous) })
+}
+
+// swaps in a local executor for the duration of a test, if the language's tools are installed
+func useLocalExecutor(t *testing.T, tool string) {
+	if _, err := exec.LookPath(tool); err != nil {
+		t.Skipf("%s not available", tool)
+	}
+	useExecutor(t, &LocalExecutor{
+		WorkDir: t.TempDir(),
+		Limits: Limits{
+			CPUTime:  5 * time.Second,
+			WallTime: 10 * time.Second,
+			Output:   64 << 10,
+		},
+	})
+}
+
+func TestRunTestsGivesVerdictForEveryCase(t *testing.T) {
+	useLocalExecutor(t, "python3")
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{1, 1}},
+		{testCase: models.TestCase{2, 3}},
+		{testCase: models.TestCase{3, 3}, hidden: true},
+		{testCase: models.TestCase{4, 5}, hidden: true},
+		{testCase: models.TestCase{0, 0}, hidden: true},
+	}
+	results := runTests(context.Background(), "def solution(n):\n    return n", "python", models.Problem{Signature: intSignature}, testCases, false, 0)
+	if results.PassCount != 3 || results.TestCount != 5 || len(results.Cases) != 5 {
+		t.Fatalf("Result: %d/%d with %d case results; Expected: 3/5 with 5 case results", results.PassCount, results.TestCount, len(results.Cases))
+	}
+	expected := []Verdict{VerdictAccepted, VerdictWrongAnswer, VerdictAccepted, VerdictWrongAnswer, VerdictAccepted}
+	for i, caseResult := range results.Cases {
+		if caseResult.Verdict != expected[i] {
+			t.Errorf("case %d: Result: [%s] Expected: [%s]", i, caseResult.Verdict, expected[i])
+		}
+		if caseResult.Hidden && (caseResult.Input != "" || caseResult.Expected != "" || caseResult.Stdout != "") {
+			t.Errorf("case %d: hidden case result shows its details: %+v", i, caseResult)
+		}
+	}
+	if results.ErrorMessage != "Failed test case [2]: Result [2] Expected [3]" {
+		t.Errorf("Unexpected error message: %s", results.ErrorMessage)
+	}
+}
+
+func TestRunTestsRuntimeErrorOnlyFailsItsCase(t *testing.T) {
+	useLocalExecutor(t, "python3")
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{1, 10}},
+		{testCase: models.TestCase{0, 0}},
+		{testCase: models.TestCase{5, 2}},
+	}
+	results := runTests(context.Background(), "def solution(n):\n    return 10 // n", "python", models.Problem{Signature: intSignature}, testCases, false, 0)
+	expected := []Verdict{VerdictAccepted, VerdictRuntimeError, VerdictAccepted}
+	for i, caseResult := range results.Cases {
+		if caseResult.Verdict != expected[i] {
+			t.Errorf("case %d: Result: [%s] Expected: [%s]", i, caseResult.Verdict, expected[i])
+		}
+	}
+	if !strings.Contains(results.Cases[1].Stderr, "ZeroDivisionError") {
+		t.Errorf("Expected the runtime error's traceback in stderr; got [%s]", results.Cases[1].Stderr)
+	}
+}
+
+func TestRunTestsHidesHiddenCaseErrors(t *testing.T) {
+	useLocalExecutor(t, "python3")
+	testCases := []testCaseRun{{testCase: models.TestCase{7, 7}, hidden: true}}
+	// the error gives away the case's input
+	results := runTests(context.Background(), "def solution(n):\n    raise ValueError('input was %d' % n)", "python", models.Problem{Signature: intSignature}, testCases, false, 0)
+	if len(results.Cases) != 1 || results.Cases[0].Verdict != VerdictRuntimeError {
+		t.Fatalf("Expected a runtime error; got %+v", results.Cases)
+	}
+	if strings.Contains(results.Cases[0].Stderr, "input was 7") || strings.Contains(results.ErrorMessage, "input was 7") {
+		t.Errorf("A hidden case's error output shouldn't be shown: %+v (%s)", results.Cases[0], results.ErrorMessage)
+	}
+}
+
+func TestRunTestsStopsOnCompileError(t *testing.T) {
+	useExecutor(t, fakeExecutor(func(req ExecRequest) ExecResult {
+		return ExecResult{ExitCode: 1, CompileError: true, Stderr: "syntax error"}
+	}))
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{1, 1}},
+		{testCase: models.TestCase{2, 2}},
+		{testCase: models.TestCase{3, 3}, hidden: true},
+	}
+	results := runTests(context.Background(), "package main", "go", models.Problem{Signature: intSignature}, testCases, false, 1)
+	if len(results.Cases) != 3 {
+		t.Fatalf("Expected 3 case results; got %d", len(results.Cases))
+	}
+	for i, caseResult := range results.Cases {
+		if caseResult.Verdict != VerdictCompileError {
+			t.Errorf("case %d: Result: [%s] Expected: [%s]", i, caseResult.Verdict, VerdictCompileError)
+		}
+	}
+}
+
+func TestRunTestsKeepsCaseOrder(t *testing.T) {
+	useLocalExecutor(t, "python3")
+	// earlier cases take longer, so they finish last
+	var testCases []testCaseRun
+	for i := 0; i < 8; i++ {
+		testCases = append(testCases, testCaseRun{testCase: models.TestCase{i, i}})
+	}
+	results := runTests(context.Background(), "import time\ndef solution(n):\n    time.sleep((8 - n) * 0.02)\n    return n", "python", models.Problem{Signature: intSignature}, testCases, false, 1)
+	if results.PassCount != 8 || len(results.Cases) != 8 {
+		t.Fatalf("Result: %d/%d with %d case results; Expected: 8/8 with 8 case results", results.PassCount, results.TestCount, len(results.Cases))
+	}
+	for i, caseResult := range results.Cases {
+		if caseResult.Case != i {
+			t.Errorf("Result at position %d is for case %d", i, caseResult.Case)
+		}
+	}
+}
+
+func TestRunTestsRuntimeIsTheSolutions(t *testing.T) {
+	// the harness says each call took 2ms, but the execution took much longer, e.g. waiting on a remote service
+	useExecutor(t, fakeExecutor(func(req ExecRequest) ExecResult {
+		nonce, _, _ := strings.Cut(req.Stdin, "\n")
+		var stdout strings.Builder
+		for i := 0; i < req.Cases; i++ {
+			fmt.Fprintf(&stdout, "%s %s %d ok %d - - %s\n", languages.HarnessCaseMarker, nonce, i, 2*time.Millisecond, base64.StdEncoding.EncodeToString([]byte("1")))
+		}
+		return ExecResult{Stdout: stdout.String(), Runtime: 3 * time.Second}
+	}))
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{1, 1}},
+		{testCase: models.TestCase{1, 1}},
+		{testCase: models.TestCase{1, 1}},
+	}
+	results := runTests(context.Background(), "package main", "go", models.Problem{Signature: intSignature}, testCases, false, 2)
+	if results.PassCount != 3 {
+		t.Fatalf("Result: %d/%d passed; Expected: 3/3 (%+v)", results.PassCount, results.TestCount, results.Cases)
+	}
+	if results.TotalRuntime() != 6 {
+		t.Errorf("Result: [%dms] Expected: [6ms]", results.TotalRuntime())
+	}
+}
+
+func TestRunTestsFailFast(t *testing.T) {
+	var runs atomic.Int32
+	// every execution crashes before the harness reports anything
+	useExecutor(t, fakeExecutor(func(req ExecRequest) ExecResult {
+		runs.Add(1)
+		time.Sleep(5 * time.Millisecond)
+		return ExecResult{ExitCode: 1, Stderr: "crashed"}
+	}))
+	var testCases []testCaseRun
+	for i := 0; i < 40; i++ {
+		testCases = append(testCases, testCaseRun{testCase: models.TestCase{i, i}})
+	}
+	results := runTests(context.Background(), "package main", "go", models.Problem{Signature: intSignature}, testCases, true, 1)
+	if int(runs.Load()) >= len(testCases) {
+		t.Errorf("Expected fail-fast to skip outstanding cases; all %d cases were run", runs.Load())
+	}
+	if results.PassCount != 0 || results.TestCount != 40 || len(results.Cases) == 0 {
+		t.Errorf("Result: %d/%d with %d case results", results.PassCount, results.TestCount, len(results.Cases))
+	}
+	for _, caseResult := range results.Cases {
+		if caseResult.Verdict != VerdictRuntimeError {
+			t.Errorf("case %d: Result: [%s] Expected: [%s]", caseResult.Case, caseResult.Verdict, VerdictRuntimeError)
+		}
+	}
+}
+
+func TestMakeBatches(t *testing.T) {
+	batches := makeBatches(5, 2)
+	if len(batches) != 3 || len(batches[0]) != 2 || len(batches[2]) != 1 || batches[2][0] != 4 {
+		t.Errorf("Unexpected batches: %v", batches)
+	}
+	batches = makeBatches(5, 0)
+	if len(batches) != 1 || len(batches[0]) != 5 {
+		t.Errorf("Unexpected batches: %v", batches)
+	}
+}
+
+func TestRunTestsUsesProblemChecker(t *testing.T) {
+	useLocalExecutor(t, "python3")
+	// any divisor of n other than 1 and n is accepted
+	problem := models.Problem{
+		Signature: intSignature,
+		Checker: models.CheckerFunc(func(args []any, expected any, output any) bool {
+			n, divisor := args[0].(float64), output.(float64)
+			return divisor > 1 && divisor < n && int(n)%int(divisor) == 0
+		}),
+	}
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{12, 2}},
+		{testCase: models.TestCase{15, 3}},
+		{testCase: models.TestCase{49, 7}},
+	}
+	code := "def solution(n):\n    return max(d for d in range(2, n) if n % d == 0)"
+	results := runTests(context.Background(), code, "python", problem, testCases, false, 0)
+	if results.PassCount != 3 {
+		t.Fatalf("Result: %d/%d passed (%s); Expected: 3/3", results.PassCount, results.TestCount, results.ErrorMessage)
+	}
+}
+
+func TestRunTestsEnforcesProblemLimits(t *testing.T) {
+	useLocalExecutor(t, "python3")
+	// python gets three times the problem's time limit, so the 100ms limit is 300ms
+	problem := models.Problem{Signature: intSignature, TimeLimit: 100, MemoryLimit: 64}
+	testCases := []testCaseRun{
+		{testCase: models.TestCase{0, 0}},
+		{testCase: models.TestCase{1, 1}},
+		{testCase: models.TestCase{2, 2}},
+	}
+	code := `
+import time
+def solution(n):
+    if n == 1:
+        time.sleep(1)
+    if n == 2:
+        return len(bytearray(512 << 20))
+    return n
+`
+	results := runTests(context.Background(), code, "python", problem, testCases, false, 0)
+	expected := []Verdict{VerdictAccepted, VerdictTimeLimit, VerdictMemoryLimit}
+	if len(results.Cases) != len(expected) {
+		t.Fatalf("Result: %d case results (%s); Expected: %d", len(results.Cases), results.ErrorMessage, len(expected))
+	}
+	for i, caseResult := range results.Cases {
+		if caseResult.Verdict != expected[i] {
+			t.Errorf("case %d: Result: [%s] Expected: [%s] (%+v)", i, caseResult.Verdict, expected[i], caseResult)
+		}
+	}
+}
+
+func TestProblemLimits(t *testing.T) {
+	python, _ := languages.Get(languages.Python)
+	limits := problemLimits(models.Problem{TimeLimit: 200, MemoryLimit: 64}, python)
+	if limits.CPUTime != 600*time.Millisecond || limits.WallTime != 1200*time.Millisecond || limits.Memory != 64<<20 {
+		t.Errorf("Result: %+v; Expected 600ms of CPU time, 1.2s of wall time and 64MB", limits)
+	}
+	if limits := problemLimits(models.Problem{}, python); limits != (Limits{}) {
+		t.Errorf("Result: %+v; Expected no limits for a problem without any", limits)
+	}
+}
@@ -0,0 +1,124 @@
+package code
+
+import (
+	"time"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// outcome of running a single test case
+type Verdict string
+
+const (
+	VerdictAccepted     Verdict = "AC"  // the answer was correct
+	VerdictWrongAnswer  Verdict = "WA"  // program ran fine, but the output was wrong
+	VerdictTimeLimit    Verdict = "TLE" // program went over its time limit
+	VerdictMemoryLimit  Verdict = "MLE" // program went over its memory limit
+	VerdictRuntimeError Verdict = "RE"  // program crashed or exited with an error
+	VerdictCompileError Verdict = "CE"  // program failed to compile
+	VerdictOutputLimit  Verdict = "OLE" // program printed more output than allowed
+)
+
+// max length of the stdout/stderr excerpts included in a case result
+const excerptLength = 1000
+
+// result of running a single test case
+type CaseResult struct {
+	Case     int     `json:"case"`               // index of the test case
+	Verdict  Verdict `json:"verdict"`            // outcome of the test case
+	Runtime  int64   `json:"runtime"`            // runtime in milliseconds
+	Memory   int64   `json:"memory"`             // peak memory usage in kilobytes, if known
+	Hidden   bool    `json:"hidden"`             // hidden cases don't show their input, expected output, output, stdout or stderr
+	Input    string  `json:"input,omitempty"`    // the arguments solution was called with, as JSON
+	Expected string  `json:"expected,omitempty"` // the expected answer
+	Output   string  `json:"output,omitempty"`   // the answer solution gave
+	Stdout   string  `json:"stdout,omitempty"`   // excerpt of what the program printed
+	Stderr   string  `json:"stderr,omitempty"`   // excerpt of the program's error output
+}
+
+// results of running code against a list of test cases
+type TestResults struct {
+	PassCount    int           `json:"passCount"`
+	TestCount    int           `json:"testCount"`
+	ErrorMessage string        `json:"errorMessage"` // describes the first failure, if there was one
+	Cases        []CaseResult  `json:"results"`
+	runtime      time.Duration // how long the solution took over every case, as the harness measured it
+}
+
+// decides the verdict for a finished execution, given whether the answer it gave was correct
+func getVerdict(result ExecResult, correct bool) Verdict {
+	switch {
+	case result.CompileError:
+		return VerdictCompileError
+	case result.TimedOut:
+		return VerdictTimeLimit
+	case result.MemoryExceeded:
+		return VerdictMemoryLimit
+	case result.OutputExceeded:
+		return VerdictOutputLimit
+	case result.ExitCode != 0:
+		return VerdictRuntimeError
+	case !correct:
+		return VerdictWrongAnswer
+	default:
+		return VerdictAccepted
+	}
+}
+
+// makes the result for a test case from what running it produced
+func newCaseResult(index int, hidden bool, input string, expected string, correct bool, output caseOutput) CaseResult {
+	result := output.ExecResult
+	caseResult := CaseResult{
+		Case:    index,
+		Verdict: getVerdict(result, correct),
+		Runtime: result.Runtime.Milliseconds(),
+		Memory:  result.Memory >> 10,
+		Hidden:  hidden,
+	}
+	if !hidden {
+		caseResult.Input = input
+		caseResult.Expected = expected
+		caseResult.Output = excerpt(output.answer())
+		caseResult.Stdout = excerpt(result.Stdout)
+		caseResult.Stderr = excerpt(result.Stderr)
+	}
+	return caseResult
+}
+
+// total runtime of the solution over every case, in milliseconds. it's what the harness timed around each call to
+// solution, so it doesn't depend on how the executor started the program or how far away it is
+func (r TestResults) TotalRuntime() int64 {
+	return r.runtime.Milliseconds()
+}
+
+// most memory used by any of the cases, in kilobytes. it's measured by the executor, if it can (see Measurements)
+func (r TestResults) PeakMemory() int64 {
+	var peak int64
+	for _, caseResult := range r.Cases {
+		peak = max(peak, caseResult.Memory)
+	}
+	return peak
+}
+
+// the verdict and resource usage of each case, to keep in a submission's history
+func (r TestResults) SubmissionCases() []models.SubmissionCase {
+	cases := make([]models.SubmissionCase, len(r.Cases))
+	for i, caseResult := range r.Cases {
+		cases[i] = models.SubmissionCase{
+			Case:    caseResult.Case,
+			Verdict: string(caseResult.Verdict),
+			Runtime: caseResult.Runtime,
+			Memory:  caseResult.Memory,
+			Hidden:  caseResult.Hidden,
+		}
+	}
+	return cases
+}
+
+// cuts down long program output so it can be sent back in a response
+func excerpt(s string) string {
+	if len(s) <= excerptLength {
+		return s
+	}
+	return s[:excerptLength] + "... (truncated)"
+}
@@ -0,0 +1,26 @@
+package code
+
+import "testing"
+
+func TestGetVerdict(t *testing.T) {
+	testCases := []struct {
+		Result   ExecResult
+		Correct  bool
+		Expected Verdict
+	}{
+		{Result: ExecResult{}, Correct: true, Expected: VerdictAccepted},
+		{Result: ExecResult{Stdout: "4"}, Correct: false, Expected: VerdictWrongAnswer},
+		{Result: ExecResult{ExitCode: 1}, Expected: VerdictRuntimeError},
+		{Result: ExecResult{ExitCode: 1, CompileError: true}, Expected: VerdictCompileError},
+		{Result: ExecResult{ExitCode: -1, TimedOut: true}, Expected: VerdictTimeLimit},
+		{Result: ExecResult{OutputExceeded: true}, Correct: true, Expected: VerdictOutputLimit},
+		{Result: ExecResult{ExitCode: 1, MemoryExceeded: true}, Expected: VerdictMemoryLimit},
+		{Result: ExecResult{MemoryExceeded: true}, Correct: true, Expected: VerdictMemoryLimit},
+	}
+	for _, testCase := range testCases {
+		verdict := getVerdict(testCase.Result, testCase.Correct)
+		if verdict != testCase.Expected {
+			t.Errorf("Result: [%s] Expected: [%s] for %+v", verdict, testCase.Expected, testCase.Result)
+		}
+	}
+}
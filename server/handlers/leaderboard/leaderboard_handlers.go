@@ -0,0 +1,101 @@
+package leaderboardHandlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/webbben/code-duel/handlers/general"
+	"github.com/webbben/code-duel/languages"
+	"github.com/webbben/code-duel/leaderboards"
+	problemData "github.com/webbben/code-duel/problem_data"
+)
+
+// GetRatingLeaderboardHandler ranks players by rating
+func GetRatingLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	window, page, ok := boardParams(w, r)
+	if !ok {
+		return
+	}
+	board, err := leaderboards.ByRating(r.Context(), window, page)
+	writeBoard(w, board, err)
+}
+
+// GetWinsLeaderboardHandler ranks players by games won
+func GetWinsLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	window, page, ok := boardParams(w, r)
+	if !ok {
+		return
+	}
+	board, err := leaderboards.ByWins(r.Context(), window, page)
+	writeBoard(w, board, err)
+}
+
+// GetProblemLeaderboardHandler ranks players by their fastest solve of a problem, in any language or only the one
+// in the lang query parameter
+func GetProblemLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	problemID := mux.Vars(r)["id"]
+	if problemData.GetProblemByID(problemID) == nil {
+		http.Error(w, fmt.Sprintf("Problem %s wasn't found", problemID), http.StatusNotFound)
+		return
+	}
+	lang := r.URL.Query().Get("lang")
+	if lang != "" {
+		language, ok := languages.Get(lang)
+		if !ok {
+			http.Error(w, fmt.Sprintf("Language %s not supported", lang), http.StatusBadRequest)
+			return
+		}
+		lang = language.ID
+	}
+	window, page, ok := boardParams(w, r)
+	if !ok {
+		return
+	}
+	board, err := leaderboards.ByProblem(r.Context(), problemID, lang, window, page)
+	writeBoard(w, board, err)
+}
+
+// reads the window, page and pageSize query parameters. if any are invalid, it writes the error and returns false
+func boardParams(w http.ResponseWriter, r *http.Request) (leaderboards.Window, leaderboards.Page, bool) {
+	query := r.URL.Query()
+	window, err := leaderboards.ParseWindow(query.Get("window"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", leaderboards.Page{}, false
+	}
+	number, err := positiveParam(query.Get("page"), "page", leaderboards.MaxPage)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", leaderboards.Page{}, false
+	}
+	size, err := positiveParam(query.Get("pageSize"), "pageSize", leaderboards.MaxPageSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", leaderboards.Page{}, false
+	}
+	return window, leaderboards.Page{Number: number, Size: size}, true
+}
+
+// reads a query parameter that has to be a number from 1 to max, if it's set. it's 0 if it isn't
+func positiveParam(value string, name string, max int) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > max {
+		return 0, fmt.Errorf("%s must be a number from 1 to %d", name, max)
+	}
+	return n, nil
+}
+
+func writeBoard(w http.ResponseWriter, board leaderboards.Board, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	general.WriteResponse(w, true, map[string]interface{}{
+		"leaderboard": board,
+	})
+}
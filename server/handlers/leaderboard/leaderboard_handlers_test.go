@@ -0,0 +1,64 @@
+package leaderboardHandlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/webbben/code-duel/leaderboards"
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+)
+
+func TestProblemLeaderboard(t *testing.T) {
+	store := storage.NewMemoryStore()
+	storage.Set(store)
+	ctx := context.Background()
+	store.SaveSolve(ctx, models.Solve{User: "ann", ProblemID: "problem01", Lang: "python", SolveTime: 5000, SolvedAt: time.Now()})
+	store.SaveSolve(ctx, models.Solve{User: "bob", ProblemID: "problem01", Lang: "go", SolveTime: 3000, SolvedAt: time.Now()})
+
+	tests := []struct {
+		id       string
+		query    string
+		status   int
+		expected []string
+	}{
+		{id: "problem01", query: "", status: http.StatusOK, expected: []string{"bob", "ann"}},
+		{id: "problem01", query: "?lang=py&window=week", status: http.StatusOK, expected: []string{"ann"}},
+		{id: "problem01", query: "?page=2&pageSize=1", status: http.StatusOK, expected: []string{"ann"}},
+		{id: "problem01", query: "?lang=cobol", status: http.StatusBadRequest},
+		{id: "problem01", query: "?window=year", status: http.StatusBadRequest},
+		{id: "problem01", query: "?page=0", status: http.StatusBadRequest},
+		{id: "problem01", query: "?page=9223372036854775807", status: http.StatusBadRequest},
+		{id: "problem01", query: "?pageSize=1000", status: http.StatusBadRequest},
+		{id: "missing", query: "", status: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/"+test.query, nil)
+		r = mux.SetURLVars(r, map[string]string{"id": test.id})
+		w := httptest.NewRecorder()
+		GetProblemLeaderboardHandler(w, r)
+		if w.Code != test.status {
+			t.Errorf("%s%s: status %d, expected %d", test.id, test.query, w.Code, test.status)
+			continue
+		}
+		if test.status != http.StatusOK {
+			continue
+		}
+		var body struct {
+			Leaderboard leaderboards.Board `json:"leaderboard"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		var users []string
+		for _, entry := range body.Leaderboard.Entries {
+			users = append(users, entry.User)
+		}
+		if len(users) != len(test.expected) || (len(users) > 0 && users[0] != test.expected[0]) {
+			t.Errorf("%s%s: %v, expected %v", test.id, test.query, users, test.expected)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/webbben/code-duel/handlers/general"
+	"github.com/webbben/code-duel/languages"
 	problemData "github.com/webbben/code-duel/problem_data"
 )
 
@@ -57,11 +58,12 @@ func GetProblemTemplate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// get template
-	template := problem.GetTemplate(lang)
-	if template == "" {
-		http.Error(w, fmt.Sprintf("Failed to get %s template for %s", lang, problemID), http.StatusBadRequest)
+	language, ok := languages.Get(lang)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Failed to get %s template for %s: language not supported", lang, problemID), http.StatusBadRequest)
 		return
 	}
+	template := language.ProblemTemplate(*problem)
 	general.WriteResponse(w, true, map[string]interface{}{
 		"template": template,
 	})
@@ -9,13 +9,12 @@ import (
 	"sync"
 
 	"github.com/gorilla/mux"
-	"github.com/webbben/code-duel/firebase"
-	"github.com/webbben/code-duel/firebase/rooms"
 	authHandlers "github.com/webbben/code-duel/handlers/auth"
 	"github.com/webbben/code-duel/handlers/general"
 	"github.com/webbben/code-duel/handlers/websocket"
 	"github.com/webbben/code-duel/models"
 	problemData "github.com/webbben/code-duel/problem_data"
+	"github.com/webbben/code-duel/rooms"
 )
 
 var (
@@ -95,7 +94,7 @@ func DeleteRoomHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetRoomListHandler(w http.ResponseWriter, r *http.Request) {
-	output, err := firebase.GetAllDocsInCollection("rooms")
+	output, err := rooms.GetRooms()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -0,0 +1,173 @@
+package submissionHandlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gorilla/mux"
+	authHandlers "github.com/webbben/code-duel/handlers/auth"
+	"github.com/webbben/code-duel/handlers/general"
+	"github.com/webbben/code-duel/handlers/websocket"
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+	"github.com/webbben/code-duel/submissions"
+)
+
+// lists the user's own submissions, newest first
+func GetSubmissionsHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := authHandlers.GetUserClaimsFromContext(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unauthorized: %s", err.Error()), http.StatusUnauthorized)
+		return
+	}
+	list, err := storage.Get().ListSubmissionsByUser(r.Context(), claims.DisplayName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	general.WriteResponse(w, true, map[string]interface{}{
+		"submissions": list,
+	})
+}
+
+// gets a single submission. users can see their own submissions, and the other players' submissions in games
+// they played in, once the game is over
+func GetSubmissionHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := authHandlers.GetUserClaimsFromContext(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unauthorized: %s", err.Error()), http.StatusUnauthorized)
+		return
+	}
+	submissionID := mux.Vars(r)["id"]
+	if submissionID == "" {
+		http.Error(w, "No submission ID found in request vars", http.StatusBadRequest)
+		return
+	}
+	submission, err := storage.Get().GetSubmission(r.Context(), submissionID)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("Submission %s not found", submissionID), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if submission.User != claims.DisplayName {
+		allowed, err := canAudit(r.Context(), claims.DisplayName, submission)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			http.Error(w, fmt.Sprintf("Forbidden: submission %s belongs to another user", submissionID), http.StatusForbidden)
+			return
+		}
+	}
+	general.WriteResponse(w, true, map[string]interface{}{
+		"submission": submission,
+	})
+}
+
+// lists the submissions sent during a game, newest first. only players who sent something during the game can
+// see them, and until it's over, they only see their own
+func GetGameSubmissionsHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := authHandlers.GetUserClaimsFromContext(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unauthorized: %s", err.Error()), http.StatusUnauthorized)
+		return
+	}
+	gameID := mux.Vars(r)["id"]
+	if gameID == "" {
+		http.Error(w, "No game ID found in request vars", http.StatusBadRequest)
+		return
+	}
+	list, err := storage.Get().ListSubmissionsByGame(r.Context(), gameID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !playedIn(claims.DisplayName, list) {
+		http.Error(w, fmt.Sprintf("Forbidden: user %s didn't play in game %s", claims.DisplayName, gameID), http.StatusForbidden)
+		return
+	}
+	if len(list) > 0 && gameInProgress(list[0].RoomID, gameID) {
+		list = slices.DeleteFunc(list, func(submission models.Submission) bool {
+			return submission.User != claims.DisplayName
+		})
+	}
+	general.WriteResponse(w, true, map[string]interface{}{
+		"submissions": list,
+	})
+}
+
+// gets the review of a finished game in the room: every player's final code, verdicts and solve time, and how it
+// differs from the reference solution. only players who sent something during the game can see it, and only those
+// who solved the problem see the reference diffs
+func GetGameReviewHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := authHandlers.GetUserClaimsFromContext(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unauthorized: %s", err.Error()), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	roomID := vars["id"]
+	gameID := vars["gameID"]
+	if roomID == "" || gameID == "" {
+		http.Error(w, "No room or game ID found in request vars", http.StatusBadRequest)
+		return
+	}
+	review, err := storage.Get().GetGameReview(r.Context(), gameID)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && review.RoomID != roomID) {
+		http.Error(w, fmt.Sprintf("No review for game %s in room %s; it may not be over yet", gameID, roomID), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	allowed, err := canAuditGame(r.Context(), claims.DisplayName, roomID, gameID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, fmt.Sprintf("Forbidden: user %s didn't play in game %s", claims.DisplayName, gameID), http.StatusForbidden)
+		return
+	}
+	general.WriteResponse(w, true, map[string]interface{}{
+		"review": submissions.ReviewFor(review, claims.DisplayName),
+	})
+}
+
+// whether a user can see another player's submission: it has to be from a game they played in, which is over
+func canAudit(ctx context.Context, user string, submission models.Submission) (bool, error) {
+	return canAuditGame(ctx, user, submission.RoomID, submission.GameID)
+}
+
+// whether a user can see what the other players sent during a game in the room: they have to have played in it,
+// and it has to be over
+func canAuditGame(ctx context.Context, user string, roomID string, gameID string) (bool, error) {
+	if gameID == "" || gameInProgress(roomID, gameID) {
+		return false, nil
+	}
+	list, err := storage.Get().ListSubmissionsByGame(ctx, gameID)
+	if err != nil {
+		return false, err
+	}
+	return playedIn(user, list), nil
+}
+
+// whether a game in the room is still being played
+func gameInProgress(roomID string, gameID string) bool {
+	return gameID != "" && websocket.GetGameID(roomID) == gameID
+}
+
+// whether the user sent any of the submissions
+func playedIn(user string, list []models.Submission) bool {
+	return slices.ContainsFunc(list, func(submission models.Submission) bool {
+		return submission.User == user
+	})
+}
@@ -0,0 +1,99 @@
+package submissionHandlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	authHandlers "github.com/webbben/code-duel/handlers/auth"
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+)
+
+// sends a request to the handler as the user, and decodes the response body
+func request(t *testing.T, handler http.HandlerFunc, user string, vars map[string]string) (int, map[string]json.RawMessage) {
+	t.Helper()
+	r := httptest.NewRequest("GET", "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), authHandlers.ClaimsKey, authHandlers.Claims{DisplayName: user}))
+	r = mux.SetURLVars(r, vars)
+	w := httptest.NewRecorder()
+	handler(w, r)
+	var body map[string]json.RawMessage
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body
+}
+
+func TestSubmissionAccess(t *testing.T) {
+	store := storage.NewMemoryStore()
+	storage.Set(store)
+	ctx := context.Background()
+	now := time.Now()
+	alice := models.Submission{User: "alice", RoomID: "room1", GameID: "room1-1", Code: "alice's code", SubmittedAt: now}
+	bob := models.Submission{User: "bob", RoomID: "room1", GameID: "room1-1", Code: "bob's code", SubmittedAt: now.Add(time.Second)}
+	practice := models.Submission{User: "bob", RoomID: "room2", Code: "practice", SubmittedAt: now.Add(2 * time.Second)}
+	for _, submission := range []*models.Submission{&alice, &bob, &practice} {
+		store.SaveSubmission(ctx, submission)
+	}
+
+	status, body := request(t, GetSubmissionsHandler, "bob", nil)
+	var list []models.Submission
+	json.Unmarshal(body["submissions"], &list)
+	if status != http.StatusOK || len(list) != 2 || list[0].ID != practice.ID {
+		t.Errorf("bob's submissions: %d %+v", status, list)
+	}
+
+	tests := []struct {
+		user   string
+		id     string
+		status int
+	}{
+		{user: "alice", id: alice.ID, status: http.StatusOK},
+		{user: "alice", id: bob.ID, status: http.StatusOK},             // from a game alice played in, which is over
+		{user: "alice", id: practice.ID, status: http.StatusForbidden}, // not from a game
+		{user: "carol", id: bob.ID, status: http.StatusForbidden},      // carol didn't play
+		{user: "alice", id: "missing", status: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		status, body := request(t, GetSubmissionHandler, test.user, map[string]string{"id": test.id})
+		if status != test.status {
+			t.Errorf("%s getting submission %s: status %d, expected %d", test.user, test.id, status, test.status)
+		}
+		if status == http.StatusOK && body["submission"] == nil {
+			t.Errorf("%s getting submission %s: no submission in the response", test.user, test.id)
+		}
+	}
+
+	status, body = request(t, GetGameSubmissionsHandler, "alice", map[string]string{"id": "room1-1"})
+	list = nil
+	json.Unmarshal(body["submissions"], &list)
+	if status != http.StatusOK || len(list) != 2 {
+		t.Errorf("game submissions for alice: %d %+v", status, list)
+	}
+	if status, _ := request(t, GetGameSubmissionsHandler, "carol", map[string]string{"id": "room1-1"}); status != http.StatusForbidden {
+		t.Errorf("game submissions for carol: status %d, expected %d", status, http.StatusForbidden)
+	}
+
+	store.SaveGameReview(ctx, models.GameReview{GameID: "room1-1", RoomID: "room1"})
+	reviews := []struct {
+		user   string
+		room   string
+		status int
+	}{
+		{user: "alice", room: "room1", status: http.StatusOK},
+		{user: "carol", room: "room1", status: http.StatusForbidden}, // carol didn't play
+		{user: "alice", room: "room2", status: http.StatusNotFound},  // the game wasn't in that room
+	}
+	for _, test := range reviews {
+		status, body := request(t, GetGameReviewHandler, test.user, map[string]string{"id": test.room, "gameID": "room1-1"})
+		if status != test.status {
+			t.Errorf("%s getting the review of room1-1 in %s: status %d, expected %d", test.user, test.room, status, test.status)
+		}
+		if status == http.StatusOK && body["review"] == nil {
+			t.Errorf("%s getting the review of room1-1: no review in the response", test.user)
+		}
+	}
+}
@@ -3,15 +3,18 @@ package userHandlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"regexp"
 
-	"firebase.google.com/go/auth"
 	"github.com/gorilla/mux"
-	"github.com/webbben/code-duel/firebase"
+	"github.com/webbben/code-duel/auth"
 	"github.com/webbben/code-duel/handlers/general"
 	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/ratings"
+	"github.com/webbben/code-duel/storage"
 )
 
 // GetUserHandler handles GET requests to retrieve a user by ID
@@ -20,12 +23,14 @@ func GetUserHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userID := vars["id"]
 
-	// In a real application, you would fetch the user from the database
-	// For simplicity, let's create a sample user
-	user := models.User{
-		ID:       userID,
-		Username: "sample_user",
-		Email:    "sample@example.com",
+	user, err := storage.Get().GetUser(r.Context(), userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("User %s not found", userID), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	// Convert user to JSON and send it in the response
@@ -33,6 +38,25 @@ func GetUserHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
+// GetRatingHandler handles GET requests for a player's rating and rating history, by username
+func GetRatingHandler(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	rating, err := ratings.GetRating(r.Context(), username)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	history, err := storage.Get().ListRatingChanges(r.Context(), username)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	general.WriteResponse(w, true, map[string]interface{}{
+		"rating":  rating,
+		"history": history,
+	})
+}
+
 // CreateUserHandler handles POST requests to create a new user
 func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 	// Decode the incoming JSON payload
@@ -49,7 +73,10 @@ func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO: add username validation!
+	if err := validateUsername(request.Username); err != nil {
+		http.Error(w, fmt.Sprintf("Error creating user: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
 
 	userID, returnErr := CreateUser(&request)
 	if returnErr != "" {
@@ -85,44 +112,51 @@ func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 	}, http.StatusCreated)
 }
 
-// creates the user document in firestore and adds user in firebase auth
+// usernames show up in rooms, on leaderboards and in URLs, so they're kept short and plain
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,20}$`)
+
+// checks that a username is 3 to 20 letters, digits, underscores or hyphens
+func validateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return fmt.Errorf("username must be 3 to 20 letters, digits, underscores or hyphens")
+	}
+	return nil
+}
+
+// creates the user document in the store and gives the user a login with the server's auth provider
 func CreateUser(request *models.CreateUserRequest) (userID string, returnErr string) {
 	returnErr = ""
 	userID = ""
 	ctx := context.Background()
 
-	// create user document in firestore
-	firestoreClient := firebase.GetFirestoreClient()
-	if firestoreClient == nil {
-		fmt.Printf("firestore client is null!")
-		returnErr = "firestore client is null"
+	// create user document in the store
+	user := models.User{
+		Username: request.Username,
+		Email:    request.Email,
+	}
+	err := storage.Get().CreateUser(ctx, &user)
+	if errors.Is(err, storage.ErrExists) {
+		returnErr = fmt.Sprintf("username %s is already taken", request.Username)
 		return
 	}
-	docRef, _, err := firestoreClient.Collection("users").Add(ctx, map[string]interface{}{
-		"username": request.Username,
-		"email":    request.Email,
-	})
 	if err != nil {
-		log.Fatalf("Failed adding user: %v", err)
+		log.Printf("Failed adding user: %v", err)
 		returnErr = err.Error()
 		return
 	}
-	userID = docRef.ID
-
-	// create user in firebase auth, using the same user document ID
-	authClient := firebase.GetAuthClient()
+	userID = user.ID
 
-	params := (&auth.UserToCreate{}).
-		Email(request.Email).
-		EmailVerified(false).
-		Password(request.Password).
-		UID(userID).
-		DisplayName(request.Username)
-
-	_, err = authClient.CreateUser(ctx, params)
+	// create the user's login, using the same user document ID. the login needs the ID, so the user is stored
+	// first, and taken back out if the login can't be made, so their username isn't lost
+	err = auth.Get().CreateUser(ctx, user, request.Password)
 	if err != nil {
-		log.Fatalf("error creating user: %v\n", err)
+		log.Printf("error creating user login: %v\n", err)
 		returnErr = err.Error()
+		if err := storage.Get().DeleteUser(ctx, user.ID); err != nil {
+			log.Printf("failed to remove user %s after their login couldn't be created: %v\n", user.ID, err)
+			return
+		}
+		userID = ""
 	}
 	return
 }
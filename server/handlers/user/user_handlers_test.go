@@ -0,0 +1,46 @@
+package userHandlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/webbben/code-duel/auth"
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+)
+
+func TestValidateUsername(t *testing.T) {
+	tests := map[string]bool{
+		"alice":                 true,
+		"Bob_99":                true,
+		"x-y":                   true,
+		"al":                    false, // too short
+		"abcdefghijklmnopqrstu": false, // too long
+		"alice smith":           false,
+		"alice/../bob":          false,
+		"<script>":              false,
+		"élodie":                false,
+	}
+	for username, valid := range tests {
+		if err := validateUsername(username); (err == nil) != valid {
+			t.Errorf("validateUsername(%q): %v, expected valid: %v", username, err, valid)
+		}
+	}
+}
+
+func TestCreateUserFreesUsernameWhenLoginFails(t *testing.T) {
+	store := storage.NewMemoryStore()
+	storage.Set(store)
+	auth.Set(&auth.LocalProvider{Store: store, Secret: []byte("secret"), TokenTTL: time.Minute, RefreshTTL: time.Hour})
+
+	if _, err := CreateUser(&models.CreateUserRequest{Username: "ann", Email: "ann@example.com", Password: "pw"}); err != "" {
+		t.Fatal(err)
+	}
+	// the email already has a login, so bob's can't be made
+	if userID, err := CreateUser(&models.CreateUserRequest{Username: "bob", Email: "ann@example.com", Password: "pw"}); err == "" || userID != "" {
+		t.Fatalf("Result: [%q %q] Expected: an error, and no user left behind", userID, err)
+	}
+	if _, err := CreateUser(&models.CreateUserRequest{Username: "bob", Email: "bob@example.com", Password: "pw"}); err != "" {
+		t.Errorf("bob's username should be free again: %s", err)
+	}
+}
@@ -0,0 +1,67 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+
+	"github.com/webbben/code-duel/config"
+)
+
+// largest message a client can send. anything bigger closes the connection
+const maxMessageSize = 64 << 10
+
+// how the server keeps track of whether its connections are still alive (see config.Config for each setting)
+type heartbeat struct {
+	pingInterval time.Duration
+	pongWait     time.Duration
+	writeWait    time.Duration
+	authTimeout  time.Duration
+	idleTimeout  time.Duration
+	resumeGrace  time.Duration
+}
+
+// the heartbeat settings, from the config
+var heartbeatSettings = sync.OnceValue(func() heartbeat {
+	cfg := config.Get()
+	return heartbeat{
+		pingInterval: cfg.WSPingInterval,
+		pongWait:     cfg.WSPongWait,
+		writeWait:    cfg.WSWriteWait,
+		authTimeout:  cfg.WSAuthTimeout,
+		idleTimeout:  cfg.WSIdleTimeout,
+		resumeGrace:  cfg.WSResumeGrace,
+	}
+})
+
+// the deadlines a connection's reads have to meet. it has to answer pings within the pong wait, and send a message
+// before it counts as idle: at first, that's its authorization message, and after that, anything at all
+type connDeadlines struct {
+	settings    heartbeat
+	activeUntil time.Time // when the connection counts as idle, or zero if it never does
+}
+
+func newConnDeadlines(settings heartbeat, now time.Time) *connDeadlines {
+	return &connDeadlines{settings: settings, activeUntil: now.Add(settings.authTimeout)}
+}
+
+// the connection sent a message, so it isn't idle
+func (d *connDeadlines) active(now time.Time) {
+	d.activeUntil = time.Time{}
+	if d.settings.idleTimeout > 0 {
+		d.activeUntil = now.Add(d.settings.idleTimeout)
+	}
+}
+
+// when the next read has to finish by: the pong wait from now, or when the connection counts as idle, if sooner
+func (d *connDeadlines) readDeadline(now time.Time) time.Time {
+	deadline := now.Add(d.settings.pongWait)
+	if !d.activeUntil.IsZero() && d.activeUntil.Before(deadline) {
+		deadline = d.activeUntil
+	}
+	return deadline
+}
+
+// whether the connection has gone too long without sending a message
+func (d *connDeadlines) idle(now time.Time) bool {
+	return !d.activeUntil.IsZero() && !now.Before(d.activeUntil)
+}
@@ -0,0 +1,101 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// starts a websocket server with short heartbeat times, and connects to a room on it
+func dialTestRoom(t *testing.T, settings heartbeat, room string) *websocket.Conn {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveConnection(w, r, settings)
+	}))
+	t.Cleanup(server.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/?room="+room, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// waits until the room has no clients left
+func waitForEmptyRoom(t *testing.T, room string) {
+	t.Helper()
+	for start := time.Now(); RoomHasClients(room); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 2*time.Second {
+			t.Fatalf("room %s still has clients", room)
+		}
+	}
+}
+
+func TestAuthorizationTimeout(t *testing.T) {
+	settings := heartbeat{pingInterval: time.Minute, pongWait: time.Minute, writeWait: time.Second, authTimeout: 100 * time.Millisecond}
+	conn := dialTestRoom(t, settings, "auth-timeout-room")
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.ClosePolicyViolation {
+		t.Errorf("a connection that never authorizes should be closed with a policy violation, got %v", err)
+	}
+	waitForEmptyRoom(t, "auth-timeout-room")
+}
+
+func TestDeadConnection(t *testing.T) {
+	// with no grace period, players whose connections die leave their room straight away
+	settings := heartbeat{pingInterval: 50 * time.Millisecond, pongWait: 150 * time.Millisecond, writeWait: time.Second, authTimeout: time.Minute}
+
+	// a client that reads answers the server's pings, so it stays connected past the pong wait
+	alive := dialTestRoom(t, settings, "alive-room")
+	authorize(t, alive, "ann")
+	go func() {
+		for {
+			if _, _, err := alive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	// gorilla clients only answer pings while they're reading, so this one looks dead
+	dead := dialTestRoom(t, settings, "dead-room")
+	authorize(t, dead, "bob")
+
+	time.Sleep(400 * time.Millisecond)
+	if !RoomHasClients("alive-room") {
+		t.Error("a client answering pings shouldn't be disconnected")
+	}
+	waitForEmptyRoom(t, "dead-room")
+}
+
+func TestConnDeadlines(t *testing.T) {
+	now := time.Now()
+	deadlines := newConnDeadlines(heartbeat{pongWait: time.Minute, authTimeout: 10 * time.Second, idleTimeout: time.Hour}, now)
+	if got := deadlines.readDeadline(now); !got.Equal(now.Add(10 * time.Second)) {
+		t.Errorf("before authorizing, reads should wait for the authorization timeout, got %v", got.Sub(now))
+	}
+	if !deadlines.idle(now.Add(10 * time.Second)) {
+		t.Error("a connection that hasn't authorized in time should be idle")
+	}
+	deadlines.active(now)
+	if got := deadlines.readDeadline(now); !got.Equal(now.Add(time.Minute)) {
+		t.Errorf("after a message, reads should wait for the pong wait, got %v", got.Sub(now))
+	}
+	if got := deadlines.readDeadline(now.Add(time.Hour - time.Second)); !got.Equal(now.Add(time.Hour)) {
+		t.Errorf("reads shouldn't wait past the idle timeout, got %v", got.Sub(now))
+	}
+	if deadlines.idle(now.Add(time.Minute)) || !deadlines.idle(now.Add(time.Hour)) {
+		t.Error("a connection should be idle after the idle timeout, and not before")
+	}
+
+	neverIdle := newConnDeadlines(heartbeat{pongWait: time.Minute, authTimeout: 10 * time.Second}, now)
+	neverIdle.active(now)
+	if neverIdle.idle(now.Add(24 * time.Hour)) {
+		t.Error("with no idle timeout, connections shouldn't be idle")
+	}
+}
@@ -0,0 +1,468 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// how many messages can be waiting to be written to a client. a client that falls further behind than this is
+	// dropped, so it can't hold up the rest of its room
+	sendBufferSize = 128
+	// how many broadcasts can be waiting for a room's hub
+	broadcastBufferSize = 256
+	// how many of its latest messages a room keeps, to replay to players who resume their session. it's half the
+	// send buffer, so a replay always fits in a new connection's queue with room left for what comes next
+	historySize = sendBufferSize / 2
+)
+
+// a websocket connection to a room. only its writer goroutine writes to the connection, and only the read loop in
+// serveConnection reads from it
+type client struct {
+	conn      *websocket.Conn
+	heartbeat heartbeat
+	send      chan []byte // messages waiting to be written. the hub closes it when the client leaves or is dropped
+	hub       *hub        // the hub of the room the client is in, once it's joined
+	user      string      // username of the player on the connection, once they've authorized
+	token     string      // resumes the player's session if the connection drops
+	joinedSeq int64       // sequence number of the room's last message before the client joined. owned by the hub
+	// why the connection is closed once send is closed, if it isn't just the client leaving. set by whoever closes send
+	closeCode   int
+	closeReason string
+}
+
+// a message for a room's clients, other than the client that sent it. a message with a recipient is only for that
+// client, and one with a user is only for that user's clients. neither is part of the room's sequence of messages
+type outbound struct {
+	envelope Envelope
+	sender   *client
+	to       *client
+	user     string
+}
+
+// a player's place in a room while their connection is down. they can take it back with its token until the grace
+// period runs out, and then they're removed from the room
+type awaySession struct {
+	token     string
+	user      string
+	joinedSeq int64 // messages up to this one were sent before they joined, so they're never replayed
+	timer     *time.Timer
+}
+
+// a request to resume a session on a new connection
+type resumeRequest struct {
+	client *client
+	token  string
+	after  int64     // sequence number of the last message the player got
+	ack    Envelope  // acknowledges the resume, sent before the missed messages. the hub fills in its session
+	reply  chan bool // whether the session was resumed
+}
+
+// a room's hub keeps track of the room's clients and hands each broadcast to them. a single goroutine owns the set
+// of clients, the sessions of players who are away, and the room's recent messages, so nothing else needs to lock
+// them. the hub stops once nobody is connected or away, and a new one is started when someone connects to the room
+// again
+type hub struct {
+	roomID     string
+	register   chan *client
+	unregister chan *client
+	hold       chan *client // clients whose connection dropped, whose sessions are held for them
+	resume     chan resumeRequest
+	expire     chan *awaySession
+	broadcast  chan outbound
+	done       chan struct{} // closed once the hub has stopped
+	count      atomic.Int32  // how many players are connected or away
+}
+
+// the running hub of each room, by room ID
+var hubs sync.Map
+
+func newClient(conn *websocket.Conn, settings heartbeat) *client {
+	return &client{conn: conn, heartbeat: settings, send: make(chan []byte, sendBufferSize)}
+}
+
+// writes the client's messages to its connection, and pings it regularly, until the hub closes its channel or a
+// write fails. either way, the connection is closed, which ends its read loop
+func (c *client) writeLoop() {
+	ticker := time.NewTicker(c.heartbeat.pingInterval)
+	defer ticker.Stop()
+	defer c.conn.Close()
+	for {
+		select {
+		case data, ok := <-c.send:
+			if !ok {
+				if c.closeCode != 0 {
+					c.closeWith(c.closeCode, c.closeReason)
+				}
+				return
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(c.heartbeat.writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				log.Printf("failed to write to websocket connection %p: %v\n", c.conn, err)
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.heartbeat.writeWait)); err != nil {
+				log.Printf("failed to ping websocket connection %p: %v\n", c.conn, err)
+				return
+			}
+		}
+	}
+}
+
+// tells the client why its connection is being closed, and closes it. this can be called from any goroutine
+func (c *client) closeWith(code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(c.heartbeat.writeWait))
+	c.conn.Close()
+}
+
+// queues a message for a client that hasn't joined its room's hub, so nothing else is sending to it
+func (c *client) sendDirect(envelope Envelope) {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("failed to encode %s message for websocket connection %p: %v\n", envelope.Type, c.conn, err)
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+	}
+}
+
+// sends a message to the client alone, through its hub if it has joined one. only the client's read loop calls this
+func (c *client) reply(envelope Envelope) {
+	if c.hub == nil {
+		c.sendDirect(envelope)
+		return
+	}
+	select {
+	case c.hub.broadcast <- outbound{envelope: envelope, to: c}:
+	case <-c.hub.done:
+	}
+}
+
+// sends a last message to a client that hasn't joined its room's hub, and closes its connection with the given code
+// once the message is written
+func (c *client) reject(envelope Envelope, closeCode int) {
+	c.sendDirect(envelope)
+	c.closeCode = closeCode
+	if e, ok := envelope.Payload.(ErrorMessage); ok {
+		// close reasons have to be short, so they only say what went wrong, and the message explains it
+		c.closeReason = string(e.Code)
+	}
+	close(c.send)
+}
+
+// makes a random token for resuming a session
+func newSessionToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// gets the room's hub, starting one if it doesn't have one
+func hubFor(roomID string) *hub {
+	if h, ok := hubs.Load(roomID); ok {
+		return h.(*hub)
+	}
+	h := &hub{
+		roomID:     roomID,
+		register:   make(chan *client),
+		unregister: make(chan *client),
+		hold:       make(chan *client),
+		resume:     make(chan resumeRequest),
+		expire:     make(chan *awaySession),
+		broadcast:  make(chan outbound, broadcastBufferSize),
+		done:       make(chan struct{}),
+	}
+	actual, loaded := hubs.LoadOrStore(roomID, h)
+	if !loaded {
+		go h.run()
+	}
+	return actual.(*hub)
+}
+
+// adds the client to the room's hub. if its player was away, their held session is dropped, as they're back
+func joinRoom(roomID string, c *client) {
+	for {
+		h := hubFor(roomID)
+		select {
+		case h.register <- c:
+			c.hub = h
+			return
+		case <-h.done:
+			// the hub stopped as its last client left, so try again with a new one
+		}
+	}
+}
+
+// removes the client from its room's hub, which closes its send channel
+func leaveRoom(c *client) {
+	select {
+	case c.hub.unregister <- c:
+	case <-c.hub.done:
+	}
+}
+
+// removes the client from its room's hub, and holds its player's session for the grace period so they can resume
+// it on a new connection. the client may have been dropped already, or its hub may have stopped since, so this goes
+// to whichever hub the room has now
+func holdSession(roomID string, c *client) {
+	for {
+		h := hubFor(roomID)
+		select {
+		case h.hold <- c:
+			return
+		case <-h.done:
+		}
+	}
+}
+
+// resumes the session with the given token on a new client, replaying the room's messages after the sequence
+// number the player last got. returns false if there's no such session, or it has expired
+func resumeSession(roomID string, c *client, token string, after int64, ack Envelope) bool {
+	if token == "" {
+		return false // clients without a token have no session to resume
+	}
+	h := hubFor(roomID)
+	request := resumeRequest{client: c, token: token, after: after, ack: ack, reply: make(chan bool, 1)}
+	select {
+	case h.resume <- request:
+	case <-h.done:
+		// a hub that's stopped has no sessions
+		return false
+	}
+	if !<-request.reply {
+		return false
+	}
+	c.hub = h
+	return true
+}
+
+// sends a message to every client in its room, other than the sender (which can be nil). this only queues the
+// message with the room's hub, so it doesn't wait for any client
+func broadcastMessage(envelope Envelope, sender *client) {
+	h, ok := hubs.Load(envelope.Room)
+	if !ok {
+		return // nobody is connected to the room
+	}
+	select {
+	case h.(*hub).broadcast <- outbound{envelope: envelope, sender: sender}:
+	case <-h.(*hub).done:
+	}
+}
+
+// sends a message to a user's clients in its room, if they have any. it isn't kept to be replayed, so a user who's
+// away when it's sent never gets it
+func sendToUser(envelope Envelope, user string) {
+	h, ok := hubs.Load(envelope.Room)
+	if !ok {
+		return
+	}
+	select {
+	case h.(*hub).broadcast <- outbound{envelope: envelope, user: user}:
+	case <-h.(*hub).done:
+	}
+}
+
+// checks if a given room has any client connections, counting players who are away but can still resume
+func RoomHasClients(roomID string) bool {
+	h, ok := hubs.Load(roomID)
+	return ok && h.(*hub).count.Load() > 0
+}
+
+func (h *hub) run() {
+	clients := map[*client]bool{}
+	away := map[string]*awaySession{} // by token
+	var seq int64                     // sequence number of the room's last message
+	var history [][]byte              // the room's latest messages, ending with seq
+
+	// a player who's back doesn't need the sessions they left behind
+	returned := func(user string) {
+		for token, s := range away {
+			if s.user == user {
+				s.timer.Stop()
+				delete(away, token)
+			}
+		}
+	}
+	// whether the player has another connection to the room
+	connected := func(user string) bool {
+		for c := range clients {
+			if c.user == user {
+				return true
+			}
+		}
+		return false
+	}
+
+	for {
+		select {
+		case c := <-h.register:
+			clients[c] = true
+			c.joinedSeq = seq
+			if c.user != "" {
+				returned(c.user)
+			}
+		case c := <-h.unregister:
+			if clients[c] {
+				delete(clients, c)
+				close(c.send)
+			}
+		case c := <-h.hold:
+			if clients[c] {
+				delete(clients, c)
+				close(c.send)
+			}
+			if c.token == "" {
+				break // its session was taken over
+			}
+			s := &awaySession{token: c.token, user: c.user, joinedSeq: c.joinedSeq}
+			s.timer = time.AfterFunc(c.heartbeat.resumeGrace, func() {
+				select {
+				case h.expire <- s:
+				case <-h.done:
+				}
+			})
+			away[s.token] = s
+		case s := <-h.expire:
+			// the session may have been resumed just as it expired
+			if away[s.token] == s {
+				delete(away, s.token)
+				if !connected(s.user) {
+					go removeFromRoom(s.user, h.roomID)
+				}
+			}
+		case request := <-h.resume:
+			s, ok := away[request.token]
+			if ok {
+				s.timer.Stop()
+				delete(away, request.token)
+			} else {
+				// the player may be back before their old connection was noticed to have dropped. that connection is
+				// closed, and its session is taken over
+				for old := range clients {
+					if old.token == request.token {
+						s = &awaySession{token: old.token, user: old.user, joinedSeq: old.joinedSeq}
+						delete(clients, old)
+						old.token = ""
+						close(old.send)
+						break
+					}
+				}
+			}
+			if s == nil {
+				request.reply <- false
+				break
+			}
+			c := request.client
+			c.user = s.user
+			returned(c.user)
+
+			// messages the player missed, unless some of them aren't kept anymore. then the client has to reload the
+			// room instead
+			from := min(max(request.after, s.joinedSeq), seq)
+			first := seq - int64(len(history)) + 1
+			complete := from >= first-1
+			var missed [][]byte
+			if complete {
+				missed = history[from-first+1:]
+			}
+			if ack, ok := request.ack.Payload.(Ack); ok && ack.Session != nil {
+				session := *ack.Session
+				session.Resumed = true
+				session.Complete = complete
+				ack.Session = &session
+				request.ack.Payload = ack
+			}
+			// the client is new, so it has room for all of these
+			welcome, err := json.Marshal(request.ack)
+			if err == nil {
+				c.send <- welcome
+			} else {
+				log.Printf("failed to encode resume ack for room %s: %v\n", h.roomID, err)
+			}
+			for _, data := range missed {
+				c.send <- data
+			}
+			clients[c] = true
+			c.joinedSeq = seq
+			request.reply <- true
+		case outgoing := <-h.broadcast:
+			if outgoing.to != nil {
+				if !clients[outgoing.to] {
+					break // it has left since
+				}
+				data, err := json.Marshal(outgoing.envelope)
+				if err != nil {
+					log.Printf("failed to encode %s message for room %s: %v\n", outgoing.envelope.Type, h.roomID, err)
+					break
+				}
+				h.deliver(clients, outgoing.to, data)
+				break
+			}
+			if outgoing.user != "" {
+				data, err := json.Marshal(outgoing.envelope)
+				if err != nil {
+					log.Printf("failed to encode %s message for room %s: %v\n", outgoing.envelope.Type, h.roomID, err)
+					break
+				}
+				for c := range clients {
+					if c.user == outgoing.user {
+						h.deliver(clients, c, data)
+					}
+				}
+				break
+			}
+			seq++
+			outgoing.envelope.Seq = seq
+			data, err := json.Marshal(outgoing.envelope)
+			if err != nil {
+				log.Printf("failed to encode %s message for room %s: %v\n", outgoing.envelope.Type, h.roomID, err)
+				seq--
+				break
+			}
+			history = append(history, data)
+			if len(history) > historySize {
+				history = history[1:]
+			}
+			for c := range clients {
+				if c != outgoing.sender {
+					h.deliver(clients, c, data)
+				}
+			}
+		}
+		h.count.Store(int32(len(clients) + len(away)))
+		if len(clients) == 0 && len(away) == 0 {
+			// stop taking new clients before stopping, so anyone joining now starts a new hub
+			hubs.CompareAndDelete(h.roomID, h)
+			close(h.done)
+			return
+		}
+	}
+}
+
+// queues a message for one of the hub's clients, dropping the client if its queue is full. only the hub's goroutine
+// calls this
+func (h *hub) deliver(clients map[*client]bool, c *client, data []byte) {
+	select {
+	case c.send <- data:
+	default:
+		// closing its channel makes its writer close the connection, which ends its read loop
+		log.Printf("dropping websocket connection %p in room %s: it isn't keeping up with its messages\n", c.conn, h.roomID)
+		delete(clients, c)
+		c.closeCode = websocket.CloseTryAgainLater
+		c.closeReason = "too slow to keep up with the room"
+		close(c.send)
+	}
+}
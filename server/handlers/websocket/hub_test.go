@@ -0,0 +1,231 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// a message as a client gets it, with its payload left to decode once its type is known
+type received struct {
+	Type    MessageType     `json:"type"`
+	ID      string          `json:"id"`
+	Seq     int64           `json:"seq"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// decodes the message's payload into the given value
+func (m received) decode(t *testing.T, payload interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(m.Payload, payload); err != nil {
+		t.Fatalf("failed to decode %s payload %s: %v", m.Type, m.Payload, err)
+	}
+}
+
+// a chat message for the room
+func chat(roomID string, content string) Envelope {
+	return newEnvelope(roomID, TypeChat, Chat{Content: content})
+}
+
+// the content of the next chat message queued for a client, or "" if it's been dropped or nothing arrives
+func nextMessage(t *testing.T, c *client) string {
+	t.Helper()
+	select {
+	case data, ok := <-c.send:
+		if !ok {
+			return ""
+		}
+		var message received
+		json.Unmarshal(data, &message)
+		var payload Chat
+		json.Unmarshal(message.Payload, &payload)
+		return payload.Content
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a message")
+		return ""
+	}
+}
+
+func TestHubBroadcast(t *testing.T) {
+	ann, bob := newClient(nil, heartbeat{}), newClient(nil, heartbeat{})
+	joinRoom("hub-room", ann)
+	joinRoom("hub-room", bob)
+	if !RoomHasClients("hub-room") || RoomHasClients("other-room") {
+		t.Error("RoomHasClients should only be true for rooms with clients")
+	}
+
+	broadcastMessage(chat("hub-room", "hi"), ann)
+	broadcastMessage(chat("hub-room", "hello"), nil)
+	broadcastMessage(chat("other-room", "elsewhere"), nil)
+	if got := nextMessage(t, bob); got != "hi" {
+		t.Errorf("bob's first message: %q, expected hi", got)
+	}
+	if got := nextMessage(t, bob); got != "hello" {
+		t.Errorf("bob's second message: %q, expected hello", got)
+	}
+	// ann doesn't get her own message back
+	if got := nextMessage(t, ann); got != "hello" {
+		t.Errorf("ann's first message: %q, expected hello", got)
+	}
+
+	leaveRoom(ann)
+	if _, ok := <-ann.send; ok {
+		t.Error("leaving should close the client's channel")
+	}
+	leaveRoom(bob)
+	<-bob.hub.done
+	if RoomHasClients("hub-room") {
+		t.Error("the room shouldn't have clients once everyone left")
+	}
+
+	// joining again starts a new hub
+	carol := newClient(nil, heartbeat{})
+	joinRoom("hub-room", carol)
+	broadcastMessage(chat("hub-room", "back"), nil)
+	if got := nextMessage(t, carol); got != "back" {
+		t.Errorf("carol's message: %q, expected back", got)
+	}
+	leaveRoom(carol)
+}
+
+func TestHubSendToUser(t *testing.T) {
+	ann, spectator := newClient(nil, heartbeat{}), newClient(nil, heartbeat{})
+	ann.user, spectator.user = "ann", "eve"
+	joinRoom("user-room", ann)
+	joinRoom("user-room", spectator)
+	sendToUser(chat("user-room", "just for ann"), "ann")
+	broadcastMessage(chat("user-room", "everyone"), nil)
+	if got := nextMessage(t, ann); got != "just for ann" {
+		t.Errorf("ann's first message: %q, expected just for ann", got)
+	}
+	// the spectator's first message is the one for the whole room
+	if got := nextMessage(t, spectator); got != "everyone" {
+		t.Errorf("the spectator's first message: %q, expected everyone", got)
+	}
+	leaveRoom(ann)
+	leaveRoom(spectator)
+}
+
+func TestHubDropsSlowClients(t *testing.T) {
+	slow := newClient(nil, heartbeat{})
+	// room for every message, so it keeps up without anything reading it
+	fast := &client{send: make(chan []byte, 2*sendBufferSize)}
+	joinRoom("slow-room", slow)
+	joinRoom("slow-room", fast)
+	for i := 0; i <= sendBufferSize; i++ {
+		broadcastMessage(chat("slow-room", ""), nil)
+	}
+	// once the fast client has every message, the hub has handed out all of them
+	for i := 0; i <= sendBufferSize; i++ {
+		if _, ok := <-fast.send; !ok {
+			t.Fatal("the fast client should get every message")
+		}
+	}
+
+	// the slow client gets what fit in its buffer, then its channel is closed
+	for i := 0; i < sendBufferSize; i++ {
+		<-slow.send
+	}
+	if _, ok := <-slow.send; ok {
+		t.Error("the slow client should have been dropped")
+	}
+	leaveRoom(slow)
+	leaveRoom(fast)
+}
+
+// the next message queued for a client, decoded
+func nextDecoded(t *testing.T, c *client) received {
+	t.Helper()
+	var message received
+	select {
+	case data := <-c.send:
+		json.Unmarshal(data, &message)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a message")
+	}
+	return message
+}
+
+// the ack the hub sends to a client resuming with the given new token
+func resumeAck(token string) Envelope {
+	return newEnvelope("replay-room", TypeAck, Ack{Version: ProtocolVersion, Session: &Session{Token: token}})
+}
+
+// the session in the next message queued for a client, which has to be an ack
+func nextSession(t *testing.T, c *client) Session {
+	t.Helper()
+	message := nextDecoded(t, c)
+	var ack Ack
+	message.decode(t, &ack)
+	if message.Type != TypeAck || ack.Session == nil {
+		t.Fatalf("expected an ack with a session, got %s %s", message.Type, message.Payload)
+	}
+	return *ack.Session
+}
+
+func TestHubReplay(t *testing.T) {
+	settings := heartbeat{resumeGrace: time.Minute}
+	// bob has room for everything, and getting his messages shows the hub has handed them out
+	bob := &client{send: make(chan []byte, 2*sendBufferSize)}
+	joinRoom("replay-room", bob)
+	broadcast := func(content string) {
+		broadcastMessage(chat("replay-room", content), nil)
+		nextDecoded(t, bob)
+	}
+
+	ann := newClient(nil, settings)
+	ann.user, ann.token = "ann", "first"
+	joinRoom("replay-room", ann)
+	broadcast("seen")
+	seen := nextDecoded(t, ann)
+	holdSession("replay-room", ann)
+	broadcast("missed")
+
+	resumed := newClient(nil, settings)
+	resumed.token = "second"
+	if !resumeSession("replay-room", resumed, "first", seen.Seq, resumeAck("second")) {
+		t.Fatal("the session should resume")
+	}
+	if session := nextSession(t, resumed); !session.Resumed || !session.Complete || session.Token != "second" {
+		t.Errorf("expected a complete resume: %+v", session)
+	}
+	if got := nextMessage(t, resumed); got != "missed" {
+		t.Errorf("replayed message: %q, expected missed", got)
+	}
+	if resumeSession("replay-room", newClient(nil, settings), "first", 0, resumeAck("again")) {
+		t.Error("a session should only resume once")
+	}
+
+	// resuming before the old connection is noticed to have dropped takes it over
+	takeover := newClient(nil, settings)
+	takeover.token = "third"
+	if !resumeSession("replay-room", takeover, "second", seen.Seq+1, resumeAck("third")) {
+		t.Fatal("the session should resume from a connection that's still open")
+	}
+	if _, ok := <-resumed.send; ok {
+		t.Error("the old connection should be closed")
+	}
+	nextDecoded(t, takeover)
+
+	// a player who missed more than the room keeps has to reload it
+	holdSession("replay-room", takeover)
+	for i := 0; i <= historySize; i++ {
+		broadcast("gone")
+	}
+	late := newClient(nil, settings)
+	late.token = "fourth"
+	if !resumeSession("replay-room", late, "third", seen.Seq+1, resumeAck("fourth")) {
+		t.Fatal("the session should resume")
+	}
+	if session := nextSession(t, late); !session.Resumed || session.Complete {
+		t.Errorf("expected an incomplete resume: %+v", session)
+	}
+	broadcast("live")
+	if got := nextMessage(t, late); got != "live" {
+		t.Errorf("after an incomplete resume, only new messages should be sent: %q", got)
+	}
+
+	leaveRoom(bob)
+	leaveRoom(late)
+	<-late.hub.done
+}
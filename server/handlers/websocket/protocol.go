@@ -0,0 +1,444 @@
+package websocket
+
+//go:generate go run ../../cmd/protocolts -o ../../../client/src/protocol.ts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// the version of the protocol this server speaks. clients say which versions they speak when they authorize or
+// resume, and the connection uses the newest one both sides know. anything that changes the shape of a message
+// needs a new version
+const ProtocolVersion = 1
+
+// versions of the protocol the server can speak, newest first
+var protocolVersions = []int{ProtocolVersion}
+
+// Envelope is how every message is sent, in both directions. its type says what its payload is
+type Envelope struct {
+	Version   int         `json:"v"`
+	Type      MessageType `json:"type"`
+	ID        string      `json:"id,omitempty"`   // chosen by the client for messages it sends, and given back in the ack or error for them
+	Room      string      `json:"room,omitempty"` // the room the connection is for
+	Seq       int64       `json:"seq,omitempty"`  // the message's place among the room's messages, for resuming sessions. only set on messages to the whole room
+	Timestamp int64       `json:"timestamp"`      // unix milliseconds
+	Payload   interface{} `json:"payload"`
+}
+
+type MessageType string
+
+// the types of message
+const (
+	TypeAuthorization MessageType = "authorization"
+	TypeResume        MessageType = "resume"
+	TypeChat          MessageType = "chat"
+	TypeRoomUpdate    MessageType = "room_update"
+	TypeGameEvent     MessageType = "game_event"
+	TypeError         MessageType = "error"
+	TypeAck           MessageType = "ack"
+)
+
+// the first message on a connection, unless it's resuming a session
+type Authorization struct {
+	Token    string `json:"token"`    // the user's auth token
+	Versions []int  `json:"versions"` // versions of the protocol the client speaks
+}
+
+// resumes a session whose connection dropped, instead of authorizing
+type Resume struct {
+	Token    string `json:"token"`    // the session token the server gave the client
+	After    int64  `json:"after"`    // seq of the last message the client got
+	Versions []int  `json:"versions"` // versions of the protocol the client speaks
+}
+
+// a chat message
+type Chat struct {
+	Sender  string `json:"sender"` // filled in by the server, from who the connection is authorized as
+	Content string `json:"content"`
+}
+
+// longest chat message, in bytes
+const maxChatLength = 2000
+
+// a change to a room, or to who's in it. what its data is depends on its type
+type RoomUpdate struct {
+	Type RoomUpdateType `json:"type"`
+	Data interface{}    `json:"data"`
+}
+
+type RoomUpdateType string
+
+// the types of room update
+const (
+	ChangeDifficulty  RoomUpdateType = "CHANGE_DIFFICULTY"
+	ChangeTimeLimit   RoomUpdateType = "CHANGE_TIME_LIMIT"
+	ChangeProblem     RoomUpdateType = "CHANGE_PROBLEM"
+	ChangeRandom      RoomUpdateType = "RANDOM_PROBLEM"
+	ChangeScoringMode RoomUpdateType = "CHANGE_SCORING_MODE"
+	SetUserReady      RoomUpdateType = "SET_USER_READY"
+	UserJoin          RoomUpdateType = "USER_JOIN"
+	UserLeave         RoomUpdateType = "USER_LEAVE"
+	LaunchGame        RoomUpdateType = "LAUNCH_GAME"
+)
+
+// (CHANGE_DIFFICULTY) 1=easy, 2=med, 3=hard
+type Difficulty struct {
+	Value int `json:"value"`
+}
+
+// (CHANGE_TIME_LIMIT) in minutes
+type TimeLimit struct {
+	Value int `json:"value"`
+}
+
+// longest time limit a room can have, in minutes
+const maxTimeLimit = 120
+
+// (CHANGE_PROBLEM) the problem the room will solve, or null to unset it
+type ProblemChoice struct {
+	Value *models.ProblemOverview `json:"value"`
+}
+
+// (RANDOM_PROBLEM) whether the room gets a random problem
+type RandomProblem struct {
+	Value bool `json:"value"`
+}
+
+// (CHANGE_SCORING_MODE) one of the models.Scoring modes
+type ScoringMode struct {
+	Value string `json:"value"`
+}
+
+// (SET_USER_READY) whether the user is ready to start
+type UserReady struct {
+	Value bool   `json:"value"`
+	User  string `json:"user"` // filled in by the server, from who the connection is authorized as
+}
+
+// (USER_JOIN, USER_LEAVE) the user's username
+type UserChange struct {
+	Value string `json:"value"`
+}
+
+// (LAUNCH_GAME) ID of the game that started, to find its review afterwards
+type GameLaunch struct {
+	Value string `json:"value"`
+}
+
+// something that happened in a room's game. what its data is depends on its type
+type GameEvent struct {
+	Type GameEventType `json:"type"`
+	Data interface{}   `json:"data"`
+}
+
+type GameEventType string
+
+// the types of game event
+const (
+	CodeSubmitResult GameEventType = "CODE_SUBMIT_RESULT"
+	GameOverEvent    GameEventType = "GAME_OVER"
+	GameReviewEvent  GameEventType = "GAME_REVIEW"
+)
+
+// (CODE_SUBMIT_RESULT) how a player's submission did
+type SubmitResult struct {
+	Value   int                     `json:"value"` // how many tests it passed
+	User    string                  `json:"user"`
+	Results []models.SubmissionCase `json:"results"` // the verdict of each case
+	Runtime int64                   `json:"runtime"` // total runtime of its cases, in milliseconds
+	Memory  int64                   `json:"memory"`  // peak memory, in kilobytes
+}
+
+// (GAME_OVER) who won, and how each player's rating changed. ratings is empty if the game wasn't rated
+type GameOver struct {
+	Value   string                         `json:"value"`
+	Ratings map[string]models.RatingChange `json:"ratings"`
+}
+
+// (GAME_REVIEW) the review of a finished game
+type GameReview struct {
+	Value  string            `json:"value"` // the game's ID
+	Review models.GameReview `json:"review"`
+}
+
+// tells the client something it sent was wrong
+type ErrorMessage struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+type ErrorCode string
+
+// what can be wrong with a message
+const (
+	ErrBadMessage         ErrorCode = "bad_message"         // it isn't JSON, or doesn't match its type's schema
+	ErrUnknownType        ErrorCode = "unknown_type"        // clients can't send messages of its type
+	ErrInvalid            ErrorCode = "invalid"             // it matches its schema, but its values aren't allowed
+	ErrUnsupportedVersion ErrorCode = "unsupported_version" // the client and server have no protocol version in common
+	ErrUnauthorized       ErrorCode = "unauthorized"        // it was sent before authorizing
+	ErrAuthFailed         ErrorCode = "auth_failed"         // the auth token isn't valid
+	ErrResumeFailed       ErrorCode = "resume_failed"       // the session has expired or doesn't exist. the client can still authorize
+	ErrForbidden          ErrorCode = "forbidden"           // the user isn't allowed to do that, like changing a room they don't own
+	ErrInternal           ErrorCode = "internal"            // the server failed to handle it
+)
+
+// every error code, in the order they're listed in the TypeScript definitions
+var errorCodes = []ErrorCode{ErrBadMessage, ErrUnknownType, ErrInvalid, ErrUnsupportedVersion, ErrUnauthorized, ErrAuthFailed, ErrResumeFailed, ErrForbidden, ErrInternal}
+
+// tells the client a message it sent with an ID was handled. the ack for authorizing or resuming has the protocol
+// version the connection uses, and the session it started
+type Ack struct {
+	Version int      `json:"version,omitempty"`
+	Session *Session `json:"session,omitempty"`
+}
+
+// a session, which the client can resume with its token if its connection drops
+type Session struct {
+	Token    string         `json:"token"`              // empty if sessions can't be resumed
+	Grace    float64        `json:"grace"`              // how long after the connection drops the session can be resumed, in seconds
+	Resumed  bool           `json:"resumed"`            // whether an earlier session was resumed
+	Complete bool           `json:"complete"`           // (resumed) whether every missed message is replayed after this. if not, the client should reload the room
+	Progress map[string]int `json:"progress,omitempty"` // (resumed) each player's progress in the room's game, if it's in one
+}
+
+// a type of message, and who can send it
+type messageSpec struct {
+	Type       MessageType
+	Payload    interface{} // a value of the payload's type
+	FromClient bool
+	FromServer bool
+}
+
+// every type of message, in the order they're listed in the TypeScript definitions
+var messageSpecs = []messageSpec{
+	{Type: TypeAuthorization, Payload: Authorization{}, FromClient: true},
+	{Type: TypeResume, Payload: Resume{}, FromClient: true},
+	{Type: TypeChat, Payload: Chat{}, FromClient: true, FromServer: true},
+	{Type: TypeRoomUpdate, Payload: RoomUpdate{}, FromClient: true, FromServer: true},
+	{Type: TypeGameEvent, Payload: GameEvent{}, FromServer: true},
+	{Type: TypeError, Payload: ErrorMessage{}, FromServer: true},
+	{Type: TypeAck, Payload: Ack{}, FromServer: true},
+}
+
+// one of the types in a union like RoomUpdate, whose data's type depends on its type
+type variantSpec struct {
+	Type       string
+	Data       interface{} // a value of the data's type
+	FromClient bool        // whether clients can send it. the server can send any of them
+	OwnerOnly  bool        // whether only the room's owner can send it
+}
+
+// every type of room update
+var roomUpdateSpecs = []variantSpec{
+	{Type: string(ChangeDifficulty), Data: Difficulty{}, FromClient: true, OwnerOnly: true},
+	{Type: string(ChangeTimeLimit), Data: TimeLimit{}, FromClient: true, OwnerOnly: true},
+	{Type: string(ChangeProblem), Data: ProblemChoice{}, FromClient: true, OwnerOnly: true},
+	{Type: string(ChangeRandom), Data: RandomProblem{}, FromClient: true, OwnerOnly: true},
+	{Type: string(ChangeScoringMode), Data: ScoringMode{}, FromClient: true, OwnerOnly: true},
+	{Type: string(SetUserReady), Data: UserReady{}, FromClient: true},
+	{Type: string(UserJoin), Data: UserChange{}},
+	{Type: string(UserLeave), Data: UserChange{}},
+	{Type: string(LaunchGame), Data: GameLaunch{}},
+}
+
+// every type of game event
+var gameEventSpecs = []variantSpec{
+	{Type: string(CodeSubmitResult), Data: SubmitResult{}},
+	{Type: string(GameOverEvent), Data: GameOver{}},
+	{Type: string(GameReviewEvent), Data: GameReview{}},
+}
+
+// what's wrong with a message from a client
+type protocolError struct {
+	code    ErrorCode
+	message string
+}
+
+func (e *protocolError) Error() string {
+	return fmt.Sprintf("%s: %s", e.code, e.message)
+}
+
+func errorf(code ErrorCode, format string, args ...interface{}) *protocolError {
+	return &protocolError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// payloads and data that have rules beyond their schema. validate returns a *protocolError
+type validator interface {
+	validate() error
+}
+
+// makes a message for a room, in the current protocol version
+func newEnvelope(roomID string, messageType MessageType, payload interface{}) Envelope {
+	return Envelope{
+		Version:   ProtocolVersion,
+		Type:      messageType,
+		Room:      roomID,
+		Timestamp: time.Now().UnixMilli(),
+		Payload:   payload,
+	}
+}
+
+// decodes and validates a message from a client. version is the protocol version the connection agreed on, or 0
+// if it hasn't agreed on one yet. the payload is one of the payload types, and the envelope is returned even if
+// the payload isn't valid, as long as it could be read, so errors can be given its ID
+func decodeMessage(data []byte, version int) (Envelope, error) {
+	var raw struct {
+		Envelope
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := strictUnmarshal(data, &raw); err != nil {
+		return Envelope{}, errorf(ErrBadMessage, "messages must be JSON envelopes: %v", err)
+	}
+	envelope := raw.Envelope
+	if version != 0 && envelope.Version != version {
+		return envelope, errorf(ErrUnsupportedVersion, "this connection uses version %d of the protocol", version)
+	}
+	i := slices.IndexFunc(messageSpecs, func(spec messageSpec) bool { return spec.Type == envelope.Type })
+	if i < 0 || !messageSpecs[i].FromClient {
+		return envelope, errorf(ErrUnknownType, "clients can't send %q messages", envelope.Type)
+	}
+	payload, err := decodeValue(raw.Payload, messageSpecs[i].Payload)
+	if err != nil {
+		return envelope, err
+	}
+	envelope.Payload = payload
+	return envelope, nil
+}
+
+// decodes data into a new value of the same type as like, and validates it
+func decodeValue(data []byte, like interface{}) (interface{}, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, errorf(ErrBadMessage, "missing payload")
+	}
+	value := reflect.New(reflect.TypeOf(like))
+	if err := strictUnmarshal(data, value.Interface()); err != nil {
+		// errors in a union's data are already protocol errors
+		var protoErr *protocolError
+		if errors.As(err, &protoErr) {
+			return nil, protoErr
+		}
+		return nil, errorf(ErrBadMessage, "%v", err)
+	}
+	if v, ok := value.Interface().(validator); ok {
+		if err := v.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return value.Elem().Interface(), nil
+}
+
+// unmarshals JSON, refusing fields the value doesn't have
+func strictUnmarshal(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// decodes a union's type and data, from the types a client can send
+func decodeVariant(data []byte, specs []variantSpec) (string, interface{}, error) {
+	var raw struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := strictUnmarshal(data, &raw); err != nil {
+		return "", nil, err
+	}
+	i := slices.IndexFunc(specs, func(spec variantSpec) bool { return spec.Type == raw.Type })
+	if i < 0 || !specs[i].FromClient {
+		return "", nil, errorf(ErrUnknownType, "clients can't send %q updates", raw.Type)
+	}
+	value, err := decodeValue(raw.Data, specs[i].Data)
+	return raw.Type, value, err
+}
+
+// whether only the room's owner can send the update
+func (u RoomUpdate) ownerOnly() bool {
+	i := slices.IndexFunc(roomUpdateSpecs, func(spec variantSpec) bool { return spec.Type == string(u.Type) })
+	return i >= 0 && roomUpdateSpecs[i].OwnerOnly
+}
+
+func (u *RoomUpdate) UnmarshalJSON(data []byte) error {
+	updateType, value, err := decodeVariant(data, roomUpdateSpecs)
+	if err != nil {
+		return err
+	}
+	u.Type, u.Data = RoomUpdateType(updateType), value
+	return nil
+}
+
+func (a *Authorization) validate() error {
+	if a.Token == "" {
+		return errorf(ErrInvalid, "no token given")
+	}
+	return nil
+}
+
+func (r *Resume) validate() error {
+	if r.Token == "" {
+		return errorf(ErrInvalid, "no token given")
+	}
+	if r.After < 0 {
+		return errorf(ErrInvalid, "after can't be negative")
+	}
+	return nil
+}
+
+func (c *Chat) validate() error {
+	if strings.TrimSpace(c.Content) == "" {
+		return errorf(ErrInvalid, "chat messages can't be empty")
+	}
+	if len(c.Content) > maxChatLength {
+		return errorf(ErrInvalid, "chat messages can't be longer than %d bytes", maxChatLength)
+	}
+	return nil
+}
+
+func (d *Difficulty) validate() error {
+	if d.Value < 1 || d.Value > 3 {
+		return errorf(ErrInvalid, "difficulty must be 1, 2 or 3")
+	}
+	return nil
+}
+
+func (t *TimeLimit) validate() error {
+	if t.Value < 1 || t.Value > maxTimeLimit {
+		return errorf(ErrInvalid, "time limit must be from 1 to %d minutes", maxTimeLimit)
+	}
+	return nil
+}
+
+func (p *ProblemChoice) validate() error {
+	if p.Value != nil && p.Value.ID == "" {
+		return errorf(ErrInvalid, "problem has no ID")
+	}
+	return nil
+}
+
+func (s *ScoringMode) validate() error {
+	if !models.ValidScoringMode(s.Value) {
+		return errorf(ErrInvalid, "unknown scoring mode %q", s.Value)
+	}
+	if !slices.Contains(scoringModes, s.Value) {
+		return errorf(ErrInvalid, "scoring mode %q isn't available: the code executor can't measure it", s.Value)
+	}
+	return nil
+}
+
+// the newest protocol version both the server and the client speak, or 0 if there isn't one
+func agreeVersion(clientVersions []int) int {
+	for _, version := range protocolVersions {
+		if slices.Contains(clientVersions, version) {
+			return version
+		}
+	}
+	return 0
+}
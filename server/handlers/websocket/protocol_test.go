@@ -0,0 +1,91 @@
+package websocket
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/webbben/code-duel/models"
+)
+
+func TestDecodeMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		version int
+		code    ErrorCode // "" if it's valid
+	}{
+		{"authorization", `{"v":1,"type":"authorization","payload":{"token":"t","versions":[1]}}`, 0, ""},
+		{"chat", `{"v":1,"type":"chat","id":"1","room":"r","timestamp":5,"payload":{"content":"hi"}}`, 1, ""},
+		{"room update", `{"v":1,"type":"room_update","payload":{"type":"CHANGE_DIFFICULTY","data":{"value":2}}}`, 1, ""},
+		{"unset problem", `{"v":1,"type":"room_update","payload":{"type":"CHANGE_PROBLEM","data":{"value":null}}}`, 1, ""},
+		{"not json", `hello`, 1, ErrBadMessage},
+		{"unknown field", `{"v":1,"type":"chat","content":"hi","payload":{"content":"hi"}}`, 1, ErrBadMessage},
+		{"wrong payload", `{"v":1,"type":"chat","payload":{"content":5}}`, 1, ErrBadMessage},
+		{"missing payload", `{"v":1,"type":"chat"}`, 1, ErrBadMessage},
+		{"wrong version", `{"v":2,"type":"chat","payload":{"content":"hi"}}`, 1, ErrUnsupportedVersion},
+		{"unknown type", `{"v":1,"type":"chat_message","payload":{}}`, 1, ErrUnknownType},
+		{"server only type", `{"v":1,"type":"game_event","payload":{"type":"GAME_OVER","data":{"value":"ann"}}}`, 1, ErrUnknownType},
+		{"server only update", `{"v":1,"type":"room_update","payload":{"type":"USER_JOIN","data":{"value":"ann"}}}`, 1, ErrUnknownType},
+		{"unknown data field", `{"v":1,"type":"room_update","payload":{"type":"CHANGE_TIME_LIMIT","data":{"value":5,"extra":1}}}`, 1, ErrBadMessage},
+		{"empty chat", `{"v":1,"type":"chat","payload":{"content":"  "}}`, 1, ErrInvalid},
+		{"bad difficulty", `{"v":1,"type":"room_update","payload":{"type":"CHANGE_DIFFICULTY","data":{"value":4}}}`, 1, ErrInvalid},
+		{"bad time limit", `{"v":1,"type":"room_update","payload":{"type":"CHANGE_TIME_LIMIT","data":{"value":0}}}`, 1, ErrInvalid},
+		{"bad scoring mode", `{"v":1,"type":"room_update","payload":{"type":"CHANGE_SCORING_MODE","data":{"value":"fastest"}}}`, 1, ErrInvalid},
+		{"no token", `{"v":1,"type":"authorization","payload":{"token":"","versions":[1]}}`, 0, ErrInvalid},
+	}
+	for _, test := range tests {
+		envelope, err := decodeMessage([]byte(test.data), test.version)
+		if test.code == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", test.name, err)
+			}
+			continue
+		}
+		var protoErr *protocolError
+		if !errors.As(err, &protoErr) || protoErr.code != test.code {
+			t.Errorf("%s: expected a %s error, got %v", test.name, test.code, err)
+		}
+		if test.code != ErrBadMessage && envelope.Type == "" {
+			t.Errorf("%s: the envelope should be returned with the error", test.name)
+		}
+	}
+
+	envelope, _ := decodeMessage([]byte(`{"v":1,"type":"room_update","payload":{"type":"CHANGE_TIME_LIMIT","data":{"value":30}}}`), 1)
+	if update, ok := envelope.Payload.(RoomUpdate); !ok || update.Data != (TimeLimit{Value: 30}) {
+		t.Errorf("room updates should be decoded into their data's type: %+v", envelope.Payload)
+	}
+}
+
+func TestUnavailableScoringMode(t *testing.T) {
+	previous := scoringModes
+	SetScoringModes([]string{models.ScoringFirstToSolve, models.ScoringRuntime})
+	t.Cleanup(func() { SetScoringModes(previous) })
+	_, err := decodeMessage([]byte(`{"v":1,"type":"room_update","payload":{"type":"CHANGE_SCORING_MODE","data":{"value":"memory"}}}`), 1)
+	var protoErr *protocolError
+	if !errors.As(err, &protoErr) || protoErr.code != ErrInvalid {
+		t.Errorf("an executor that doesn't measure memory can't score by it, got %v", err)
+	}
+	if _, err := decodeMessage([]byte(`{"v":1,"type":"room_update","payload":{"type":"CHANGE_SCORING_MODE","data":{"value":"runtime"}}}`), 1); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestAgreeVersion(t *testing.T) {
+	if version := agreeVersion([]int{3, ProtocolVersion, 0}); version != ProtocolVersion {
+		t.Errorf("expected version %d, got %d", ProtocolVersion, version)
+	}
+	if version := agreeVersion([]int{ProtocolVersion + 1}); version != 0 {
+		t.Errorf("clients speaking no version in common shouldn't agree on one, got %d", version)
+	}
+}
+
+func TestTypeScriptUpToDate(t *testing.T) {
+	generated, err := os.ReadFile("../../../client/src/protocol.ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(generated) != TypeScript() {
+		t.Error("client/src/protocol.ts is out of date. run go generate ./handlers/websocket")
+	}
+}
@@ -0,0 +1,221 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/webbben/code-duel/auth"
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+)
+
+// auth tokens for the test users, by username
+var testTokens = map[string]string{}
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	storage.Set(store)
+	provider := &auth.LocalProvider{Store: store, Secret: []byte("test secret"), TokenTTL: time.Hour, RefreshTTL: time.Hour}
+	auth.Set(provider)
+	for _, username := range []string{"ann", "bob"} {
+		user := models.User{Username: username, Email: username + "@example.com"}
+		store.CreateUser(ctx, &user)
+		if err := provider.CreateUser(ctx, user, "password"); err != nil {
+			log.Fatal(err)
+		}
+		tokens, err := provider.Login(ctx, user.Email, "password")
+		if err != nil {
+			log.Fatal(err)
+		}
+		testTokens[username] = tokens.Token
+	}
+	os.Exit(m.Run())
+}
+
+// makes a room in the store with the users in it
+func createTestRoom(t *testing.T, users ...string) string {
+	t.Helper()
+	room := models.Room{Owner: users[0], Users: users, MaxCapacity: 5}
+	if err := storage.Get().CreateRoom(context.Background(), &room); err != nil {
+		t.Fatal(err)
+	}
+	return room.ID
+}
+
+// the users in a room in the store
+func roomUsers(t *testing.T, roomID string) []string {
+	t.Helper()
+	room, err := storage.Get().GetRoom(context.Background(), roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return room.Users
+}
+
+// reads the connection's messages until one of the given type arrives, and returns it
+func readUntil(t *testing.T, conn *websocket.Conn, messageType MessageType) received {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		var message received
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("waiting for %s: %v", messageType, err)
+		}
+		if message.Type == messageType {
+			return message
+		}
+	}
+}
+
+// reads the connection's messages until a user joins or leaves the room, as the given update type says, and
+// returns the message and who it was
+func readUserChange(t *testing.T, conn *websocket.Conn, updateType RoomUpdateType) (received, string) {
+	t.Helper()
+	for {
+		message := readUntil(t, conn, TypeRoomUpdate)
+		var update struct {
+			Type RoomUpdateType `json:"type"`
+			Data UserChange     `json:"data"`
+		}
+		message.decode(t, &update)
+		if update.Type == updateType {
+			return message, update.Data.Value
+		}
+	}
+}
+
+// the session in an ack
+func ackSession(t *testing.T, message received) Session {
+	t.Helper()
+	var ack Ack
+	message.decode(t, &ack)
+	if ack.Session == nil {
+		t.Fatalf("expected an ack with a session: %s", message.Payload)
+	}
+	return *ack.Session
+}
+
+// authorizes the connection as the user, and returns the token that resumes their session
+func authorize(t *testing.T, conn *websocket.Conn, username string) string {
+	t.Helper()
+	message := map[string]interface{}{
+		"v":       ProtocolVersion,
+		"type":    TypeAuthorization,
+		"payload": Authorization{Token: testTokens[username], Versions: []int{ProtocolVersion}},
+	}
+	if err := conn.WriteJSON(message); err != nil {
+		t.Fatal(err)
+	}
+	return ackSession(t, readUntil(t, conn, TypeAck)).Token
+}
+
+// asks to resume the session with the token, after the given message
+func resume(t *testing.T, conn *websocket.Conn, token string, after int64) {
+	t.Helper()
+	message := map[string]interface{}{
+		"v":       ProtocolVersion,
+		"type":    TypeResume,
+		"payload": Resume{Token: token, After: after, Versions: []int{ProtocolVersion}},
+	}
+	if err := conn.WriteJSON(message); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResumeSession(t *testing.T) {
+	settings := heartbeat{pingInterval: time.Minute, pongWait: time.Minute, writeWait: time.Second, authTimeout: time.Minute, resumeGrace: 300 * time.Millisecond}
+	roomID := createTestRoom(t, "ann", "bob")
+	gameStateMapMutex.Lock()
+	gameStateMap[roomID] = GameState{Players: []string{"ann", "bob"}, UserProgress: map[string]int{"ann": 2, "bob": 1}}
+	gameStateMapMutex.Unlock()
+	t.Cleanup(func() {
+		gameStateMapMutex.Lock()
+		delete(gameStateMap, roomID)
+		gameStateMapMutex.Unlock()
+	})
+
+	ann := dialTestRoom(t, settings, roomID)
+	token := authorize(t, ann, "ann")
+	bob := dialTestRoom(t, settings, roomID)
+	authorize(t, bob, "bob")
+	readUserChange(t, ann, UserJoin) // her own
+	last, user := readUserChange(t, ann, UserJoin)
+	if user != "bob" || last.Seq == 0 {
+		t.Fatalf("expected bob joining, with a sequence number: %+v", last)
+	}
+
+	// ann's connection drops without closing, and she misses a message
+	ann.UnderlyingConn().Close()
+	bob.WriteJSON(map[string]interface{}{"v": ProtocolVersion, "type": TypeChat, "payload": Chat{Content: "still there?"}})
+
+	ann = dialTestRoom(t, settings, roomID)
+	resume(t, ann, token, last.Seq)
+	session := ackSession(t, readUntil(t, ann, TypeAck))
+	if session.Token == token || !session.Resumed || !session.Complete {
+		t.Errorf("resuming should give a new token, and replay everything: %+v", session)
+	}
+	if session.Progress["ann"] != 2 {
+		t.Errorf("resuming should give ann her progress in the game: %+v", session.Progress)
+	}
+	missed := readUntil(t, ann, TypeChat)
+	var payload Chat
+	missed.decode(t, &payload)
+	if payload.Content != "still there?" || payload.Sender != "bob" || missed.Seq != last.Seq+1 {
+		t.Errorf("the missed message should be replayed: %+v", missed)
+	}
+
+	// she never left, so she's still in the room after the grace period
+	time.Sleep(500 * time.Millisecond)
+	if !slices.Contains(roomUsers(t, roomID), "ann") {
+		t.Error("a player who resumed their session shouldn't be removed from the room")
+	}
+}
+
+func TestSessionExpires(t *testing.T) {
+	settings := heartbeat{pingInterval: time.Minute, pongWait: time.Minute, writeWait: time.Second, authTimeout: time.Minute, resumeGrace: 100 * time.Millisecond}
+	roomID := createTestRoom(t, "ann", "bob")
+	ann := dialTestRoom(t, settings, roomID)
+	token := authorize(t, ann, "ann")
+	bob := dialTestRoom(t, settings, roomID)
+	authorize(t, bob, "bob")
+
+	ann.UnderlyingConn().Close()
+	if _, user := readUserChange(t, bob, UserLeave); user != "ann" {
+		t.Errorf("expected ann leaving once her session expired, got %s", user)
+	}
+	if slices.Contains(roomUsers(t, roomID), "ann") {
+		t.Error("a player whose session expired should be removed from the room")
+	}
+
+	ann = dialTestRoom(t, settings, roomID)
+	resume(t, ann, token, 0)
+	var failure ErrorMessage
+	readUntil(t, ann, TypeError).decode(t, &failure)
+	if failure.Code != ErrResumeFailed {
+		t.Errorf("expected the resume to fail, got %+v", failure)
+	}
+	// she can still authorize as usual
+	authorize(t, ann, "ann")
+}
+
+func TestCloseLeavesRoom(t *testing.T) {
+	settings := heartbeat{pingInterval: time.Minute, pongWait: time.Minute, writeWait: time.Second, authTimeout: time.Minute, resumeGrace: time.Minute}
+	roomID := createTestRoom(t, "ann", "bob")
+	ann := dialTestRoom(t, settings, roomID)
+	authorize(t, ann, "ann")
+	bob := dialTestRoom(t, settings, roomID)
+	authorize(t, bob, "bob")
+
+	// closing the connection on purpose leaves straight away, whatever the grace period
+	ann.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+	if _, user := readUserChange(t, bob, UserLeave); user != "ann" {
+		t.Errorf("expected ann leaving, got %s", user)
+	}
+}
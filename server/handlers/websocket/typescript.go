@@ -0,0 +1,219 @@
+package websocket
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// TypeScript makes the client's definitions of the protocol from its Go types, so the Go types stay the only
+// place it's defined. cmd/protocolts writes them to client/src/protocol.ts
+func TypeScript() string {
+	g := newTSGenerator()
+	var out strings.Builder
+	out.WriteString("// Code generated by cmd/protocolts from server/handlers/websocket/protocol.go. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "export const protocolVersion = %d;\n\n", ProtocolVersion)
+
+	// the enums
+	var messageTypes, roomUpdateTypes, gameEventTypes, codes []string
+	for _, spec := range messageSpecs {
+		messageTypes = append(messageTypes, string(spec.Type))
+	}
+	for _, spec := range roomUpdateSpecs {
+		roomUpdateTypes = append(roomUpdateTypes, spec.Type)
+	}
+	for _, spec := range gameEventSpecs {
+		gameEventTypes = append(gameEventTypes, spec.Type)
+	}
+	for _, code := range errorCodes {
+		codes = append(codes, string(code))
+	}
+	writeUnion(&out, "MessageType", quoted(messageTypes))
+	writeUnion(&out, "RoomUpdateType", quoted(roomUpdateTypes))
+	writeUnion(&out, "GameEventType", quoted(gameEventTypes))
+	writeUnion(&out, "ErrorCode", quoted(codes))
+
+	// the unions whose data depends on their type
+	roomUpdates, clientRoomUpdates := g.variants(roomUpdateSpecs)
+	gameEvents, _ := g.variants(gameEventSpecs)
+	writeUnion(&out, "RoomUpdate", roomUpdates)
+	writeUnion(&out, "ClientRoomUpdate", clientRoomUpdates)
+	writeUnion(&out, "GameEvent", gameEvents)
+
+	// every message is an envelope, whose payload's type depends on the message's type
+	out.WriteString("export interface Envelope<T extends MessageType, P> {\n")
+	out.WriteString(g.fields(reflect.TypeOf(Envelope{}), map[string]string{"type": "T", "payload": "P"}))
+	out.WriteString("}\n\n")
+	var clientMessages, serverMessages []string
+	for _, spec := range messageSpecs {
+		payload := g.payload(reflect.TypeOf(spec.Payload))
+		if spec.FromClient {
+			clientPayload := payload
+			if spec.Type == TypeRoomUpdate {
+				clientPayload = "ClientRoomUpdate"
+			}
+			clientMessages = append(clientMessages, fmt.Sprintf("Envelope<%q, %s>", spec.Type, clientPayload))
+		}
+		if spec.FromServer {
+			serverMessages = append(serverMessages, fmt.Sprintf("Envelope<%q, %s>", spec.Type, payload))
+		}
+	}
+	writeUnion(&out, "ClientMessage", clientMessages)
+	writeUnion(&out, "ServerMessage", serverMessages)
+
+	// and then every struct the messages use
+	for i := 0; i < len(g.order); i++ {
+		t := g.order[i]
+		fmt.Fprintf(&out, "export interface %s {\n%s}\n\n", g.names[t], g.fields(t, nil))
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// makes TypeScript types for Go types, naming each struct it comes across so it's defined once
+type tsGenerator struct {
+	names map[reflect.Type]string
+	order []reflect.Type // structs in the order they were named, which is the order they're defined in
+	taken map[string]bool
+}
+
+func newTSGenerator() *tsGenerator {
+	g := &tsGenerator{names: map[reflect.Type]string{}, taken: map[string]bool{}}
+	// the protocol's own types keep their names. types from other packages get their package's name in front of
+	// theirs if it's taken
+	for _, spec := range messageSpecs {
+		g.taken[reflect.TypeOf(spec.Payload).Name()] = true
+	}
+	for _, specs := range [][]variantSpec{roomUpdateSpecs, gameEventSpecs} {
+		for _, spec := range specs {
+			g.taken[reflect.TypeOf(spec.Data).Name()] = true
+		}
+	}
+	for _, name := range []string{"Envelope", "MessageType", "RoomUpdateType", "GameEventType", "ErrorCode", "ClientRoomUpdate", "ClientMessage", "ServerMessage"} {
+		g.taken[name] = true
+	}
+	return g
+}
+
+// the name of a payload's type. the unions are defined separately, as their data's type depends on their type
+func (g *tsGenerator) payload(t reflect.Type) string {
+	switch t {
+	case reflect.TypeOf(RoomUpdate{}), reflect.TypeOf(GameEvent{}):
+		return t.Name()
+	}
+	return g.tsType(t)
+}
+
+// each variant of a union, and the ones clients can send
+func (g *tsGenerator) variants(specs []variantSpec) (all []string, fromClient []string) {
+	for _, spec := range specs {
+		variant := fmt.Sprintf("{ type: %q; data: %s }", spec.Type, g.tsType(reflect.TypeOf(spec.Data)))
+		all = append(all, variant)
+		if spec.FromClient {
+			fromClient = append(fromClient, variant)
+		}
+	}
+	return all, fromClient
+}
+
+// the TypeScript type for a Go type
+func (g *tsGenerator) tsType(t reflect.Type) string {
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return "string"
+	case reflect.TypeOf(MessageType("")), reflect.TypeOf(RoomUpdateType("")), reflect.TypeOf(GameEventType("")), reflect.TypeOf(ErrorCode("")):
+		return t.Name()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		// nil slices are null in JSON
+		return g.tsType(t.Elem()) + "[] | null"
+	case reflect.Map:
+		return fmt.Sprintf("Record<string, %s> | null", g.tsType(t.Elem()))
+	case reflect.Pointer:
+		return g.tsType(t.Elem()) + " | null"
+	case reflect.Struct:
+		return g.name(t)
+	}
+	return "unknown"
+}
+
+// names a struct, queueing it to be defined if it hasn't been named yet
+func (g *tsGenerator) name(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if t.PkgPath() != reflect.TypeOf(Envelope{}).PkgPath() && g.taken[name] {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	g.taken[name] = true
+	g.names[t] = name
+	g.order = append(g.order, t)
+	return name
+}
+
+// the fields of a struct, as they're encoded to JSON. embedded structs' fields are included in it, and fields that
+// are left out when empty are optional. override gives the types of some fields by their JSON names
+func (g *tsGenerator) fields(t reflect.Type, override map[string]string) string {
+	var out strings.Builder
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			out.WriteString(g.fields(field.Type, override))
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		optional := ""
+		if strings.Contains(options, "omitempty") {
+			optional = "?"
+		}
+		tsType, ok := override[name]
+		if !ok {
+			tsType = g.tsType(field.Type)
+		}
+		fmt.Fprintf(&out, "    %s%s: %s;\n", name, optional, tsType)
+	}
+	return out.String()
+}
+
+// quotes each string, as TypeScript string literal types
+func quoted(values []string) []string {
+	literals := make([]string, len(values))
+	for i, value := range values {
+		literals[i] = fmt.Sprintf("%q", value)
+	}
+	return literals
+}
+
+// writes a union type of the given types, one per line
+func writeUnion(out *strings.Builder, name string, types []string) {
+	fmt.Fprintf(out, "export type %s =\n", name)
+	for i, t := range types {
+		end := ""
+		if i == len(types)-1 {
+			end = ";"
+		}
+		fmt.Fprintf(out, "    | %s%s\n", t, end)
+	}
+	out.WriteString("\n")
+}
@@ -2,35 +2,26 @@ package websocket
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
 	"net/http"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
-	"github.com/webbben/code-duel/firebase/rooms"
 	authHandlers "github.com/webbben/code-duel/handlers/auth"
 	"github.com/webbben/code-duel/models"
 	problemData "github.com/webbben/code-duel/problem_data"
+	"github.com/webbben/code-duel/ratings"
+	"github.com/webbben/code-duel/rooms"
+	"github.com/webbben/code-duel/storage"
+	"github.com/webbben/code-duel/submissions"
 )
 
-// general message struct, including all possible fields in a message. fields will vary depending on type
-type Message struct {
-	Type       string     `json:"type"`       // type of message: "chat_message", "room_message", etc.
-	Room       string     `json:"room"`       // (all messages) room ID
-	Timestamp  int        `json:"timestamp"`  // (all messages) timestamp
-	Content    string     `json:"content"`    // (chat_message) chat message content
-	Sender     string     `json:"sender"`     // (chat_message) chat message sender
-	RoomUpdate RoomUpdate `json:"roomupdate"` // (room_update) update made to the room
-}
-
-type RoomUpdate struct {
-	Type string                 `json:"type"`
-	Data map[string]interface{} `json:"data"` // should have at least a "value" field, but potentially others too
-}
-
 var (
 	ctx      context.Context
 	upgrader = websocket.Upgrader{
@@ -40,260 +31,490 @@ var (
 			return true
 		},
 	}
-	// connected clients for each chatroom
-	roomClients = make(map[string]map[*websocket.Conn]bool)
-	// Mutex to lock roomClients to enable synchronization between threads
-	roomClientsMutex sync.Mutex
 	// map of rooms to gamestates
 	gameStateMap = make(map[string]GameState)
 	// Mutex to lock gameStateMap to synchronize access
 	gameStateMapMutex sync.Mutex
+	// scoring modes rooms can choose, which are the ones the code executor measures enough for
+	scoringModes = []string{models.ScoringFirstToSolve, models.ScoringRuntime, models.ScoringMemory}
 )
 
-// checks if a given room has any client connections
-func RoomHasClients(roomID string) bool {
-	connMap := roomClients[roomID]
-	if connMap == nil {
-		return false
-	}
-	return len(connMap) != 0
+// SetScoringModes limits the scoring modes rooms can choose to the ones the code executor can decide. it's called
+// once on startup, before any connections are served
+func SetScoringModes(modes []string) {
+	scoringModes = modes
 }
 
 func HandleWebSocketConnection(w http.ResponseWriter, r *http.Request) {
+	serveConnection(w, r, heartbeatSettings())
+}
+
+// handles a websocket connection for as long as it lasts, keeping it alive with the given heartbeat settings
+func serveConnection(w http.ResponseWriter, r *http.Request, settings heartbeat) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println(err)
 		return
 	}
 
+	client := newClient(conn, settings)
 	room := r.URL.Query().Get("room")
 	if room == "" || room == "undefined" {
 		log.Println("Room parameter is missing from websocket request.")
+		client.closeWith(websocket.ClosePolicyViolation, "no room given")
 		return
 	}
 
-	// wait until an auth message comes over websocket before allowing regular communication
+	// wait until an auth message comes over websocket before allowing regular communication. the protocol version
+	// is agreed on then, and the client only joins its room's hub once it's authorized, or has resumed an earlier
+	// session
 	authorized := false
+	version := 0
 	username := ""
+	// whether the connection dropped, rather than being closed on purpose, so the player may be back
+	dropped := false
+	// whether the client was turned away before it joined the room, which closes its channel
+	rejected := false
 
 	defer func() {
-		// Remove the client when the connection is closed
 		log.Printf("Connection closed for %s (%p) in room %s\n", username, conn, room)
-		roomClientsMutex.Lock()
-		if roomClients[room][conn] == false {
-			log.Println("error: connection doesn't exist in room clients map!")
-		}
-		delete(roomClients[room], conn)
-		roomClientsMutex.Unlock()
-		// try to remove the user from room as well, just in case they didn't leave properly
-		if username != "" {
-			rooms.AddOrRemoveUser(username, room, false)
-			BroadcastUserJoinLeave(username, room, false)
+		switch {
+		case client.hub == nil:
+			// it never joined the room, so nothing else will close its channel
+			if !rejected {
+				close(client.send)
+			}
+		case dropped && settings.resumeGrace > 0:
+			holdSession(room, client)
+		default:
+			leaveRoom(client)
+			// try to remove the user from room as well, just in case they didn't leave properly
+			removeFromRoom(username, room)
 		}
 		conn.Close()
 	}()
 
-	// Add the new client to the roomClients map
-	roomClientsMutex.Lock()
-	if roomClients[room] == nil {
-		roomClients[room] = make(map[*websocket.Conn]bool)
-	}
-	roomClients[room][conn] = true
-	roomClientsMutex.Unlock()
+	// start writing to the client, so it's pinged while it authorizes
+	go client.writeLoop()
 
 	log.Println(fmt.Sprintf("new websocket connection %p for room %s", conn, room))
 
+	// every read has to finish before the connection stops answering pings or goes idle. pongs answer pings, so they
+	// push the deadline back, but only messages count as activity
+	deadlines := newConnDeadlines(settings, time.Now())
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(deadlines.readDeadline(time.Now()))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(deadlines.readDeadline(time.Now()))
+	})
+
 	// Handle incoming messages
 	for {
 		_, p, err := conn.ReadMessage()
 		if err != nil {
-			log.Println(err)
+			switch {
+			case deadlines.idle(time.Now()) && !authorized:
+				client.closeWith(websocket.ClosePolicyViolation, "no authorization message received")
+			case deadlines.idle(time.Now()):
+				client.closeWith(websocket.CloseNormalClosure, "idle for too long")
+			case errors.As(err, new(*websocket.CloseError)) && !websocket.IsCloseError(err, websocket.CloseAbnormalClosure):
+				// the client closed the connection, so the player is leaving. abnormal closures are connections that
+				// ended without a close message
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+					log.Printf("websocket connection %p closed unexpectedly: %v\n", conn, err)
+				}
+			default:
+				log.Printf("websocket connection %p dropped: %v\n", conn, err)
+				dropped = true
+			}
 			return
 		}
+		if authorized {
+			deadlines.active(time.Now())
+		}
+		conn.SetReadDeadline(deadlines.readDeadline(time.Now()))
 
-		var receivedMessage Message
-		err = json.Unmarshal(p, &receivedMessage)
+		// every message is checked against its type's schema, and anything wrong with it is sent back as an error
+		envelope, err := decodeMessage(p, version)
+		if err == nil && !authorized && envelope.Type != TypeAuthorization && envelope.Type != TypeResume {
+			err = errorf(ErrUnauthorized, "authorize before sending %q messages", envelope.Type)
+		}
+		// a connection can only send messages to its own room
+		if err == nil && envelope.Room != "" && envelope.Room != room {
+			err = errorf(ErrForbidden, "this connection is for room %s", room)
+		}
 		if err != nil {
-			log.Println(err)
-			return
+			client.reply(newReply(room, envelope.ID, TypeError, errorMessage(err)))
+			continue
 		}
 
-		// we'll handle each message type explicitly to ensure correct info is sent
-		switch receivedMessage.Type {
-		case "authorization":
+		switch payload := envelope.Payload.(type) {
+		case Authorization:
 			// authorization message required before regular communication is allowed
 			if authorized {
+				client.reply(newReply(room, envelope.ID, TypeError, ErrorMessage{Code: ErrInvalid, Message: "already authorized"}))
 				break
 			}
-			authToken := receivedMessage.Content
-			claimsMap, err := authHandlers.VerifyTokenAndGetClaims(authToken)
+			version = agreeVersion(payload.Versions)
+			if version == 0 {
+				client.reject(newReply(room, envelope.ID, TypeError, unsupportedVersion()), websocket.ClosePolicyViolation)
+				rejected = true
+				return
+			}
+			claimsMap, err := authHandlers.VerifyTokenAndGetClaims(payload.Token)
 			if err != nil {
-				http.Error(w, "Websocket: Failed to validate auth token", http.StatusUnauthorized)
-				break
+				client.reject(newReply(room, envelope.ID, TypeError, ErrorMessage{Code: ErrAuthFailed, Message: "failed to validate auth token"}), websocket.ClosePolicyViolation)
+				rejected = true
+				return
 			}
 			claims, err := authHandlers.ExtractTokenClaims(claimsMap)
 			if err != nil {
-				http.Error(w, "Websocket: Failed to extract claims from token", http.StatusUnauthorized)
-				break
+				client.reject(newReply(room, envelope.ID, TypeError, ErrorMessage{Code: ErrAuthFailed, Message: "failed to extract claims from token"}), websocket.ClosePolicyViolation)
+				rejected = true
+				return
+			}
+			// sessions can't be resumed without a grace period, so there's no token for them
+			token := ""
+			if settings.resumeGrace > 0 {
+				token, err = newSessionToken()
+				if err != nil {
+					log.Printf("failed to make a session token: %v\n", err)
+					client.closeWith(websocket.CloseInternalServerErr, "failed to start session")
+					return
+				}
 			}
-			// authorize and record user info for this connection
+			// authorize and record user info for this connection, and give it the token to resume its session with
 			authorized = true
 			username = claims.DisplayName
+			client.user = username
+			client.token = token
+			client.sendDirect(newReply(room, envelope.ID, TypeAck, Ack{
+				Version: version,
+				Session: &Session{Token: token, Grace: settings.resumeGrace.Seconds()},
+			}))
+			joinRoom(room, client)
+			deadlines.active(time.Now())
+			conn.SetReadDeadline(deadlines.readDeadline(time.Now()))
 			BroadcastUserJoinLeave(username, room, true)
-		case "chat_message":
-			// chat messages
-			if !authorized {
+		case Resume:
+			// resumes a session whose connection dropped, instead of authorizing. the player never left the room, so
+			// nobody is told they joined
+			if authorized {
+				client.reply(newReply(room, envelope.ID, TypeError, ErrorMessage{Code: ErrInvalid, Message: "already authorized"}))
 				break
 			}
-			messageToSend := Message{
-				Type:      "chat_message",
-				Room:      receivedMessage.Room,
-				Timestamp: receivedMessage.Timestamp,
-				Content:   receivedMessage.Content,
-				Sender:    receivedMessage.Sender,
+			version = agreeVersion(payload.Versions)
+			if version == 0 {
+				client.reject(newReply(room, envelope.ID, TypeError, unsupportedVersion()), websocket.ClosePolicyViolation)
+				rejected = true
+				return
 			}
-			broadcastMessage(messageToSend, conn)
-			// We don't save the message history in firebase, just to preserve storage space
-		case "room_message":
-			// messages for updating room settings, users, etc.
-			if !authorized {
+			token, err := newSessionToken()
+			if err != nil {
+				log.Printf("failed to make a session token: %v\n", err)
+				client.closeWith(websocket.CloseInternalServerErr, "failed to resume session")
+				return
+			}
+			client.token = token
+			session := &Session{Token: token, Grace: settings.resumeGrace.Seconds(), Progress: gameProgress(room)}
+			ack := newReply(room, envelope.ID, TypeAck, Ack{Version: version, Session: session})
+			if !resumeSession(room, client, payload.Token, payload.After, ack) {
+				// the client can still authorize as usual
+				client.token = ""
+				version = 0
+				client.reply(newReply(room, envelope.ID, TypeError, ErrorMessage{Code: ErrResumeFailed, Message: "session expired or not found"}))
 				break
 			}
-			// update room in firestore if applicable
-			go updateRoom(receivedMessage, room)
-
-			messageToSend := Message{
-				Type:       "room_message",
-				Room:       receivedMessage.Room,
-				Timestamp:  receivedMessage.Timestamp,
-				RoomUpdate: receivedMessage.RoomUpdate,
+			authorized = true
+			username = client.user
+			deadlines.active(time.Now())
+			conn.SetReadDeadline(deadlines.readDeadline(time.Now()))
+		case Chat:
+			// the sender is whoever the connection is authorized as, whatever the client says
+			broadcastMessage(newEnvelope(room, TypeChat, Chat{Sender: username, Content: payload.Content}), client)
+			// We don't save the message history in firebase, just to preserve storage space
+		case RoomUpdate:
+			// players can only say they're ready themselves
+			if ready, ok := payload.Data.(UserReady); ok {
+				ready.User = username
+				payload.Data = ready
+			}
+			// update room in the store if applicable, and tell everyone else in the room once it's allowed and saved.
+			// nothing else is read from the connection meanwhile, so the store gets half the pong wait, which leaves
+			// time to read the pongs that piled up before the read deadline passes
+			ctx, cancel := context.WithTimeout(context.Background(), settings.pongWait/2)
+			err := updateRoom(ctx, payload, room, username)
+			cancel()
+			if err != nil {
+				client.reply(newReply(room, envelope.ID, TypeError, errorMessage(err)))
+				continue
 			}
-			broadcastMessage(messageToSend, conn)
+			broadcastMessage(newEnvelope(room, TypeRoomUpdate, payload), client)
 		}
+		if envelope.ID != "" && (envelope.Type == TypeChat || envelope.Type == TypeRoomUpdate) {
+			client.reply(newReply(room, envelope.ID, TypeAck, Ack{}))
+		}
+	}
+}
+
+// makes a reply to a client's message with the given ID (if it had one)
+func newReply(roomID string, id string, messageType MessageType, payload interface{}) Envelope {
+	envelope := newEnvelope(roomID, messageType, payload)
+	envelope.ID = id
+	return envelope
+}
+
+// the error to send back for a message that was wrong
+func errorMessage(err error) ErrorMessage {
+	var protoErr *protocolError
+	if errors.As(err, &protoErr) {
+		return ErrorMessage{Code: protoErr.code, Message: protoErr.message}
 	}
+	return ErrorMessage{Code: ErrBadMessage, Message: err.Error()}
 }
 
-// check for updates in room messages that we want to forward to firestore
-func updateRoom(receivedMessage Message, roomID string) {
+// the error for a client that speaks none of the server's protocol versions
+func unsupportedVersion() ErrorMessage {
+	return ErrorMessage{Code: ErrUnsupportedVersion, Message: fmt.Sprintf("the server speaks versions %v of the protocol", protocolVersions)}
+}
+
+// check for updates in room messages that we want to forward to the store. updates to the room's settings can only
+// come from its owner. returns a *protocolError if the update isn't allowed or couldn't be saved, which includes
+// the store not answering before ctx is done
+func updateRoom(ctx context.Context, roomUpdate RoomUpdate, roomID string, username string) error {
+	if roomUpdate.ownerOnly() {
+		room, err := storage.Get().GetRoom(ctx, roomID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return errorf(ErrInvalid, "room %s doesn't exist", roomID)
+		}
+		if err != nil {
+			log.Printf("failed to get room %s to check its owner: %v\n", roomID, err)
+			return errorf(ErrInternal, "failed to check who owns the room")
+		}
+		if room.Owner != username {
+			return errorf(ErrForbidden, "only the room's owner can change its settings")
+		}
+	}
+
 	var update map[string]interface{}
-	switch receivedMessage.RoomUpdate.Type {
-	case "CHANGE_DIFFICULTY":
+	switch data := roomUpdate.Data.(type) {
+	case Difficulty:
 		update = map[string]interface{}{
-			"Difficulty": receivedMessage.RoomUpdate.Data["value"],
+			"Difficulty": data.Value,
 		}
-	case "CHANGE_TIME_LIMIT":
+	case TimeLimit:
 		update = map[string]interface{}{
-			"TimeLimit": receivedMessage.RoomUpdate.Data["value"],
+			"TimeLimit": data.Value,
 		}
-	case "CHANGE_PROBLEM":
-		dataValue, ok := receivedMessage.RoomUpdate.Data["value"].(map[string]interface{})
-		if !ok {
-			// client sent unexpected data
-			log.Printf("Error while updating room problem: data received from client socket is of unexpected format.")
-			return
+	case ProblemChoice:
+		problemID := ""
+		if data.Value != nil {
+			problemID = data.Value.ID
+		}
+		update = map[string]interface{}{
+			"Problem": problemID,
 		}
+	case ScoringMode:
 		update = map[string]interface{}{
-			"Problem": dataValue["id"],
+			"ScoringMode": data.Value,
 		}
-	case "RANDOM_PROBLEM":
+	case RandomProblem:
 		update = map[string]interface{}{
-			"RandomProblem": receivedMessage.RoomUpdate.Data["value"],
+			"RandomProblem": data.Value,
 			"Problem":       "",
 		}
 	}
 	if update != nil {
-		err := rooms.UpdateRoom(roomID, update)
+		err := storage.Get().UpdateRoom(ctx, roomID, update)
 		if err != nil {
 			log.Printf("Error while sending room update: %s", err)
+			return errorf(ErrInternal, "failed to save the room update")
 		}
 	}
+	return nil
 }
 
-func broadcastMessage(message Message, sendingConnection *websocket.Conn) {
-	// Iterate over all connected clients in the same room and send the message
-	roomClientsMutex.Lock()
-	defer roomClientsMutex.Unlock()
-
-	counter := 0
-
-	for client := range roomClients[message.Room] {
-		// don't send messages back to the sending client, if applicable
-		if client == sendingConnection {
-			continue
-		}
-		err := client.WriteJSON(Message{
-			Type:       message.Type,
-			Timestamp:  message.Timestamp,
-			Room:       message.Room,
-			Content:    message.Content,
-			Sender:     message.Sender,
-			RoomUpdate: message.RoomUpdate,
-		})
-		if err != nil {
-			log.Println(err)
-		}
-		counter += 1
+// takes a user out of a room for good, once they've left it or their session has expired
+func removeFromRoom(username string, roomID string) {
+	if username == "" {
+		return
 	}
+	rooms.AddOrRemoveUser(username, roomID, false)
+	BroadcastUserJoinLeave(username, roomID, false)
 }
 
 // broadcasts when a user joins or leaves a room
 func BroadcastUserJoinLeave(username string, roomID string, join bool) {
-	var updateType string
+	updateType := UserLeave
 	if join {
-		updateType = "USER_JOIN"
-	} else {
-		updateType = "USER_LEAVE"
-	}
-	messageToSend := Message{
-		Type:      "room_message",
-		Room:      roomID,
-		Timestamp: int(time.Now().UnixMilli()),
-		RoomUpdate: RoomUpdate{
-			Type: updateType,
-			Data: map[string]interface{}{
-				"value": username,
-			},
-		},
+		updateType = UserJoin
 	}
-	broadcastMessage(messageToSend, nil)
+	broadcastMessage(newEnvelope(roomID, TypeRoomUpdate, RoomUpdate{Type: updateType, Data: UserChange{Value: username}}), nil)
 }
 
 type GameState struct {
-	UserProgress map[string]int // maps user (by username) to their current progress (number of tests passed)
-	TotalCases   int            // total number of test cases (incl submission tests) for this game/problem
-	GameOver     bool           // whether this game has ended
-	TimeLimit    int            // time limit for this game, in minutes
-	TimeElapsed  int            // current time elapsed, in minutes
-	Winner       string         // username of user who is currently winning - used to designate winner when game over
-	WinnerScore  int            // number of tests the current winner has passed
+	GameID       string           // identifies this game among all the games played in any room
+	ProblemID    string           // the problem being solved
+	StartedAt    time.Time        // when the game started
+	Players      []string         // usernames of the room's members when the game started, sorted
+	UserProgress map[string]int   // maps user (by username) to their current progress (number of tests passed)
+	TotalCases   int              // total number of test cases (incl submission tests) for this game/problem
+	GameOver     bool             // whether this game has ended
+	TimeLimit    int              // time limit for this game, in minutes
+	TimeElapsed  int              // current time elapsed, in minutes
+	Winner       string           // username of user who is currently winning - used to designate winner when game over
+	WinnerScore  int              // number of tests the current winner has passed
+	ScoringMode  string           // how the winner is decided (see models.Room)
+	UserRuntime  map[string]int64 // best total runtime (ms) of each user's submissions that passed every test
+	UserMemory   map[string]int64 // least peak memory (KB) of each user's submissions that passed every test
+	// when each user's standing was last set: when their progress last changed, or, in the performance scoring
+	// modes, when they last improved their measurement. ties go to whoever got there first
+	UserScoredAt map[string]time.Time
 }
 
-// Notify users that game has started
-func broadcastLaunchGame(roomID string) {
-	messageToSend := Message{
-		Type:      "room_message",
-		Room:      roomID,
-		Timestamp: int(time.Now().UnixMilli()),
-		RoomUpdate: RoomUpdate{
-			Type: "LAUNCH_GAME",
-		},
+// the best measurements of each user's solutions that the scoring mode ranks them by, or nil if it doesn't rank
+// them by performance
+func (g GameState) performance() map[string]int64 {
+	switch g.ScoringMode {
+	case models.ScoringRuntime:
+		return g.UserRuntime
+	case models.ScoringMemory:
+		return g.UserMemory
+	default:
+		return nil
 	}
-	broadcastMessage(messageToSend, nil)
 }
 
-func broadcastGameOver(roomID string, winner string) {
-	messageToSend := Message{
-		Type:      "game_message",
-		Room:      roomID,
-		Timestamp: int(time.Now().UnixMilli()),
-		RoomUpdate: RoomUpdate{
-			Type: "GAME_OVER",
-			Data: map[string]interface{}{
-				"value": winner,
-			},
-		},
+// the user who is currently winning, and how many tests they've passed. players with the most tests passed are
+// ahead, and in the performance scoring modes, players who've passed every test are ranked by their best
+// measurement. ties go to whoever got there first
+func (g GameState) leader() (string, int) {
+	measured := g.performance()
+	// whether a is ahead of b
+	ahead := func(a string, b string) bool {
+		if g.UserProgress[a] != g.UserProgress[b] {
+			return g.UserProgress[a] > g.UserProgress[b]
+		}
+		if measured != nil && g.UserProgress[a] == g.TotalCases && measured[a] != measured[b] {
+			return measured[a] < measured[b]
+		}
+		if !g.UserScoredAt[a].Equal(g.UserScoredAt[b]) {
+			return g.UserScoredAt[a].Before(g.UserScoredAt[b])
+		}
+		return a < b
+	}
+	winner := ""
+	for user := range g.UserProgress {
+		if winner == "" || ahead(user, winner) {
+			winner = user
+		}
 	}
-	broadcastMessage(messageToSend, nil)
+	// nobody is winning until they've passed a test
+	if g.UserProgress[winner] == 0 {
+		return "", 0
+	}
+	return winner, g.UserProgress[winner]
+}
+
+// whether the game can end before its time runs out: in first to solve, as soon as someone passes every test, and
+// in the performance modes, once everyone has
+func (g GameState) decided() bool {
+	if g.performance() == nil {
+		return g.WinnerScore == g.TotalCases
+	}
+	for _, progress := range g.UserProgress {
+		if progress < g.TotalCases {
+			return false
+		}
+	}
+	return len(g.UserProgress) > 0
+}
+
+// where each player finished, by username: 1 for the winner, then the others by how many tests they passed and,
+// in the performance scoring modes, their best measurement. players who did equally well share a place
+func (g GameState) places(winner string) map[string]int {
+	measured := g.performance()
+	// whether a finished ahead of b
+	ahead := func(a string, b string) bool {
+		if a == winner || b == winner {
+			return a == winner && b != winner
+		}
+		if g.UserProgress[a] != g.UserProgress[b] {
+			return g.UserProgress[a] > g.UserProgress[b]
+		}
+		valueA, okA := measured[a]
+		valueB, okB := measured[b]
+		if g.UserProgress[a] < g.TotalCases || !okA || !okB {
+			return false
+		}
+		return valueA < valueB
+	}
+
+	users := slices.Clone(g.Players)
+	sort.SliceStable(users, func(i, j int) bool {
+		return ahead(users[i], users[j])
+	})
+	places := make(map[string]int, len(users))
+	for i, user := range users {
+		if i > 0 && !ahead(users[i-1], user) {
+			places[user] = places[users[i-1]]
+		} else {
+			places[user] = i + 1
+		}
+	}
+	return places
+}
+
+// ID of the game being played in a room, or "" if the room isn't in game
+func GetGameID(roomID string) string {
+	gameStateMapMutex.Lock()
+	defer gameStateMapMutex.Unlock()
+	return gameStateMap[roomID].GameID
+}
+
+// the ID and problem of the game a user is playing in a room, and when the game started. ok is false if the room
+// isn't in game or the user isn't one of its players
+func GetPlayerGame(roomID string, username string) (gameID string, problemID string, startedAt time.Time, ok bool) {
+	gameStateMapMutex.Lock()
+	defer gameStateMapMutex.Unlock()
+	gameState, ok := gameStateMap[roomID]
+	if !ok || !slices.Contains(gameState.Players, username) {
+		return "", "", time.Time{}, false
+	}
+	return gameState.GameID, gameState.ProblemID, gameState.StartedAt, true
+}
+
+// whether the user is one of the players of the game being played in a room
+func IsGamePlayer(roomID string, username string) bool {
+	gameStateMapMutex.Lock()
+	defer gameStateMapMutex.Unlock()
+	return slices.Contains(gameStateMap[roomID].Players, username)
+}
+
+// each player's progress in the room's game, or nil if the room isn't in game
+func gameProgress(roomID string) map[string]int {
+	gameStateMapMutex.Lock()
+	defer gameStateMapMutex.Unlock()
+	gameState, ok := gameStateMap[roomID]
+	if !ok {
+		return nil
+	}
+	return maps.Clone(gameState.UserProgress)
+}
+
+// Notify users that game has started, and the ID of the game so they can find its review afterwards
+func broadcastLaunchGame(roomID string, gameID string) {
+	broadcastMessage(newEnvelope(roomID, TypeRoomUpdate, RoomUpdate{Type: LaunchGame, Data: GameLaunch{Value: gameID}}), nil)
+}
+
+// Notify users that the game is over, who won, and how each player's rating changed (by username). ratings is
+// empty if the game wasn't rated
+func broadcastGameOver(roomID string, winner string, ratingChanges map[string]models.RatingChange) {
+	if ratingChanges == nil {
+		ratingChanges = map[string]models.RatingChange{}
+	}
+	gameOver := GameOver{Value: winner, Ratings: ratingChanges}
+	broadcastMessage(newEnvelope(roomID, TypeGameEvent, GameEvent{Type: GameOverEvent, Data: gameOver}), nil)
 }
 
 // handle ending the game
@@ -302,18 +523,84 @@ func broadcastGameOver(roomID string, winner string) {
 // failure to do so will cause deadlock
 func handleGameOver(roomID string, winner string) {
 	log.Printf("Game over for room %s\n", roomID)
-	// broadcast game over to clients
-	broadcastGameOver(roomID, winner)
-
 	gameStateMapMutex.Lock()
-	// TODO record winner information to leaderboard
+	gameState, exists := gameStateMap[roomID]
 	// delete game state
 	delete(gameStateMap, roomID)
 	gameStateMapMutex.Unlock()
+
+	// only the call that actually ended the game records and reviews it
+	var ratingChanges map[string]models.RatingChange
+	if exists {
+		ratingChanges = recordResult(roomID, winner, gameState)
+	}
+	// broadcast game over to clients
+	broadcastGameOver(roomID, winner, ratingChanges)
+	if exists {
+		reviewGame(roomID, winner, gameState)
+	}
+}
+
+// saves the result of a finished game for the leaderboards, and updates its players' ratings from where they
+// finished. games with only one player, or that nobody made any progress in (such as ones everybody left), don't
+// count. returns how each player's rating changed
+func recordResult(roomID string, winner string, gameState GameState) map[string]models.RatingChange {
+	if winner == "" || len(gameState.Players) < 2 {
+		return nil
+	}
+	ctx := context.Background()
+	err := storage.Get().SaveGameResult(ctx, models.GameResult{
+		GameID:    gameState.GameID,
+		RoomID:    roomID,
+		ProblemID: gameState.ProblemID,
+		Winner:    winner,
+		Players:   gameState.Players,
+		EndedAt:   time.Now(),
+	})
+	if err != nil {
+		log.Printf("failed to save the result of game %s: %v\n", gameState.GameID, err)
+	}
+	ratingChanges, err := ratings.RateGame(ctx, gameState.GameID, gameState.places(winner))
+	if err != nil {
+		log.Printf("failed to rate game %s: %v\n", gameState.GameID, err)
+	}
+	return ratingChanges
+}
+
+// saves the review of a finished game, with every player's final code, and sends it to each of the game's
+// players. spectators don't get it, and players only see the reference diffs if they've earned them (see
+// submissions.ReviewFor)
+func reviewGame(roomID string, winner string, gameState GameState) {
+	review := models.GameReview{
+		GameID:    gameState.GameID,
+		RoomID:    roomID,
+		ProblemID: gameState.ProblemID,
+		Winner:    winner,
+		StartedAt: gameState.StartedAt,
+		EndedAt:   time.Now(),
+	}
+	var references map[string]string
+	if problem := problemData.GetProblemByID(gameState.ProblemID); problem != nil {
+		references = problem.Solutions
+	}
+	ctx := context.Background()
+	review, err := submissions.BuildReview(ctx, review, gameState.Players, references)
+	if err != nil {
+		log.Printf("failed to build the review of game %s: %v\n", gameState.GameID, err)
+		return
+	}
+	if err := storage.Get().SaveGameReview(ctx, review); err != nil {
+		log.Printf("failed to save the review of game %s: %v\n", gameState.GameID, err)
+	}
+	for _, player := range gameState.Players {
+		gameReview := GameReview{Value: review.GameID, Review: submissions.ReviewFor(review, player)}
+		sendToUser(newEnvelope(roomID, TypeGameEvent, GameEvent{Type: GameReviewEvent, Data: gameReview}), player)
+	}
 }
 
-// when a user submits code, update game state with the results and check for a winner
-func UpdateGameState(username string, roomID string, updateType string, updateData map[string]interface{}) {
+// when a user tests or submits code, update game state with the results and check for a winner. fullTest is
+// whether it was a full submission, rather than a test of the basic cases
+func UpdateGameState(username string, roomID string, result SubmitResult, fullTest bool, submittedAt time.Time) {
 	gameStateMapMutex.Lock()
 	gameState, exists := gameStateMap[roomID]
 	if !exists {
@@ -321,41 +608,48 @@ func UpdateGameState(username string, roomID string, updateType string, updateDa
 		gameStateMapMutex.Unlock()
 		return
 	}
-
-	messageToSend := Message{
-		Type:      "game_message",
-		Room:      roomID,
-		Timestamp: int(time.Now().UnixMilli()),
+	// only the game's players are scored; anyone else's results would put them in the standings and the ratings
+	if !slices.Contains(gameState.Players, username) {
+		log.Printf("ignoring code submit result for %s in room %s: not a player in its game\n", username, roomID)
+		gameStateMapMutex.Unlock()
+		return
+	}
+	// a test only runs the basic cases, so it doesn't say anything new about a player who's passed every case. only
+	// a full submission can replace or clear their standing and measurements
+	if !fullTest && gameState.UserProgress[username] == gameState.TotalCases {
+		gameStateMapMutex.Unlock()
+		return
 	}
 
-	switch updateType {
-	// update a users test case results
-	case "CODE_SUBMIT_RESULT":
-		log.Println("code submit result", updateData)
-		passCount := updateData["passCount"]
-		if passCount == nil {
-			log.Println("Error updating game state: failed to receive passCount from updateData", updateData)
-			break
+	// update the user's test case results
+	log.Printf("code submit result for %s in room %s: %d passed\n", username, roomID, result.Value)
+	result.User = username
+	if progress, ok := gameState.UserProgress[username]; !ok || progress != result.Value {
+		gameState.UserScoredAt[username] = submittedAt
+	}
+	gameState.UserProgress[username] = result.Value
+	if result.Value == gameState.TotalCases {
+		// keep the best of each user's passing submissions, for the performance scoring modes
+		measured := gameState.performance()
+		before, measuredBefore := measured[username]
+		if best, ok := gameState.UserRuntime[username]; !ok || result.Runtime < best {
+			gameState.UserRuntime[username] = result.Runtime
+		}
+		if best, ok := gameState.UserMemory[username]; !ok || result.Memory < best {
+			gameState.UserMemory[username] = result.Memory
 		}
-		gameState.UserProgress[username] = passCount.(int)
-		messageToSend.RoomUpdate = RoomUpdate{
-			Type: updateType,
-			Data: map[string]interface{}{
-				"value": passCount,
-				"user":  username,
-			},
+		if measured != nil && (!measuredBefore || measured[username] < before) {
+			gameState.UserScoredAt[username] = submittedAt
 		}
+	} else {
+		// a submission that doesn't pass every test is where the user stands now, so their earlier passing
+		// submissions don't count any more
+		delete(gameState.UserRuntime, username)
+		delete(gameState.UserMemory, username)
 	}
 
 	// update who the current winner should be
-	currentWinner := gameState.Winner
-	currentWinnerScore := gameState.WinnerScore
-	for user, progress := range gameState.UserProgress {
-		if progress > currentWinnerScore {
-			currentWinner = user
-			currentWinnerScore = progress
-		}
-	}
+	currentWinner, currentWinnerScore := gameState.leader()
 	gameState.Winner = currentWinner
 	gameState.WinnerScore = currentWinnerScore
 
@@ -363,10 +657,10 @@ func UpdateGameState(username string, roomID string, updateType string, updateDa
 	gameStateMapMutex.Unlock()
 
 	// send update to clients
-	broadcastMessage(messageToSend, nil)
+	broadcastMessage(newEnvelope(roomID, TypeGameEvent, GameEvent{Type: CodeSubmitResult, Data: result}), nil)
 
 	// check for win condition
-	if currentWinnerScore == gameState.TotalCases {
+	if gameState.decided() {
 		gameState.GameOver = true
 		handleGameOver(roomID, currentWinner)
 	}
@@ -432,24 +726,40 @@ func StartGame(roomID string, roomData models.Room) {
 	defer ticker.Stop()
 
 	// initialize gamestate
+	players := slices.Clone(roomData.Users)
+	sort.Strings(players)
 	userProgressMap := map[string]int{}
-	for _, user := range roomData.Users {
+	for _, user := range players {
 		userProgressMap[user] = 0
 	}
 	// TODO make a function to get the list of test cases (or count) so we don't have to hold this in memory?
 	problem := problemData.GetProblemByID(roomData.Problem)
+	// a room can't have chosen a mode that isn't available, unless the server has changed executors since
+	scoringMode := roomData.ScoringMode
+	if !slices.Contains(scoringModes, scoringMode) {
+		scoringMode = models.ScoringFirstToSolve
+	}
 	gameStateMap[roomID] = GameState{
+		GameID:       fmt.Sprintf("%s-%d", roomID, time.Now().UnixMilli()),
+		ProblemID:    roomData.Problem,
+		StartedAt:    time.Now(),
+		Players:      players,
 		UserProgress: userProgressMap,
 		GameOver:     false,
 		TimeLimit:    roomData.TimeLimit,
 		TimeElapsed:  0,
 		Winner:       "",
 		TotalCases:   len(problem.TestCases) + len(problem.FullCases),
+		ScoringMode:  scoringMode,
+		UserRuntime:  map[string]int64{},
+		UserMemory:   map[string]int64{},
+		UserScoredAt: map[string]time.Time{},
 	}
+	gameID := gameStateMap[roomID].GameID
 	gameStateMapMutex.Unlock()
 
 	// notify other members of the room that the game is starting
-	broadcastLaunchGame(roomID)
+	broadcastLaunchGame(roomID, gameID)
 	log.Printf("Game started for room %s\n", roomID)
 
 	gameOver := false
@@ -0,0 +1,394 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+)
+
+func TestGameStateLeader(t *testing.T) {
+	testCases := map[string]struct {
+		state          GameState
+		expectedWinner string
+		decided        bool
+	}{
+		"first to solve": {
+			state: GameState{
+				ScoringMode:  models.ScoringFirstToSolve,
+				TotalCases:   3,
+				UserProgress: map[string]int{"ann": 3, "bob": 2},
+				UserRuntime:  map[string]int64{"ann": 50},
+			},
+			expectedWinner: "ann",
+			decided:        true,
+		},
+		"fastest of the players who passed": {
+			state: GameState{
+				ScoringMode:  models.ScoringRuntime,
+				TotalCases:   3,
+				UserProgress: map[string]int{"ann": 3, "bob": 3, "cat": 1},
+				UserRuntime:  map[string]int64{"ann": 50, "bob": 20},
+				UserMemory:   map[string]int64{"ann": 100, "bob": 900},
+			},
+			expectedWinner: "bob",
+			decided:        false,
+		},
+		"least memory once everyone passed": {
+			state: GameState{
+				ScoringMode:  models.ScoringMemory,
+				TotalCases:   3,
+				UserProgress: map[string]int{"ann": 3, "bob": 3},
+				UserRuntime:  map[string]int64{"ann": 50, "bob": 20},
+				UserMemory:   map[string]int64{"ann": 100, "bob": 900},
+			},
+			expectedWinner: "ann",
+			decided:        true,
+		},
+		"equally fast goes to whoever got there first": {
+			state: GameState{
+				ScoringMode:  models.ScoringRuntime,
+				TotalCases:   3,
+				UserProgress: map[string]int{"ann": 3, "bob": 3, "cat": 2},
+				UserRuntime:  map[string]int64{"ann": 20, "bob": 20},
+				UserScoredAt: map[string]time.Time{"ann": time.Unix(20, 0), "bob": time.Unix(10, 0), "cat": time.Unix(5, 0)},
+			},
+			expectedWinner: "bob",
+			decided:        false,
+		},
+		"as many passed goes to whoever got there first": {
+			state: GameState{
+				ScoringMode:  models.ScoringFirstToSolve,
+				TotalCases:   3,
+				UserProgress: map[string]int{"ann": 2, "bob": 2},
+				UserScoredAt: map[string]time.Time{"ann": time.Unix(10, 0), "bob": time.Unix(20, 0)},
+			},
+			expectedWinner: "ann",
+			decided:        false,
+		},
+		"nobody winning before anyone passed a test": {
+			state: GameState{
+				ScoringMode:  models.ScoringFirstToSolve,
+				TotalCases:   3,
+				UserProgress: map[string]int{"ann": 0, "bob": 0},
+			},
+			expectedWinner: "",
+			decided:        false,
+		},
+		"most passed when nobody passed everything": {
+			state: GameState{
+				ScoringMode:  models.ScoringRuntime,
+				TotalCases:   3,
+				UserProgress: map[string]int{"ann": 1, "bob": 2},
+				UserRuntime:  map[string]int64{},
+			},
+			expectedWinner: "bob",
+			decided:        false,
+		},
+	}
+	for name, testCase := range testCases {
+		state := testCase.state
+		state.Winner, state.WinnerScore = state.leader()
+		if state.Winner != testCase.expectedWinner {
+			t.Errorf("%s: Result: [%s] Expected: [%s]", name, state.Winner, testCase.expectedWinner)
+		}
+		if state.decided() != testCase.decided {
+			t.Errorf("%s: decided: Result: [%v] Expected: [%v]", name, state.decided(), testCase.decided)
+		}
+	}
+}
+
+func TestUpdateGameStateClearsStaleMeasurements(t *testing.T) {
+	roomID := "scoring-room"
+	gameStateMapMutex.Lock()
+	gameStateMap[roomID] = GameState{
+		ScoringMode: models.ScoringRuntime,
+		TotalCases:  3,
+		Players:     []string{"ann", "bob", "cat"},
+		// cat hasn't passed everything, so the game isn't over
+		UserProgress: map[string]int{"ann": 0, "bob": 0, "cat": 0},
+		UserRuntime:  map[string]int64{},
+		UserMemory:   map[string]int64{},
+		UserScoredAt: map[string]time.Time{},
+	}
+	gameStateMapMutex.Unlock()
+	t.Cleanup(func() {
+		gameStateMapMutex.Lock()
+		delete(gameStateMap, roomID)
+		gameStateMapMutex.Unlock()
+	})
+	start := time.Now()
+	UpdateGameState("ann", roomID, SubmitResult{Value: 3, Runtime: 10}, true, start)
+	UpdateGameState("bob", roomID, SubmitResult{Value: 3, Runtime: 50}, true, start.Add(time.Second))
+	// testing the basic cases again doesn't take anything away from bob
+	UpdateGameState("bob", roomID, SubmitResult{Value: 2, Runtime: 1}, false, start.Add(time.Second))
+	gameStateMapMutex.Lock()
+	bobRuntime, bobProgress := gameStateMap[roomID].UserRuntime["bob"], gameStateMap[roomID].UserProgress["bob"]
+	gameStateMapMutex.Unlock()
+	if bobRuntime != 50 || bobProgress != 3 {
+		t.Errorf("a test shouldn't change bob's standing: Result: [%d passed in %dms] Expected: [3 passed in 50ms]", bobProgress, bobRuntime)
+	}
+	// ann's next submission fails a test, so her fast one doesn't count any more
+	UpdateGameState("ann", roomID, SubmitResult{Value: 2, Runtime: 5}, true, start.Add(2*time.Second))
+
+	gameStateMapMutex.Lock()
+	state := gameStateMap[roomID]
+	gameStateMapMutex.Unlock()
+	if _, ok := state.UserRuntime["ann"]; ok {
+		t.Errorf("ann's runtime should be cleared: %v", state.UserRuntime)
+	}
+	if state.Winner != "bob" {
+		t.Errorf("Result: [%s] Expected: [bob]", state.Winner)
+	}
+}
+
+func TestUpdateGameStateIgnoresOutsiders(t *testing.T) {
+	roomID := "outsider-room"
+	gameStateMapMutex.Lock()
+	gameStateMap[roomID] = GameState{
+		ScoringMode:  models.ScoringFirstToSolve,
+		TotalCases:   3,
+		Players:      []string{"ann", "bob"},
+		UserProgress: map[string]int{"ann": 0, "bob": 1},
+		UserRuntime:  map[string]int64{},
+		UserMemory:   map[string]int64{},
+		UserScoredAt: map[string]time.Time{},
+	}
+	gameStateMapMutex.Unlock()
+	t.Cleanup(func() {
+		gameStateMapMutex.Lock()
+		delete(gameStateMap, roomID)
+		gameStateMapMutex.Unlock()
+	})
+	// eve isn't playing, so her passing every test mustn't end the game or put her in the standings
+	UpdateGameState("eve", roomID, SubmitResult{Value: 3}, true, time.Now())
+
+	gameStateMapMutex.Lock()
+	state, ok := gameStateMap[roomID]
+	gameStateMapMutex.Unlock()
+	if !ok {
+		t.Fatal("the game ended on an outsider's result")
+	}
+	if _, ok := state.UserProgress["eve"]; ok {
+		t.Errorf("eve shouldn't have progress: %v", state.UserProgress)
+	}
+	if places := state.places("bob"); len(places) != 2 {
+		t.Errorf("Result: %v Expected: places for ann and bob", places)
+	}
+}
+
+func TestGetPlayerGame(t *testing.T) {
+	roomID := "player-game-room"
+	gameStateMapMutex.Lock()
+	gameStateMap[roomID] = GameState{GameID: "game-1", ProblemID: "problem01", Players: []string{"ann", "bob"}}
+	gameStateMapMutex.Unlock()
+	t.Cleanup(func() {
+		gameStateMapMutex.Lock()
+		delete(gameStateMap, roomID)
+		gameStateMapMutex.Unlock()
+	})
+	if gameID, problemID, _, ok := GetPlayerGame(roomID, "ann"); !ok || gameID != "game-1" || problemID != "problem01" {
+		t.Errorf("Result: [%s %s %v] Expected: [game-1 problem01 true]", gameID, problemID, ok)
+	}
+	if _, _, _, ok := GetPlayerGame(roomID, "eve"); ok {
+		t.Error("eve isn't a player, so she shouldn't get the game")
+	}
+	if _, _, _, ok := GetPlayerGame("no-such-room", "ann"); ok {
+		t.Error("a room that isn't in game shouldn't have one")
+	}
+}
+
+func TestGameStatePlaces(t *testing.T) {
+	testCases := map[string]struct {
+		state    GameState
+		winner   string
+		expected map[string]int
+	}{
+		"ties on tests passed": {
+			state: GameState{
+				ScoringMode:  models.ScoringFirstToSolve,
+				TotalCases:   3,
+				Players:      []string{"ann", "bob", "cat", "dan"},
+				UserProgress: map[string]int{"ann": 3, "bob": 1, "cat": 1, "dan": 0},
+			},
+			winner:   "ann",
+			expected: map[string]int{"ann": 1, "bob": 2, "cat": 2, "dan": 4},
+		},
+		"the winner is ahead of players who got as far": {
+			state: GameState{
+				ScoringMode:  models.ScoringFirstToSolve,
+				TotalCases:   3,
+				Players:      []string{"ann", "bob"},
+				UserProgress: map[string]int{"ann": 2, "bob": 2},
+			},
+			winner:   "bob",
+			expected: map[string]int{"ann": 2, "bob": 1},
+		},
+		"passing players ranked by runtime": {
+			state: GameState{
+				ScoringMode:  models.ScoringRuntime,
+				TotalCases:   3,
+				Players:      []string{"ann", "bob", "cat", "dan"},
+				UserProgress: map[string]int{"ann": 3, "bob": 3, "cat": 3, "dan": 2},
+				UserRuntime:  map[string]int64{"ann": 50, "bob": 20, "cat": 90},
+			},
+			winner:   "bob",
+			expected: map[string]int{"ann": 2, "bob": 1, "cat": 3, "dan": 4},
+		},
+	}
+	for name, testCase := range testCases {
+		places := testCase.state.places(testCase.winner)
+		for user, place := range testCase.expected {
+			if places[user] != place {
+				t.Errorf("%s: %s's place: Result: [%d] Expected: [%d]", name, user, places[user], place)
+			}
+		}
+	}
+}
+
+// sends a message on the connection, with the ID to match its ack or error by
+func send(t *testing.T, conn *websocket.Conn, messageType MessageType, id string, roomID string, payload interface{}) {
+	t.Helper()
+	message := map[string]interface{}{"v": ProtocolVersion, "type": messageType, "id": id, "room": roomID, "payload": payload}
+	if err := conn.WriteJSON(message); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// reads the connection's messages until the ack or error for the message with the ID arrives, and returns it
+func readReply(t *testing.T, conn *websocket.Conn, id string) received {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		var message received
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("waiting for the reply to %s: %v", id, err)
+		}
+		if message.ID == id && (message.Type == TypeAck || message.Type == TypeError) {
+			return message
+		}
+	}
+}
+
+// the code of the error in a reply, or "" if it's an ack
+func errorCode(t *testing.T, message received) ErrorCode {
+	t.Helper()
+	if message.Type != TypeError {
+		return ""
+	}
+	var payload ErrorMessage
+	message.decode(t, &payload)
+	return payload.Code
+}
+
+func TestSenderIdentity(t *testing.T) {
+	settings := heartbeat{pingInterval: time.Minute, pongWait: time.Minute, writeWait: time.Second, authTimeout: time.Minute}
+	roomID := createTestRoom(t, "ann", "bob")
+	ann := dialTestRoom(t, settings, roomID)
+	authorize(t, ann, "ann")
+	bob := dialTestRoom(t, settings, roomID)
+	authorize(t, bob, "bob")
+
+	// bob can't speak for ann, or to another room
+	send(t, bob, TypeChat, "1", roomID, Chat{Sender: "ann", Content: "hi"})
+	if code := errorCode(t, readReply(t, bob, "1")); code != "" {
+		t.Fatalf("chat should be acked, got %s", code)
+	}
+	var chatPayload Chat
+	readUntil(t, ann, TypeChat).decode(t, &chatPayload)
+	if chatPayload.Sender != "bob" {
+		t.Errorf("chat should come from whoever the connection is authorized as, got %s", chatPayload.Sender)
+	}
+	send(t, bob, TypeChat, "2", "another-room", Chat{Content: "hi"})
+	if code := errorCode(t, readReply(t, bob, "2")); code != ErrForbidden {
+		t.Errorf("chat to another room should be forbidden, got %q", code)
+	}
+
+	// players say they're ready for themselves
+	send(t, bob, TypeRoomUpdate, "3", roomID, RoomUpdate{Type: SetUserReady, Data: UserReady{Value: true, User: "ann"}})
+	readReply(t, bob, "3")
+	var ready struct {
+		Data UserReady `json:"data"`
+	}
+	readUntil(t, ann, TypeRoomUpdate).decode(t, &ready)
+	if ready.Data.User != "bob" {
+		t.Errorf("readiness should be for whoever the connection is authorized as, got %s", ready.Data.User)
+	}
+}
+
+func TestOnlyOwnerChangesSettings(t *testing.T) {
+	settings := heartbeat{pingInterval: time.Minute, pongWait: time.Minute, writeWait: time.Second, authTimeout: time.Minute}
+	roomID := createTestRoom(t, "ann", "bob")
+	ann := dialTestRoom(t, settings, roomID)
+	authorize(t, ann, "ann")
+	bob := dialTestRoom(t, settings, roomID)
+	authorize(t, bob, "bob")
+	difficulty := func() int {
+		room, err := storage.Get().GetRoom(context.Background(), roomID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return room.Difficulty
+	}
+
+	send(t, bob, TypeRoomUpdate, "1", roomID, RoomUpdate{Type: ChangeDifficulty, Data: Difficulty{Value: 3}})
+	if code := errorCode(t, readReply(t, bob, "1")); code != ErrForbidden {
+		t.Errorf("only the owner should change the difficulty, got %q", code)
+	}
+	if difficulty() == 3 {
+		t.Error("a forbidden update shouldn't change the room")
+	}
+
+	send(t, ann, TypeRoomUpdate, "2", roomID, RoomUpdate{Type: ChangeDifficulty, Data: Difficulty{Value: 3}})
+	if code := errorCode(t, readReply(t, ann, "2")); code != "" {
+		t.Fatalf("the owner should change the difficulty, got %s", code)
+	}
+	if difficulty() != 3 {
+		t.Error("the owner's update should be saved")
+	}
+	// bob is told about the owner's update, and not about his own
+	var update struct {
+		Type RoomUpdateType `json:"type"`
+		Data Difficulty     `json:"data"`
+	}
+	for update.Type != ChangeDifficulty {
+		readUntil(t, bob, TypeRoomUpdate).decode(t, &update)
+	}
+	if update.Data.Value != 3 {
+		t.Errorf("expected the difficulty to change to 3, got %d", update.Data.Value)
+	}
+}
+
+// a store whose room updates hang until they're given up on
+type hangingStore struct {
+	storage.Store
+}
+
+func (s hangingStore) UpdateRoom(ctx context.Context, id string, updates map[string]interface{}) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRoomUpdateTimesOut(t *testing.T) {
+	settings := heartbeat{pingInterval: 50 * time.Millisecond, pongWait: 200 * time.Millisecond, writeWait: time.Second, authTimeout: time.Minute}
+	roomID := createTestRoom(t, "ann")
+	ann := dialTestRoom(t, settings, roomID)
+	authorize(t, ann, "ann")
+	store := storage.Get()
+	storage.Set(hangingStore{store})
+	t.Cleanup(func() { storage.Set(store) })
+
+	// the update is given up on before the connection misses its pong deadline, so the connection stays up
+	send(t, ann, TypeRoomUpdate, "1", roomID, RoomUpdate{Type: ChangeDifficulty, Data: Difficulty{Value: 3}})
+	if code := errorCode(t, readReply(t, ann, "1")); code != ErrInternal {
+		t.Errorf("an update the store doesn't answer should fail, got %q", code)
+	}
+	send(t, ann, TypeChat, "2", roomID, Chat{Content: "still here"})
+	if code := errorCode(t, readReply(t, ann, "2")); code != "" {
+		t.Errorf("the connection should still work after a timed out update, got %q", code)
+	}
+}
@@ -0,0 +1,31 @@
+package languages
+
+/*
+ * A harness wraps a player's code so that a single execution calls its solution once for every test case in a
+ * batch. The harness reads the cases' arguments from stdin (see protocol.go in handlers/code), and for each case
+ * captures what the solution printed, what it returned, how long it took and any error it raised. It then prints a
+ * record line to the real stdout:
+ *
+ *	@@CODEDUEL_CASE@@ <nonce> <case> <ok|error> <runtime in ns> <base64 stdout> <base64 error> <base64 JSON result>
+ *
+ * empty base64 fields are written as "-". A result of "-" means the solution didn't return anything. If the code
+ * fails to compile inside the harness (e.g. a python syntax error), the harness prints a single compile error record
+ * instead:
+ *
+ *	@@CODEDUEL_COMPILE_ERROR@@ <nonce> <base64 error>
+ *
+ * Compiled languages report compile errors through the executor instead.
+ *
+ * The nonce is random for each execution and is the first line of stdin. The player's code can write to the real
+ * stdout too, so records are only believed if they carry it, and each case is recorded once, in order. Harnesses
+ * read the nonce before the player's code runs and keep it where that code can't name it: a local of the driver
+ * function, a closure, or a separate process for bash. Never a global, which python and ruby code can reach through
+ * sys.modules or TOPLEVEL_BINDING. Interpreted code could still dig a local out with frame or ObjectSpace
+ * introspection, so the nonce stops accidental and casual forgery, not a determined player; time and memory limits
+ * are enforced by the executor, outside the harness.
+ */
+
+const (
+	HarnessCaseMarker         = "@@CODEDUEL_CASE@@"
+	HarnessCompileErrorMarker = "@@CODEDUEL_COMPILE_ERROR@@"
+)
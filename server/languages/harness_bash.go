@@ -0,0 +1,207 @@
+package languages
+
+import (
+	"strings"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// the bash harness checks the code's syntax, then runs it in a new bash for each case to define and call solution, so
+// its output can be captured. it reads the test cases from stdin a line at a time, with a small JSON reader.
+//
+// arguments are passed to solution as positional parameters. strings, numbers and booleans are passed as they
+// are, and a list is passed as its elements separated by spaces (or one row per line, for lists of lists), so it
+// can be split with read -a or a for loop. linked lists are passed like lists, and trees like lists of their
+// values in level order, with null for missing nodes. maps are passed as one "key value" pair per line.
+// bash functions can't return values, so solution echoes its answer.
+var bashHarnessTemplate = `
+cd_code={{CODE}}
+
+cd_encode () {
+	local encoded
+	encoded=$(printf '%s' "$1" | base64 -w0)
+	printf '%s' "${encoded:--}"
+}
+
+# the first line of stdin is the nonce every record has to carry, and the rest is the cases
+IFS= read -r cd_nonce
+
+if ! cd_syntax_error=$(bash -n 2>&1 <<<"$cd_code"); then
+	echo "{{COMPILE_ERROR_MARKER}} $cd_nonce $(cd_encode "$cd_syntax_error")"
+	exit 1
+fi
+
+# the JSON reader works through cd_json from cd_pos, and leaves each value it reads in cd_value
+cd_json=""
+cd_pos=0
+
+cd_skip_space () {
+	while [[ "${cd_json:cd_pos:1}" == [[:space:]] ]]; do
+		cd_pos=$(( cd_pos + 1 ))
+	done
+}
+
+cd_read_string () {
+	local value="" c
+	cd_pos=$(( cd_pos + 1 ))
+	while true; do
+		c="${cd_json:cd_pos:1}"
+		cd_pos=$(( cd_pos + 1 ))
+		case "$c" in
+		'"'|'') break ;;
+		'\')
+			c="${cd_json:cd_pos:1}"
+			cd_pos=$(( cd_pos + 1 ))
+			case "$c" in
+			n) value+=$'\n' ;;
+			t) value+=$'\t' ;;
+			r) value+=$'\r' ;;
+			b) value+=$'\b' ;;
+			f) value+=$'\f' ;;
+			u)
+				value+=$(printf "\\u${cd_json:cd_pos:4}")
+				cd_pos=$(( cd_pos + 4 ))
+				;;
+			*) value+="$c" ;;
+			esac
+			;;
+		*) value+="$c" ;;
+		esac
+	done
+	cd_value="$value"
+}
+
+# reads a list into cd_items. cd_items_nested is 1 if any of its elements were lists
+cd_read_items () {
+	local items=() nested=0
+	cd_pos=$(( cd_pos + 1 ))
+	cd_skip_space
+	local first="${cd_json:cd_pos:1}" flat=""
+	if [[ "$first" != [\[\"{] ]]; then
+		# indexing into a string is slow in bash, so a list of plain values is split all at once
+		IFS="]" read -r flat _ <<<"${cd_json:cd_pos}"
+	fi
+	if [[ "$first" == "]" ]]; then
+		cd_pos=$(( cd_pos + 1 ))
+	elif [[ -n "$flat" && "$flat" != *[\[\"{]* ]]; then
+		IFS=$', \t\r' read -ra items <<<"$flat"
+		cd_pos=$(( cd_pos + ${#flat} + 1 ))
+	else
+		while true; do
+			cd_skip_space
+			if [[ "${cd_json:cd_pos:1}" == "[" ]]; then
+				nested=1
+			fi
+			cd_read_value
+			items+=("$cd_value")
+			cd_skip_space
+			cd_pos=$(( cd_pos + 1 ))
+			[[ "${cd_json:cd_pos-1:1}" == "," ]] || break
+		done
+	fi
+	cd_items=("${items[@]}")
+	cd_items_nested=$nested
+}
+
+# reads a map into cd_value, as "key value" lines sorted by key
+cd_read_entries () {
+	local entries=() key
+	cd_pos=$(( cd_pos + 1 ))
+	cd_skip_space
+	if [[ "${cd_json:cd_pos:1}" == "}" ]]; then
+		cd_pos=$(( cd_pos + 1 ))
+	else
+		while true; do
+			cd_skip_space
+			cd_read_string
+			key="$cd_value"
+			cd_skip_space
+			cd_pos=$(( cd_pos + 1 ))
+			cd_read_value
+			entries+=("$key $cd_value")
+			cd_skip_space
+			cd_pos=$(( cd_pos + 1 ))
+			[[ "${cd_json:cd_pos-1:1}" == "," ]] || break
+		done
+	fi
+	cd_value=$(printf '%s\n' "${entries[@]}" | LC_ALL=C sort)
+}
+
+cd_read_value () {
+	local literal='^[^],}[:space:]]+'
+	cd_skip_space
+	case "${cd_json:cd_pos:1}" in
+	'"') cd_read_string ;;
+	'[')
+		cd_read_items
+		local IFS=" "
+		if [ "$cd_items_nested" -eq 1 ]; then
+			IFS=$'\n'
+		fi
+		cd_value="${cd_items[*]}"
+		;;
+	'{')
+		cd_read_entries
+		;;
+	*)
+		# numbers, true, false and null
+		cd_value=""
+		if [[ "${cd_json:cd_pos}" =~ $literal ]]; then
+			cd_value="${BASH_REMATCH[0]}"
+		fi
+		cd_pos=$(( cd_pos + ${#cd_value} ))
+		;;
+	esac
+}
+
+# the code only ever runs in a bash of its own, which doesn't get the harness's variables or stdin, so it can't get
+# hold of the nonce to forge records. running it defines solution. anything printed along the way isn't part of
+# any case's output
+cd_define='eval "$1" >/dev/null 2>&1 </dev/null; shift; '
+cd_setup_error=""
+if ! bash -c "$cd_define"'declare -F {{NAME}} >/dev/null' {{NAME}} "$cd_code"; then
+	cd_setup_error="no {{NAME}} function found"
+fi
+cd_errfile=$(mktemp)
+
+# each case is on a line of its own, so the reader only ever works through one case at a time. the other lines
+# are the brackets around them
+cd_case=0
+while IFS= read -r cd_json; do
+	if [[ "$cd_json" != "["?* ]]; then
+		continue
+	fi
+	cd_pos=0
+	cd_read_items
+	cd_args=("${cd_items[@]}")
+	if [ -n "$cd_setup_error" ]; then
+		echo "{{CASE_MARKER}} $cd_nonce $cd_case error 0 - $(cd_encode "$cd_setup_error") -"
+	else
+		cd_start=$(date +%s%N)
+		cd_stdout=$(bash -c "$cd_define"'{{NAME}} "$@"' {{NAME}} "$cd_code" "${cd_args[@]}" 2>"$cd_errfile" </dev/null)
+		cd_exit=$?
+		cd_elapsed=$(( $(date +%s%N) - cd_start ))
+		cd_status=ok
+		if [ $cd_exit -ne 0 ]; then
+			cd_status=error
+		fi
+		echo "{{CASE_MARKER}} $cd_nonce $cd_case $cd_status $cd_elapsed $(cd_encode "$cd_stdout") $(cd_encode "$(cat "$cd_errfile")") -"
+	fi
+	cd_case=$(( cd_case + 1 ))
+done
+rm -f "$cd_errfile"
+`
+
+func bashHarness(code string, signature models.Signature) string {
+	return strings.NewReplacer(
+		"{{CODE}}", bashQuote(code),
+		"{{NAME}}", signature.Name,
+		"{{CASE_MARKER}}", HarnessCaseMarker,
+		"{{COMPILE_ERROR_MARKER}}", HarnessCompileErrorMarker,
+	).Replace(bashHarnessTemplate)
+}
+
+// quotes a string so bash reads it as a single literal word
+func bashQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
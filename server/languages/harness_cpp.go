@@ -0,0 +1,513 @@
+package languages
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// the c++ harness comes in two parts. the prelude goes before the player's code, and includes the standard library
+// and defines the node types, as leetcode does. the rest goes after it, and adds a main function that reads each
+// test case with a small JSON reader, decodes its arguments into the types the signature gives for them (through
+// overloads of cd_decode), and calls solution. stdout is pointed at a file during each call to capture what
+// solution prints, whether through cout or printf.
+var cppHarnessPrelude = `#include <bits/stdc++.h>
+#include <fcntl.h>
+#include <signal.h>
+#include <unistd.h>
+using namespace std;
+{{NODES}}
+#line 1 "solution.cpp"
+`
+
+var cppListNode = `
+struct ListNode {
+    {{ELEM}} val;
+    ListNode *next;
+    ListNode() : val(), next(nullptr) {}
+    ListNode(const {{ELEM}} &val, ListNode *next = nullptr) : val(val), next(next) {}
+};
+`
+
+var cppTreeNode = `
+struct TreeNode {
+    {{ELEM}} val;
+    TreeNode *left;
+    TreeNode *right;
+    TreeNode() : val(), left(nullptr), right(nullptr) {}
+    TreeNode(const {{ELEM}} &val, TreeNode *left = nullptr, TreeNode *right = nullptr) : val(val), left(left), right(right) {}
+};
+`
+
+var cppHarnessTemplate = `
+#line 1 "harness.cpp"
+// a JSON value, as read by cd_parse
+struct CdJson {
+    enum Kind { Null, Boolean, Number, Text, Array, Object } kind = Null;
+    bool boolean = false;
+    string text; // a string's value, or a number as it was written
+    vector<CdJson> items;
+    vector<pair<string, CdJson>> fields;
+};
+
+static void cd_append_utf8(string &out, unsigned code) {
+    if (code < 0x80) {
+        out += char(code);
+    } else if (code < 0x800) {
+        out += char(0xC0 | code >> 6);
+        out += char(0x80 | (code & 0x3F));
+    } else if (code < 0x10000) {
+        out += char(0xE0 | code >> 12);
+        out += char(0x80 | (code >> 6 & 0x3F));
+        out += char(0x80 | (code & 0x3F));
+    } else {
+        out += char(0xF0 | code >> 18);
+        out += char(0x80 | (code >> 12 & 0x3F));
+        out += char(0x80 | (code >> 6 & 0x3F));
+        out += char(0x80 | (code & 0x3F));
+    }
+}
+
+static string cd_parse_string(const string &s, size_t &pos) {
+    string value;
+    pos++;
+    while (pos < s.size() && s[pos] != '"') {
+        char c = s[pos++];
+        if (c != '\\') {
+            value += c;
+            continue;
+        }
+        c = s[pos++];
+        switch (c) {
+        case 'n': value += '\n'; break;
+        case 't': value += '\t'; break;
+        case 'r': value += '\r'; break;
+        case 'b': value += '\b'; break;
+        case 'f': value += '\f'; break;
+        case 'u': {
+            unsigned code = stoul(s.substr(pos, 4), nullptr, 16);
+            pos += 4;
+            if (code >= 0xD800 && code < 0xDC00 && s.compare(pos, 2, "\\u") == 0) {
+                unsigned low = stoul(s.substr(pos + 2, 4), nullptr, 16);
+                code = 0x10000 + ((code - 0xD800) << 10) + (low - 0xDC00);
+                pos += 6;
+            }
+            cd_append_utf8(value, code);
+            break;
+        }
+        default: value += c;
+        }
+    }
+    pos++;
+    return value;
+}
+
+static void cd_skip(const string &s, size_t &pos, const char *chars) {
+    while (pos < s.size() && (isspace((unsigned char)s[pos]) || strchr(chars, s[pos]) != nullptr)) {
+        pos++;
+    }
+}
+
+static CdJson cd_parse(const string &s, size_t &pos) {
+    cd_skip(s, pos, "");
+    if (pos >= s.size()) {
+        throw runtime_error("harness: unexpected end of test case");
+    }
+    CdJson value;
+    char c = s[pos];
+    if (c == '[' || c == '{') {
+        value.kind = c == '[' ? CdJson::Array : CdJson::Object;
+        char close = c == '[' ? ']' : '}';
+        pos++;
+        while (true) {
+            cd_skip(s, pos, ",");
+            if (pos >= s.size()) {
+                throw runtime_error("harness: unexpected end of test case");
+            }
+            if (s[pos] == close) {
+                pos++;
+                break;
+            }
+            if (value.kind == CdJson::Array) {
+                value.items.push_back(cd_parse(s, pos));
+            } else {
+                string key = cd_parse_string(s, pos);
+                cd_skip(s, pos, ":");
+                value.fields.emplace_back(key, cd_parse(s, pos));
+            }
+        }
+    } else if (c == '"') {
+        value.kind = CdJson::Text;
+        value.text = cd_parse_string(s, pos);
+    } else if (c == 't' || c == 'f') {
+        value.kind = CdJson::Boolean;
+        value.boolean = c == 't';
+        pos += c == 't' ? 4 : 5;
+    } else if (c == 'n') {
+        pos += 4;
+    } else {
+        value.kind = CdJson::Number;
+        size_t start = pos;
+        while (pos < s.size() && strchr("+-.0123456789eE", s[pos]) != nullptr) {
+            pos++;
+        }
+        if (pos == start) {
+            throw runtime_error(string("harness: unexpected character in test case: ") + c);
+        }
+        value.text = s.substr(start, pos - start);
+    }
+    return value;
+}
+
+// decoders, which turn JSON values into values of the types solution takes
+static void cd_decode(const CdJson &j, long long &out) {
+    size_t used = 0;
+    out = stoll(j.text, &used);
+    if (used != j.text.size()) {
+        out = llround(stod(j.text));
+    }
+}
+
+static void cd_decode(const CdJson &j, double &out) {
+    out = stod(j.text);
+}
+
+static void cd_decode(const CdJson &j, string &out) {
+    out = j.text;
+}
+
+static void cd_decode(const CdJson &j, bool &out) {
+    out = j.boolean;
+}
+{{NODE_DECODERS}}
+template <class T> static void cd_decode(const CdJson &j, vector<T> &out);
+template <class T> static void cd_decode(const CdJson &j, map<string, T> &out);
+
+template <class T> static void cd_decode(const CdJson &j, vector<T> &out) {
+    for (const CdJson &item : j.items) {
+        T value{};
+        cd_decode(item, value);
+        out.push_back(move(value));
+    }
+}
+
+template <class T> static void cd_decode(const CdJson &j, map<string, T> &out) {
+    for (const auto &field : j.fields) {
+        cd_decode(field.second, out[field.first]);
+    }
+}
+
+// encoders, which write values of the type solution returns as JSON
+static void cd_encode(string &out, long long value) {
+    out += to_string(value);
+}
+
+static void cd_encode(string &out, double value) {
+    if (!isfinite(value)) {
+        out += "null";
+        return;
+    }
+    char buf[32];
+    snprintf(buf, sizeof buf, "%.17g", value);
+    out += buf;
+}
+
+static void cd_encode(string &out, bool value) {
+    out += value ? "true" : "false";
+}
+
+static void cd_encode(string &out, const string &value) {
+    out += '"';
+    for (unsigned char c : value) {
+        if (c == '"' || c == '\\') {
+            out += '\\';
+            out += char(c);
+        } else if (c < 0x20) {
+            char buf[8];
+            snprintf(buf, sizeof buf, "\\u%04x", c);
+            out += buf;
+        } else {
+            out += char(c);
+        }
+    }
+    out += '"';
+}
+{{NODE_ENCODERS}}
+template <class T> static void cd_encode(string &out, const vector<T> &value);
+template <class T> static void cd_encode(string &out, const map<string, T> &value);
+
+template <class T> static void cd_encode(string &out, const vector<T> &value) {
+    out += '[';
+    bool first = true;
+    for (const auto &item : value) {
+        if (!first) {
+            out += ',';
+        }
+        first = false;
+        cd_encode(out, item);
+    }
+    out += ']';
+}
+
+template <class T> static void cd_encode(string &out, const map<string, T> &value) {
+    out += '{';
+    bool first = true;
+    for (const auto &entry : value) {
+        if (!first) {
+            out += ',';
+        }
+        first = false;
+        cd_encode(out, entry.first);
+        out += ':';
+        cd_encode(out, entry.second);
+    }
+    out += '}';
+}
+
+// decodes a test case's arguments and calls solution with them, leaving its answer in cd_result as JSON.
+// cd_start is set just before the call, so decoding doesn't count towards its runtime
+static bool cd_call(const CdJson &cd_args, string &cd_result, chrono::steady_clock::time_point &cd_start) {
+{{CALL}}
+}
+
+static string cd_base64(const string &s) {
+    if (s.empty()) {
+        return "-";
+    }
+    static const char *chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/";
+    string out;
+    for (size_t i = 0; i < s.size(); i += 3) {
+        unsigned n = (unsigned char)s[i] << 16;
+        if (i + 1 < s.size()) {
+            n |= (unsigned char)s[i + 1] << 8;
+        }
+        if (i + 2 < s.size()) {
+            n |= (unsigned char)s[i + 2];
+        }
+        out += chars[n >> 18 & 63];
+        out += chars[n >> 12 & 63];
+        out += i + 1 < s.size() ? chars[n >> 6 & 63] : '=';
+        out += i + 2 < s.size() ? chars[n & 63] : '=';
+    }
+    return out;
+}
+
+static void cd_write(int fd, const string &s) {
+    size_t done = 0;
+    while (done < s.size()) {
+        ssize_t n = write(fd, s.data() + done, s.size() - done);
+        if (n <= 0) {
+            return;
+        }
+        done += n;
+    }
+}
+
+static string cd_read_all(int fd) {
+    string data;
+    char buf[4096];
+    lseek(fd, 0, SEEK_SET);
+    for (ssize_t n; (n = read(fd, buf, sizeof buf)) > 0;) {
+        data.append(buf, n);
+    }
+    return data;
+}
+
+// crashes can't be caught like exceptions, so they're at least explained before the program dies
+static void cd_crash(int sig) {
+    const char *message = sig == SIGSEGV ? "segmentation fault (a bad pointer, or too much recursion)\n" : "arithmetic error (such as dividing by zero)\n";
+    cd_write(2, message);
+    signal(sig, SIG_DFL);
+    raise(sig);
+}
+
+int main() {
+    // the handler runs on a stack of its own, so it still works when the stack has overflowed
+    static char cd_signal_stack[1 << 16];
+    stack_t cd_stack{};
+    cd_stack.ss_sp = cd_signal_stack;
+    cd_stack.ss_size = sizeof cd_signal_stack;
+    sigaltstack(&cd_stack, nullptr);
+    struct sigaction cd_action{};
+    cd_action.sa_handler = cd_crash;
+    cd_action.sa_flags = SA_ONSTACK;
+    sigaction(SIGSEGV, &cd_action, nullptr);
+    sigaction(SIGFPE, &cd_action, nullptr);
+
+    int cd_stdout = dup(1);
+    int cd_output = open("cd_output", O_RDWR | O_CREAT | O_TRUNC, 0600);
+    if (cd_stdout < 0 || cd_output < 0) {
+        cerr << "harness: failed to capture output" << endl;
+        return 1;
+    }
+    string cd_nonce, cd_line;
+    getline(cin, cd_nonce);
+    for (int cd_case = 0; getline(cin, cd_line);) {
+        // each case is on a line of its own, between the lines with the brackets of the array around them
+        if (cd_line.size() < 2 || cd_line[0] != '[') {
+            continue;
+        }
+        string cd_result, cd_error;
+        bool cd_returned = false;
+        cout.flush();
+        fflush(stdout);
+        if (ftruncate(cd_output, 0) != 0 || lseek(cd_output, 0, SEEK_SET) != 0 || dup2(cd_output, 1) < 0) {
+            cd_error = "harness: failed to capture output";
+        }
+        auto cd_start = chrono::steady_clock::now();
+        try {
+            size_t cd_pos = 0;
+            if (cd_error.empty()) {
+                cd_returned = cd_call(cd_parse(cd_line, cd_pos), cd_result, cd_start);
+            }
+        } catch (const exception &e) {
+            cd_error = string("uncaught exception: ") + e.what();
+        } catch (...) {
+            cd_error = "uncaught exception";
+        }
+        long long cd_elapsed = chrono::duration_cast<chrono::nanoseconds>(chrono::steady_clock::now() - cd_start).count();
+        cout.flush();
+        fflush(stdout);
+        dup2(cd_stdout, 1);
+        string cd_printed = cd_read_all(cd_output);
+        const char *cd_status = cd_error.empty() ? "ok" : "error";
+        cd_write(cd_stdout, "{{CASE_MARKER}} " + cd_nonce + " " + to_string(cd_case) + " " + cd_status + " " + to_string(cd_elapsed) + " " +
+            cd_base64(cd_printed) + " " + cd_base64(cd_error) + " " + cd_base64(cd_returned ? cd_result : "") + "\n");
+        cd_case++;
+    }
+}
+`
+
+var cppListNodeCoders = `
+static void cd_decode(const CdJson &j, ListNode *&out) {
+    out = nullptr;
+    for (size_t i = j.items.size(); i-- > 0;) {
+        {{ELEM}} value{};
+        cd_decode(j.items[i], value);
+        out = new ListNode(value, out);
+    }
+}
+`
+
+var cppTreeNodeCoders = `
+static void cd_decode(const CdJson &j, TreeNode *&out) {
+    vector<TreeNode *> nodes;
+    for (const CdJson &item : j.items) {
+        TreeNode *node = nullptr;
+        if (item.kind != CdJson::Null) {
+            {{ELEM}} value{};
+            cd_decode(item, value);
+            node = new TreeNode(value);
+        }
+        nodes.push_back(node);
+    }
+    out = nodes.empty() ? nullptr : nodes[0];
+    if (out == nullptr) {
+        return;
+    }
+    vector<TreeNode *> parents{out};
+    size_t next = 1;
+    for (size_t i = 0; i < parents.size() && next < nodes.size(); i++) {
+        parents[i]->left = nodes[next++];
+        if (next < nodes.size()) {
+            parents[i]->right = nodes[next++];
+        }
+        for (TreeNode *child : {parents[i]->left, parents[i]->right}) {
+            if (child != nullptr) {
+                parents.push_back(child);
+            }
+        }
+    }
+}
+`
+
+var cppListNodeEncoder = `
+static void cd_encode(string &out, ListNode *head) {
+    out += '[';
+    for (ListNode *node = head; node != nullptr; node = node->next) {
+        if (node != head) {
+            out += ',';
+        }
+        cd_encode(out, node->val);
+    }
+    out += ']';
+}
+`
+
+var cppTreeNodeEncoder = `
+static void cd_encode(string &out, TreeNode *root) {
+    vector<string> values;
+    vector<TreeNode *> level{root};
+    for (size_t i = 0; i < level.size(); i++) {
+        if (level[i] == nullptr) {
+            values.push_back("null");
+            continue;
+        }
+        string value;
+        cd_encode(value, level[i]->val);
+        values.push_back(value);
+        level.push_back(level[i]->left);
+        level.push_back(level[i]->right);
+    }
+    while (!values.empty() && values.back() == "null") {
+        values.pop_back();
+    }
+    out += '[';
+    for (size_t i = 0; i < values.size(); i++) {
+        if (i > 0) {
+            out += ',';
+        }
+        out += values[i];
+    }
+    out += ']';
+}
+`
+
+func cppHarness(code string, signature models.Signature) string {
+	var nodes, decoders, encoders strings.Builder
+	if elem := nodeElem(signature, models.KindLinkedList); elem != nil {
+		replacer := strings.NewReplacer("{{ELEM}}", cppType(*elem))
+		nodes.WriteString(replacer.Replace(cppListNode))
+		decoders.WriteString(replacer.Replace(cppListNodeCoders))
+		encoders.WriteString(cppListNodeEncoder)
+	}
+	if elem := nodeElem(signature, models.KindTree); elem != nil {
+		replacer := strings.NewReplacer("{{ELEM}}", cppType(*elem))
+		nodes.WriteString(replacer.Replace(cppTreeNode))
+		decoders.WriteString(replacer.Replace(cppTreeNodeCoders))
+		encoders.WriteString(cppTreeNodeEncoder)
+	}
+	var program strings.Builder
+	program.WriteString(strings.ReplaceAll(cppHarnessPrelude, "{{NODES}}", nodes.String()))
+	program.WriteString(code)
+	program.WriteString("\n")
+	program.WriteString(strings.NewReplacer(
+		"{{NODE_DECODERS}}", decoders.String(),
+		"{{NODE_ENCODERS}}", encoders.String(),
+		"{{CALL}}", cppHarnessCall(signature),
+		"{{CASE_MARKER}}", HarnessCaseMarker,
+	).Replace(cppHarnessTemplate))
+	return program.String()
+}
+
+// writes the body of cd_call for a solution with the given signature
+func cppHarnessCall(signature models.Signature) string {
+	var call strings.Builder
+	fmt.Fprintf(&call, "    if (cd_args.items.size() != %d) {\n", len(signature.Params))
+	fmt.Fprintf(&call, "        throw runtime_error(\"harness: %s takes %d arguments, but the test case has \" + to_string(cd_args.items.size()));\n    }\n", signature.Name, len(signature.Params))
+	args := make([]string, len(signature.Params))
+	for i, param := range signature.Params {
+		args[i] = fmt.Sprintf("cd_arg%d", i)
+		fmt.Fprintf(&call, "    %s %s{};\n    cd_decode(cd_args.items[%d], %s);\n", cppType(param.Type), args[i], i, args[i])
+	}
+	call.WriteString("    cd_start = chrono::steady_clock::now();\n")
+	solution := fmt.Sprintf("%s(%s)", signature.Name, strings.Join(args, ", "))
+	if signature.Returns.Kind == models.KindVoid {
+		fmt.Fprintf(&call, "    %s;\n    return false;", solution)
+	} else {
+		// the answer is converted to the declared type, so the right encoder is used whatever solution returns
+		fmt.Fprintf(&call, "    %s cd_value = %s;\n    cd_encode(cd_result, cd_value);\n    return true;", cppType(signature.Returns), solution)
+	}
+	return call.String()
+}
@@ -0,0 +1,274 @@
+package languages
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// imports the go harness needs. they're aliased so they can't clash with the player's own imports;
+// go allows the same package to be imported more than once under different names.
+var goHarnessImports = `
+import (
+	cdBufio "bufio"
+	cdBase64 "encoding/base64"
+	cdJson "encoding/json"
+	cdFmt "fmt"
+	cdOs "os"
+	cdDebug "runtime/debug"
+	cdStrings "strings"
+	cdTime "time"
+)
+`
+
+// the go harness adds a main function that decodes each test case's arguments into the types the signature
+// gives for them, and calls solution. os.Stdout is swapped for a temp file during each call so the output can be
+// captured.
+var goHarnessTemplate = `
+func main() {
+	cdStdout := cdOs.Stdout
+	cdStdin := cdBufio.NewReader(cdOs.Stdin)
+	cdNonce, _ := cdStdin.ReadString('\n')
+	cdNonce = cdStrings.TrimSpace(cdNonce)
+	var cdCases [][]cdJson.RawMessage
+	if err := cdJson.NewDecoder(cdStdin).Decode(&cdCases); err != nil {
+		cdFmt.Fprintln(cdOs.Stderr, "harness: failed to read test cases:", err)
+		cdOs.Exit(1)
+	}
+	for cdCase, cdArgs := range cdCases {
+		cdOutput, cdResult, cdErr, cdElapsed := cdHarnessRunCase(cdArgs)
+		cdStatus := "ok"
+		if cdErr != "" {
+			cdStatus = "error"
+		}
+		cdFmt.Fprintf(cdStdout, "\n{{CASE_MARKER}} %s %d %s %d %s %s %s\n", cdNonce, cdCase, cdStatus, cdElapsed.Nanoseconds(), cdHarnessEncode(cdOutput), cdHarnessEncode(cdErr), cdHarnessEncode(cdResult))
+	}
+}
+
+// decodes the arguments for solution, and gives a function that calls it with them
+func cdHarnessPrepare(cdArgs []cdJson.RawMessage) (func() (any, bool), error) {
+{{PREPARE}}
+}
+
+{{NODES}}
+func cdHarnessRunCase(cdArgs []cdJson.RawMessage) (cdOutput string, cdResult string, cdErr string, cdElapsed cdTime.Duration) {
+	cdCall, err := cdHarnessPrepare(cdArgs)
+	if err != nil {
+		return "", "", "harness: " + err.Error(), 0
+	}
+	cdStdout := cdOs.Stdout
+	cdFile, err := cdOs.CreateTemp("", "case-output")
+	if err != nil {
+		return "", "", "harness: failed to capture output: " + err.Error(), 0
+	}
+	cdOs.Stdout = cdFile
+	defer func() {
+		cdOs.Stdout = cdStdout
+		cdFile.Close()
+		cdData, _ := cdOs.ReadFile(cdFile.Name())
+		cdOs.Remove(cdFile.Name())
+		cdOutput = string(cdData)
+	}()
+	cdStart := cdTime.Now()
+	defer func() {
+		cdElapsed = cdTime.Since(cdStart)
+		if r := recover(); r != nil {
+			cdErr = cdFmt.Sprintf("panic: %v\n\n%s", r, cdDebug.Stack())
+		}
+	}()
+	cdValue, cdReturned := cdCall()
+	if cdReturned {
+		cdJSON, err := cdJson.Marshal(cdValue)
+		if err != nil {
+			cdErr = "harness: failed to encode return value: " + err.Error()
+			return
+		}
+		cdResult = string(cdJSON)
+	}
+	return
+}
+
+func cdHarnessEncode(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return cdBase64.StdEncoding.EncodeToString([]byte(s))
+}
+`
+
+// declares the node types for linked lists and trees, and the functions that convert them to and from lists
+var goListNodeTemplate = `
+type ListNode struct {
+	Val  {{ELEM}}
+	Next *ListNode
+}
+
+func cdHarnessLinkedList(cdValues []{{ELEM}}) *ListNode {
+	var cdHead *ListNode
+	for i := len(cdValues) - 1; i >= 0; i-- {
+		cdHead = &ListNode{Val: cdValues[i], Next: cdHead}
+	}
+	return cdHead
+}
+
+func cdHarnessLinkedListValues(cdHead *ListNode) []{{ELEM}} {
+	cdValues := []{{ELEM}}{}
+	for ; cdHead != nil; cdHead = cdHead.Next {
+		cdValues = append(cdValues, cdHead.Val)
+	}
+	return cdValues
+}
+`
+
+var goTreeNodeTemplate = `
+type TreeNode struct {
+	Val   {{ELEM}}
+	Left  *TreeNode
+	Right *TreeNode
+}
+
+func cdHarnessTree(cdValues []*{{ELEM}}) *TreeNode {
+	if len(cdValues) == 0 || cdValues[0] == nil {
+		return nil
+	}
+	cdNodes := make([]*TreeNode, len(cdValues))
+	for i, cdValue := range cdValues {
+		if cdValue != nil {
+			cdNodes[i] = &TreeNode{Val: *cdValue}
+		}
+	}
+	cdParents, cdNext := []*TreeNode{cdNodes[0]}, 1
+	for i := 0; i < len(cdParents) && cdNext < len(cdNodes); i++ {
+		cdParents[i].Left = cdNodes[cdNext]
+		cdNext++
+		if cdNext < len(cdNodes) {
+			cdParents[i].Right = cdNodes[cdNext]
+			cdNext++
+		}
+		for _, cdChild := range []*TreeNode{cdParents[i].Left, cdParents[i].Right} {
+			if cdChild != nil {
+				cdParents = append(cdParents, cdChild)
+			}
+		}
+	}
+	return cdNodes[0]
+}
+
+func cdHarnessTreeValues(cdRoot *TreeNode) []*{{ELEM}} {
+	cdValues := []*{{ELEM}}{}
+	for cdLevel := []*TreeNode{cdRoot}; len(cdLevel) > 0; cdLevel = cdLevel[1:] {
+		if cdLevel[0] == nil {
+			cdValues = append(cdValues, nil)
+			continue
+		}
+		cdValue := cdLevel[0].Val
+		cdValues = append(cdValues, &cdValue)
+		cdLevel = append(cdLevel, cdLevel[0].Left, cdLevel[0].Right)
+	}
+	for len(cdValues) > 0 && cdValues[len(cdValues)-1] == nil {
+		cdValues = cdValues[:len(cdValues)-1]
+	}
+	return cdValues
+}
+`
+
+func goHarness(code string, signature models.Signature) string {
+	// find the end of the player's imports, so the harness's imports can be spliced in after them.
+	// if the code doesn't parse, it won't compile either, so it's run as-is to get the compile error
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", code, parser.ImportsOnly)
+	if err != nil {
+		return code
+	}
+	importsEnd := fset.Position(file.Name.End()).Offset
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			importsEnd = fset.Position(gen.End()).Offset
+		}
+	}
+
+	var nodes strings.Builder
+	if elem := nodeElem(signature, models.KindLinkedList); elem != nil {
+		nodes.WriteString(strings.ReplaceAll(goListNodeTemplate, "{{ELEM}}", goType(*elem)))
+	}
+	if elem := nodeElem(signature, models.KindTree); elem != nil {
+		nodes.WriteString(strings.ReplaceAll(goTreeNodeTemplate, "{{ELEM}}", goType(*elem)))
+	}
+	harness := strings.NewReplacer(
+		"{{PREPARE}}", goHarnessPrepare(signature),
+		"{{NODES}}", nodes.String(),
+		"{{CASE_MARKER}}", HarnessCaseMarker,
+	).Replace(goHarnessTemplate)
+
+	var program strings.Builder
+	program.WriteString(code[:importsEnd])
+	program.WriteString("\n")
+	program.WriteString(goHarnessImports)
+	program.WriteString(code[importsEnd:])
+	program.WriteString("\n")
+	program.WriteString(harness)
+	return program.String()
+}
+
+// writes the body of cdHarnessPrepare for a solution with the given signature
+func goHarnessPrepare(signature models.Signature) string {
+	var prepare strings.Builder
+	fmt.Fprintf(&prepare, "\tif len(cdArgs) != %d {\n", len(signature.Params))
+	fmt.Fprintf(&prepare, "\t\treturn nil, cdFmt.Errorf(\"%s takes %d arguments, but the test case has %%d\", len(cdArgs))\n\t}\n", signature.Name, len(signature.Params))
+	args := make([]string, len(signature.Params))
+	for i, param := range signature.Params {
+		args[i] = fmt.Sprintf("cdArg%d", i)
+		fmt.Fprintf(&prepare, "\t%s, err := %s(cdArgs[%d])\n", args[i], goDecoder(param.Type), i)
+		fmt.Fprintf(&prepare, "\tif err != nil {\n\t\treturn nil, cdFmt.Errorf(\"failed to read argument %s: %%w\", err)\n\t}\n", param.Name)
+	}
+	call := fmt.Sprintf("%s(%s)", signature.Name, strings.Join(args, ", "))
+	if signature.Returns.Kind == models.KindVoid {
+		fmt.Fprintf(&prepare, "\treturn func() (any, bool) {\n\t\t%s\n\t\treturn nil, false\n\t}, nil", call)
+	} else {
+		fmt.Fprintf(&prepare, "\treturn func() (any, bool) {\n\t\treturn %s(%s), true\n\t}, nil", goEncoder(signature.Returns), call)
+	}
+	return prepare.String()
+}
+
+// a go function literal that decodes a JSON value into a value of the given type
+func goDecoder(t models.Type) string {
+	goT := goType(t)
+	switch {
+	case t.Kind == models.KindLinkedList:
+		return fmt.Sprintf("func(cdRaw cdJson.RawMessage) (*ListNode, error) {\n\t\tvar cdValues []%s\n\t\terr := cdJson.Unmarshal(cdRaw, &cdValues)\n\t\treturn cdHarnessLinkedList(cdValues), err\n\t}", goType(*t.Elem))
+	case t.Kind == models.KindTree:
+		return fmt.Sprintf("func(cdRaw cdJson.RawMessage) (*TreeNode, error) {\n\t\tvar cdValues []*%s\n\t\terr := cdJson.Unmarshal(cdRaw, &cdValues)\n\t\treturn cdHarnessTree(cdValues), err\n\t}", goType(*t.Elem))
+	case t.Kind == models.KindList && hasNodes(t):
+		return fmt.Sprintf("func(cdRaw cdJson.RawMessage) (%[1]s, error) {\n\t\tvar cdItems []cdJson.RawMessage\n\t\tif err := cdJson.Unmarshal(cdRaw, &cdItems); err != nil {\n\t\t\treturn nil, err\n\t\t}\n\t\tcdValue := make(%[1]s, len(cdItems))\n\t\tfor i, cdItem := range cdItems {\n\t\t\tvar err error\n\t\t\tif cdValue[i], err = %[2]s(cdItem); err != nil {\n\t\t\t\treturn nil, err\n\t\t\t}\n\t\t}\n\t\treturn cdValue, nil\n\t}", goT, goDecoder(*t.Elem))
+	case t.Kind == models.KindMap && hasNodes(t):
+		return fmt.Sprintf("func(cdRaw cdJson.RawMessage) (%[1]s, error) {\n\t\tvar cdItems map[string]cdJson.RawMessage\n\t\tif err := cdJson.Unmarshal(cdRaw, &cdItems); err != nil {\n\t\t\treturn nil, err\n\t\t}\n\t\tcdValue := make(%[1]s, len(cdItems))\n\t\tfor cdKey, cdItem := range cdItems {\n\t\t\tvar err error\n\t\t\tif cdValue[cdKey], err = %[2]s(cdItem); err != nil {\n\t\t\t\treturn nil, err\n\t\t\t}\n\t\t}\n\t\treturn cdValue, nil\n\t}", goT, goDecoder(*t.Elem))
+	default:
+		return fmt.Sprintf("func(cdRaw cdJson.RawMessage) (%[1]s, error) {\n\t\tvar cdValue %[1]s\n\t\terr := cdJson.Unmarshal(cdRaw, &cdValue)\n\t\treturn cdValue, err\n\t}", goT)
+	}
+}
+
+// a go function literal that turns a value of the given type into a value that encodes to the right JSON
+func goEncoder(t models.Type) string {
+	goT := goType(t)
+	switch {
+	case t.Kind == models.KindLinkedList:
+		return "cdHarnessLinkedListValues"
+	case t.Kind == models.KindTree:
+		return "cdHarnessTreeValues"
+	case t.Kind == models.KindList && hasNodes(t):
+		return fmt.Sprintf("func(cdValue %s) any {\n\t\t\tcdItems := make([]any, len(cdValue))\n\t\t\tfor i, cdItem := range cdValue {\n\t\t\t\tcdItems[i] = %s(cdItem)\n\t\t\t}\n\t\t\treturn cdItems\n\t\t}", goT, goEncoder(*t.Elem))
+	case t.Kind == models.KindMap && hasNodes(t):
+		return fmt.Sprintf("func(cdValue %s) any {\n\t\t\tcdItems := make(map[string]any, len(cdValue))\n\t\t\tfor cdKey, cdItem := range cdValue {\n\t\t\t\tcdItems[cdKey] = %s(cdItem)\n\t\t\t}\n\t\t\treturn cdItems\n\t\t}", goT, goEncoder(*t.Elem))
+	default:
+		return fmt.Sprintf("func(cdValue %s) any {\n\t\t\treturn cdValue\n\t\t}", goT)
+	}
+}
+
+// whether values of the type contain linked lists or trees
+func hasNodes(t models.Type) bool {
+	return t.Uses(models.KindLinkedList) || t.Uses(models.KindTree)
+}
@@ -0,0 +1,454 @@
+package languages
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// the java harness goes after the player's code, which has a Solution class with solution as a method, as on
+// leetcode. it adds the node classes and a Main class that reads each test case with a small JSON reader, decodes
+// its arguments by their types in the signature (see CdType), and calls solution on a new Solution. System.out
+// is swapped for a buffer during each call to capture what solution prints. the harness only uses fully
+// qualified names, so it works whatever the player imports, and never names the types solution takes: cdCast
+// leaves java to infer them from solution's parameters.
+var javaHarnessTemplate = `
+{{NODES}}
+public class Main {
+    // JSON values are read as Longs, Doubles, Strings, Booleans, nulls, Lists and Maps
+    static final class CdParser {
+        final String s;
+        int pos;
+
+        CdParser(String s) {
+            this.s = s;
+        }
+
+        void skip(String chars) {
+            while (pos < s.length() && (Character.isWhitespace(s.charAt(pos)) || chars.indexOf(s.charAt(pos)) >= 0)) {
+                pos++;
+            }
+        }
+
+        Object value() {
+            skip("");
+            if (pos >= s.length()) {
+                throw new IllegalArgumentException("unexpected end of test case");
+            }
+            char c = s.charAt(pos);
+            if (c == '[' || c == '{') {
+                java.util.List<Object> items = new java.util.ArrayList<>();
+                java.util.Map<String, Object> fields = new java.util.LinkedHashMap<>();
+                char close = c == '[' ? ']' : '}';
+                pos++;
+                while (true) {
+                    skip(",");
+                    if (pos >= s.length()) {
+                        throw new IllegalArgumentException("unexpected end of test case");
+                    }
+                    if (s.charAt(pos) == close) {
+                        pos++;
+                        return c == '[' ? items : fields;
+                    }
+                    if (c == '[') {
+                        items.add(value());
+                    } else {
+                        String key = string();
+                        skip(":");
+                        fields.put(key, value());
+                    }
+                }
+            }
+            if (c == '"') {
+                return string();
+            }
+            if (c == 't' || c == 'f') {
+                pos += c == 't' ? 4 : 5;
+                return c == 't';
+            }
+            if (c == 'n') {
+                pos += 4;
+                return null;
+            }
+            int start = pos;
+            while (pos < s.length() && "+-.0123456789eE".indexOf(s.charAt(pos)) >= 0) {
+                pos++;
+            }
+            if (pos == start) {
+                throw new IllegalArgumentException("unexpected character in test case: " + c);
+            }
+            String number = s.substring(start, pos);
+            try {
+                return Long.parseLong(number);
+            } catch (NumberFormatException e) {
+                return Double.parseDouble(number);
+            }
+        }
+
+        String string() {
+            StringBuilder value = new StringBuilder();
+            pos++;
+            while (pos < s.length() && s.charAt(pos) != '"') {
+                char c = s.charAt(pos++);
+                if (c != '\\') {
+                    value.append(c);
+                    continue;
+                }
+                c = s.charAt(pos++);
+                switch (c) {
+                case 'n': value.append('\n'); break;
+                case 't': value.append('\t'); break;
+                case 'r': value.append('\r'); break;
+                case 'b': value.append('\b'); break;
+                case 'f': value.append('\f'); break;
+                case 'u':
+                    value.append((char) Integer.parseInt(s.substring(pos, pos + 4), 16));
+                    pos += 4;
+                    break;
+                default: value.append(c);
+                }
+            }
+            pos++;
+            return value.toString();
+        }
+    }
+
+    // one of the signature's types, which knows how to turn JSON values into java values of the type and back
+    static final class CdType {
+        final String kind;
+        final CdType elem;
+
+        CdType(String kind, CdType elem) {
+            this.kind = kind;
+            this.elem = elem;
+        }
+
+        // the class values of this type have, for making arrays of them
+        Class<?> javaClass() {
+            switch (kind) {
+            case "int": return long.class;
+            case "float": return double.class;
+            case "string": return String.class;
+            case "bool": return boolean.class;
+            case "list": return java.lang.reflect.Array.newInstance(elem.javaClass(), 0).getClass();
+{{NODE_CLASSES}}
+            default: return java.util.Map.class;
+            }
+        }
+
+        @SuppressWarnings("unchecked")
+        Object decode(Object json) {
+            switch (kind) {
+            case "int": return ((Number) json).longValue();
+            case "float": return ((Number) json).doubleValue();
+            case "list": {
+                java.util.List<Object> items = (java.util.List<Object>) json;
+                Object array = java.lang.reflect.Array.newInstance(elem.javaClass(), items.size());
+                for (int i = 0; i < items.size(); i++) {
+                    java.lang.reflect.Array.set(array, i, elem.decode(items.get(i)));
+                }
+                return array;
+            }
+            case "map": {
+                java.util.Map<String, Object> map = new java.util.HashMap<>();
+                for (java.util.Map.Entry<String, Object> entry : ((java.util.Map<String, Object>) json).entrySet()) {
+                    map.put(entry.getKey(), elem.decode(entry.getValue()));
+                }
+                return map;
+            }
+{{NODE_DECODERS}}
+            default: return json;
+            }
+        }
+
+        // lists can be arrays or any Iterable
+        void encode(StringBuilder out, Object value) {
+            switch (kind) {
+            case "string":
+                cdQuote(out, String.valueOf(value));
+                break;
+            case "list": {
+                out.append('[');
+                if (value.getClass().isArray()) {
+                    for (int i = 0; i < java.lang.reflect.Array.getLength(value); i++) {
+                        if (i > 0) {
+                            out.append(',');
+                        }
+                        elem.encode(out, java.lang.reflect.Array.get(value, i));
+                    }
+                } else {
+                    boolean first = true;
+                    for (Object item : (Iterable<?>) value) {
+                        if (!first) {
+                            out.append(',');
+                        }
+                        first = false;
+                        elem.encode(out, item);
+                    }
+                }
+                out.append(']');
+                break;
+            }
+            case "map": {
+                out.append('{');
+                boolean first = true;
+                for (java.util.Map.Entry<?, ?> entry : ((java.util.Map<?, ?>) value).entrySet()) {
+                    if (!first) {
+                        out.append(',');
+                    }
+                    first = false;
+                    cdQuote(out, String.valueOf(entry.getKey()));
+                    out.append(':');
+                    elem.encode(out, entry.getValue());
+                }
+                out.append('}');
+                break;
+            }
+{{NODE_ENCODERS}}
+            default:
+                if (value instanceof Double && !Double.isFinite((Double) value)) {
+                    out.append("null");
+                } else {
+                    out.append(value);
+                }
+            }
+        }
+    }
+
+    static void cdQuote(StringBuilder out, String s) {
+        out.append('"');
+        for (int i = 0; i < s.length(); i++) {
+            char c = s.charAt(i);
+            if (c == '"' || c == '\\') {
+                out.append('\\').append(c);
+            } else if (c < 0x20) {
+                out.append(String.format("\\u%04x", (int) c));
+            } else {
+                out.append(c);
+            }
+        }
+        out.append('"');
+    }
+
+    @SuppressWarnings("unchecked")
+    static <T> T cdCast(Object value) {
+        return (T) value;
+    }
+
+    // decodes a test case's arguments and calls solution with them, giving back its answer as JSON, or null if it
+    // doesn't return one. cdStart is set just before the call, so decoding doesn't count towards its runtime
+    static String cdCall(Object cdJson, CdType[] cdParams, CdType cdReturns, long[] cdStart) {
+{{CALL}}
+    }
+
+    static String cdBase64(String s) throws Exception {
+        if (s == null || s.isEmpty()) {
+            return "-";
+        }
+        return java.util.Base64.getEncoder().encodeToString(s.getBytes("UTF-8"));
+    }
+
+    public static void main(String[] args) throws Exception {
+        CdType[] cdParams = {{{PARAM_TYPES}}};
+        CdType cdReturns = {{RETURN_TYPE}};
+        java.io.PrintStream cdStdout = System.out;
+        java.io.BufferedReader cdReader = new java.io.BufferedReader(new java.io.InputStreamReader(System.in, "UTF-8"));
+        String cdNonce = cdReader.readLine();
+        int cdCase = 0;
+        for (String cdLine; (cdLine = cdReader.readLine()) != null;) {
+            // each case is on a line of its own, between the lines with the brackets of the array around them
+            if (cdLine.length() < 2 || cdLine.charAt(0) != '[') {
+                continue;
+            }
+            java.io.ByteArrayOutputStream cdOutput = new java.io.ByteArrayOutputStream();
+            System.setOut(new java.io.PrintStream(cdOutput, true, "UTF-8"));
+            String cdResult = null;
+            String cdError = "";
+            long[] cdStart = {System.nanoTime()};
+            try {
+                cdResult = cdCall(new CdParser(cdLine).value(), cdParams, cdReturns, cdStart);
+            } catch (Throwable e) {
+                java.io.StringWriter trace = new java.io.StringWriter();
+                e.printStackTrace(new java.io.PrintWriter(trace));
+                cdError = trace.toString();
+            }
+            long cdElapsed = System.nanoTime() - cdStart[0];
+            System.out.flush();
+            System.setOut(cdStdout);
+            String cdStatus = cdError.isEmpty() ? "ok" : "error";
+            cdStdout.print("{{CASE_MARKER}} " + cdNonce + " " + cdCase + " " + cdStatus + " " + cdElapsed + " " +
+                cdBase64(cdOutput.toString("UTF-8")) + " " + cdBase64(cdError) + " " + cdBase64(cdResult) + "\n");
+            cdStdout.flush();
+            cdCase++;
+        }
+    }
+}
+`
+
+var javaListNode = `
+class ListNode {
+    {{ELEM}} val;
+    ListNode next;
+    ListNode() {}
+    ListNode({{ELEM}} val) { this.val = val; }
+    ListNode({{ELEM}} val, ListNode next) { this.val = val; this.next = next; }
+}
+`
+
+var javaTreeNode = `
+class TreeNode {
+    {{ELEM}} val;
+    TreeNode left;
+    TreeNode right;
+    TreeNode() {}
+    TreeNode({{ELEM}} val) { this.val = val; }
+    TreeNode({{ELEM}} val, TreeNode left, TreeNode right) { this.val = val; this.left = left; this.right = right; }
+}
+`
+
+var javaListNodeDecoder = `            case "linkedList": {
+                java.util.List<Object> items = (java.util.List<Object>) json;
+                ListNode head = null;
+                for (int i = items.size() - 1; i >= 0; i--) {
+                    head = new ListNode(({{ELEM}}) elem.decode(items.get(i)), head);
+                }
+                return head;
+            }
+`
+
+var javaTreeNodeDecoder = `            case "tree": {
+                java.util.List<TreeNode> nodes = new java.util.ArrayList<>();
+                for (Object item : (java.util.List<Object>) json) {
+                    nodes.add(item == null ? null : new TreeNode(({{ELEM}}) elem.decode(item)));
+                }
+                if (nodes.isEmpty() || nodes.get(0) == null) {
+                    return null;
+                }
+                java.util.List<TreeNode> parents = new java.util.ArrayList<>();
+                parents.add(nodes.get(0));
+                int next = 1;
+                for (int i = 0; i < parents.size() && next < nodes.size(); i++) {
+                    TreeNode parent = parents.get(i);
+                    parent.left = nodes.get(next++);
+                    if (next < nodes.size()) {
+                        parent.right = nodes.get(next++);
+                    }
+                    if (parent.left != null) {
+                        parents.add(parent.left);
+                    }
+                    if (parent.right != null) {
+                        parents.add(parent.right);
+                    }
+                }
+                return nodes.get(0);
+            }
+`
+
+var javaListNodeEncoder = `            case "linkedList": {
+                out.append('[');
+                for (ListNode node = (ListNode) value; node != null; node = node.next) {
+                    if (node != value) {
+                        out.append(',');
+                    }
+                    elem.encode(out, node.val);
+                }
+                out.append(']');
+                break;
+            }
+`
+
+var javaTreeNodeEncoder = `            case "tree": {
+                java.util.List<String> values = new java.util.ArrayList<>();
+                java.util.List<TreeNode> level = new java.util.ArrayList<>();
+                level.add((TreeNode) value);
+                for (int i = 0; i < level.size(); i++) {
+                    TreeNode node = level.get(i);
+                    if (node == null) {
+                        values.add("null");
+                        continue;
+                    }
+                    StringBuilder nodeValue = new StringBuilder();
+                    elem.encode(nodeValue, node.val);
+                    values.add(nodeValue.toString());
+                    level.add(node.left);
+                    level.add(node.right);
+                }
+                while (!values.isEmpty() && values.get(values.size() - 1).equals("null")) {
+                    values.remove(values.size() - 1);
+                }
+                out.append('[').append(String.join(",", values)).append(']');
+                break;
+            }
+`
+
+// java only allows a public class in a file named after it, and the code is saved as Main.java
+var javaPublicSolution = regexp.MustCompile(`\bpublic\s+(final\s+)?class\s+Solution\b`)
+
+func javaHarness(code string, signature models.Signature) string {
+	var nodes, classes, decoders, encoders strings.Builder
+	if elem := nodeElem(signature, models.KindLinkedList); elem != nil {
+		replacer := strings.NewReplacer("{{ELEM}}", javaType(*elem))
+		nodes.WriteString(replacer.Replace(javaListNode))
+		classes.WriteString("            case \"linkedList\": return ListNode.class;\n")
+		decoders.WriteString(replacer.Replace(javaListNodeDecoder))
+		encoders.WriteString(javaListNodeEncoder)
+	}
+	if elem := nodeElem(signature, models.KindTree); elem != nil {
+		replacer := strings.NewReplacer("{{ELEM}}", javaType(*elem))
+		nodes.WriteString(replacer.Replace(javaTreeNode))
+		classes.WriteString("            case \"tree\": return TreeNode.class;\n")
+		decoders.WriteString(replacer.Replace(javaTreeNodeDecoder))
+		encoders.WriteString(javaTreeNodeEncoder)
+	}
+	paramTypes := make([]string, len(signature.Params))
+	for i, param := range signature.Params {
+		paramTypes[i] = javaTypeExpr(param.Type)
+	}
+	var program strings.Builder
+	program.WriteString(javaPublicSolution.ReplaceAllString(code, "${1}class Solution"))
+	program.WriteString("\n")
+	program.WriteString(strings.NewReplacer(
+		"{{NODES}}", nodes.String(),
+		"{{NODE_CLASSES}}", strings.TrimSuffix(classes.String(), "\n"),
+		"{{NODE_DECODERS}}", strings.TrimSuffix(decoders.String(), "\n"),
+		"{{NODE_ENCODERS}}", strings.TrimSuffix(encoders.String(), "\n"),
+		"{{CALL}}", javaHarnessCall(signature),
+		"{{PARAM_TYPES}}", strings.Join(paramTypes, ", "),
+		"{{RETURN_TYPE}}", javaTypeExpr(signature.Returns),
+		"{{CASE_MARKER}}", HarnessCaseMarker,
+	).Replace(javaHarnessTemplate))
+	return program.String()
+}
+
+// a java expression for the harness's description of a type
+func javaTypeExpr(t models.Type) string {
+	elem := "null"
+	if t.Elem != nil {
+		elem = javaTypeExpr(*t.Elem)
+	}
+	return fmt.Sprintf("new CdType(%q, %s)", t.Kind, elem)
+}
+
+// writes the body of cdCall for a solution with the given signature
+func javaHarnessCall(signature models.Signature) string {
+	var call strings.Builder
+	call.WriteString("        java.util.List<?> cdArgs = (java.util.List<?>) cdJson;\n")
+	fmt.Fprintf(&call, "        if (cdArgs.size() != %d) {\n", len(signature.Params))
+	fmt.Fprintf(&call, "            throw new IllegalArgumentException(\"harness: %s takes %d arguments, but the test case has \" + cdArgs.size());\n        }\n", signature.Name, len(signature.Params))
+	args := make([]string, len(signature.Params))
+	for i := range signature.Params {
+		args[i] = fmt.Sprintf("cdCast(cdArg%d)", i)
+		fmt.Fprintf(&call, "        Object cdArg%d = cdParams[%d].decode(cdArgs.get(%d));\n", i, i, i)
+	}
+	call.WriteString("        Solution cdSolution = new Solution();\n")
+	call.WriteString("        cdStart[0] = System.nanoTime();\n")
+	solution := fmt.Sprintf("cdSolution.%s(%s)", signature.Name, strings.Join(args, ", "))
+	if signature.Returns.Kind == models.KindVoid {
+		fmt.Fprintf(&call, "        %s;\n        return null;", solution)
+	} else {
+		fmt.Fprintf(&call, "        Object cdValue = %s;\n        StringBuilder cdResult = new StringBuilder();\n        cdReturns.encode(cdResult, cdValue);\n        return cdResult.toString();", solution)
+	}
+	return call.String()
+}
@@ -0,0 +1,311 @@
+package languages
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// the javascript and typescript harnesses share everything but how they get hold of solution. javascript code is
+// compiled with the vm module, so syntax errors can be reported as compile errors, and run to define solution.
+// typescript code is compiled by tsc along with the harness, which calls solution directly.
+//
+// arguments are decoded into the types the signature gives for them, as in the python harness, and
+// process.stdout.write is swapped out during each call to capture what solution prints. the body is written to
+// type check as typescript too, which is why nothing in it is annotated.
+var javascriptHarnessBody = `
+const cdFs = require("fs");
+const cdParamTypes = {{PARAM_TYPES}};
+const cdReturnType = {{RETURN_TYPE}};
+const cdWrite = process.stdout.write.bind(process.stdout);
+
+// turns a JSON value into a value of the given type
+function cdDecode(t, value) {
+    switch (t.kind) {
+    case "list":
+        return value.map((v) => cdDecode(t.elem, v));
+    case "map":
+        return Object.fromEntries(Object.entries(value).map(([k, v]) => [k, cdDecode(t.elem, v)]));
+    case "linkedList": {
+        let head = null;
+        for (let i = value.length - 1; i >= 0; i--) {
+            head = new cdListNode(cdDecode(t.elem, value[i]), head);
+        }
+        return head;
+    }
+    case "tree": {
+        const nodes = value.map((v) => (v === null ? null : new cdTreeNode(cdDecode(t.elem, v))));
+        if (nodes.length === 0 || nodes[0] === null) {
+            return null;
+        }
+        const parents = [nodes[0]];
+        let next = 1;
+        for (let i = 0; i < parents.length && next < nodes.length; i++) {
+            parents[i].left = nodes[next++];
+            if (next < nodes.length) {
+                parents[i].right = nodes[next++];
+            }
+            for (const child of [parents[i].left, parents[i].right]) {
+                if (child !== null) {
+                    parents.push(child);
+                }
+            }
+        }
+        return nodes[0];
+    }
+    default:
+        return value;
+    }
+}
+
+// turns a value of the given type back into a JSON value. lists can be any iterable, and maps can be Maps
+function cdEncodeValue(t, value) {
+    switch (t.kind) {
+    case "list":
+        return Array.from(value, (v) => cdEncodeValue(t.elem, v));
+    case "map": {
+        const entries = value instanceof Map ? Array.from(value.entries()) : Object.entries(value);
+        return Object.fromEntries(entries.map(([k, v]) => [k, cdEncodeValue(t.elem, v)]));
+    }
+    case "linkedList": {
+        const values = [];
+        for (; value !== null && value !== undefined; value = value.next) {
+            values.push(cdEncodeValue(t.elem, value.val));
+        }
+        return values;
+    }
+    case "tree": {
+        const values = [];
+        const level = [value];
+        for (let i = 0; i < level.length; i++) {
+            const node = level[i];
+            if (node === null || node === undefined) {
+                values.push(null);
+                continue;
+            }
+            values.push(cdEncodeValue(t.elem, node.val));
+            level.push(node.left, node.right);
+        }
+        while (values.length > 0 && values[values.length - 1] === null) {
+            values.pop();
+        }
+        return values;
+    }
+    default:
+        return value;
+    }
+}
+
+function cdEncode(s) {
+    return Buffer.from(s).toString("base64") || "-";
+}
+
+function cdErrorText(e) {
+    return e instanceof Error ? String(e.stack) : "uncaught " + String(e);
+}
+
+// calls f, and gives back what it printed instead of letting it through to stdout
+function cdCapture(f) {
+    let output = "";
+    process.stdout.write = (chunk) => {
+        output += chunk;
+        return true;
+    };
+    try {
+        f();
+    } finally {
+        process.stdout.write = cdWrite;
+    }
+    return output;
+}
+
+// stdin's first line is the nonce every record has to carry, and the rest is the cases. everything that uses the
+// nonce is inside cdMain, so the player's code can't get hold of it to forge records, even when it shares the
+// harness's scope as typescript does
+function cdMain(cdInput) {
+    const cdNonce = cdInput.slice(0, cdInput.indexOf("\n")).trim();
+    const cdCases = JSON.parse(cdInput.slice(cdInput.indexOf("\n") + 1));
+    const cdRecord = (cdCase, elapsed, stdout, error, result) => {
+        const status = error ? "error" : "ok";
+        cdWrite("{{CASE_MARKER}} " + [cdNonce, cdCase, status, elapsed, cdEncode(stdout), cdEncode(error), cdEncode(result)].join(" ") + "\n");
+    };
+
+    {{LOAD}}
+
+    cdCases.forEach((cdArgs, cdCase) => {
+        if (cdSetupError) {
+            cdRecord(cdCase, 0, "", cdSetupError, "");
+            return;
+        }
+        let cdError = "";
+        let cdResult = "";
+        let cdElapsed = 0;
+        const cdStdout = cdCapture(() => {
+            try {
+                const args = cdParamTypes.map((t, i) => cdDecode(t, cdArgs[i]));
+                const start = process.hrtime.bigint();
+                let value;
+                try {
+                    value = cdSolution(...args);
+                } finally {
+                    cdElapsed = Number(process.hrtime.bigint() - start);
+                }
+                if (value !== undefined) {
+                    cdResult = JSON.stringify(cdEncodeValue(cdReturnType, value));
+                }
+            } catch (e) {
+                cdError = cdErrorText(e);
+            }
+        });
+        cdRecord(cdCase, cdElapsed, cdStdout, cdError, cdResult);
+    });
+}
+
+cdMain(cdFs.readFileSync(0, "utf8"));
+`
+
+var javascriptNodes = `
+class ListNode {
+    constructor(val = 0, next = null) {
+        this.val = val;
+        this.next = next;
+    }
+}
+
+class TreeNode {
+    constructor(val = 0, left = null, right = null) {
+        this.val = val;
+        this.left = left;
+        this.right = right;
+    }
+}
+
+const cdListNode = ListNode;
+const cdTreeNode = TreeNode;
+`
+
+// the javascript harness's own declarations are scoped to its module, so the player's code can't see them.
+// the node classes are made global so that it can
+var javascriptLoad = `
+const cdVm = require("vm");
+const cdCode = {{CODE}};
+let cdScript;
+try {
+    cdScript = new cdVm.Script(cdCode, { filename: "solution.js" });
+} catch (e) {
+    cdWrite("{{COMPILE_ERROR_MARKER}} " + cdNonce + " " + cdEncode(cdErrorText(e)) + "\n");
+    process.exit(1);
+}
+
+// running the code defines solution. anything printed along the way isn't part of any case's output
+let cdSolution = null;
+let cdSetupError = "";
+Object.assign(globalThis, { ListNode, TreeNode, require });
+cdCapture(() => {
+    try {
+        cdScript.runInThisContext();
+        cdSolution = cdVm.runInThisContext("typeof {{NAME}} === \"function\" ? {{NAME}} : null");
+        if (cdSolution === null) {
+            cdSetupError = "no {{NAME}} function found";
+        }
+    } catch (e) {
+        cdSetupError = cdErrorText(e);
+    }
+});
+`
+
+// tsc only knows about node's globals with @types/node installed, so the ones the harness uses are declared here
+var typescriptDeclarations = `
+declare const process: any;
+declare const Buffer: any;
+declare function require(name: string): any;
+`
+
+var typescriptNodes = `
+class ListNode {
+    val: {{ELEM}};
+    next: ListNode | null;
+    constructor(val?: {{ELEM}}, next?: ListNode | null) {
+        this.val = val === undefined ? {{ZERO}} : val;
+        this.next = next === undefined ? null : next;
+    }
+}
+`
+
+var typescriptTreeNodes = `
+class TreeNode {
+    val: {{ELEM}};
+    left: TreeNode | null;
+    right: TreeNode | null;
+    constructor(val?: {{ELEM}}, left?: TreeNode | null, right?: TreeNode | null) {
+        this.val = val === undefined ? {{ZERO}} : val;
+        this.left = left === undefined ? null : left;
+        this.right = right === undefined ? null : right;
+    }
+}
+`
+
+// solution is any to the harness, since it's called with arguments spread from an array. the node classes are
+// only defined when the signature uses them, so the harness doesn't refer to them by name
+var typescriptLoad = `
+const cdSolution: any = {{NAME}};
+const cdSetupError = "";
+const cdListNode: any = {{LIST_NODE}};
+const cdTreeNode: any = {{TREE_NODE}};
+`
+
+func javascriptHarness(code string, signature models.Signature) string {
+	codeLiteral, _ := json.Marshal(code)
+	load := strings.NewReplacer(
+		"{{CODE}}", string(codeLiteral),
+		"{{NAME}}", signature.Name,
+		"{{COMPILE_ERROR_MARKER}}", HarnessCompileErrorMarker,
+	).Replace(javascriptLoad)
+	return javascriptNodes + javascriptHarnessWith(signature, load)
+}
+
+func typescriptHarness(code string, signature models.Signature) string {
+	var program strings.Builder
+	program.WriteString(typescriptDeclarations)
+	// the harness's node classes are defined for the player's code, using the same value types as the template
+	if elem := nodeElem(signature, models.KindLinkedList); elem != nil {
+		program.WriteString(strings.NewReplacer("{{ELEM}}", typescriptType(*elem), "{{ZERO}}", typescriptZero(*elem)).Replace(typescriptNodes))
+	}
+	if elem := nodeElem(signature, models.KindTree); elem != nil {
+		program.WriteString(strings.NewReplacer("{{ELEM}}", typescriptType(*elem), "{{ZERO}}", typescriptZero(*elem)).Replace(typescriptTreeNodes))
+	}
+	program.WriteString(code)
+	program.WriteString("\n")
+	load := strings.NewReplacer(
+		"{{NAME}}", signature.Name,
+		"{{LIST_NODE}}", typescriptNodeClass(signature, models.KindLinkedList, "ListNode"),
+		"{{TREE_NODE}}", typescriptNodeClass(signature, models.KindTree, "TreeNode"),
+	).Replace(typescriptLoad)
+	program.WriteString(javascriptHarnessWith(signature, load))
+	return program.String()
+}
+
+// the name of a node class if the signature uses it, or null if it doesn't, in which case it isn't defined
+func typescriptNodeClass(signature models.Signature, kind models.TypeKind, class string) string {
+	if !signature.Uses(kind) {
+		return "null"
+	}
+	return class
+}
+
+// fills in the shared harness body, with load as the code that defines cdSolution and cdSetupError
+func javascriptHarnessWith(signature models.Signature, load string) string {
+	paramTypes := make([]models.Type, len(signature.Params))
+	for i, param := range signature.Params {
+		paramTypes[i] = param.Type
+	}
+	paramTypesLiteral, _ := json.Marshal(paramTypes)
+	returnTypeLiteral, _ := json.Marshal(signature.Returns)
+	return strings.NewReplacer(
+		"{{LOAD}}", strings.ReplaceAll(strings.TrimSpace(load), "\n", "\n    "),
+		"{{PARAM_TYPES}}", string(paramTypesLiteral),
+		"{{RETURN_TYPE}}", string(returnTypeLiteral),
+		"{{CASE_MARKER}}", HarnessCaseMarker,
+	).Replace(javascriptHarnessBody)
+}
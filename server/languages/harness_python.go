@@ -0,0 +1,168 @@
+package languages
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// the python harness compiles and runs the code once to define solution, then calls solution with each test
+// case's arguments, decoded into the types the signature gives for them. stdout is redirected into a buffer for
+// each call.
+var pythonHarnessTemplate = `
+import base64 as _cd_base64
+import contextlib as _cd_contextlib
+import io as _cd_io
+import json as _cd_json
+import sys as _cd_sys
+import time as _cd_time
+import traceback as _cd_traceback
+
+_cd_code = {{CODE}}
+_cd_param_types = {{PARAM_TYPES}}
+_cd_return_type = {{RETURN_TYPE}}
+
+class ListNode:
+    def __init__(self, val=0, next=None):
+        self.val = val
+        self.next = next
+
+class TreeNode:
+    def __init__(self, val=0, left=None, right=None):
+        self.val = val
+        self.left = left
+        self.right = right
+
+# turns a JSON value into a value of the given type
+def _cd_decode(t, value):
+    kind = t["kind"]
+    if kind == "list":
+        return [_cd_decode(t["elem"], v) for v in value]
+    if kind == "map":
+        return {k: _cd_decode(t["elem"], v) for k, v in value.items()}
+    if kind == "linkedList":
+        head = None
+        for v in reversed(value):
+            head = ListNode(_cd_decode(t["elem"], v), head)
+        return head
+    if kind == "tree":
+        nodes = [None if v is None else TreeNode(_cd_decode(t["elem"], v)) for v in value]
+        if not nodes or nodes[0] is None:
+            return None
+        parents, i = [nodes[0]], 1
+        for parent in parents:
+            if i < len(nodes):
+                parent.left = nodes[i]
+                i += 1
+            if i < len(nodes):
+                parent.right = nodes[i]
+                i += 1
+            parents.extend(n for n in (parent.left, parent.right) if n is not None)
+        return nodes[0]
+    if kind == "float":
+        return float(value)
+    return value
+
+# turns a value of the given type back into a JSON value
+def _cd_encode_value(t, value):
+    kind = t["kind"]
+    if kind == "list":
+        return [_cd_encode_value(t["elem"], v) for v in value]
+    if kind == "map":
+        return {k: _cd_encode_value(t["elem"], v) for k, v in value.items()}
+    if kind == "linkedList":
+        values = []
+        while value is not None:
+            values.append(_cd_encode_value(t["elem"], value.val))
+            value = value.next
+        return values
+    if kind == "tree":
+        values, level = [], [value]
+        while level:
+            node = level.pop(0)
+            if node is None:
+                values.append(None)
+                continue
+            values.append(_cd_encode_value(t["elem"], node.val))
+            level.extend([node.left, node.right])
+        while values and values[-1] is None:
+            values.pop()
+        return values
+    return value
+
+def _cd_encode(s):
+    return _cd_base64.b64encode(s.encode()).decode() or "-"
+
+# the driver is a function, so the nonce is one of its locals rather than a global of __main__, which the code could
+# get at through sys.modules
+def _cd_main():
+    # the first line of stdin is the nonce every record has to carry, and the rest is the cases
+    nonce = _cd_sys.stdin.readline().strip()
+
+    def record(case, elapsed, stdout, error, result):
+        status = "error" if error else "ok"
+        print("{{CASE_MARKER}}", nonce, case, status, elapsed, _cd_encode(stdout), _cd_encode(error), _cd_encode(result), flush=True)
+
+    try:
+        compiled = compile(_cd_code, "solution.py", "exec")
+    except SyntaxError:
+        print("{{COMPILE_ERROR_MARKER}}", nonce, _cd_encode(_cd_traceback.format_exc(limit=0)), flush=True)
+        _cd_sys.exit(1)
+
+    cases = _cd_json.load(_cd_sys.stdin)
+
+    # running the code defines solution. anything printed along the way isn't part of any case's output
+    env = {"__name__": "solution", "ListNode": ListNode, "TreeNode": TreeNode}
+    setup_error = ""
+    try:
+        with _cd_contextlib.redirect_stdout(_cd_io.StringIO()):
+            exec(compiled, env)
+    except BaseException:
+        setup_error = _cd_traceback.format_exc()
+    else:
+        if not callable(env.get("{{NAME}}")):
+            setup_error = "no {{NAME}} function found"
+
+    for case, args in enumerate(cases):
+        if setup_error:
+            record(case, 0, "", setup_error, "")
+            continue
+        stdout = _cd_io.StringIO()
+        error, result = "", ""
+        start = _cd_time.perf_counter_ns()
+        try:
+            args = [_cd_decode(t, arg) for t, arg in zip(_cd_param_types, args)]
+            with _cd_contextlib.redirect_stdout(stdout):
+                value = env["{{NAME}}"](*args)
+            if value is not None:
+                result = _cd_json.dumps(_cd_encode_value(_cd_return_type, value))
+        except SystemExit as stop:
+            if stop.code not in (None, 0):
+                error = "exited with status " + str(stop.code)
+        except BaseException:
+            error = _cd_traceback.format_exc()
+        elapsed = _cd_time.perf_counter_ns() - start
+        record(case, elapsed, stdout.getvalue(), error, result)
+
+_cd_main()
+`
+
+func pythonHarness(code string, signature models.Signature) string {
+	// JSON strings, lists and objects are also valid python literals
+	codeLiteral, _ := json.Marshal(code)
+	paramTypes := make([]models.Type, len(signature.Params))
+	for i, param := range signature.Params {
+		paramTypes[i] = param.Type
+	}
+	paramTypesLiteral, _ := json.Marshal(paramTypes)
+	returnTypeLiteral, _ := json.Marshal(signature.Returns)
+	return strings.NewReplacer(
+		"{{CODE}}", string(codeLiteral),
+		"{{PARAM_TYPES}}", string(paramTypesLiteral),
+		"{{RETURN_TYPE}}", string(returnTypeLiteral),
+		"{{NAME}}", signature.Name,
+		"{{CASE_MARKER}}", HarnessCaseMarker,
+		"{{COMPILE_ERROR_MARKER}}", HarnessCompileErrorMarker,
+	).Replace(pythonHarnessTemplate)
+}
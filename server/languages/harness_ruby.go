@@ -0,0 +1,199 @@
+package languages
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// the ruby harness works like the python one: it compiles and runs the code once to define solution at the top
+// level, then calls solution with each test case's arguments, decoded into the types the signature gives for them.
+// $stdout is swapped for a buffer during each call. the code is embedded as base64, since a ruby string literal
+// would interpolate anything in it that looks like #{...}
+var rubyHarnessTemplate = `
+require "base64"
+require "json"
+require "stringio"
+
+CD_STDOUT = $stdout
+CD_CODE = Base64.strict_decode64("{{CODE}}").force_encoding(Encoding::UTF_8)
+CD_PARAM_TYPES = JSON.parse('{{PARAM_TYPES}}')
+CD_RETURN_TYPE = JSON.parse('{{RETURN_TYPE}}')
+
+class ListNode
+  attr_accessor :val, :next
+
+  def initialize(val = 0, next_node = nil)
+    @val = val
+    @next = next_node
+  end
+end
+
+class TreeNode
+  attr_accessor :val, :left, :right
+
+  def initialize(val = 0, left = nil, right = nil)
+    @val = val
+    @left = left
+    @right = right
+  end
+end
+
+# turns a JSON value into a value of the given type
+def cd_decode(t, value)
+  case t["kind"]
+  when "list"
+    value.map { |v| cd_decode(t["elem"], v) }
+  when "map"
+    value.transform_values { |v| cd_decode(t["elem"], v) }
+  when "linkedList"
+    head = nil
+    value.reverse_each { |v| head = ListNode.new(cd_decode(t["elem"], v), head) }
+    head
+  when "tree"
+    nodes = value.map { |v| v.nil? ? nil : TreeNode.new(cd_decode(t["elem"], v)) }
+    return nil if nodes.empty? || nodes[0].nil?
+    parents = [nodes[0]]
+    i = 1
+    parents.each do |parent|
+      break if i >= nodes.length
+      parent.left = nodes[i]
+      i += 1
+      if i < nodes.length
+        parent.right = nodes[i]
+        i += 1
+      end
+      parents.push(parent.left) unless parent.left.nil?
+      parents.push(parent.right) unless parent.right.nil?
+    end
+    nodes[0]
+  when "float"
+    value.to_f
+  else
+    value
+  end
+end
+
+# turns a value of the given type back into a JSON value. lists can be anything enumerable
+def cd_encode_value(t, value)
+  case t["kind"]
+  when "list"
+    value.to_a.map { |v| cd_encode_value(t["elem"], v) }
+  when "map"
+    value.to_h { |k, v| [k.to_s, cd_encode_value(t["elem"], v)] }
+  when "linkedList"
+    values = []
+    until value.nil?
+      values << cd_encode_value(t["elem"], value.val)
+      value = value.next
+    end
+    values
+  when "tree"
+    values = []
+    level = [value]
+    until level.empty?
+      node = level.shift
+      if node.nil?
+        values << nil
+        next
+      end
+      values << cd_encode_value(t["elem"], node.val)
+      level.push(node.left, node.right)
+    end
+    values.pop while !values.empty? && values.last.nil?
+    values
+  else
+    value
+  end
+end
+
+def cd_encode(s)
+  s.empty? ? "-" : Base64.strict_encode64(s)
+end
+
+def cd_record(nonce, cd_case, elapsed, stdout, error, result)
+  status = error.empty? ? "ok" : "error"
+  CD_STDOUT.write(["{{CASE_MARKER}}", nonce, cd_case, status, elapsed, cd_encode(stdout), cd_encode(error), cd_encode(result)].join(" ") + "\n")
+  CD_STDOUT.flush
+end
+
+# the driver is a method, so the nonce is one of its locals. locals at the top level of the script would be
+# visible to the code through TOPLEVEL_BINDING
+def cd_main
+  # the first line of stdin is the nonce every record has to carry, and the rest is the cases
+  nonce = $stdin.gets.to_s.strip
+
+  begin
+    compiled = RubyVM::InstructionSequence.compile(CD_CODE, "solution.rb")
+  rescue SyntaxError => e
+    CD_STDOUT.write("{{COMPILE_ERROR_MARKER}} " + nonce + " " + cd_encode(e.message) + "\n")
+    exit 1
+  end
+
+  cases = JSON.parse($stdin.read)
+
+  # running the code defines solution. anything printed along the way isn't part of any case's output
+  setup_error = ""
+  $stdout = StringIO.new
+  begin
+    compiled.eval
+    setup_error = "no {{NAME}} method found" unless respond_to?(:{{NAME}}, true)
+  rescue Exception => e
+    setup_error = e.full_message(highlight: false)
+  ensure
+    $stdout = CD_STDOUT
+  end
+
+  cases.each_with_index do |args, cd_case|
+    unless setup_error.empty?
+      cd_record(nonce, cd_case, 0, "", setup_error, "")
+      next
+    end
+    stdout = StringIO.new
+    error = ""
+    result = ""
+    start = Process.clock_gettime(Process::CLOCK_MONOTONIC, :nanosecond)
+    begin
+      decoded = CD_PARAM_TYPES.zip(args).map { |t, arg| cd_decode(t, arg) }
+      $stdout = stdout
+      begin
+        value = send(:{{NAME}}, *decoded)
+      ensure
+        $stdout = CD_STDOUT
+      end
+      # ruby methods always give back their last value, so only non-void solutions have an answer
+      unless value.nil? || CD_RETURN_TYPE["kind"] == "void"
+        result = JSON.generate(cd_encode_value(CD_RETURN_TYPE, value))
+      end
+    rescue SystemExit => e
+      error = "exited with status #{e.status}" unless e.success?
+    rescue Exception => e
+      error = e.full_message(highlight: false)
+    end
+    elapsed = Process.clock_gettime(Process::CLOCK_MONOTONIC, :nanosecond) - start
+    cd_record(nonce, cd_case, elapsed, stdout.string, error, result)
+  end
+end
+
+cd_main
+`
+
+func rubyHarness(code string, signature models.Signature) string {
+	paramTypes := make([]models.Type, len(signature.Params))
+	for i, param := range signature.Params {
+		paramTypes[i] = param.Type
+	}
+	// type kinds never have quotes or backslashes in them, so the JSON can go in single quoted strings as it is
+	paramTypesLiteral, _ := json.Marshal(paramTypes)
+	returnTypeLiteral, _ := json.Marshal(signature.Returns)
+	return strings.NewReplacer(
+		"{{CODE}}", base64.StdEncoding.EncodeToString([]byte(code)),
+		"{{PARAM_TYPES}}", string(paramTypesLiteral),
+		"{{RETURN_TYPE}}", string(returnTypeLiteral),
+		"{{NAME}}", signature.Name,
+		"{{CASE_MARKER}}", HarnessCaseMarker,
+		"{{COMPILE_ERROR_MARKER}}", HarnessCompileErrorMarker,
+	).Replace(rubyHarnessTemplate)
+}
@@ -0,0 +1,503 @@
+package languages
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// the rust harness goes after the player's code, and defines the node types leetcode uses, a small JSON reader,
+// and a main function that calls solution once for each test case. arguments are decoded through the CdDecode
+// trait, which is implemented for every type solution can take, so the harness never has to name their types.
+// stdout is pointed at a file during each call to capture what solution prints, and panics are caught with
+// catch_unwind and reported through a panic hook. everything is referred to by its full path, so the harness
+// doesn't clash with the player's use declarations.
+var rustHarnessTemplate = `
+{{NODES}}
+#[allow(dead_code)]
+enum CdJson {
+    Null,
+    Bool(bool),
+    Number(String),
+    Text(String),
+    Array(Vec<CdJson>),
+    Object(Vec<(String, CdJson)>),
+}
+
+fn cd_skip(s: &[u8], pos: &mut usize, chars: &[u8]) {
+    while *pos < s.len() && (s[*pos].is_ascii_whitespace() || chars.contains(&s[*pos])) {
+        *pos += 1;
+    }
+}
+
+fn cd_hex(s: &[u8], pos: usize) -> Result<u32, String> {
+    let digits = s.get(pos..pos + 4).ok_or("unexpected end of test case")?;
+    u32::from_str_radix(&String::from_utf8_lossy(digits), 16).map_err(|e| e.to_string())
+}
+
+fn cd_parse_string(s: &[u8], pos: &mut usize) -> Result<String, String> {
+    let mut value: Vec<u8> = Vec::new();
+    *pos += 1;
+    while *pos < s.len() && s[*pos] != b'"' {
+        let c = s[*pos];
+        *pos += 1;
+        if c != b'\\' {
+            value.push(c);
+            continue;
+        }
+        let c = *s.get(*pos).ok_or("unexpected end of test case")?;
+        *pos += 1;
+        match c {
+            b'n' => value.push(b'\n'),
+            b't' => value.push(b'\t'),
+            b'r' => value.push(b'\r'),
+            b'b' => value.push(8),
+            b'f' => value.push(12),
+            b'u' => {
+                let mut code = cd_hex(s, *pos)?;
+                *pos += 4;
+                if (0xD800..0xDC00).contains(&code) && s[*pos..].starts_with(b"\\u") {
+                    let low = cd_hex(s, *pos + 2)?;
+                    code = 0x10000 + ((code - 0xD800) << 10) + (low.wrapping_sub(0xDC00) & 0x3FF);
+                    *pos += 6;
+                }
+                let mut buf = [0u8; 4];
+                value.extend_from_slice(char::from_u32(code).unwrap_or('\u{FFFD}').encode_utf8(&mut buf).as_bytes());
+            }
+            _ => value.push(c),
+        }
+    }
+    *pos += 1;
+    Ok(String::from_utf8_lossy(&value).into_owned())
+}
+
+fn cd_parse(s: &[u8], pos: &mut usize) -> Result<CdJson, String> {
+    cd_skip(s, pos, b"");
+    let c = *s.get(*pos).ok_or("unexpected end of test case")?;
+    match c {
+        b'[' | b'{' => {
+            let close = if c == b'[' { b']' } else { b'}' };
+            let mut items = Vec::new();
+            let mut fields = Vec::new();
+            *pos += 1;
+            loop {
+                cd_skip(s, pos, b",");
+                if *s.get(*pos).ok_or("unexpected end of test case")? == close {
+                    *pos += 1;
+                    break;
+                }
+                if close == b']' {
+                    items.push(cd_parse(s, pos)?);
+                } else {
+                    let key = cd_parse_string(s, pos)?;
+                    cd_skip(s, pos, b":");
+                    fields.push((key, cd_parse(s, pos)?));
+                }
+            }
+            Ok(if close == b']' { CdJson::Array(items) } else { CdJson::Object(fields) })
+        }
+        b'"' => Ok(CdJson::Text(cd_parse_string(s, pos)?)),
+        b't' => {
+            *pos += 4;
+            Ok(CdJson::Bool(true))
+        }
+        b'f' => {
+            *pos += 5;
+            Ok(CdJson::Bool(false))
+        }
+        b'n' => {
+            *pos += 4;
+            Ok(CdJson::Null)
+        }
+        _ => {
+            let start = *pos;
+            while *pos < s.len() && b"+-.0123456789eE".contains(&s[*pos]) {
+                *pos += 1;
+            }
+            if *pos == start {
+                return Err(format!("unexpected character in test case: {}", c as char));
+            }
+            Ok(CdJson::Number(String::from_utf8_lossy(&s[start..*pos]).into_owned()))
+        }
+    }
+}
+
+fn cd_items(j: &CdJson) -> Result<&Vec<CdJson>, String> {
+    match j {
+        CdJson::Array(items) => Ok(items),
+        _ => Err("expected a list".to_string()),
+    }
+}
+
+// turns a JSON value into a value of the type solution takes
+trait CdDecode: Sized {
+    fn cd_decode(j: &CdJson) -> Result<Self, String>;
+}
+
+impl CdDecode for i64 {
+    fn cd_decode(j: &CdJson) -> Result<Self, String> {
+        match j {
+            CdJson::Number(n) => n.parse::<i64>().or_else(|_| n.parse::<f64>().map(|f| f.round() as i64)).map_err(|e| e.to_string()),
+            _ => Err("expected a number".to_string()),
+        }
+    }
+}
+
+impl CdDecode for f64 {
+    fn cd_decode(j: &CdJson) -> Result<Self, String> {
+        match j {
+            CdJson::Number(n) => n.parse::<f64>().map_err(|e| e.to_string()),
+            _ => Err("expected a number".to_string()),
+        }
+    }
+}
+
+impl CdDecode for String {
+    fn cd_decode(j: &CdJson) -> Result<Self, String> {
+        match j {
+            CdJson::Text(s) => Ok(s.clone()),
+            _ => Err("expected a string".to_string()),
+        }
+    }
+}
+
+impl CdDecode for bool {
+    fn cd_decode(j: &CdJson) -> Result<Self, String> {
+        match j {
+            CdJson::Bool(b) => Ok(*b),
+            _ => Err("expected a boolean".to_string()),
+        }
+    }
+}
+
+impl<T: CdDecode> CdDecode for Vec<T> {
+    fn cd_decode(j: &CdJson) -> Result<Self, String> {
+        cd_items(j)?.iter().map(T::cd_decode).collect()
+    }
+}
+
+impl<T: CdDecode> CdDecode for std::collections::HashMap<String, T> {
+    fn cd_decode(j: &CdJson) -> Result<Self, String> {
+        match j {
+            CdJson::Object(fields) => fields.iter().map(|(k, v)| Ok((k.clone(), T::cd_decode(v)?))).collect(),
+            _ => Err("expected a map".to_string()),
+        }
+    }
+}
+
+// writes a value of the type solution returns as JSON
+trait CdEncode {
+    fn cd_encode(&self, out: &mut String);
+}
+
+impl CdEncode for i64 {
+    fn cd_encode(&self, out: &mut String) {
+        out.push_str(&self.to_string());
+    }
+}
+
+impl CdEncode for f64 {
+    fn cd_encode(&self, out: &mut String) {
+        if self.is_finite() {
+            out.push_str(&self.to_string());
+        } else {
+            out.push_str("null");
+        }
+    }
+}
+
+impl CdEncode for String {
+    fn cd_encode(&self, out: &mut String) {
+        out.push('"');
+        for c in self.chars() {
+            match c {
+                '"' | '\\' => {
+                    out.push('\\');
+                    out.push(c);
+                }
+                c if (c as u32) < 0x20 => out.push_str(&format!("\\u{:04x}", c as u32)),
+                c => out.push(c),
+            }
+        }
+        out.push('"');
+    }
+}
+
+impl CdEncode for bool {
+    fn cd_encode(&self, out: &mut String) {
+        out.push_str(if *self { "true" } else { "false" });
+    }
+}
+
+impl<T: CdEncode> CdEncode for Vec<T> {
+    fn cd_encode(&self, out: &mut String) {
+        out.push('[');
+        for (i, item) in self.iter().enumerate() {
+            if i > 0 {
+                out.push(',');
+            }
+            item.cd_encode(out);
+        }
+        out.push(']');
+    }
+}
+
+impl<T: CdEncode> CdEncode for std::collections::HashMap<String, T> {
+    fn cd_encode(&self, out: &mut String) {
+        out.push('{');
+        for (i, (key, value)) in self.iter().enumerate() {
+            if i > 0 {
+                out.push(',');
+            }
+            key.cd_encode(out);
+            out.push(':');
+            value.cd_encode(out);
+        }
+        out.push('}');
+    }
+}
+{{NODE_CODERS}}
+// decodes a test case's arguments and calls solution with them, giving back its answer as JSON.
+// cd_start is set just before the call, so decoding doesn't count towards its runtime
+fn cd_call(cd_line: &str, cd_start: &mut std::time::Instant) -> Result<Option<String>, String> {
+{{CALL}}
+}
+
+fn cd_base64(s: &[u8]) -> String {
+    if s.is_empty() {
+        return "-".to_string();
+    }
+    let chars = b"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/";
+    let mut out = String::new();
+    for chunk in s.chunks(3) {
+        let n = (chunk[0] as u32) << 16 | (*chunk.get(1).unwrap_or(&0) as u32) << 8 | *chunk.get(2).unwrap_or(&0) as u32;
+        out.push(chars[(n >> 18 & 63) as usize] as char);
+        out.push(chars[(n >> 12 & 63) as usize] as char);
+        out.push(if chunk.len() > 1 { chars[(n >> 6 & 63) as usize] as char } else { '=' });
+        out.push(if chunk.len() > 2 { chars[(n & 63) as usize] as char } else { '=' });
+    }
+    out
+}
+
+extern "C" {
+    fn dup(fd: i32) -> i32;
+    fn dup2(fd: i32, to: i32) -> i32;
+}
+
+thread_local! {
+    static CD_PANIC: std::cell::RefCell<String> = std::cell::RefCell::new(String::new());
+}
+
+fn main() {
+    // panics are reported in the case's record instead of on stderr
+    std::panic::set_hook(Box::new(|info| {
+        CD_PANIC.with(|p| *p.borrow_mut() = info.to_string());
+    }));
+    let cd_stdout = unsafe { dup(1) };
+    let mut cd_records = unsafe { <std::fs::File as std::os::unix::io::FromRawFd>::from_raw_fd(cd_stdout) };
+    let mut cd_output = std::fs::OpenOptions::new().read(true).write(true).create(true).truncate(true).open("cd_output").expect("harness: failed to capture output");
+    let cd_output_fd = std::os::unix::io::AsRawFd::as_raw_fd(&cd_output);
+    let cd_input = std::io::read_to_string(std::io::stdin()).expect("harness: failed to read test cases");
+    let mut cd_lines = cd_input.lines();
+    let cd_nonce = cd_lines.next().unwrap_or_default().trim();
+    let mut cd_case = 0;
+    for cd_line in cd_lines {
+        // each case is on a line of its own, between the lines with the brackets of the array around them
+        if cd_line.len() < 2 || !cd_line.starts_with('[') {
+            continue;
+        }
+        let _ = std::io::Write::flush(&mut std::io::stdout());
+        let _ = cd_output.set_len(0);
+        let _ = std::io::Seek::seek(&mut cd_output, std::io::SeekFrom::Start(0));
+        unsafe { dup2(cd_output_fd, 1) };
+        let mut cd_start = std::time::Instant::now();
+        let cd_outcome = std::panic::catch_unwind(std::panic::AssertUnwindSafe(|| cd_call(cd_line, &mut cd_start)));
+        let cd_elapsed = cd_start.elapsed().as_nanos();
+        let _ = std::io::Write::flush(&mut std::io::stdout());
+        unsafe { dup2(cd_stdout, 1) };
+        let mut cd_printed = Vec::new();
+        let _ = std::io::Seek::seek(&mut cd_output, std::io::SeekFrom::Start(0));
+        let _ = std::io::Read::read_to_end(&mut cd_output, &mut cd_printed);
+        let (cd_result, cd_error) = match cd_outcome {
+            Ok(Ok(result)) => (result.unwrap_or_default(), String::new()),
+            Ok(Err(error)) => (String::new(), format!("harness: {}", error)),
+            Err(_) => (String::new(), CD_PANIC.with(|p| p.borrow().clone())),
+        };
+        let cd_status = if cd_error.is_empty() { "ok" } else { "error" };
+        let _ = std::io::Write::write_all(&mut cd_records, format!("{} {} {} {} {} {} {} {}\n", "{{CASE_MARKER}}", cd_nonce, cd_case, cd_status, cd_elapsed,
+            cd_base64(&cd_printed), cd_base64(cd_error.as_bytes()), cd_base64(cd_result.as_bytes())).as_bytes());
+        cd_case += 1;
+    }
+}
+`
+
+var rustListNode = `
+#[derive(PartialEq, Clone, Debug)]
+pub struct ListNode {
+    pub val: {{ELEM}},
+    pub next: Option<Box<ListNode>>,
+}
+
+#[allow(dead_code)]
+impl ListNode {
+    pub fn new(val: {{ELEM}}) -> Self {
+        ListNode { next: None, val }
+    }
+}
+`
+
+var rustTreeNode = `
+#[derive(PartialEq, Clone, Debug)]
+pub struct TreeNode {
+    pub val: {{ELEM}},
+    pub left: Option<std::rc::Rc<std::cell::RefCell<TreeNode>>>,
+    pub right: Option<std::rc::Rc<std::cell::RefCell<TreeNode>>>,
+}
+
+#[allow(dead_code)]
+impl TreeNode {
+    pub fn new(val: {{ELEM}}) -> Self {
+        TreeNode { val, left: None, right: None }
+    }
+}
+`
+
+var rustListNodeCoders = `
+impl CdDecode for Option<Box<ListNode>> {
+    fn cd_decode(j: &CdJson) -> Result<Self, String> {
+        let mut head = None;
+        for item in cd_items(j)?.iter().rev() {
+            let mut node = ListNode::new(CdDecode::cd_decode(item)?);
+            node.next = head;
+            head = Some(Box::new(node));
+        }
+        Ok(head)
+    }
+}
+
+impl CdEncode for Option<Box<ListNode>> {
+    fn cd_encode(&self, out: &mut String) {
+        out.push('[');
+        let mut node = self.as_ref();
+        let mut first = true;
+        while let Some(n) = node {
+            if !first {
+                out.push(',');
+            }
+            first = false;
+            n.val.cd_encode(out);
+            node = n.next.as_ref();
+        }
+        out.push(']');
+    }
+}
+`
+
+var rustTreeNodeCoders = `
+impl CdDecode for Option<std::rc::Rc<std::cell::RefCell<TreeNode>>> {
+    fn cd_decode(j: &CdJson) -> Result<Self, String> {
+        let mut nodes = Vec::new();
+        for item in cd_items(j)? {
+            nodes.push(match item {
+                CdJson::Null => None,
+                _ => Some(std::rc::Rc::new(std::cell::RefCell::new(TreeNode::new(CdDecode::cd_decode(item)?)))),
+            });
+        }
+        let root = match nodes.first() {
+            Some(Some(root)) => root.clone(),
+            _ => return Ok(None),
+        };
+        let mut parents = vec![root.clone()];
+        let mut next = 1;
+        let mut i = 0;
+        while i < parents.len() && next < nodes.len() {
+            let parent = parents[i].clone();
+            let mut parent = parent.borrow_mut();
+            parent.left = nodes[next].clone();
+            next += 1;
+            if next < nodes.len() {
+                parent.right = nodes[next].clone();
+                next += 1;
+            }
+            parents.extend(parent.left.iter().chain(parent.right.iter()).cloned());
+            i += 1;
+        }
+        Ok(Some(root))
+    }
+}
+
+impl CdEncode for Option<std::rc::Rc<std::cell::RefCell<TreeNode>>> {
+    fn cd_encode(&self, out: &mut String) {
+        let mut values: Vec<Option<String>> = Vec::new();
+        let mut level = std::collections::VecDeque::from(vec![self.clone()]);
+        while let Some(node) = level.pop_front() {
+            let Some(node) = node else {
+                values.push(None);
+                continue;
+            };
+            let node = node.borrow();
+            let mut value = String::new();
+            node.val.cd_encode(&mut value);
+            values.push(Some(value));
+            level.push_back(node.left.clone());
+            level.push_back(node.right.clone());
+        }
+        while values.last() == Some(&None) {
+            values.pop();
+        }
+        out.push('[');
+        for (i, value) in values.iter().enumerate() {
+            if i > 0 {
+                out.push(',');
+            }
+            out.push_str(value.as_deref().unwrap_or("null"));
+        }
+        out.push(']');
+    }
+}
+`
+
+func rustHarness(code string, signature models.Signature) string {
+	var nodes, coders strings.Builder
+	if elem := nodeElem(signature, models.KindLinkedList); elem != nil {
+		nodes.WriteString(strings.ReplaceAll(rustListNode, "{{ELEM}}", rustType(*elem)))
+		coders.WriteString(rustListNodeCoders)
+	}
+	if elem := nodeElem(signature, models.KindTree); elem != nil {
+		nodes.WriteString(strings.ReplaceAll(rustTreeNode, "{{ELEM}}", rustType(*elem)))
+		coders.WriteString(rustTreeNodeCoders)
+	}
+	var program strings.Builder
+	program.WriteString(code)
+	program.WriteString("\n")
+	program.WriteString(strings.NewReplacer(
+		"{{NODES}}", nodes.String(),
+		"{{NODE_CODERS}}", coders.String(),
+		"{{CALL}}", rustHarnessCall(signature),
+		"{{CASE_MARKER}}", HarnessCaseMarker,
+	).Replace(rustHarnessTemplate))
+	return program.String()
+}
+
+// writes the body of cd_call for a solution with the given signature. the argument types are inferred from
+// solution's parameters
+func rustHarnessCall(signature models.Signature) string {
+	var call strings.Builder
+	call.WriteString("    let cd_json = cd_parse(cd_line.as_bytes(), &mut 0)?;\n    let cd_args = cd_items(&cd_json)?;\n")
+	fmt.Fprintf(&call, "    if cd_args.len() != %d {\n", len(signature.Params))
+	fmt.Fprintf(&call, "        return Err(format!(\"%s takes %d arguments, but the test case has {}\", cd_args.len()));\n    }\n", signature.Name, len(signature.Params))
+	args := make([]string, len(signature.Params))
+	for i, param := range signature.Params {
+		args[i] = fmt.Sprintf("cd_arg%d", i)
+		fmt.Fprintf(&call, "    let %s = CdDecode::cd_decode(&cd_args[%d]).map_err(|e| format!(\"failed to read argument %s: {}\", e))?;\n", args[i], i, param.Name)
+	}
+	call.WriteString("    *cd_start = std::time::Instant::now();\n")
+	solution := fmt.Sprintf("%s(%s)", signature.Name, strings.Join(args, ", "))
+	if signature.Returns.Kind == models.KindVoid {
+		fmt.Fprintf(&call, "    %s;\n    Ok(None)", solution)
+	} else {
+		fmt.Fprintf(&call, "    let cd_value = %s;\n    let mut cd_result = String::new();\n    CdEncode::cd_encode(&cd_value, &mut cd_result);\n    Ok(Some(cd_result))", solution)
+	}
+	return call.String()
+}
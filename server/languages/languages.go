@@ -0,0 +1,177 @@
+// Package languages is the registry of the languages code can be submitted in. each language has its canonical
+// ID, the other names the client uses for it, how its starting code is generated, the test harness its code is
+// wrapped in, how it's compiled and run, and how its output is cleaned up before it's judged. everything else
+// looks languages up here, so adding a language only needs an entry in the registry.
+package languages
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// canonical IDs of the registered languages
+const (
+	Python     = "python"
+	Go         = "go"
+	Bash       = "bash"
+	JavaScript = "javascript"
+	TypeScript = "typescript"
+	Java       = "java"
+	Cpp        = "cpp"
+	Rust       = "rust"
+	Ruby       = "ruby"
+)
+
+// Language describes a language code can be submitted in, and how it's compiled and run.
+// commands run in the directory the source file is saved in
+type Language struct {
+	ID        string   `json:"id"`
+	Aliases   []string `json:"aliases,omitempty"` // other names the client uses for the language
+	Name      string   `json:"name"`
+	Version   string   `json:"version"`   // the version the harness and templates are written for
+	Extension string   `json:"extension"` // file extension for source files, with the dot
+	Source    string   `json:"source"`    // name of the file the code is saved as
+	Compile   []string `json:"compile,omitempty"`
+	Run       []string `json:"run"`
+	// a problem's time and memory limits are multiplied by these, for languages that need more time or memory to
+	// do the same work
+	TimeFactor   float64 `json:"timeFactor"`
+	MemoryFactor float64 `json:"memoryFactor"`
+
+	template  func(signature models.Signature) string
+	harness   func(code string, signature models.Signature) string
+	normalize func(output string) string // if not set, trailing whitespace is trimmed
+}
+
+// the registered languages, in the order they're listed to players
+var registry = []Language{
+	{
+		ID: Python, Aliases: []string{"py"}, Name: "Python", Version: "3.9+", Extension: ".py", Source: "main.py",
+		Run:        []string{"python3", "main.py"},
+		TimeFactor: 3, MemoryFactor: 1,
+		template: pythonTemplate, harness: pythonHarness,
+	},
+	{
+		ID: Go, Name: "Go", Version: "1.18+", Extension: ".go", Source: "main.go",
+		Compile:    []string{"go", "build", "-o", "main", "main.go"},
+		Run:        []string{"./main"},
+		TimeFactor: 1, MemoryFactor: 1,
+		template: goTemplate, harness: goHarness,
+	},
+	{
+		ID: Bash, Aliases: []string{"sh"}, Name: "Bash", Version: "4+", Extension: ".sh", Source: "main.sh",
+		Run:        []string{"bash", "main.sh"},
+		TimeFactor: 5, MemoryFactor: 1,
+		template: bashTemplate, harness: bashHarness,
+	},
+	{
+		ID: JavaScript, Aliases: []string{"js"}, Name: "JavaScript", Version: "Node.js 18+", Extension: ".js", Source: "main.js",
+		Run:        []string{"node", "main.js"},
+		TimeFactor: 2, MemoryFactor: 1,
+		template: javascriptTemplate, harness: javascriptHarness,
+	},
+	{
+		ID: TypeScript, Aliases: []string{"ts"}, Name: "TypeScript", Version: "5.x, on Node.js 18+", Extension: ".ts", Source: "main.ts",
+		Compile:    []string{"tsc", "--target", "es2022", "--module", "commonjs", "--noEmitOnError", "main.ts"},
+		Run:        []string{"node", "main.js"},
+		TimeFactor: 2, MemoryFactor: 1,
+		template: typescriptTemplate, harness: typescriptHarness,
+	},
+	{
+		ID: Java, Name: "Java", Version: "17+", Extension: ".java", Source: "Main.java",
+		Compile: []string{"javac", "-encoding", "UTF-8", "-nowarn", "Main.java"},
+		// the heap is kept under the executor's default memory limit, or the JVM can't start
+		Run:        []string{"java", "-Xmx192m", "-XX:+UseSerialGC", "Main"},
+		TimeFactor: 2, MemoryFactor: 2,
+		template: javaTemplate, harness: javaHarness,
+	},
+	{
+		ID: Cpp, Aliases: []string{"c++"}, Name: "C++", Version: "C++17 (g++)", Extension: ".cpp", Source: "main.cpp",
+		Compile:    []string{"g++", "-std=c++17", "-O2", "-o", "main", "main.cpp"},
+		Run:        []string{"./main"},
+		TimeFactor: 1, MemoryFactor: 1,
+		template: cppTemplate, harness: cppHarness,
+	},
+	{
+		ID: Rust, Aliases: []string{"rs"}, Name: "Rust", Version: "2021 edition", Extension: ".rs", Source: "main.rs",
+		Compile:    []string{"rustc", "--edition", "2021", "-O", "-o", "main", "main.rs"},
+		Run:        []string{"./main"},
+		TimeFactor: 1, MemoryFactor: 1,
+		template: rustTemplate, harness: rustHarness,
+	},
+	{
+		ID: Ruby, Aliases: []string{"rb"}, Name: "Ruby", Version: "3.0+", Extension: ".rb", Source: "main.rb",
+		Run:        []string{"ruby", "main.rb"},
+		TimeFactor: 3, MemoryFactor: 1,
+		template: rubyTemplate, harness: rubyHarness,
+	},
+}
+
+// finds a language by its ID or one of its aliases
+func Get(name string) (Language, bool) {
+	for _, lang := range registry {
+		if lang.ID == name {
+			return lang, true
+		}
+		for _, alias := range lang.Aliases {
+			if alias == name {
+				return lang, true
+			}
+		}
+	}
+	return Language{}, false
+}
+
+// finds the language whose source files have the given extension (with the dot)
+func ByExtension(extension string) (Language, bool) {
+	for _, lang := range registry {
+		if lang.Extension == extension {
+			return lang, true
+		}
+	}
+	return Language{}, false
+}
+
+// every registered language
+func All() []Language {
+	return append([]Language(nil), registry...)
+}
+
+// IDs of every registered language
+func IDs() []string {
+	ids := make([]string, len(registry))
+	for i, lang := range registry {
+		ids[i] = lang.ID
+	}
+	return ids
+}
+
+// makes the starting code for a solution with the given signature
+func (l Language) Template(signature models.Signature) string {
+	return l.template(signature)
+}
+
+// the starting code for a problem: the problem's own template, if it has one for the language, or else one made
+// from its signature
+func (l Language) ProblemTemplate(problem models.Problem) string {
+	if template, ok := problem.Templates[l.ID]; ok {
+		return template
+	}
+	return l.template(problem.Signature)
+}
+
+// builds a program that calls the code's solution once for each test case it's given on stdin
+func (l Language) Harness(code string, signature models.Signature) string {
+	return l.harness(code, signature)
+}
+
+// cleans up what a solution printed before it's judged
+func (l Language) NormalizeOutput(output string) string {
+	if l.normalize != nil {
+		return l.normalize(output)
+	}
+	// trims the trailing whitespace, such as the newline from a print statement
+	return strings.TrimRightFunc(output, unicode.IsSpace)
+}
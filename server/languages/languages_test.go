@@ -0,0 +1,62 @@
+package languages
+
+import (
+	"testing"
+
+	"github.com/webbben/code-duel/models"
+)
+
+func TestGet(t *testing.T) {
+	testCases := map[string]string{
+		"python": Python,
+		"py":     Python,
+		"sh":     Bash,
+		"c++":    Cpp,
+		"rust":   Rust,
+	}
+	for name, expected := range testCases {
+		lang, ok := Get(name)
+		if !ok || lang.ID != expected {
+			t.Errorf("%s: Result: [%s] Expected: [%s]", name, lang.ID, expected)
+		}
+	}
+	if _, ok := Get("cobol"); ok {
+		t.Error("Expected cobol not to be found")
+	}
+}
+
+func TestEveryLanguageIsComplete(t *testing.T) {
+	seen := map[string]bool{}
+	for _, lang := range All() {
+		for _, name := range append([]string{lang.ID, lang.Extension}, lang.Aliases...) {
+			if seen[name] {
+				t.Errorf("%s: %s is used by more than one language", lang.ID, name)
+			}
+			seen[name] = true
+		}
+		if found, ok := ByExtension(lang.Extension); !ok || found.ID != lang.ID {
+			t.Errorf("%s: not found by its extension %s", lang.ID, lang.Extension)
+		}
+		if lang.template == nil || lang.harness == nil || len(lang.Run) == 0 {
+			t.Errorf("%s: missing a template, harness or run command", lang.ID)
+		}
+		if lang.TimeFactor < 1 || lang.MemoryFactor < 1 {
+			t.Errorf("%s: limit factors %v and %v should be at least 1", lang.ID, lang.TimeFactor, lang.MemoryFactor)
+		}
+	}
+}
+
+func TestProblemTemplate(t *testing.T) {
+	problem := models.Problem{
+		Signature: models.Signature{Name: "solution", Params: []models.Param{{Name: "n", Type: models.Int}}, Returns: models.Int},
+		Templates: map[string]string{Python: "def solution(n):\n    pass\n"},
+	}
+	python, _ := Get(Python)
+	if template := python.ProblemTemplate(problem); template != problem.Templates[Python] {
+		t.Errorf("Result: [%s] Expected the problem's own template", template)
+	}
+	golang, _ := Get(Go)
+	if template := golang.ProblemTemplate(problem); template != golang.Template(problem.Signature) {
+		t.Errorf("Result: [%s] Expected the template made from the signature", template)
+	}
+}
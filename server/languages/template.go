@@ -0,0 +1,513 @@
+package languages
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// what the template asks the player to do with their answer
+func answerInstruction(returns models.Type) string {
+	if returns.Kind == models.KindVoid {
+		return "print your answer"
+	}
+	return "return your answer"
+}
+
+func pythonTemplate(signature models.Signature) string {
+	var template strings.Builder
+	template.WriteString("\n")
+	if signature.Uses(models.KindLinkedList) {
+		template.WriteString("# linked list nodes are defined for you:\n# class ListNode:\n#     def __init__(self, val=0, next=None):\n#         self.val = val\n#         self.next = next\n\n")
+	}
+	if signature.Uses(models.KindTree) {
+		template.WriteString("# tree nodes are defined for you:\n# class TreeNode:\n#     def __init__(self, val=0, left=None, right=None):\n#         self.val = val\n#         self.left = left\n#         self.right = right\n\n")
+	}
+	params := make([]string, len(signature.Params))
+	for i, param := range signature.Params {
+		params[i] = fmt.Sprintf("%s: %s", param.Name, pythonType(param.Type))
+	}
+	fmt.Fprintf(&template, "def %s(%s) -> %s:\n", signature.Name, strings.Join(params, ", "), pythonType(signature.Returns))
+	fmt.Fprintf(&template, "\t# write your solution here, and %s\n\tpass\n", answerInstruction(signature.Returns))
+	return template.String()
+}
+
+func pythonType(t models.Type) string {
+	switch t.Kind {
+	case models.KindInt:
+		return "int"
+	case models.KindFloat:
+		return "float"
+	case models.KindString:
+		return "str"
+	case models.KindBool:
+		return "bool"
+	case models.KindList:
+		return fmt.Sprintf("list[%s]", pythonType(*t.Elem))
+	case models.KindMap:
+		return fmt.Sprintf("dict[str, %s]", pythonType(*t.Elem))
+	case models.KindLinkedList:
+		return "ListNode"
+	case models.KindTree:
+		return "TreeNode"
+	default:
+		return "None"
+	}
+}
+
+func goTemplate(signature models.Signature) string {
+	var template strings.Builder
+	template.WriteString("\npackage main\n\n")
+	if signature.Returns.Kind == models.KindVoid {
+		template.WriteString("import \"fmt\"\n\n")
+	}
+	if elem := nodeElem(signature, models.KindLinkedList); elem != nil {
+		fmt.Fprintf(&template, "// linked list nodes are defined for you:\n// type ListNode struct {\n// \tVal  %s\n// \tNext *ListNode\n// }\n\n", goType(*elem))
+	}
+	if elem := nodeElem(signature, models.KindTree); elem != nil {
+		fmt.Fprintf(&template, "// tree nodes are defined for you:\n// type TreeNode struct {\n// \tVal   %s\n// \tLeft  *TreeNode\n// \tRight *TreeNode\n// }\n\n", goType(*elem))
+	}
+	params := make([]string, len(signature.Params))
+	for i, param := range signature.Params {
+		params[i] = fmt.Sprintf("%s %s", param.Name, goType(param.Type))
+	}
+	returns := ""
+	if signature.Returns.Kind != models.KindVoid {
+		returns = " " + goType(signature.Returns)
+	}
+	fmt.Fprintf(&template, "func %s(%s)%s {\n", signature.Name, strings.Join(params, ", "), returns)
+	fmt.Fprintf(&template, "\t// write your solution here, and %s\n", answerInstruction(signature.Returns))
+	// the template should compile as it is
+	if signature.Returns.Kind == models.KindVoid {
+		template.WriteString("\tfmt.Println()\n}\n")
+	} else {
+		fmt.Fprintf(&template, "\treturn %s\n}\n", goZero(signature.Returns))
+	}
+	return template.String()
+}
+
+func goType(t models.Type) string {
+	switch t.Kind {
+	case models.KindInt:
+		return "int"
+	case models.KindFloat:
+		return "float64"
+	case models.KindString:
+		return "string"
+	case models.KindBool:
+		return "bool"
+	case models.KindList:
+		return "[]" + goType(*t.Elem)
+	case models.KindMap:
+		return "map[string]" + goType(*t.Elem)
+	case models.KindLinkedList:
+		return "*ListNode"
+	case models.KindTree:
+		return "*TreeNode"
+	default:
+		return ""
+	}
+}
+
+func goZero(t models.Type) string {
+	switch t.Kind {
+	case models.KindInt, models.KindFloat:
+		return "0"
+	case models.KindString:
+		return "\"\""
+	case models.KindBool:
+		return "false"
+	default:
+		return "nil"
+	}
+}
+
+// the value type of the signature's linked list or tree nodes, or nil if it doesn't use them
+func nodeElem(signature models.Signature, kind models.TypeKind) *models.Type {
+	types := []models.Type{signature.Returns}
+	for _, param := range signature.Params {
+		types = append(types, param.Type)
+	}
+	for len(types) > 0 {
+		t := types[0]
+		types = types[1:]
+		if t.Kind == kind {
+			return t.Elem
+		}
+		if t.Elem != nil {
+			types = append(types, *t.Elem)
+		}
+	}
+	return nil
+}
+
+func bashTemplate(signature models.Signature) string {
+	var template strings.Builder
+	template.WriteString("\n")
+	collections := false
+	for _, param := range signature.Params {
+		collections = collections || param.Type.Elem != nil
+	}
+	if collections {
+		template.WriteString("# lists are given as values separated by spaces (one row per line for lists of lists),\n")
+		template.WriteString("# maps as one \"key value\" pair per line, and trees as their values in level order\n")
+	}
+	fmt.Fprintf(&template, "%s () {\n", signature.Name)
+	template.WriteString("\t# write your solution here, and echo your answer\n")
+	for i, param := range signature.Params {
+		fmt.Fprintf(&template, "\t%s=$%d\n", param.Name, i+1)
+	}
+	template.WriteString("}\n")
+	return template.String()
+}
+
+func javascriptTemplate(signature models.Signature) string {
+	var template strings.Builder
+	template.WriteString("\n")
+	if signature.Uses(models.KindLinkedList) {
+		template.WriteString("// linked list nodes are defined for you:\n// class ListNode {\n//     constructor(val = 0, next = null) {\n//         this.val = val;\n//         this.next = next;\n//     }\n// }\n\n")
+	}
+	if signature.Uses(models.KindTree) {
+		template.WriteString("// tree nodes are defined for you:\n// class TreeNode {\n//     constructor(val = 0, left = null, right = null) {\n//         this.val = val;\n//         this.left = left;\n//         this.right = right;\n//     }\n// }\n\n")
+	}
+	params := make([]string, len(signature.Params))
+	template.WriteString("/**\n")
+	for i, param := range signature.Params {
+		params[i] = param.Name
+		fmt.Fprintf(&template, " * @param {%s} %s\n", typescriptType(param.Type), param.Name)
+	}
+	if signature.Returns.Kind != models.KindVoid {
+		fmt.Fprintf(&template, " * @return {%s}\n", typescriptType(signature.Returns))
+	}
+	template.WriteString(" */\n")
+	fmt.Fprintf(&template, "function %s(%s) {\n", signature.Name, strings.Join(params, ", "))
+	fmt.Fprintf(&template, "\t// write your solution here, and %s\n}\n", answerInstruction(signature.Returns))
+	return template.String()
+}
+
+func typescriptTemplate(signature models.Signature) string {
+	var template strings.Builder
+	template.WriteString("\n")
+	if elem := nodeElem(signature, models.KindLinkedList); elem != nil {
+		fmt.Fprintf(&template, "// linked list nodes are defined for you:\n// class ListNode {\n//     val: %s;\n//     next: ListNode | null;\n//     constructor(val?: %[1]s, next?: ListNode | null);\n// }\n\n", typescriptType(*elem))
+	}
+	if elem := nodeElem(signature, models.KindTree); elem != nil {
+		fmt.Fprintf(&template, "// tree nodes are defined for you:\n// class TreeNode {\n//     val: %s;\n//     left: TreeNode | null;\n//     right: TreeNode | null;\n//     constructor(val?: %[1]s, left?: TreeNode | null, right?: TreeNode | null);\n// }\n\n", typescriptType(*elem))
+	}
+	params := make([]string, len(signature.Params))
+	for i, param := range signature.Params {
+		params[i] = fmt.Sprintf("%s: %s", param.Name, typescriptType(param.Type))
+	}
+	fmt.Fprintf(&template, "function %s(%s): %s {\n", signature.Name, strings.Join(params, ", "), typescriptType(signature.Returns))
+	fmt.Fprintf(&template, "\t// write your solution here, and %s\n", answerInstruction(signature.Returns))
+	if signature.Returns.Kind != models.KindVoid {
+		fmt.Fprintf(&template, "\treturn %s;\n", typescriptZero(signature.Returns))
+	}
+	template.WriteString("}\n")
+	return template.String()
+}
+
+// typescript types, which the javascript template also uses in its doc comment
+func typescriptType(t models.Type) string {
+	switch t.Kind {
+	case models.KindInt, models.KindFloat:
+		return "number"
+	case models.KindString:
+		return "string"
+	case models.KindBool:
+		return "boolean"
+	case models.KindList:
+		elem := typescriptType(*t.Elem)
+		if strings.Contains(elem, "|") {
+			// nodes can be null, and the union needs brackets around it to be an array's element type
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case models.KindMap:
+		return fmt.Sprintf("Record<string, %s>", typescriptType(*t.Elem))
+	case models.KindLinkedList:
+		return "ListNode | null"
+	case models.KindTree:
+		return "TreeNode | null"
+	default:
+		return "void"
+	}
+}
+
+func typescriptZero(t models.Type) string {
+	switch t.Kind {
+	case models.KindInt, models.KindFloat:
+		return "0"
+	case models.KindString:
+		return "\"\""
+	case models.KindBool:
+		return "false"
+	case models.KindList:
+		return "[]"
+	case models.KindMap:
+		return "{}"
+	default:
+		return "null"
+	}
+}
+
+func javaTemplate(signature models.Signature) string {
+	var template strings.Builder
+	template.WriteString("\nimport java.util.*;\n\n")
+	if elem := nodeElem(signature, models.KindLinkedList); elem != nil {
+		fmt.Fprintf(&template, "// linked list nodes are defined for you:\n// class ListNode {\n//     %s val;\n//     ListNode next;\n//     ListNode(%[1]s val, ListNode next);\n// }\n\n", javaType(*elem))
+	}
+	if elem := nodeElem(signature, models.KindTree); elem != nil {
+		fmt.Fprintf(&template, "// tree nodes are defined for you:\n// class TreeNode {\n//     %s val;\n//     TreeNode left;\n//     TreeNode right;\n//     TreeNode(%[1]s val, TreeNode left, TreeNode right);\n// }\n\n", javaType(*elem))
+	}
+	params := make([]string, len(signature.Params))
+	for i, param := range signature.Params {
+		params[i] = fmt.Sprintf("%s %s", javaType(param.Type), param.Name)
+	}
+	template.WriteString("class Solution {\n")
+	fmt.Fprintf(&template, "\tpublic %s %s(%s) {\n", javaType(signature.Returns), signature.Name, strings.Join(params, ", "))
+	fmt.Fprintf(&template, "\t\t// write your solution here, and %s\n", answerInstruction(signature.Returns))
+	if signature.Returns.Kind != models.KindVoid {
+		fmt.Fprintf(&template, "\t\treturn %s;\n", javaZero(signature.Returns))
+	}
+	template.WriteString("\t}\n}\n")
+	return template.String()
+}
+
+// java types. ints are longs, to hold the same values as the other languages' ints
+func javaType(t models.Type) string {
+	switch t.Kind {
+	case models.KindInt:
+		return "long"
+	case models.KindFloat:
+		return "double"
+	case models.KindString:
+		return "String"
+	case models.KindBool:
+		return "boolean"
+	case models.KindList:
+		return javaType(*t.Elem) + "[]"
+	case models.KindMap:
+		return fmt.Sprintf("Map<String, %s>", javaBoxedType(*t.Elem))
+	case models.KindLinkedList:
+		return "ListNode"
+	case models.KindTree:
+		return "TreeNode"
+	default:
+		return "void"
+	}
+}
+
+// the java type for values in generic collections, which can't be primitives
+func javaBoxedType(t models.Type) string {
+	switch t.Kind {
+	case models.KindInt:
+		return "Long"
+	case models.KindFloat:
+		return "Double"
+	case models.KindBool:
+		return "Boolean"
+	default:
+		return javaType(t)
+	}
+}
+
+func javaZero(t models.Type) string {
+	switch t.Kind {
+	case models.KindInt, models.KindFloat:
+		return "0"
+	case models.KindString:
+		return "\"\""
+	case models.KindBool:
+		return "false"
+	case models.KindList:
+		return fmt.Sprintf("new %s{}", javaType(t))
+	case models.KindMap:
+		return "new HashMap<>()"
+	default:
+		return "null"
+	}
+}
+
+func cppTemplate(signature models.Signature) string {
+	var template strings.Builder
+	template.WriteString("\n#include <bits/stdc++.h>\nusing namespace std;\n\n")
+	if elem := nodeElem(signature, models.KindLinkedList); elem != nil {
+		fmt.Fprintf(&template, "// linked list nodes are defined for you:\n// struct ListNode {\n//     %s val;\n//     ListNode *next;\n//     ListNode(%[1]s val, ListNode *next = nullptr);\n// };\n\n", cppType(*elem))
+	}
+	if elem := nodeElem(signature, models.KindTree); elem != nil {
+		fmt.Fprintf(&template, "// tree nodes are defined for you:\n// struct TreeNode {\n//     %s val;\n//     TreeNode *left;\n//     TreeNode *right;\n//     TreeNode(%[1]s val, TreeNode *left = nullptr, TreeNode *right = nullptr);\n// };\n\n", cppType(*elem))
+	}
+	params := make([]string, len(signature.Params))
+	for i, param := range signature.Params {
+		params[i] = fmt.Sprintf("%s %s", cppType(param.Type), param.Name)
+	}
+	fmt.Fprintf(&template, "%s %s(%s) {\n", cppType(signature.Returns), signature.Name, strings.Join(params, ", "))
+	fmt.Fprintf(&template, "\t// write your solution here, and %s\n", answerInstruction(signature.Returns))
+	if signature.Returns.Kind != models.KindVoid {
+		fmt.Fprintf(&template, "\treturn %s;\n", cppZero(signature.Returns))
+	}
+	template.WriteString("}\n")
+	return template.String()
+}
+
+// c++ types. ints are long longs, to hold the same values as the other languages' ints
+func cppType(t models.Type) string {
+	switch t.Kind {
+	case models.KindInt:
+		return "long long"
+	case models.KindFloat:
+		return "double"
+	case models.KindString:
+		return "string"
+	case models.KindBool:
+		return "bool"
+	case models.KindList:
+		return fmt.Sprintf("vector<%s>", cppType(*t.Elem))
+	case models.KindMap:
+		return fmt.Sprintf("map<string, %s>", cppType(*t.Elem))
+	case models.KindLinkedList:
+		return "ListNode*"
+	case models.KindTree:
+		return "TreeNode*"
+	default:
+		return "void"
+	}
+}
+
+func cppZero(t models.Type) string {
+	switch t.Kind {
+	case models.KindInt, models.KindFloat:
+		return "0"
+	case models.KindBool:
+		return "false"
+	case models.KindLinkedList, models.KindTree:
+		return "nullptr"
+	default:
+		return "{}"
+	}
+}
+
+func rustTemplate(signature models.Signature) string {
+	var template strings.Builder
+	template.WriteString("\n")
+	if signature.Uses(models.KindMap) {
+		template.WriteString("use std::collections::HashMap;\n")
+	}
+	if signature.Uses(models.KindTree) {
+		template.WriteString("use std::cell::RefCell;\nuse std::rc::Rc;\n")
+	}
+	if signature.Uses(models.KindMap) || signature.Uses(models.KindTree) {
+		template.WriteString("\n")
+	}
+	if elem := nodeElem(signature, models.KindLinkedList); elem != nil {
+		fmt.Fprintf(&template, "// linked list nodes are defined for you:\n// pub struct ListNode {\n//     pub val: %s,\n//     pub next: Option<Box<ListNode>>,\n// }\n// with ListNode::new(val)\n\n", rustType(*elem))
+	}
+	if elem := nodeElem(signature, models.KindTree); elem != nil {
+		fmt.Fprintf(&template, "// tree nodes are defined for you:\n// pub struct TreeNode {\n//     pub val: %s,\n//     pub left: Option<Rc<RefCell<TreeNode>>>,\n//     pub right: Option<Rc<RefCell<TreeNode>>>,\n// }\n// with TreeNode::new(val)\n\n", rustType(*elem))
+	}
+	params := make([]string, len(signature.Params))
+	for i, param := range signature.Params {
+		params[i] = fmt.Sprintf("%s: %s", param.Name, rustType(param.Type))
+	}
+	returns := ""
+	if signature.Returns.Kind != models.KindVoid {
+		returns = " -> " + rustType(signature.Returns)
+	}
+	fmt.Fprintf(&template, "fn %s(%s)%s {\n", signature.Name, strings.Join(params, ", "), returns)
+	fmt.Fprintf(&template, "\t// write your solution here, and %s\n", answerInstruction(signature.Returns))
+	if signature.Returns.Kind != models.KindVoid {
+		fmt.Fprintf(&template, "\t%s\n", rustZero(signature.Returns))
+	}
+	template.WriteString("}\n")
+	return template.String()
+}
+
+// rust types, with the same node types leetcode uses
+func rustType(t models.Type) string {
+	switch t.Kind {
+	case models.KindInt:
+		return "i64"
+	case models.KindFloat:
+		return "f64"
+	case models.KindString:
+		return "String"
+	case models.KindBool:
+		return "bool"
+	case models.KindList:
+		return fmt.Sprintf("Vec<%s>", rustType(*t.Elem))
+	case models.KindMap:
+		return fmt.Sprintf("HashMap<String, %s>", rustType(*t.Elem))
+	case models.KindLinkedList:
+		return "Option<Box<ListNode>>"
+	case models.KindTree:
+		return "Option<Rc<RefCell<TreeNode>>>"
+	default:
+		return "()"
+	}
+}
+
+func rustZero(t models.Type) string {
+	switch t.Kind {
+	case models.KindInt:
+		return "0"
+	case models.KindFloat:
+		return "0.0"
+	case models.KindString:
+		return "String::new()"
+	case models.KindBool:
+		return "false"
+	case models.KindList:
+		return "Vec::new()"
+	case models.KindMap:
+		return "HashMap::new()"
+	default:
+		return "None"
+	}
+}
+
+func rubyTemplate(signature models.Signature) string {
+	var template strings.Builder
+	template.WriteString("\n")
+	if signature.Uses(models.KindLinkedList) {
+		template.WriteString("# linked list nodes are defined for you:\n# class ListNode\n#   attr_accessor :val, :next\n#   def initialize(val = 0, next_node = nil)\n# end\n\n")
+	}
+	if signature.Uses(models.KindTree) {
+		template.WriteString("# tree nodes are defined for you:\n# class TreeNode\n#   attr_accessor :val, :left, :right\n#   def initialize(val = 0, left = nil, right = nil)\n# end\n\n")
+	}
+	params := make([]string, len(signature.Params))
+	for i, param := range signature.Params {
+		params[i] = param.Name
+		fmt.Fprintf(&template, "# @param {%s} %s\n", rubyType(param.Type), param.Name)
+	}
+	if signature.Returns.Kind != models.KindVoid {
+		fmt.Fprintf(&template, "# @return {%s}\n", rubyType(signature.Returns))
+	}
+	fmt.Fprintf(&template, "def %s(%s)\n", signature.Name, strings.Join(params, ", "))
+	fmt.Fprintf(&template, "\t# write your solution here, and %s\nend\n", answerInstruction(signature.Returns))
+	return template.String()
+}
+
+func rubyType(t models.Type) string {
+	switch t.Kind {
+	case models.KindInt:
+		return "Integer"
+	case models.KindFloat:
+		return "Float"
+	case models.KindString:
+		return "String"
+	case models.KindBool:
+		return "Boolean"
+	case models.KindList:
+		return rubyType(*t.Elem) + "[]"
+	case models.KindMap:
+		return fmt.Sprintf("Hash{String => %s}", rubyType(*t.Elem))
+	case models.KindLinkedList:
+		return "ListNode"
+	case models.KindTree:
+		return "TreeNode"
+	default:
+		return "nil"
+	}
+}
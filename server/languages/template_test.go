@@ -0,0 +1,149 @@
+package languages
+
+import (
+	"testing"
+
+	"github.com/webbben/code-duel/models"
+)
+
+func TestCodeTemplate(t *testing.T) {
+	signature := models.Signature{
+		Name: "mergeLists",
+		Params: []models.Param{
+			{Name: "lists", Type: models.ListOf(models.LinkedListOf(models.Int))},
+		},
+		Returns: models.LinkedListOf(models.Int),
+	}
+	testCases := map[string]string{
+		"py": `
+# linked list nodes are defined for you:
+# class ListNode:
+#     def __init__(self, val=0, next=None):
+#         self.val = val
+#         self.next = next
+
+def mergeLists(lists: list[ListNode]) -> ListNode:
+	# write your solution here, and return your answer
+	pass
+`,
+		"go": `
+package main
+
+// linked list nodes are defined for you:
+// type ListNode struct {
+// 	Val  int
+// 	Next *ListNode
+// }
+
+func mergeLists(lists []*ListNode) *ListNode {
+	// write your solution here, and return your answer
+	return nil
+}
+`,
+		"sh": `
+# lists are given as values separated by spaces (one row per line for lists of lists),
+# maps as one "key value" pair per line, and trees as their values in level order
+mergeLists () {
+	# write your solution here, and echo your answer
+	lists=$1
+}
+`,
+		"js": `
+// linked list nodes are defined for you:
+// class ListNode {
+//     constructor(val = 0, next = null) {
+//         this.val = val;
+//         this.next = next;
+//     }
+// }
+
+/**
+ * @param {(ListNode | null)[]} lists
+ * @return {ListNode | null}
+ */
+function mergeLists(lists) {
+	// write your solution here, and return your answer
+}
+`,
+		"typescript": `
+// linked list nodes are defined for you:
+// class ListNode {
+//     val: number;
+//     next: ListNode | null;
+//     constructor(val?: number, next?: ListNode | null);
+// }
+
+function mergeLists(lists: (ListNode | null)[]): ListNode | null {
+	// write your solution here, and return your answer
+	return null;
+}
+`,
+		"java": `
+import java.util.*;
+
+// linked list nodes are defined for you:
+// class ListNode {
+//     long val;
+//     ListNode next;
+//     ListNode(long val, ListNode next);
+// }
+
+class Solution {
+	public ListNode mergeLists(ListNode[] lists) {
+		// write your solution here, and return your answer
+		return null;
+	}
+}
+`,
+		"c++": `
+#include <bits/stdc++.h>
+using namespace std;
+
+// linked list nodes are defined for you:
+// struct ListNode {
+//     long long val;
+//     ListNode *next;
+//     ListNode(long long val, ListNode *next = nullptr);
+// };
+
+ListNode* mergeLists(vector<ListNode*> lists) {
+	// write your solution here, and return your answer
+	return nullptr;
+}
+`,
+		"rs": `
+// linked list nodes are defined for you:
+// pub struct ListNode {
+//     pub val: i64,
+//     pub next: Option<Box<ListNode>>,
+// }
+// with ListNode::new(val)
+
+fn mergeLists(lists: Vec<Option<Box<ListNode>>>) -> Option<Box<ListNode>> {
+	// write your solution here, and return your answer
+	None
+}
+`,
+		"rb": `
+# linked list nodes are defined for you:
+# class ListNode
+#   attr_accessor :val, :next
+#   def initialize(val = 0, next_node = nil)
+# end
+
+# @param {ListNode[]} lists
+# @return {ListNode}
+def mergeLists(lists)
+	# write your solution here, and return your answer
+end
+`,
+	}
+	for name, expected := range testCases {
+		lang, ok := Get(name)
+		if !ok {
+			t.Errorf("%s: language not found", name)
+		} else if template := lang.Template(signature); template != expected {
+			t.Errorf("%s: Result: [%s] Expected: [%s]", name, template, expected)
+		}
+	}
+}
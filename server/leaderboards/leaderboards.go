@@ -0,0 +1,205 @@
+// Package leaderboards ranks players: overall by rating or by games won, and on each problem by how quickly they
+// solved it in a game. every board can cover all time, or only the last week or month, and is split into pages.
+//
+// the boards are worked out from the game results saved when a game ends and the solves saved when a full
+// submission passes every test during a game.
+package leaderboards
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+)
+
+// how far back a board looks
+type Window string
+
+const (
+	AllTime   Window = "all"
+	LastWeek  Window = "week"  // the last 7 days
+	LastMonth Window = "month" // the last 30 days
+)
+
+// reads a window from a request. an empty window is all time
+func ParseWindow(s string) (Window, error) {
+	switch Window(s) {
+	case "", AllTime:
+		return AllTime, nil
+	case LastWeek, LastMonth:
+		return Window(s), nil
+	}
+	return "", fmt.Errorf("unknown window %q; use all, week or month", s)
+}
+
+// the start of the window, or the zero time for all time
+func (w Window) since(now time.Time) time.Time {
+	switch w {
+	case LastWeek:
+		return now.AddDate(0, 0, -7)
+	case LastMonth:
+		return now.AddDate(0, 0, -30)
+	}
+	return time.Time{}
+}
+
+const (
+	DefaultPageSize = 25
+	MaxPageSize     = 100
+	MaxPage         = 10000 // the last page number a request can ask for
+)
+
+// which page of a board to get. pages are numbered from 1
+type Page struct {
+	Number int
+	Size   int
+}
+
+// a player's place on a board
+type Entry struct {
+	Rank   int     `json:"rank"` // players with the same score share a rank
+	User   string  `json:"user"`
+	Score  float64 `json:"score"`            // what the board ranks by
+	Games  int     `json:"games,omitempty"`  // games played in the window, on the rating and wins boards
+	Lang   string  `json:"lang,omitempty"`   // language of the solve, on the problem boards
+	GameID string  `json:"gameID,omitempty"` // game of the solve, on the problem boards
+}
+
+// one page of a board
+type Board struct {
+	Window   Window  `json:"window"`
+	Page     int     `json:"page"`
+	PageSize int     `json:"pageSize"`
+	Total    int     `json:"total"` // how many players are on the board, across every page
+	Entries  []Entry `json:"entries"`
+}
+
+// ranks players by rating. over all time, the score is their current rating, and in the shorter windows, it's how
+// much their rating went up (or down) in that time
+func ByRating(ctx context.Context, window Window, page Page) (Board, error) {
+	var entries []Entry
+	if window == AllTime {
+		ratings, err := storage.Get().ListRatings(ctx)
+		if err != nil {
+			return Board{}, err
+		}
+		for _, rating := range ratings {
+			entries = append(entries, Entry{User: rating.User, Score: rating.Rating, Games: rating.Games})
+		}
+	} else {
+		changes, err := storage.Get().ListRatingChangesSince(ctx, window.since(time.Now()))
+		if err != nil {
+			return Board{}, err
+		}
+		byUser := map[string]*Entry{}
+		for _, change := range changes {
+			entry := byUser[change.User]
+			if entry == nil {
+				entry = &Entry{User: change.User}
+				byUser[change.User] = entry
+			}
+			entry.Score += change.Change
+			entry.Games++
+		}
+		for _, entry := range byUser {
+			entries = append(entries, *entry)
+		}
+	}
+	rank(entries, true)
+	return paginate(entries, window, page), nil
+}
+
+// ranks players by how many games they won. everyone who played a game in the window is on the board
+func ByWins(ctx context.Context, window Window, page Page) (Board, error) {
+	results, err := storage.Get().ListGameResults(ctx, window.since(time.Now()))
+	if err != nil {
+		return Board{}, err
+	}
+	byUser := map[string]*Entry{}
+	for _, result := range results {
+		for _, user := range result.Players {
+			entry := byUser[user]
+			if entry == nil {
+				entry = &Entry{User: user}
+				byUser[user] = entry
+			}
+			entry.Games++
+			if user == result.Winner {
+				entry.Score++
+			}
+		}
+	}
+	entries := make([]Entry, 0, len(byUser))
+	for _, entry := range byUser {
+		entries = append(entries, *entry)
+	}
+	rank(entries, true)
+	return paginate(entries, window, page), nil
+}
+
+// ranks players by their fastest solve of a problem: how many milliseconds into a game they sent a full submission
+// that passed every test. if lang is set, only solves in that language count
+func ByProblem(ctx context.Context, problemID string, lang string, window Window, page Page) (Board, error) {
+	solves, err := storage.Get().ListSolves(ctx, problemID, window.since(time.Now()))
+	if err != nil {
+		return Board{}, err
+	}
+	best := map[string]models.Solve{}
+	for _, solve := range solves {
+		if lang != "" && solve.Lang != lang {
+			continue
+		}
+		current, ok := best[solve.User]
+		if !ok || solve.SolveTime < current.SolveTime || (solve.SolveTime == current.SolveTime && solve.SolvedAt.Before(current.SolvedAt)) {
+			best[solve.User] = solve
+		}
+	}
+	entries := make([]Entry, 0, len(best))
+	for _, solve := range best {
+		entries = append(entries, Entry{User: solve.User, Score: float64(solve.SolveTime), Lang: solve.Lang, GameID: solve.GameID})
+	}
+	rank(entries, false)
+	return paginate(entries, window, page), nil
+}
+
+// sorts the entries best first, and ranks them. players with the same score share a rank, and are listed by name
+func rank(entries []Entry, highestFirst bool) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Score != entries[j].Score {
+			return (entries[i].Score > entries[j].Score) == highestFirst
+		}
+		return entries[i].User < entries[j].User
+	})
+	for i := range entries {
+		if i > 0 && entries[i].Score == entries[i-1].Score {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+}
+
+// cuts out the requested page of the ranked entries
+func paginate(entries []Entry, window Window, page Page) Board {
+	if page.Size <= 0 {
+		page.Size = DefaultPageSize
+	}
+	page.Size = min(page.Size, MaxPageSize)
+	page.Number = max(page.Number, 1)
+	// a page past the end is empty. the check comes before multiplying, so a huge page number can't overflow
+	start := len(entries)
+	if page.Number-1 < len(entries)/page.Size+1 {
+		start = min((page.Number-1)*page.Size, len(entries))
+	}
+	end := min(start+page.Size, len(entries))
+	return Board{
+		Window:   window,
+		Page:     page.Number,
+		PageSize: page.Size,
+		Total:    len(entries),
+		Entries:  append([]Entry{}, entries[start:end]...),
+	}
+}
@@ -0,0 +1,99 @@
+package leaderboards
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+)
+
+// the users on a board, in order, and their ranks
+func standings(board Board) ([]string, []int) {
+	var users []string
+	var ranks []int
+	for _, entry := range board.Entries {
+		users = append(users, entry.User)
+		ranks = append(ranks, entry.Rank)
+	}
+	return users, ranks
+}
+
+func TestBoards(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	storage.Set(store)
+	now := time.Now()
+	lastMonth := now.AddDate(0, 0, -20)
+
+	store.SaveGameResult(ctx, models.GameResult{GameID: "g1", Winner: "ann", Players: []string{"ann", "bob"}, EndedAt: lastMonth})
+	store.SaveGameResult(ctx, models.GameResult{GameID: "g2", Winner: "ann", Players: []string{"ann", "cat"}, EndedAt: lastMonth})
+	store.SaveGameResult(ctx, models.GameResult{GameID: "g3", Winner: "bob", Players: []string{"bob", "cat"}, EndedAt: now})
+	store.SaveGameResult(ctx, models.GameResult{GameID: "g4", Winner: "cat", Players: []string{"bob", "cat", "dan"}, EndedAt: now})
+
+	board, err := ByWins(ctx, AllTime, Page{})
+	users, ranks := standings(board)
+	if err != nil || board.Total != 4 || len(users) != 4 || users[0] != "ann" || users[3] != "dan" || ranks[1] != 2 || ranks[2] != 2 || ranks[3] != 4 {
+		t.Errorf("wins of all time: %v %v (%v)", users, ranks, err)
+	}
+	board, _ = ByWins(ctx, LastWeek, Page{})
+	users, ranks = standings(board)
+	if len(users) != 3 || users[0] != "bob" || users[1] != "cat" || ranks[1] != 1 || board.Entries[0].Games != 2 {
+		t.Errorf("wins this week: %+v", board.Entries)
+	}
+
+	// pages
+	board, _ = ByWins(ctx, AllTime, Page{Number: 2, Size: 3})
+	if users, _ := standings(board); board.Total != 4 || len(users) != 1 || users[0] != "dan" {
+		t.Errorf("second page of wins: %+v", board)
+	}
+	if board, _ := ByWins(ctx, AllTime, Page{Number: 3, Size: 3}); len(board.Entries) != 0 {
+		t.Errorf("page past the end: %+v", board)
+	}
+	// big enough that working out where it starts would overflow
+	if board, _ := ByWins(ctx, AllTime, Page{Number: math.MaxInt, Size: MaxPageSize}); len(board.Entries) != 0 || board.Total != 4 {
+		t.Errorf("page far past the end: %+v", board)
+	}
+
+	store.SaveRating(ctx, models.PlayerRating{User: "ann", Rating: 1600, Games: 2}, models.RatingChange{User: "ann", Change: 100, At: lastMonth})
+	store.SaveRating(ctx, models.PlayerRating{User: "bob", Rating: 1550, Games: 3}, models.RatingChange{User: "bob", Change: -20, At: lastMonth})
+	store.SaveRating(ctx, models.PlayerRating{User: "bob", Rating: 1550, Games: 3}, models.RatingChange{User: "bob", Change: 70, At: now})
+	board, _ = ByRating(ctx, AllTime, Page{})
+	if users, _ := standings(board); len(users) != 2 || users[0] != "ann" || board.Entries[0].Score != 1600 {
+		t.Errorf("rating of all time: %+v", board.Entries)
+	}
+	board, _ = ByRating(ctx, LastMonth, Page{})
+	if users, _ := standings(board); len(users) != 2 || users[0] != "ann" || board.Entries[1].Score != 50 || board.Entries[1].Games != 2 {
+		t.Errorf("rating gained this month: %+v", board.Entries)
+	}
+	board, _ = ByRating(ctx, LastWeek, Page{})
+	if users, _ := standings(board); len(users) != 1 || users[0] != "bob" {
+		t.Errorf("rating gained this week: %+v", board.Entries)
+	}
+
+	store.SaveSolve(ctx, models.Solve{User: "ann", ProblemID: "p1", Lang: "python", SolveTime: 90000, SolvedAt: lastMonth})
+	store.SaveSolve(ctx, models.Solve{User: "ann", ProblemID: "p1", Lang: "go", SolveTime: 60000, SolvedAt: now})
+	store.SaveSolve(ctx, models.Solve{User: "bob", ProblemID: "p1", Lang: "python", SolveTime: 75000, SolvedAt: now})
+	store.SaveSolve(ctx, models.Solve{User: "cat", ProblemID: "p2", Lang: "python", SolveTime: 1000, SolvedAt: now})
+	board, _ = ByProblem(ctx, "p1", "", AllTime, Page{})
+	if users, _ := standings(board); len(users) != 2 || users[0] != "ann" || board.Entries[0].Lang != "go" || board.Entries[0].Score != 60000 {
+		t.Errorf("fastest solves of p1: %+v", board.Entries)
+	}
+	board, _ = ByProblem(ctx, "p1", "python", AllTime, Page{})
+	if users, _ := standings(board); len(users) != 2 || users[0] != "bob" || board.Entries[1].Score != 90000 {
+		t.Errorf("fastest python solves of p1: %+v", board.Entries)
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	for input, expected := range map[string]Window{"": AllTime, "all": AllTime, "week": LastWeek, "month": LastMonth} {
+		if window, err := ParseWindow(input); err != nil || window != expected {
+			t.Errorf("ParseWindow(%q): %q (%v), expected %q", input, window, err, expected)
+		}
+	}
+	if _, err := ParseWindow("year"); err == nil {
+		t.Error("ParseWindow should reject unknown windows")
+	}
+}
@@ -2,28 +2,41 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/webbben/code-duel/firebase"
-	"github.com/webbben/code-duel/firebase/rooms"
+	"github.com/webbben/code-duel/auth"
+	"github.com/webbben/code-duel/config"
 	authHandlers "github.com/webbben/code-duel/handlers/auth"
 	"github.com/webbben/code-duel/handlers/code"
+	leaderboardHandlers "github.com/webbben/code-duel/handlers/leaderboard"
 	problem_handlers "github.com/webbben/code-duel/handlers/problem"
 	roomHandlers "github.com/webbben/code-duel/handlers/room"
+	submissionHandlers "github.com/webbben/code-duel/handlers/submission"
 	userHandlers "github.com/webbben/code-duel/handlers/user"
 	"github.com/webbben/code-duel/handlers/websocket"
 	"github.com/webbben/code-duel/middleware"
+	problemData "github.com/webbben/code-duel/problem_data"
+	"github.com/webbben/code-duel/rooms"
+	"github.com/webbben/code-duel/storage"
 )
 
 // functions
 
 func main() {
-	// initialize firebase
-	_ = firebase.GetFirestoreClient()
+	// open the configured store, so a misconfigured one is found straight away
+	_ = storage.Get()
+	// and the configured auth provider
+	_ = auth.Get()
+	// load the problems written as problem directories
+	loadProblems()
+	// rooms can only be scored by what the code executor measures
+	websocket.SetScoringModes(code.ScoringModes())
 	// launch task schedule goroutine
 	go scheduledJobs()
 
@@ -42,10 +55,19 @@ func main() {
 
 	// auth API
 	router.HandleFunc("/verifyToken", authHandlers.VerifyTokenHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/auth/login", authHandlers.LoginHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/auth/refresh", authHandlers.RefreshHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/auth/logout", authHandlers.LogoutHandler).Methods("POST", "OPTIONS")
 
 	// user API
 	router.HandleFunc("/users/{id}", userHandlers.GetUserHandler).Methods("GET", "OPTIONS") // TODO
 	router.HandleFunc("/users", userHandlers.CreateUserHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/ratings/{username}", userHandlers.GetRatingHandler).Methods("GET", "OPTIONS")
+
+	// leaderboard API
+	router.HandleFunc("/leaderboards/rating", leaderboardHandlers.GetRatingLeaderboardHandler).Methods("GET", "OPTIONS")
+	router.HandleFunc("/leaderboards/wins", leaderboardHandlers.GetWinsLeaderboardHandler).Methods("GET", "OPTIONS")
+	router.HandleFunc("/leaderboards/problems/{id}", leaderboardHandlers.GetProblemLeaderboardHandler).Methods("GET", "OPTIONS")
 
 	// room API
 	protectedRouter.HandleFunc("/rooms", roomHandlers.CreateRoomHandler).Methods("POST", "OPTIONS")
@@ -62,9 +84,16 @@ func main() {
 	router.HandleFunc("/problems", problem_handlers.GetProblemListHandler).Methods("GET", "OPTIONS")
 
 	// submit code API
+	router.HandleFunc("/languages", code.HandleGetLanguages).Methods("GET", "OPTIONS")
 	protectedRouter.HandleFunc("/testCode", code.HandleTestCode).Methods("POST", "OPTIONS")
 	protectedRouter.HandleFunc("/submitCode", code.HandleSubmitCode).Methods("POST", "OPTIONS")
 
+	// submission history API
+	protectedRouter.HandleFunc("/submissions", submissionHandlers.GetSubmissionsHandler).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/submissions/{id}", submissionHandlers.GetSubmissionHandler).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/games/{id}/submissions", submissionHandlers.GetGameSubmissionsHandler).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/rooms/{id}/games/{gameID}/review", submissionHandlers.GetGameReviewHandler).Methods("GET", "OPTIONS")
+
 	// websocket communication
 	router.HandleFunc("/ws", websocket.HandleWebSocketConnection)
 
@@ -79,6 +108,18 @@ func YourHandlerFunction(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintln(w, "Hello, your Go server is up and running!")
 }
 
+// loads the problem directories in the configured problems directory
+func loadProblems() {
+	dir := config.Get().ProblemsDir
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		log.Printf("no problems directory at %s; only the built in problems are available\n", dir)
+		return
+	}
+	if err := problemData.LoadProblems(dir); err != nil {
+		log.Printf("failed to load some problems from %s:\n%v\n", dir, err)
+	}
+}
+
 // place to register periodic jobs such as automated database cleanup, misc maintenance, etc.
 func scheduledJobs() {
 	ticker := time.NewTicker(time.Hour)
@@ -100,15 +141,15 @@ func scheduledJobs() {
 
 // cleans up any empty rooms that haven't been closed yet
 func cleanupEmptyRooms() {
-	rooms, err := rooms.GetRooms()
+	roomList, err := rooms.GetRooms()
 	if err != nil {
 		log.Printf("error during room cleanup: failed to get rooms data; %v\n", err)
 		return
 	}
 	delCount := 0
-	for _, room := range rooms {
+	for _, room := range roomList {
 		if len(room.Users) == 0 {
-			_, err := firebase.DeleteDocument("rooms", room.ID)
+			err := rooms.DeleteRoom(room.ID)
 			if err != nil {
 				log.Printf("error during room cleanup: failed to delete room %s; %v\n", room.ID, err)
 			} else {
@@ -118,7 +159,7 @@ func cleanupEmptyRooms() {
 		}
 		// check that each room actually has client connections still, and aren't orphaned w/ incorrect user counts
 		if !websocket.RoomHasClients(room.ID) {
-			_, err := firebase.DeleteDocument("rooms", room.ID)
+			err := rooms.DeleteRoom(room.ID)
 			if err != nil {
 				log.Printf("error during room cleanup: failed to delete room %s; %v\n", room.ID, err)
 			} else {
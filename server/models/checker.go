@@ -0,0 +1,14 @@
+package models
+
+// decides whether a solution's answer to a test case is correct. values are in the form Type.Encode gives them:
+// args are the test case's arguments in parameter order, and output is the solution's answer
+type Checker interface {
+	Check(args []any, expected any, output any) bool
+}
+
+// a function that checks answers, for problems that need a check of their own
+type CheckerFunc func(args []any, expected any, output any) bool
+
+func (f CheckerFunc) Check(args []any, expected any, output any) bool {
+	return f(args, expected, output)
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Users of the app
 type User struct {
 	ID       string `json:"id"`
@@ -14,6 +16,32 @@ type CreateUserRequest struct {
 	Password string `json:"password"`
 }
 
+// API request for logging in with the local auth provider
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// API request for refreshing or ending a session with the local auth provider
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// a user's email and password, for the local auth provider
+type Credentials struct {
+	UserID       string `json:"userID"`
+	Email        string `json:"email"`        // lowercased, so logins aren't case sensitive
+	PasswordHash string `json:"passwordHash"` // bcrypt hash of the password
+}
+
+// a refresh token handed out by the local auth provider. only a hash of the token is kept, so the stored
+// sessions can't be used to log in
+type Session struct {
+	ID        string    `json:"id"` // SHA-256 hash of the refresh token, hex encoded
+	UserID    string    `json:"userID"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
 // API request for creating room
 type CreateRoomRequest struct {
 	Title       string `json:"title"`       // title of the room
@@ -38,6 +66,124 @@ type Room struct {
 	TimeLimit     int      `json:"TimeLimit"`     // time limit to solve the problem
 	RandomProblem bool     `json:"RandomProblem"` // whether its a random problem (true) or user selects it (false)
 	Problem       string   `json:"Problem"`       // ID of the problem to solve in game
+	ScoringMode   string   `json:"ScoringMode"`   // how the winner is decided; one of the Scoring modes (first to solve if empty)
+}
+
+// ways a game's winner can be decided
+const (
+	ScoringFirstToSolve = "first"   // the first player to pass every test case wins
+	ScoringRuntime      = "runtime" // of the players who pass every test case, the one whose solution ran fastest in total wins
+	ScoringMemory       = "memory"  // of the players who pass every test case, the one whose solution used the least memory wins
+)
+
+// whether a room can use the scoring mode
+func ValidScoringMode(mode string) bool {
+	return mode == ScoringFirstToSolve || mode == ScoringRuntime || mode == ScoringMemory
+}
+
+// code a user sent to be tested or submitted, and how it did
+type Submission struct {
+	ID           string           `json:"id" firestore:"-"` // set by the store when it's saved
+	User         string           `json:"user"`             // username of the user who sent it
+	RoomID       string           `json:"roomID"`           // room it was sent from
+	GameID       string           `json:"gameID"`           // game it was sent during, if the room was in game
+	ProblemID    string           `json:"problemID"`
+	Lang         string           `json:"lang"` // canonical ID of the language it's written in
+	Code         string           `json:"code"`
+	FullTest     bool             `json:"fullTest"` // whether it was a full submission, or only a test against the sample cases
+	PassCount    int              `json:"passCount"`
+	TestCount    int              `json:"testCount"`
+	ErrorMessage string           `json:"errorMessage"` // describes the first failure, if there was one
+	Cases        []SubmissionCase `json:"cases"`        // verdict of each test case that was run
+	SubmittedAt  time.Time        `json:"submittedAt"`  // when it was received
+	FinishedAt   time.Time        `json:"finishedAt"`   // when its tests finished running
+}
+
+// milliseconds from startedAt, the start of its game, until the submission was received. this is the solve time
+// of a submission that passed every test, both in game reviews and on the leaderboards
+func (s Submission) SolveTime(startedAt time.Time) int64 {
+	return s.SubmittedAt.Sub(startedAt).Milliseconds()
+}
+
+// the verdict and resource usage of a submission on one test case
+type SubmissionCase struct {
+	Case    int    `json:"case"` // index of the test case
+	Verdict string `json:"verdict"`
+	Runtime int64  `json:"runtime"` // in milliseconds
+	Memory  int64  `json:"memory"`  // peak memory usage in kilobytes, if known
+	Hidden  bool   `json:"hidden"`
+}
+
+// how every player did in a finished game, for reviewing their solutions side by side
+type GameReview struct {
+	GameID    string         `json:"gameID"`
+	RoomID    string         `json:"roomID"`
+	ProblemID string         `json:"problemID"`
+	Winner    string         `json:"winner"`
+	StartedAt time.Time      `json:"startedAt"`
+	EndedAt   time.Time      `json:"endedAt"`
+	Players   []PlayerReview `json:"players"`
+}
+
+// a player's final submission in a game. players who never sent anything have no code or verdicts
+type PlayerReview struct {
+	User         string           `json:"user"`
+	SubmissionID string           `json:"submissionID,omitempty"`
+	Lang         string           `json:"lang,omitempty"`
+	Code         string           `json:"code"`
+	PassCount    int              `json:"passCount"`
+	TestCount    int              `json:"testCount"`
+	Cases        []SubmissionCase `json:"cases"`
+	Solved       bool             `json:"solved"`    // whether any of their submissions passed every test
+	SolveTime    int64            `json:"solveTime"` // of their first solving submission (see Submission.SolveTime)
+	// how to get from the reference solution in the same language to the player's code (see diff.Lines). empty if
+	// the problem has no reference solution in that language
+	ReferenceDiff string `json:"referenceDiff,omitempty"`
+}
+
+// a player's Glicko-2 rating
+type PlayerRating struct {
+	User       string    `json:"user"`
+	Rating     float64   `json:"rating"`
+	RD         float64   `json:"rd"`         // rating deviation; how unsure the rating is
+	Volatility float64   `json:"volatility"` // how much the player's results tend to swing
+	Games      int       `json:"games"`      // how many rated games they've played
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// how a game changed a player's rating. a player's rating history is every change to their rating
+type RatingChange struct {
+	User    string    `json:"user"`
+	GameID  string    `json:"gameID"`
+	Place   int       `json:"place"`   // where they finished; players who did equally well share a place
+	Players int       `json:"players"` // how many players were rated in the game
+	Before  float64   `json:"before"`
+	After   float64   `json:"after"`
+	Change  float64   `json:"change"` // After - Before
+	RD      float64   `json:"rd"`     // rating deviation after the game
+	At      time.Time `json:"at"`
+}
+
+// who played in a finished game and who won it, for the leaderboards
+type GameResult struct {
+	GameID    string    `json:"gameID"`
+	RoomID    string    `json:"roomID"`
+	ProblemID string    `json:"problemID"`
+	Winner    string    `json:"winner"`
+	Players   []string  `json:"players"`
+	EndedAt   time.Time `json:"endedAt"`
+}
+
+// a full submission that passed every test during a game, for the problem leaderboards
+type Solve struct {
+	User         string    `json:"user"`
+	ProblemID    string    `json:"problemID"`
+	GameID       string    `json:"gameID"`
+	SubmissionID string    `json:"submissionID"`
+	Lang         string    `json:"lang"`
+	SolveTime    int64     `json:"solveTime"` // milliseconds from the start of the game until it was sent
+	Runtime      int64     `json:"runtime"`   // total runtime of its test cases, in milliseconds
+	SolvedAt     time.Time `json:"solvedAt"`
 }
 
 type ProblemOverview struct {
@@ -47,18 +193,28 @@ type ProblemOverview struct {
 	QuickDesc  string `json:"quickDesc"`
 }
 
-// getter functions for a problem
-type ProblemFunc struct {
-	GetTemplate func(string) string `json:"-"`
-}
-
 type Problem struct {
 	ProblemOverview
-	ProblemFunc
-	FullDesc  string     `json:"fullDesc"`
-	TestCases []TestCase `json:"testCases"`
-	FullCases []TestCase `json:"-"`
-	CaseCount int        `json:"caseCount"`
+	Signature Signature         `json:"signature"` // the solution function players write; code templates are made from it
+	Checker   Checker           `json:"-"`         // decides whether answers are correct. if nil, they must match exactly
+	FullDesc  string            `json:"fullDesc"`
+	TestCases []TestCase        `json:"testCases"`
+	FullCases []TestCase        `json:"-"`
+	CaseCount int               `json:"caseCount"`
+	Templates map[string]string `json:"-"` // starting code by language, used instead of the templates made from the signature
+	Solutions map[string]string `json:"-"` // reference solutions by language, to check the problem can be solved
+	Generate  CaseGenerator     `json:"-"` // makes random test cases, for stress testing. nil if the problem has no generator
+	// limits for running a solution on each test case, before they're scaled for the solution's language.
+	// 0 leaves the executor's own limit in place
+	TimeLimit   int `json:"timeLimit"`   // in milliseconds
+	MemoryLimit int `json:"memoryLimit"` // in megabytes
 }
 
+// a test case's input and expected output. solutions that take more than one argument are given an Args as input
 type TestCase []any
+
+// the arguments for a test case, keyed by parameter name
+type Args map[string]any
+
+// makes count random test cases. the same seed always gives the same cases
+type CaseGenerator func(seed int64, count int) []TestCase
@@ -0,0 +1,284 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// kinds of values a problem's solution function can take or return
+type TypeKind string
+
+const (
+	KindInt        TypeKind = "int"
+	KindFloat      TypeKind = "float"
+	KindString     TypeKind = "string"
+	KindBool       TypeKind = "bool"
+	KindList       TypeKind = "list"
+	KindMap        TypeKind = "map"        // maps always have string keys
+	KindLinkedList TypeKind = "linkedList" // written as a list of its values
+	KindTree       TypeKind = "tree"       // binary tree, written as a list of its values in level order, with null for missing nodes
+	KindVoid       TypeKind = "void"       // only for return types: the solution prints its answer instead of returning it
+)
+
+// the type of a value passed to or returned from a solution
+type Type struct {
+	Kind TypeKind `json:"kind"`
+	Elem *Type    `json:"elem,omitempty"` // type of the elements of a list, linked list or tree, or the values of a map
+}
+
+var (
+	Int    = Type{Kind: KindInt}
+	Float  = Type{Kind: KindFloat}
+	String = Type{Kind: KindString}
+	Bool   = Type{Kind: KindBool}
+	Void   = Type{Kind: KindVoid}
+)
+
+func ListOf(elem Type) Type {
+	return Type{Kind: KindList, Elem: &elem}
+}
+
+func MapOf(value Type) Type {
+	return Type{Kind: KindMap, Elem: &value}
+}
+
+func LinkedListOf(elem Type) Type {
+	return Type{Kind: KindLinkedList, Elem: &elem}
+}
+
+func TreeOf(elem Type) Type {
+	return Type{Kind: KindTree, Elem: &elem}
+}
+
+func (t Type) String() string {
+	if t.Elem == nil {
+		return string(t.Kind)
+	}
+	return fmt.Sprintf("%s<%s>", t.Kind, t.Elem)
+}
+
+// reads a type written the way String writes it, like list<int> or map<list<string>>
+func ParseType(s string) (Type, error) {
+	s = strings.TrimSpace(s)
+	kind, elem, hasElem := strings.Cut(s, "<")
+	t := Type{Kind: TypeKind(strings.TrimSpace(kind))}
+	if hasElem {
+		if !strings.HasSuffix(elem, ">") {
+			return Type{}, fmt.Errorf("type %s is missing a closing >", s)
+		}
+		elemType, err := ParseType(strings.TrimSuffix(elem, ">"))
+		if err != nil {
+			return Type{}, err
+		}
+		t.Elem = &elemType
+	}
+	if err := t.validate(); err != nil {
+		return Type{}, err
+	}
+	return t, nil
+}
+
+// a named parameter of a solution
+type Param struct {
+	Name string `json:"name"`
+	Type Type   `json:"type"`
+}
+
+// the function players write to solve a problem
+type Signature struct {
+	Name    string  `json:"name"`
+	Params  []Param `json:"params"`
+	Returns Type    `json:"returns"`
+}
+
+// converts a value to the form it takes in JSON (float64, string, bool, nil, []any or map[string]any),
+// checking that it has this type
+func (t Type) Encode(value any) (any, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var decoded any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, err
+	}
+	if err := t.check(decoded); err != nil {
+		return nil, fmt.Errorf("%s is not a valid %s: %w", encoded, t, err)
+	}
+	return decoded, nil
+}
+
+// checks that a decoded JSON value has this type
+func (t Type) check(value any) error {
+	switch t.Kind {
+	case KindInt:
+		if n, ok := value.(float64); !ok || n != math.Trunc(n) {
+			return fmt.Errorf("expected an integer")
+		}
+	case KindFloat:
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("expected a number")
+		}
+	case KindString:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("expected a string")
+		}
+	case KindBool:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("expected a boolean")
+		}
+	case KindList, KindLinkedList, KindTree:
+		elements, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("expected a list")
+		}
+		for i, element := range elements {
+			if element == nil && t.Kind == KindTree {
+				continue // a missing node
+			}
+			if err := t.Elem.check(element); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+	case KindMap:
+		entries, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("expected a map")
+		}
+		for key, entry := range entries {
+			if err := t.Elem.check(entry); err != nil {
+				return fmt.Errorf("key %s: %w", key, err)
+			}
+		}
+	case KindVoid:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("expected the printed output as a string")
+		}
+	default:
+		return fmt.Errorf("unknown type %s", t.Kind)
+	}
+	return nil
+}
+
+// checks that the signature is well formed
+func (s Signature) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("signature has no name")
+	}
+	for _, param := range s.Params {
+		if param.Name == "" {
+			return fmt.Errorf("parameter with no name")
+		}
+		if param.Type.Kind == KindVoid {
+			return fmt.Errorf("parameter %s can't be void", param.Name)
+		}
+		if err := param.Type.validate(); err != nil {
+			return fmt.Errorf("parameter %s: %w", param.Name, err)
+		}
+	}
+	if err := s.Returns.validate(); err != nil {
+		return fmt.Errorf("return type: %w", err)
+	}
+	// linked list and tree nodes are declared once per language, so they need the same value type everywhere
+	nodeElems := map[TypeKind]string{}
+	types := []Type{s.Returns}
+	for _, param := range s.Params {
+		types = append(types, param.Type)
+	}
+	for _, t := range types {
+		for _, node := range t.nodeTypes() {
+			if elem, ok := nodeElems[node.Kind]; ok && elem != node.Elem.String() {
+				return fmt.Errorf("all %s values need the same element type", node.Kind)
+			}
+			nodeElems[node.Kind] = node.Elem.String()
+		}
+	}
+	return nil
+}
+
+func (t Type) validate() error {
+	switch t.Kind {
+	case KindInt, KindFloat, KindString, KindBool, KindVoid:
+		if t.Elem != nil {
+			return fmt.Errorf("%s can't have an element type", t.Kind)
+		}
+		return nil
+	case KindList, KindMap, KindLinkedList, KindTree:
+		if t.Elem == nil {
+			return fmt.Errorf("%s needs an element type", t.Kind)
+		}
+		if t.Elem.Kind == KindVoid {
+			return fmt.Errorf("%s can't have void elements", t.Kind)
+		}
+		if (t.Kind == KindLinkedList || t.Kind == KindTree) && t.Elem.Elem != nil {
+			return fmt.Errorf("%s values must be int, float, string or bool", t.Kind)
+		}
+		return t.Elem.validate()
+	default:
+		return fmt.Errorf("unknown type %s", t.Kind)
+	}
+}
+
+// the linked list and tree types used in this type, including itself
+func (t Type) nodeTypes() []Type {
+	var nodes []Type
+	if t.Kind == KindLinkedList || t.Kind == KindTree {
+		nodes = append(nodes, t)
+	}
+	if t.Elem != nil {
+		nodes = append(nodes, t.Elem.nodeTypes()...)
+	}
+	return nodes
+}
+
+// whether this type, or any type inside it, is of the given kind
+func (t Type) Uses(kind TypeKind) bool {
+	return t.Kind == kind || (t.Elem != nil && t.Elem.Uses(kind))
+}
+
+// whether any of the signature's types are of the given kind
+func (s Signature) Uses(kind TypeKind) bool {
+	if s.Returns.Uses(kind) {
+		return true
+	}
+	for _, param := range s.Params {
+		if param.Type.Uses(kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// the arguments to call the solution with for a test case, in parameter order
+func (s Signature) Args(testCase TestCase) ([]any, error) {
+	if len(testCase) != 2 {
+		return nil, fmt.Errorf("test case should have an input and an expected output, but has %d values", len(testCase))
+	}
+	args, ok := testCase[0].(Args)
+	if !ok {
+		// a single argument can be given as it is
+		if len(s.Params) != 1 {
+			return nil, fmt.Errorf("%s takes %d arguments, so its test case input should be an Args", s.Name, len(s.Params))
+		}
+		return []any{testCase[0]}, nil
+	}
+	values := make([]any, len(s.Params))
+	for i, param := range s.Params {
+		value, ok := args[param.Name]
+		if !ok {
+			return nil, fmt.Errorf("test case has no argument for %s", param.Name)
+		}
+		values[i] = value
+	}
+	if len(args) != len(s.Params) {
+		for name := range args {
+			if !slices.ContainsFunc(s.Params, func(param Param) bool { return param.Name == name }) {
+				return nil, fmt.Errorf("test case has an argument for %s, which isn't a parameter of %s", name, s.Name)
+			}
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,289 @@
+package problemData
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/webbben/code-duel/checkers"
+	"github.com/webbben/code-duel/languages"
+	"github.com/webbben/code-duel/models"
+	"gopkg.in/yaml.v3"
+)
+
+/*
+ * Problems can also be written as directories of files, which are loaded when the server starts, so adding one
+ * doesn't need the server to be rebuilt. A problem directory has:
+ *
+ *   problem.yaml (or problem.json)  the manifest: the problem's metadata, limits, signature and checker
+ *   description.md                  the full description, in markdown
+ *   tests.yaml (or tests.json)      the test cases: samples that are shown to players, and hidden ones
+ *   templates/                      optional starting code for any language, replacing the generated template
+ *   solutions/                      reference solutions, to check the problem can be solved
+ *
+ * Templates and solutions are recognized by their file extension, like solution.py or solution.go.
+ */
+
+// what a problem's manifest file says about it
+type problemManifest struct {
+	ID          string `yaml:"id" json:"id"` // defaults to the name of the problem's directory
+	Name        string `yaml:"name" json:"name"`
+	Difficulty  int    `yaml:"difficulty" json:"difficulty"`
+	QuickDesc   string `yaml:"quickDesc" json:"quickDesc"`
+	Description string `yaml:"description" json:"description"` // file with the full description; defaults to description.md
+	Tests       string `yaml:"tests" json:"tests"`             // file with the test cases; defaults to tests.yaml or tests.json
+	TimeLimit   int    `yaml:"timeLimit" json:"timeLimit"`     // for each test case, in milliseconds
+	MemoryLimit int    `yaml:"memoryLimit" json:"memoryLimit"` // in megabytes
+	Signature   struct {
+		Name   string `yaml:"name" json:"name"`
+		Params []struct {
+			Name string `yaml:"name" json:"name"`
+			Type string `yaml:"type" json:"type"` // written like list<int>
+		} `yaml:"params" json:"params"`
+		Returns string `yaml:"returns" json:"returns"`
+	} `yaml:"signature" json:"signature"`
+	Checker struct {
+		Kind     string  `yaml:"kind" json:"kind"` // exact (the default), tokens, float, unordered or set
+		Absolute float64 `yaml:"absolute" json:"absolute"`
+		Relative float64 `yaml:"relative" json:"relative"`
+	} `yaml:"checker" json:"checker"`
+}
+
+// a problem's test cases file
+type problemTests struct {
+	Samples []problemTest `yaml:"samples" json:"samples"` // shown to players
+	Hidden  []problemTest `yaml:"hidden" json:"hidden"`   // only run for full submissions
+}
+
+// a test case, as written in a tests file. if the solution takes more than one argument, the input is a
+// mapping of parameter names to values
+type problemTest struct {
+	Input  any `yaml:"input" json:"input"`
+	Output any `yaml:"output" json:"output"`
+}
+
+// loads every problem directory in dir and adds the problems to the problem map.
+// problems that fail to load are left out, and their errors are returned together
+func LoadProblems(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		problem, err := LoadProblem(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("problem %s: %w", entry.Name(), err))
+			continue
+		}
+		if _, exists := problemMap[problem.ID]; exists {
+			errs = append(errs, fmt.Errorf("problem %s: there's already a problem with ID %s", entry.Name(), problem.ID))
+			continue
+		}
+		problemMap[problem.ID] = problem
+	}
+	return errors.Join(errs...)
+}
+
+// loads the problem in a problem directory
+func LoadProblem(dir string) (models.Problem, error) {
+	var manifest problemManifest
+	if err := readDataFile(dir, []string{"problem.yaml", "problem.json"}, &manifest); err != nil {
+		return models.Problem{}, err
+	}
+	if manifest.ID == "" {
+		manifest.ID = filepath.Base(dir)
+	}
+	problem := models.Problem{
+		ProblemOverview: models.ProblemOverview{
+			ID:         manifest.ID,
+			Name:       manifest.Name,
+			Difficulty: manifest.Difficulty,
+			QuickDesc:  manifest.QuickDesc,
+		},
+		TimeLimit:   manifest.TimeLimit,
+		MemoryLimit: manifest.MemoryLimit,
+	}
+
+	// signature and checker
+	problem.Signature.Name = manifest.Signature.Name
+	for _, param := range manifest.Signature.Params {
+		t, err := models.ParseType(param.Type)
+		if err != nil {
+			return models.Problem{}, fmt.Errorf("parameter %s: %w", param.Name, err)
+		}
+		problem.Signature.Params = append(problem.Signature.Params, models.Param{Name: param.Name, Type: t})
+	}
+	returns, err := models.ParseType(manifest.Signature.Returns)
+	if err != nil {
+		return models.Problem{}, fmt.Errorf("return type: %w", err)
+	}
+	problem.Signature.Returns = returns
+	switch manifest.Checker.Kind {
+	case "", "exact":
+	case "tokens":
+		problem.Checker = checkers.Tokens()
+	case "float":
+		problem.Checker = checkers.Float(manifest.Checker.Absolute, manifest.Checker.Relative)
+	case "unordered":
+		problem.Checker = checkers.Unordered()
+	case "set":
+		problem.Checker = checkers.Set()
+	default:
+		return models.Problem{}, fmt.Errorf("unknown checker %s", manifest.Checker.Kind)
+	}
+
+	// description
+	if manifest.Description == "" {
+		manifest.Description = "description.md"
+	}
+	description, err := os.ReadFile(filepath.Join(dir, manifest.Description))
+	if err != nil {
+		return models.Problem{}, err
+	}
+	problem.FullDesc = strings.TrimSpace(string(description))
+
+	// test cases
+	testFiles := []string{"tests.yaml", "tests.json"}
+	if manifest.Tests != "" {
+		testFiles = []string{manifest.Tests}
+	}
+	var tests problemTests
+	if err := readDataFile(dir, testFiles, &tests); err != nil {
+		return models.Problem{}, err
+	}
+	if problem.TestCases, err = toTestCases(problem.Signature, tests.Samples); err != nil {
+		return models.Problem{}, fmt.Errorf("samples: %w", err)
+	}
+	if problem.FullCases, err = toTestCases(problem.Signature, tests.Hidden); err != nil {
+		return models.Problem{}, fmt.Errorf("hidden tests: %w", err)
+	}
+	problem.CaseCount = len(problem.TestCases) + len(problem.FullCases)
+
+	// templates and reference solutions
+	if problem.Templates, err = readCodeFiles(filepath.Join(dir, "templates")); err != nil {
+		return models.Problem{}, err
+	}
+	if problem.Solutions, err = readCodeFiles(filepath.Join(dir, "solutions")); err != nil {
+		return models.Problem{}, err
+	}
+
+	if err := ValidateProblem(problem); err != nil {
+		return models.Problem{}, err
+	}
+	return problem, nil
+}
+
+// reads the first of the given files in dir that exists, as YAML or JSON depending on its extension
+func readDataFile(dir string, names []string, value any) error {
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if filepath.Ext(name) == ".json" {
+			err = json.Unmarshal(data, value)
+		} else {
+			err = yaml.Unmarshal(data, value)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("no %s found", strings.Join(names, " or "))
+}
+
+// turns tests from a tests file into test cases
+func toTestCases(signature models.Signature, tests []problemTest) ([]models.TestCase, error) {
+	testCases := make([]models.TestCase, len(tests))
+	for i, test := range tests {
+		input := test.Input
+		if len(signature.Params) != 1 {
+			args, ok := test.Input.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("test %d: %s takes %d arguments, so the input should map parameter names to values", i+1, signature.Name, len(signature.Params))
+			}
+			input = models.Args(args)
+		}
+		testCases[i] = models.TestCase{input, test.Output}
+	}
+	return testCases, nil
+}
+
+// reads the code files in a directory, keyed by their language. a directory that doesn't exist has none
+func readCodeFiles(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	for _, entry := range entries {
+		lang, ok := languages.ByExtension(filepath.Ext(entry.Name()))
+		if entry.IsDir() || !ok {
+			continue
+		}
+		if _, exists := files[lang.ID]; exists {
+			return nil, fmt.Errorf("%s has more than one %s file", dir, lang.ID)
+		}
+		code, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files[lang.ID] = string(code)
+	}
+	return files, nil
+}
+
+// checks that a problem's signature is well formed, its test cases match the types it declares and are
+// accepted by its checker, and its templates and solutions are keyed by the IDs of known languages
+func ValidateProblem(problem models.Problem) error {
+	if err := problem.Signature.Validate(); err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	if problem.TimeLimit < 0 || problem.MemoryLimit < 0 {
+		return fmt.Errorf("negative time or memory limit")
+	}
+	for _, code := range []map[string]string{problem.Templates, problem.Solutions} {
+		for id := range code {
+			if lang, ok := languages.Get(id); !ok || lang.ID != id {
+				return fmt.Errorf("template or solution for unknown language %s", id)
+			}
+		}
+	}
+	testCases := append(append([]models.TestCase{}, problem.TestCases...), problem.FullCases...)
+	for i, testCase := range testCases {
+		args, err := problem.Signature.Args(testCase)
+		if err != nil {
+			return fmt.Errorf("case %d: %w", i, err)
+		}
+		encodedArgs := make([]any, len(args))
+		for j, arg := range args {
+			param := problem.Signature.Params[j]
+			if encodedArgs[j], err = param.Type.Encode(arg); err != nil {
+				return fmt.Errorf("case %d: argument %s: %w", i, param.Name, err)
+			}
+		}
+		expected, err := problem.Signature.Returns.Encode(testCase[1])
+		if err != nil {
+			return fmt.Errorf("case %d: expected output: %w", i, err)
+		}
+		// the expected output should always be accepted as an answer
+		if problem.Checker != nil && !problem.Checker.Check(encodedArgs, expected, expected) {
+			return fmt.Errorf("case %d: checker rejects the expected output", i)
+		}
+	}
+	return nil
+}
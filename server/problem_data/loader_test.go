@@ -0,0 +1,119 @@
+package problemData
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// writes files into a new problem directory
+func writeProblemDir(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadProblem(t *testing.T) {
+	dir := writeProblemDir(t, map[string]string{
+		"problem.yaml":        "id: sum\nname: Sum\ndifficulty: 2\ntimeLimit: 500\nmemoryLimit: 64\nsignature:\n  name: add\n  params:\n    - {name: a, type: int}\n    - {name: b, type: int}\n  returns: int\n",
+		"description.md":      "Add *a* and *b*.\n",
+		"tests.yaml":          "samples:\n  - {input: {a: 1, b: 2}, output: 3}\nhidden:\n  - {input: {a: -1, b: 1}, output: 0}\n",
+		"templates/start.py":  "def add(a, b):\n    pass\n",
+		"solutions/add.py":    "def add(a, b):\n    return a + b\n",
+		"solutions/add.rs":    "fn add(a: i64, b: i64) -> i64 {\n    a + b\n}\n",
+		"solutions/README.md": "not a solution",
+	})
+	problem, err := LoadProblem(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problem.ID != "sum" || problem.Name != "Sum" || problem.Difficulty != 2 || problem.FullDesc != "Add *a* and *b*." {
+		t.Errorf("Loaded overview: %+v, description %q", problem.ProblemOverview, problem.FullDesc)
+	}
+	if len(problem.TestCases) != 1 || len(problem.FullCases) != 1 || problem.CaseCount != 2 {
+		t.Errorf("Loaded %d samples and %d hidden tests; Expected 1 and 1", len(problem.TestCases), len(problem.FullCases))
+	}
+	if args, ok := problem.TestCases[0][0].(models.Args); !ok || args["b"] != 2 {
+		t.Errorf("Loaded input: %#v; Expected an Args", problem.TestCases[0][0])
+	}
+	if problem.Templates["python"] == "" || len(problem.Solutions) != 2 || problem.Solutions["python"] == "" || problem.Solutions["rust"] == "" {
+		t.Errorf("Loaded templates %v and solutions %v", problem.Templates, problem.Solutions)
+	}
+	if problem.Checker != nil {
+		t.Error("Expected no checker, for exact answers")
+	}
+	if problem.TimeLimit != 500 || problem.MemoryLimit != 64 {
+		t.Errorf("Loaded limits %dms and %dMB; Expected 500ms and 64MB", problem.TimeLimit, problem.MemoryLimit)
+	}
+}
+
+func TestLoadProblemFromJSON(t *testing.T) {
+	dir := writeProblemDir(t, map[string]string{
+		"problem.json":   `{"name": "Halve", "signature": {"name": "solution", "params": [{"name": "n", "type": "int"}], "returns": "float"}, "checker": {"kind": "float", "absolute": 0.01}}`,
+		"description.md": "Halve n.",
+		"tests.json":     `{"samples": [{"input": 3, "output": 1.5}]}`,
+	})
+	problem, err := LoadProblem(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problem.ID != filepath.Base(dir) {
+		t.Errorf("Result: [%s] Expected: [%s], the directory name", problem.ID, filepath.Base(dir))
+	}
+	if problem.Checker == nil || !problem.Checker.Check(nil, 1.5, 1.505) {
+		t.Error("Expected a float checker")
+	}
+}
+
+func TestLoadProblemErrors(t *testing.T) {
+	manifest := "name: Broken\nsignature:\n  name: solution\n  params:\n    - {name: nums, type: list<int>}\n  returns: int\n"
+	testCases := map[string]map[string]string{
+		"no manifest": {"description.md": "", "tests.yaml": ""},
+		"unknown type": {
+			"problem.yaml":   strings.Replace(manifest, "list<int>", "list<integer>", 1),
+			"description.md": "", "tests.yaml": "",
+		},
+		"unknown checker": {
+			"problem.yaml":   manifest + "checker: {kind: fuzzy}\n",
+			"description.md": "", "tests.yaml": "",
+		},
+		"no description": {"problem.yaml": manifest, "tests.yaml": ""},
+		"wrong test type": {
+			"problem.yaml":   manifest,
+			"description.md": "",
+			"tests.yaml":     "samples:\n  - {input: [1, 2], output: nope}\n",
+		},
+	}
+	for name, files := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadProblem(writeProblemDir(t, files)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+// the problem directories that ship with the server should all load
+func TestLoadProblemsDir(t *testing.T) {
+	dir := filepath.Join("..", "..", "problems")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if _, err := LoadProblem(filepath.Join(dir, entry.Name())); err != nil {
+			t.Errorf("%s: %s", entry.Name(), err)
+		}
+	}
+}
@@ -1,8 +1,6 @@
 package problem_01
 
 import (
-	"reflect"
-
 	"github.com/webbben/code-duel/models"
 )
 
@@ -13,6 +11,11 @@ var problem = models.Problem{
 		Difficulty: 1,
 		QuickDesc:  "First one to print the given text to stdout wins!",
 	},
+	Signature: models.Signature{
+		Name:    "solution",
+		Params:  []models.Param{{Name: "text", Type: models.String}},
+		Returns: models.Void,
+	},
 	FullDesc: "Seriously - it's just printing some text to the console. Don't overthink it.",
 	TestCases: []models.TestCase{
 		{"Hello world!", "Hello world!"},
@@ -21,13 +24,10 @@ var problem = models.Problem{
 	},
 	FullCases: []models.TestCase{
 		{"abcdefghijklmnopqrstuvwxyz", "abcdefghijklmnopqrstuvwxyz"},
-		{1234567890, "1234567890"},
+		{"1234567890", "1234567890"},
 		{"abcdefghijklmnopqrstuvwxyzABCDEFGHIJ", "abcdefghijklmnopqrstuvwxyzABCDEFGHIJ"},
 		{"ok do we really need to write test cases for this...", "ok do we really need to write test cases for this..."},
-		{1.1, "1.1"},
-	},
-	ProblemFunc: models.ProblemFunc{
-		GetTemplate: GetProblemTemplate,
+		{"1.1", "1.1"},
 	},
 }
 
@@ -37,61 +37,11 @@ func GetOverview() models.ProblemOverview {
 
 func GetProblem() models.Problem {
 	problem.CaseCount = len(problem.TestCases) + len(problem.FullCases)
+	problem.Solutions = solutions
+	problem.Generate = generator.Cases
 	return problem
 }
 
-/*
- * ====================================================================
- * Code Templates
- * Define a code template for each of the supported languages below
- * ====================================================================
- */
-
-var goTemplate = `
-package main
-
-import "fmt"
-
-func main() {
-	// don't change this or your code may not compile correctly!
-	solution(%s)
-}
-
-func solution(input any) {
-	// write your solution here
-}
-`
-
-var pythonTemplate = `
-def solution(input):
-	# write your solution here
-
-# don't change this or your code may not compile correctly!
-solution(%s)
-`
-
-var bashTemplate = `
-solution () {
-	# write your solution here
-}
-
-# don't change this or your code may not compile correctly!
-solution %s
-`
-
-func GetProblemTemplate(lang string) string {
-	switch lang {
-	case "go":
-		return goTemplate
-	case "py":
-		return pythonTemplate
-	case "sh":
-		return bashTemplate
-	default:
-		return ""
-	}
-}
-
 /*
  * ====================================================================
  * Sample Solution
@@ -100,12 +50,6 @@ func GetProblemTemplate(lang string) string {
  * ====================================================================
  */
 
-func sampleSolution(input any) string {
-	if reflect.TypeOf(input).String() == "bool" {
-		if input == true {
-			return "true"
-		}
-		return "false"
-	}
-	return input.(string)
+func sampleSolution(text string) string {
+	return text
 }
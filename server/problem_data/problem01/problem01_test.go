@@ -12,7 +12,7 @@ func TestSampleSolution(t *testing.T) {
 	for i, testCase := range testCases {
 		t.Run(fmt.Sprintf("problem01_case_%d", i), func(t *testing.T) {
 			input, expOut := testCase[0], testCase[1]
-			output := sampleSolution(input)
+			output := sampleSolution(input.(string))
 			if output != expOut {
 				t.Errorf("Sample solution output: [%s] Expected: [%s]", output, expOut)
 			}
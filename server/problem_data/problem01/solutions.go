@@ -0,0 +1,61 @@
+package problem_01
+
+import "github.com/webbben/code-duel/languages"
+
+// reference solutions in each language, which cmd/problemcheck runs against the test cases
+var solutions = map[string]string{
+	languages.Python: `
+def solution(text: str) -> None:
+	print(text)
+`,
+	languages.Go: `
+package main
+
+import "fmt"
+
+func solution(text string) {
+	fmt.Println(text)
+}
+`,
+	languages.Bash: `
+solution () {
+	text=$1
+	printf '%s\n' "$text"
+}
+`,
+	languages.JavaScript: `
+function solution(text) {
+	console.log(text);
+}
+`,
+	languages.TypeScript: `
+function solution(text: string): void {
+	console.log(text);
+}
+`,
+	languages.Java: `
+class Solution {
+	public void solution(String text) {
+		System.out.println(text);
+	}
+}
+`,
+	languages.Cpp: `
+#include <bits/stdc++.h>
+using namespace std;
+
+void solution(string text) {
+	cout << text << endl;
+}
+`,
+	languages.Rust: `
+fn solution(text: String) {
+	println!("{}", text);
+}
+`,
+	languages.Ruby: `
+def solution(text)
+	puts text
+end
+`,
+}
@@ -0,0 +1,19 @@
+package problem_01
+
+import (
+	"strings"
+
+	"github.com/webbben/code-duel/problem_data/stress"
+)
+
+// random inputs for stress testing. printed output has its trailing whitespace trimmed, so the text can't end
+// with any
+var generator = stress.Generator[string, string]{
+	Solution: sampleSolution,
+	Inputs: []stress.Gen[string]{
+		stress.Map(stress.String(stress.Sizes(0, 100), "abcXYZ 0123456789 !?%$\\'\"`"), func(text string) string {
+			return strings.TrimRight(text, " ")
+		}),
+		stress.Pick("%s", "%d %v", "$HOME", "\\n", "-n", "'quoted'", "\"quoted\"", "héllo wörld"),
+	},
+}
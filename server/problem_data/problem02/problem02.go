@@ -13,13 +13,19 @@ var problem = models.Problem{
 		Difficulty: 2,
 		QuickDesc:  "Given a spread of stock prices (in chronological order), return the maximum possible profit.",
 	},
+	Signature: models.Signature{
+		Name:    "solution",
+		Params:  []models.Param{{Name: "stockPrices", Type: models.ListOf(models.Int)}},
+		Returns: models.Int,
+	},
 	FullDesc: "You're given a list of stock prices which are ordered chronologically: item at index n represents the stock price at day n. Assume you are allowed to purchase one share of the stock once, and then sell that share of the stock later. This means: If you purchase a stock on day=n, you may only sell it on day>n. Return the maximum possible profit, or 0 if no profit is possible.",
 	TestCases: []models.TestCase{
 		{[]int{1, 2, 3, 4, 5}, 4},
 		{[]int{5, 4, 3, 2, 1}, 0},
 		{[]int{6, 2, 3, 8, 1}, 6},
 	},
-	FullCases: []models.TestCase{
+	// hidden cases are the hand written ones, and some generated ones (see stress.go)
+	FullCases: append([]models.TestCase{
 		{[]int{}, 0},
 		{[]int{1}, 0},
 		{[]int{7, 1, 5, 3, 6, 4}, 5},
@@ -32,10 +38,7 @@ var problem = models.Problem{
 		{[]int{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, 0},
 		{[]int{1, 2, 3, 2, 3, 4, 3, 4, 5}, 4},
 		{[]int{3, 2, 1, 4, 5, 6, 7, 8}, 7},
-	},
-	ProblemFunc: models.ProblemFunc{
-		GetTemplate: GetProblemTemplate,
-	},
+	}, generator.Cases(1, 20)...),
 }
 
 func GetOverview() models.ProblemOverview {
@@ -44,63 +47,11 @@ func GetOverview() models.ProblemOverview {
 
 func GetProblem() models.Problem {
 	problem.CaseCount = len(problem.TestCases) + len(problem.FullCases)
+	problem.Solutions = solutions
+	problem.Generate = generator.Cases
 	return problem
 }
 
-/*
- * ====================================================================
- * Code Templates
- * Define a code template for each of the supported languages below
- * ====================================================================
- */
-
-var pythonTemplate = `
-def solution(stockPrices):
-	# write your solution here
-
-# don't change this or your code may not run correctly!
-print(solution(%s))
-`
-
-var goTemplate = `
-package main
-
-import "fmt"
-
-func main() {
-	// don't change this or your code may not run correctly!
-	fmt.Print(solution(%s))
-}
-
-func solution(stockPrices []int) int {
-	// write your solution here
-}
-`
-
-var bashTemplate = `
-solution () {
-	# write your solution here
-	stockPrices=$1
-}
-
-# don't change this or your code may not run correctly!
-output=solution %s
-echo $output
-`
-
-func GetProblemTemplate(lang string) string {
-	switch lang {
-	case "go":
-		return goTemplate
-	case "py":
-		return pythonTemplate
-	case "sh":
-		return bashTemplate
-	default:
-		return ""
-	}
-}
-
 /*
  * ====================================================================
  * Sample Solution
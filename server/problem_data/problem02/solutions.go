@@ -0,0 +1,46 @@
+package problem_02
+
+import "github.com/webbben/code-duel/languages"
+
+// reference solutions in each language, which cmd/problemcheck runs against the test cases
+var solutions = map[string]string{
+	languages.Python: `
+def solution(stockPrices: list[int]) -> int:
+	best, lowest = 0, None
+	for price in stockPrices:
+		if lowest is None or price < lowest:
+			lowest = price
+		best = max(best, price - lowest)
+	return best
+`,
+	languages.Go: `
+package main
+
+func solution(stockPrices []int) int {
+	best := 0
+	for i, price := range stockPrices {
+		if i > 0 {
+			stockPrices[i] = min(price, stockPrices[i-1]) // lowest price so far
+		}
+		best = max(best, price-stockPrices[i])
+	}
+	return best
+}
+`,
+	languages.Bash: `
+solution () {
+	stockPrices=$1
+	best=0
+	lowest=
+	for price in $stockPrices; do
+		if [ -z "$lowest" ] || [ "$price" -lt "$lowest" ]; then
+			lowest=$price
+		fi
+		if [ $((price - lowest)) -gt "$best" ]; then
+			best=$((price - lowest))
+		fi
+	done
+	echo "$best"
+}
+`,
+}
@@ -0,0 +1,18 @@
+package problem_02
+
+import (
+	"github.com/webbben/code-duel/problem_data/stress"
+)
+
+// random inputs for stress testing
+var generator = stress.Generator[[]int, int]{
+	Solution: sampleSolution,
+	Inputs: []stress.Gen[[]int]{
+		stress.Slice(stress.Sizes(0, 200), stress.Int(0, 10000)),
+		// prices that only go up or only go down
+		stress.Sorted(stress.Slice(stress.Sizes(0, 200), stress.Int(0, 10000))),
+		stress.Reversed(stress.Sorted(stress.Slice(stress.Sizes(0, 200), stress.Int(0, 10000)))),
+		// lots of repeated prices
+		stress.Slice(stress.Sizes(0, 200), stress.Int(0, 3)),
+	},
+}
@@ -0,0 +1,49 @@
+package problem_03
+
+import "github.com/webbben/code-duel/languages"
+
+// reference solutions in each language, which cmd/problemcheck runs against the test cases
+var solutions = map[string]string{
+	languages.Python: `
+def solution(nums: list[int]) -> int:
+	# Boyer-Moore majority vote
+	candidate, count = None, 0
+	for num in nums:
+		if count == 0:
+			candidate = num
+		count += 1 if num == candidate else -1
+	return candidate
+`,
+	languages.Go: `
+package main
+
+func solution(nums []int) int {
+	counts := map[int]int{}
+	for _, num := range nums {
+		counts[num]++
+		if counts[num] > len(nums)/2 {
+			return num
+		}
+	}
+	return 0
+}
+`,
+	languages.Bash: `
+solution () {
+	nums=$1
+	candidate=
+	count=0
+	for num in $nums; do
+		if [ "$count" -eq 0 ]; then
+			candidate=$num
+		fi
+		if [ "$num" -eq "$candidate" ]; then
+			count=$((count + 1))
+		else
+			count=$((count - 1))
+		fi
+	done
+	echo "$candidate"
+}
+`,
+}
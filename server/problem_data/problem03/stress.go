@@ -0,0 +1,39 @@
+package problem_03
+
+import (
+	"math/rand"
+
+	"github.com/webbben/code-duel/problem_data/stress"
+)
+
+// random inputs for stress testing
+var generator = stress.Generator[[]int, int]{
+	Solution: sampleSolution,
+	Inputs: []stress.Gen[[]int]{
+		majorityNums(stress.Int(-100, 100)),
+		// only just a majority, out of two values
+		majorityNums(stress.Int(0, 1)),
+	},
+}
+
+// lists with a majority element, made of values from gen
+func majorityNums(gen stress.Gen[int]) stress.Gen[[]int] {
+	return func(r *rand.Rand) []int {
+		n := stress.Sizes(1, 199)(r)
+		majority := gen(r)
+		count := n/2 + 1 + r.Intn((n+1)/2)
+		nums := make([]int, 0, n)
+		for len(nums) < count {
+			nums = append(nums, majority)
+		}
+		for len(nums) < n {
+			if num := gen(r); num != majority {
+				nums = append(nums, num)
+			} else {
+				nums = append(nums, majority+1)
+			}
+		}
+		r.Shuffle(n, func(i, j int) { nums[i], nums[j] = nums[j], nums[i] })
+		return nums
+	}
+}
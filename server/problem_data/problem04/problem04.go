@@ -14,6 +14,11 @@ var problem = models.Problem{
 		Difficulty: 1,
 		QuickDesc:  "Given a string, determine if it is a palindrome.",
 	},
+	Signature: models.Signature{
+		Name:    "solution",
+		Params:  []models.Param{{Name: "s", Type: models.String}},
+		Returns: models.Bool,
+	},
 	FullDesc: "Given a string, determine if it is a palindrome. A palindrome is a string that is written the same both frontwards and backwards.\nInput strings may contain punctuation, spaces, etc, but we only want to consider the alphanumeric characters in the given string, and should ignore letter cases.",
 	TestCases: []models.TestCase{
 		{"racecar", true},
@@ -34,9 +39,6 @@ var problem = models.Problem{
 		{"1234567890987654321", true},
 		{"1.2345 = 54.321", true},
 	},
-	ProblemFunc: models.ProblemFunc{
-		GetTemplate: GetProblemTemplate,
-	},
 }
 
 func GetOverview() models.ProblemOverview {
@@ -45,63 +47,11 @@ func GetOverview() models.ProblemOverview {
 
 func GetProblem() models.Problem {
 	problem.CaseCount = len(problem.TestCases) + len(problem.FullCases)
+	problem.Solutions = solutions
+	problem.Generate = generator.Cases
 	return problem
 }
 
-/*
- * ====================================================================
- * Code Templates
- * Define a code template for each of the supported languages below
- * ====================================================================
- */
-
-var pythonTemplate = `
-def solution(s: str):
-	# write your solution here
-
-# don't change this or your code may not run correctly!
-print(solution(%s))
-`
-
-var goTemplate = `
-package main
-
-import "fmt"
-
-func main() {
-	// don't change this or your code may not run correctly!
-	fmt.Print(solution(%s))
-}
-
-func solution(s string) bool {
-	// write your solution here
-}
-`
-
-var bashTemplate = `
-solution () {
-	# write your solution here
-	s=$1
-}
-
-# don't change this or your code may not run correctly!
-output=solution %s
-echo $output
-`
-
-func GetProblemTemplate(lang string) string {
-	switch lang {
-	case "go":
-		return goTemplate
-	case "py":
-		return pythonTemplate
-	case "sh":
-		return bashTemplate
-	default:
-		return ""
-	}
-}
-
 /*
  * ====================================================================
  * Sample Solution
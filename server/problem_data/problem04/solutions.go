@@ -0,0 +1,94 @@
+package problem_04
+
+import "github.com/webbben/code-duel/languages"
+
+// reference solutions in each language, which cmd/problemcheck runs against the test cases
+var solutions = map[string]string{
+	languages.Python: `
+def solution(s: str) -> bool:
+	chars = [c.lower() for c in s if c.isalnum()]
+	return chars == chars[::-1]
+`,
+	languages.Go: `
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+func solution(s string) bool {
+	var chars []rune
+	for _, c := range strings.ToLower(s) {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			chars = append(chars, c)
+		}
+	}
+	for i, j := 0, len(chars)-1; i < j; i, j = i+1, j-1 {
+		if chars[i] != chars[j] {
+			return false
+		}
+	}
+	return true
+}
+`,
+	languages.Bash: `
+solution () {
+	s=$1
+	chars=$(printf '%s' "$s" | tr -cd '[:alnum:]' | tr '[:upper:]' '[:lower:]')
+	for ((i = 0, j = ${#chars} - 1; i < j; i++, j--)); do
+		if [ "${chars:i:1}" != "${chars:j:1}" ]; then
+			echo false
+			return
+		fi
+	done
+	echo true
+}
+`,
+	languages.JavaScript: `
+function solution(s) {
+	const chars = s.toLowerCase().replace(/[^a-z0-9]/g, "");
+	return chars === [...chars].reverse().join("");
+}
+`,
+	languages.TypeScript: `
+function solution(s: string): boolean {
+	const chars = s.toLowerCase().replace(/[^a-z0-9]/g, "");
+	return chars === chars.split("").reverse().join("");
+}
+`,
+	languages.Java: `
+class Solution {
+	public boolean solution(String s) {
+		String chars = s.toLowerCase().replaceAll("[^a-z0-9]", "");
+		return chars.equals(new StringBuilder(chars).reverse().toString());
+	}
+}
+`,
+	languages.Cpp: `
+#include <bits/stdc++.h>
+using namespace std;
+
+bool solution(string s) {
+	string chars;
+	for (char c : s) {
+		if (isalnum((unsigned char)c)) {
+			chars += tolower((unsigned char)c);
+		}
+	}
+	return equal(chars.begin(), chars.end(), chars.rbegin());
+}
+`,
+	languages.Rust: `
+fn solution(s: String) -> bool {
+	let chars: Vec<char> = s.chars().filter(|c| c.is_alphanumeric()).map(|c| c.to_ascii_lowercase()).collect();
+	chars.iter().eq(chars.iter().rev())
+}
+`,
+	languages.Ruby: `
+def solution(s)
+	chars = s.downcase.gsub(/[^a-z0-9]/, "")
+	chars == chars.reverse
+end
+`,
+}
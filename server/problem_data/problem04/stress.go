@@ -0,0 +1,46 @@
+package problem_04
+
+import (
+	"math/rand"
+	"strings"
+
+	"github.com/webbben/code-duel/problem_data/stress"
+)
+
+// random inputs for stress testing
+var generator = stress.Generator[string, bool]{
+	Solution: sampleSolution,
+	Inputs: []stress.Gen[string]{
+		palindrome,
+		// almost always not palindromes
+		stress.String(stress.Sizes(0, 100), "abcAB12 ,.!'"),
+		// palindromes with one character changed
+		stress.Map(palindrome, func(s string) string {
+			return strings.Replace(s, "a", "b", 1)
+		}),
+	},
+}
+
+// a palindrome, once punctuation and case are ignored
+func palindrome(r *rand.Rand) string {
+	half := []rune(stress.String(stress.Sizes(0, 50), "abcAB12")(r))
+	chars := append([]rune{}, half...)
+	if r.Intn(2) == 0 {
+		chars = append(chars, 'x') // odd length
+	}
+	for i := len(half) - 1; i >= 0; i-- {
+		c := half[i]
+		if r.Intn(2) == 0 {
+			c = []rune(strings.ToUpper(string(c)))[0]
+		}
+		chars = append(chars, c)
+	}
+	var s strings.Builder
+	for _, c := range chars {
+		if r.Intn(4) == 0 {
+			s.WriteString(stress.Pick(" ", ",", ".", "!", "'")(r))
+		}
+		s.WriteRune(c)
+	}
+	return s.String()
+}
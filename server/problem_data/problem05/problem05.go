@@ -0,0 +1,71 @@
+package problem_05
+
+import (
+	"github.com/webbben/code-duel/checkers"
+	"github.com/webbben/code-duel/models"
+)
+
+var problem = models.Problem{
+	ProblemOverview: models.ProblemOverview{
+		ID:         "problem05",
+		Name:       "Two Sum",
+		Difficulty: 1,
+		QuickDesc:  "Given a list of numbers and a target, find the two numbers that add up to the target.",
+	},
+	Signature: models.Signature{
+		Name: "solution",
+		Params: []models.Param{
+			{Name: "nums", Type: models.ListOf(models.Int)},
+			{Name: "target", Type: models.Int},
+		},
+		Returns: models.ListOf(models.Int),
+	},
+	Checker:  checkers.Unordered(),
+	FullDesc: "Given a list of integers nums and an integer target, return the indices of the two numbers that add up to target, in any order.\nEach input has exactly one solution, and you may not use the same element twice.",
+	TestCases: []models.TestCase{
+		{models.Args{"nums": []int{2, 7, 11, 15}, "target": 9}, []int{0, 1}},
+		{models.Args{"nums": []int{3, 2, 4}, "target": 6}, []int{1, 2}},
+		{models.Args{"nums": []int{3, 3}, "target": 6}, []int{0, 1}},
+	},
+	// hidden cases are the hand written ones, and some generated ones (see stress.go)
+	FullCases: append([]models.TestCase{
+		{models.Args{"nums": []int{1, 5, 9, 13}, "target": 22}, []int{2, 3}},
+		{models.Args{"nums": []int{-3, 4, 3, 90}, "target": 0}, []int{0, 2}},
+		{models.Args{"nums": []int{0, 4, 3, 0}, "target": 0}, []int{0, 3}},
+		{models.Args{"nums": []int{5, 75, 25}, "target": 100}, []int{1, 2}},
+		{models.Args{"nums": []int{-1, -2, -3, -4, -5}, "target": -8}, []int{2, 4}},
+		{models.Args{"nums": []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, "target": 19}, []int{8, 9}},
+		{models.Args{"nums": []int{1000000, 500, -1000000, 7}, "target": 507}, []int{1, 3}},
+	}, generator.Cases(1, 20)...),
+}
+
+func GetOverview() models.ProblemOverview {
+	return problem.ProblemOverview
+}
+
+func GetProblem() models.Problem {
+	problem.CaseCount = len(problem.TestCases) + len(problem.FullCases)
+	problem.Solutions = solutions
+	problem.Generate = generator.Cases
+	return problem
+}
+
+/*
+ * ====================================================================
+ * Sample Solution
+ * Make a solution to the problem - to confirm its actually solvable!
+ * This should be unit tested with the test cases
+ * ====================================================================
+ */
+
+func sampleSolution(nums []int, target int) []int {
+	// index of each number we've seen so far
+	seen := map[int]int{}
+	for i, num := range nums {
+		if j, ok := seen[target-num]; ok {
+			return []int{j, i}
+		}
+		seen[num] = i
+	}
+	return nil
+}
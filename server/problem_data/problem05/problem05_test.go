@@ -0,0 +1,24 @@
+package problem_05
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/webbben/code-duel/models"
+)
+
+func TestSampleSolution(t *testing.T) {
+	testCases := GetProblem().TestCases
+	testCases = append(testCases, GetProblem().FullCases...)
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("problem05_case_%d", i), func(t *testing.T) {
+			input, expOut := testCase[0].(models.Args), testCase[1]
+			output := sampleSolution(input["nums"].([]int), input["target"].(int))
+			if !slices.Equal(output, expOut.([]int)) {
+				t.Errorf("Sample solution output: [%v] Expected: [%v]", output, expOut)
+			}
+		})
+	}
+}
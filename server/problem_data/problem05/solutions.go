@@ -0,0 +1,134 @@
+package problem_05
+
+import "github.com/webbben/code-duel/languages"
+
+// reference solutions in each language, which cmd/problemcheck runs against the test cases
+var solutions = map[string]string{
+	languages.Python: `
+def solution(nums: list[int], target: int) -> list[int]:
+	seen = {}
+	for i, num in enumerate(nums):
+		if target - num in seen:
+			return [seen[target - num], i]
+		seen[num] = i
+	return []
+`,
+	languages.Go: `
+package main
+
+func solution(nums []int, target int) []int {
+	for i := range nums {
+		for j := i + 1; j < len(nums); j++ {
+			if nums[i]+nums[j] == target {
+				return []int{i, j}
+			}
+		}
+	}
+	return nil
+}
+`,
+	languages.Bash: `
+solution () {
+	read -ra nums <<< "$1"
+	target=$2
+	# index of each number seen so far
+	declare -A seen
+	for i in "${!nums[@]}"; do
+		want=$((target - nums[i]))
+		if [ -n "${seen[$want]}" ]; then
+			echo "${seen[$want]} $i"
+			return
+		fi
+		seen[${nums[i]}]=$i
+	done
+}
+`,
+	languages.JavaScript: `
+function solution(nums, target) {
+	// index of each number seen so far
+	const seen = new Map();
+	for (let i = 0; i < nums.length; i++) {
+		if (seen.has(target - nums[i])) {
+			return [seen.get(target - nums[i]), i];
+		}
+		seen.set(nums[i], i);
+	}
+	return [];
+}
+`,
+	languages.TypeScript: `
+function solution(nums: number[], target: number): number[] {
+	// index of each number seen so far
+	const seen = new Map<number, number>();
+	for (let i = 0; i < nums.length; i++) {
+		const j = seen.get(target - nums[i]);
+		if (j !== undefined) {
+			return [j, i];
+		}
+		seen.set(nums[i], i);
+	}
+	return [];
+}
+`,
+	languages.Java: `
+import java.util.*;
+
+class Solution {
+	public long[] solution(long[] nums, long target) {
+		// index of each number seen so far
+		Map<Long, Integer> seen = new HashMap<>();
+		for (int i = 0; i < nums.length; i++) {
+			Integer j = seen.get(target - nums[i]);
+			if (j != null) {
+				return new long[]{j, i};
+			}
+			seen.put(nums[i], i);
+		}
+		return new long[]{};
+	}
+}
+`,
+	languages.Cpp: `
+#include <bits/stdc++.h>
+using namespace std;
+
+vector<long long> solution(vector<long long> nums, long long target) {
+	// index of each number seen so far
+	unordered_map<long long, long long> seen;
+	for (long long i = 0; i < (long long)nums.size(); i++) {
+		auto j = seen.find(target - nums[i]);
+		if (j != seen.end()) {
+			return {j->second, i};
+		}
+		seen[nums[i]] = i;
+	}
+	return {};
+}
+`,
+	languages.Rust: `
+use std::collections::HashMap;
+
+fn solution(nums: Vec<i64>, target: i64) -> Vec<i64> {
+	// index of each number seen so far
+	let mut seen = HashMap::new();
+	for (i, num) in nums.iter().enumerate() {
+		if let Some(&j) = seen.get(&(target - num)) {
+			return vec![j, i as i64];
+		}
+		seen.insert(*num, i as i64);
+	}
+	Vec::new()
+}
+`,
+	languages.Ruby: `
+def solution(nums, target)
+	# index of each number seen so far
+	seen = {}
+	nums.each_with_index do |num, i|
+		return [seen[target - num], i] if seen.key?(target - num)
+		seen[num] = i
+	end
+	[]
+end
+`,
+}
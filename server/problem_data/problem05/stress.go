@@ -0,0 +1,50 @@
+package problem_05
+
+import (
+	"math/rand"
+
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/problem_data/stress"
+)
+
+// random inputs for stress testing
+var generator = stress.Generator[models.Args, []int]{
+	Solution: func(args models.Args) []int {
+		return sampleSolution(args["nums"].([]int), args["target"].(int))
+	},
+	Inputs: []stress.Gen[models.Args]{
+		twoSumArgs(stress.Sizes(2, 200), stress.Int(-1000000, 1000000)),
+		// small lists of small numbers, where there are lots of pairs that nearly add up
+		twoSumArgs(stress.Sizes(2, 6), stress.Int(-5, 5)),
+	},
+}
+
+// arguments with exactly one pair of numbers that add up to the target
+func twoSumArgs(length stress.Gen[int], num stress.Gen[int]) stress.Gen[models.Args] {
+	return func(r *rand.Rand) models.Args {
+		for {
+			nums := stress.Slice(length, num)(r)
+			i, j := r.Intn(len(nums)), r.Intn(len(nums))
+			if i == j {
+				continue
+			}
+			target := nums[i] + nums[j]
+			if pairs(nums, target) == 1 {
+				return models.Args{"nums": nums, "target": target}
+			}
+		}
+	}
+}
+
+// the number of pairs of numbers that add up to target
+func pairs(nums []int, target int) int {
+	count := 0
+	for i := range nums {
+		for j := i + 1; j < len(nums); j++ {
+			if nums[i]+nums[j] == target {
+				count++
+			}
+		}
+	}
+	return count
+}
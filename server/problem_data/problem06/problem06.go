@@ -11,6 +11,11 @@ var problem = models.Problem{
 		Difficulty: 2,
 		QuickDesc:  "Given an integer, convert it to its Roman numeral representation.",
 	},
+	Signature: models.Signature{
+		Name:    "solution",
+		Params:  []models.Param{{Name: "num", Type: models.Int}},
+		Returns: models.String,
+	},
 	FullDesc: "Given an integer, convert it to its Roman numeral representation.\nRoman numerals are represented by combinations of letters of the set {I, V, X, L, C, D, M}, where each letter corresponds to a specific numeric value:\nI: 1\nV: 5\nX: 10\nL: 50\nC: 100\nD: 500\nM: 1000\n\nTo represent numbers, certain rules apply. For example, the numeral for 4 is IV, which is 5 - 1. The numeral for 9 is IX, which is 10 - 1. When smaller numbers appear before larger numbers, you subtract (e.g., IV for 4). When smaller numbers appear after larger numbers, you add (e.g., VIII for 8).",
 	TestCases: []models.TestCase{
 		{4, "IV"},
@@ -33,9 +38,6 @@ var problem = models.Problem{
 		{2022, "MMXXII"},
 		{3492, "MMMCDXCII"},
 	},
-	ProblemFunc: models.ProblemFunc{
-		GetTemplate: GetProblemTemplate,
-	},
 }
 
 func GetOverview() models.ProblemOverview {
@@ -44,63 +46,11 @@ func GetOverview() models.ProblemOverview {
 
 func GetProblem() models.Problem {
 	problem.CaseCount = len(problem.TestCases) + len(problem.FullCases)
+	problem.Solutions = solutions
+	problem.Generate = generator.Cases
 	return problem
 }
 
-/*
- * ====================================================================
- * Code Templates
- * Define a code template for each of the supported languages below
- * ====================================================================
- */
-
-var pythonTemplate = `
-def solution(num: int):
-	# write your solution here
-
-# don't change this or your code may not run correctly!
-print(solution(%s))
-`
-
-var goTemplate = `
-package main
-
-import "fmt"
-
-func main() {
-	// don't change this or your code may not run correctly!
-	fmt.Print(solution(%s))
-}
-
-func solution(num int) int {
-	// write your solution here
-}
-`
-
-var bashTemplate = `
-solution () {
-	# write your solution here
-	num=$1
-}
-
-# don't change this or your code may not run correctly!
-output=solution %s
-echo $output
-`
-
-func GetProblemTemplate(lang string) string {
-	switch lang {
-	case "go":
-		return goTemplate
-	case "py":
-		return pythonTemplate
-	case "sh":
-		return bashTemplate
-	default:
-		return ""
-	}
-}
-
 /*
  * ====================================================================
  * Sample Solution
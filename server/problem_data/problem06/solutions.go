@@ -0,0 +1,51 @@
+package problem_06
+
+import "github.com/webbben/code-duel/languages"
+
+// reference solutions in each language, which cmd/problemcheck runs against the test cases
+var solutions = map[string]string{
+	languages.Python: `
+def solution(num: int) -> str:
+	numerals = [(1000, "M"), (900, "CM"), (500, "D"), (400, "CD"), (100, "C"), (90, "XC"),
+		(50, "L"), (40, "XL"), (10, "X"), (9, "IX"), (5, "V"), (4, "IV"), (1, "I")]
+	roman = ""
+	for value, numeral in numerals:
+		while num >= value:
+			roman += numeral
+			num -= value
+	return roman
+`,
+	languages.Go: `
+package main
+
+import "strings"
+
+func solution(num int) string {
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	numerals := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	var roman strings.Builder
+	for i, value := range values {
+		for num >= value {
+			roman.WriteString(numerals[i])
+			num -= value
+		}
+	}
+	return roman.String()
+}
+`,
+	languages.Bash: `
+solution () {
+	num=$1
+	values=(1000 900 500 400 100 90 50 40 10 9 5 4 1)
+	numerals=(M CM D CD C XC L XL X IX V IV I)
+	roman=
+	for i in "${!values[@]}"; do
+		while [ "$num" -ge "${values[$i]}" ]; do
+			roman+=${numerals[$i]}
+			num=$((num - values[i]))
+		done
+	done
+	echo "$roman"
+}
+`,
+}
@@ -0,0 +1,15 @@
+package problem_06
+
+import (
+	"github.com/webbben/code-duel/problem_data/stress"
+)
+
+// random inputs for stress testing
+var generator = stress.Generator[int, string]{
+	Solution: sampleSolution,
+	Inputs: []stress.Gen[int]{
+		stress.Int(1, 3999),
+		// numbers with the most subtractive numerals, the longest numeral, and the largest number
+		stress.Pick(4, 9, 40, 90, 400, 900, 444, 999, 3888, 3999),
+	},
+}
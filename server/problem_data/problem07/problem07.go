@@ -11,6 +11,11 @@ var problem = models.Problem{
 		Difficulty: 3,
 		QuickDesc:  "Calculate how much rainwater can be captured by a series of reservoirs.",
 	},
+	Signature: models.Signature{
+		Name:    "solution",
+		Params:  []models.Param{{Name: "height", Type: models.ListOf(models.Int)}},
+		Returns: models.Int,
+	},
 	FullDesc: "given an array representing the height of bars along an elevation, calculate how much rainwater can be trapped between the bars.\nIn order for water to be trapped, there should exist bars on both sides that keep it from flowing out, and there may be pockets of water captured at various points in the array of bars.",
 	TestCases: []models.TestCase{
 		{[]int{0, 1, 0}, 0},
@@ -18,7 +23,9 @@ var problem = models.Problem{
 		{[]int{2, 0, 1}, 1},
 		{[]int{2, 0, 1, 2}, 3},
 	},
-	FullCases: []models.TestCase{
+	// hidden cases are the hand written ones, some generated ones, and a long one that quadratic solutions
+	// can't finish in time (see stress.go)
+	FullCases: append(append([]models.TestCase{
 		{[]int{0, 1, 0, 2, 1, 0, 1, 3, 2, 1, 2, 1}, 6},
 		{[]int{4, 2, 0, 3, 2, 5}, 9},
 		{[]int{3, 0, 1, 3, 0, 5}, 8},
@@ -27,10 +34,9 @@ var problem = models.Problem{
 		{[]int{10, 0, 8, 6, 0, 2, 10, 7, 3}, 34},
 		{[]int{100, 90, 80, 70, 60, 50, 40, 30, 20, 10}, 0},
 		{[]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, 0},
-	},
-	ProblemFunc: models.ProblemFunc{
-		GetTemplate: GetProblemTemplate,
-	},
+	}, generator.Cases(1, 20)...), longGenerator.Cases(1, 1)...),
+	TimeLimit:   1000,
+	MemoryLimit: 256,
 }
 
 func GetOverview() models.ProblemOverview {
@@ -39,63 +45,11 @@ func GetOverview() models.ProblemOverview {
 
 func GetProblem() models.Problem {
 	problem.CaseCount = len(problem.TestCases) + len(problem.FullCases)
+	problem.Solutions = solutions
+	problem.Generate = generator.Cases
 	return problem
 }
 
-/*
- * ====================================================================
- * Code Templates
- * Define a code template for each of the supported languages below
- * ====================================================================
- */
-
-var pythonTemplate = `
-def solution(num: int):
-	# write your solution here
-
-# don't change this or your code may not run correctly!
-print(solution(%s))
-`
-
-var goTemplate = `
-package main
-
-import "fmt"
-
-func main() {
-	// don't change this or your code may not run correctly!
-	fmt.Print(solution(%s))
-}
-
-func solution(num int) int {
-	// write your solution here
-}
-`
-
-var bashTemplate = `
-solution () {
-	# write your solution here
-	num=$1
-}
-
-# don't change this or your code may not run correctly!
-output=solution %s
-echo $output
-`
-
-func GetProblemTemplate(lang string) string {
-	switch lang {
-	case "go":
-		return goTemplate
-	case "py":
-		return pythonTemplate
-	case "sh":
-		return bashTemplate
-	default:
-		return ""
-	}
-}
-
 /*
  * ====================================================================
  * Sample Solution
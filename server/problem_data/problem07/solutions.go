@@ -0,0 +1,64 @@
+package problem_07
+
+import "github.com/webbben/code-duel/languages"
+
+// reference solutions in each language, which cmd/problemcheck runs against the test cases
+var solutions = map[string]string{
+	languages.Python: `
+def solution(height: list[int]) -> int:
+	left, right = 0, len(height) - 1
+	left_max = right_max = water = 0
+	while left < right:
+		if height[left] < height[right]:
+			left_max = max(left_max, height[left])
+			water += left_max - height[left]
+			left += 1
+		else:
+			right_max = max(right_max, height[right])
+			water += right_max - height[right]
+			right -= 1
+	return water
+`,
+	languages.Go: `
+package main
+
+func solution(height []int) int {
+	left, right := 0, len(height)-1
+	leftMax, rightMax, water := 0, 0, 0
+	for left < right {
+		if height[left] < height[right] {
+			leftMax = max(leftMax, height[left])
+			water += leftMax - height[left]
+			left++
+		} else {
+			rightMax = max(rightMax, height[right])
+			water += rightMax - height[right]
+			right--
+		}
+	}
+	return water
+}
+`,
+	languages.Bash: `
+# bash arrays are slow to jump around in, so instead of two pointers this walks in from each end to the highest bar
+solution () {
+	local -a height
+	read -ra height <<< "$1"
+	local n=${#height[@]} highest=0 level=0 water=0 i
+	for ((i = 0; i < n; i++)); do
+		((height[i] > level)) && level=${height[i]} highest=$i
+	done
+	level=0
+	for ((i = 0; i < highest; i++)); do
+		((height[i] > level)) && level=${height[i]}
+		((water += level - height[i]))
+	done
+	level=0
+	for ((i = n - 1; i > highest; i--)); do
+		((height[i] > level)) && level=${height[i]}
+		((water += level - height[i]))
+	done
+	echo "$water"
+}
+`,
+}
@@ -0,0 +1,32 @@
+package problem_07
+
+import (
+	"slices"
+
+	"github.com/webbben/code-duel/problem_data/stress"
+)
+
+// random inputs for stress testing
+var generator = stress.Generator[[]int, int]{
+	Solution: sampleSolution,
+	Inputs: []stress.Gen[[]int]{
+		stress.Slice(stress.Sizes(0, 200), stress.Int(0, 100)),
+		// slopes, which can't trap any water
+		stress.Sorted(stress.Slice(stress.Sizes(0, 200), stress.Int(0, 100))),
+		stress.Reversed(stress.Sorted(stress.Slice(stress.Sizes(0, 200), stress.Int(0, 100)))),
+		// a single valley, which is full of water
+		stress.Map(stress.Sorted(stress.Slice(stress.Sizes(0, 100), stress.Int(0, 100))), func(height []int) []int {
+			valley := slices.Clone(height)
+			slices.Reverse(valley)
+			return append(valley, height...)
+		}),
+	},
+}
+
+// a long elevation, for checking solutions are linear
+var longGenerator = stress.Generator[[]int, int]{
+	Solution: sampleSolution,
+	Inputs: []stress.Gen[[]int]{
+		stress.Slice(stress.Sizes(100000, 100000), stress.Int(0, 10000)),
+	},
+}
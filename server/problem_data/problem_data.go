@@ -6,25 +6,31 @@ import (
 	problem_02 "github.com/webbben/code-duel/problem_data/problem02"
 	problem_03 "github.com/webbben/code-duel/problem_data/problem03"
 	problem_04 "github.com/webbben/code-duel/problem_data/problem04"
+	problem_05 "github.com/webbben/code-duel/problem_data/problem05"
 	problem_06 "github.com/webbben/code-duel/problem_data/problem06"
 	problem_07 "github.com/webbben/code-duel/problem_data/problem07"
 )
 
 // map of problems to their problem IDs
 //
-// new problems should be mapped here so they are exposed to the rest of the codebase
+// new problems should be mapped here so they are exposed to the rest of the codebase, unless they're loaded from
+// a problem directory (see LoadProblems)
 var problemMap map[string]models.Problem = map[string]models.Problem{
 	"problem01": problem_01.GetProblem(),
 	"problem02": problem_02.GetProblem(),
 	"problem03": problem_03.GetProblem(),
 	"problem04": problem_04.GetProblem(),
+	"problem05": problem_05.GetProblem(),
 	"problem06": problem_06.GetProblem(),
 	"problem07": problem_07.GetProblem(),
 }
 
-// Get problem object by its ID
+// Get problem object by its ID, or nil if there's no such problem
 func GetProblemByID(problemID string) *models.Problem {
-	problem := problemMap[problemID]
+	problem, ok := problemMap[problemID]
+	if !ok {
+		return nil
+	}
 	return &problem
 }
 
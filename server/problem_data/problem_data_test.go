@@ -0,0 +1,28 @@
+package problemData
+
+import (
+	"testing"
+)
+
+// every problem's test cases need to match the types its signature declares, and its checker needs to accept them
+func TestProblemsMatchSignatures(t *testing.T) {
+	for id, problem := range problemMap {
+		if err := ValidateProblem(problem); err != nil {
+			t.Errorf("%s: %s", id, err)
+		}
+	}
+}
+
+// generated test cases need to match the signature too
+func TestGeneratedCasesMatchSignatures(t *testing.T) {
+	for id, problem := range problemMap {
+		if problem.Generate == nil {
+			continue
+		}
+		problem.TestCases = problem.Generate(1, 1000)
+		problem.FullCases = nil
+		if err := ValidateProblem(problem); err != nil {
+			t.Errorf("%s: generated %s", id, err)
+		}
+	}
+}
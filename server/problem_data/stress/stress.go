@@ -0,0 +1,115 @@
+// Package stress generates random test cases for problems, with the expected outputs worked out by the problem's
+// sample solution, so problems can be tested against far more cases than anyone would write by hand.
+package stress
+
+import (
+	"math/rand"
+	"slices"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// makes a random value
+type Gen[T any] func(r *rand.Rand) T
+
+// a random integer between lo and hi, inclusive
+func Int(lo int, hi int) Gen[int] {
+	return func(r *rand.Rand) int {
+		return lo + r.Intn(hi-lo+1)
+	}
+}
+
+// always the same value
+func Const[T any](value T) Gen[T] {
+	return func(r *rand.Rand) T {
+		return value
+	}
+}
+
+// one of the given values
+func Pick[T any](values ...T) Gen[T] {
+	return func(r *rand.Rand) T {
+		return values[r.Intn(len(values))]
+	}
+}
+
+// a value from one of the given generators
+func OneOf[T any](gens ...Gen[T]) Gen[T] {
+	return func(r *rand.Rand) T {
+		return gens[r.Intn(len(gens))](r)
+	}
+}
+
+// sizes for a collection between lo and hi, favouring the edge cases: the smallest and largest sizes come up as
+// often as all the sizes between them
+func Sizes(lo int, hi int) Gen[int] {
+	return OneOf(Const(lo), Const(min(lo+1, hi)), Int(lo, hi), Const(hi))
+}
+
+// a slice with a length from length, and elements from elem
+func Slice[T any](length Gen[int], elem Gen[T]) Gen[[]T] {
+	return func(r *rand.Rand) []T {
+		values := make([]T, length(r))
+		for i := range values {
+			values[i] = elem(r)
+		}
+		return values
+	}
+}
+
+// a string with a length from length, made of characters from alphabet
+func String(length Gen[int], alphabet string) Gen[string] {
+	chars := []rune(alphabet)
+	return func(r *rand.Rand) string {
+		s := make([]rune, length(r))
+		for i := range s {
+			s[i] = chars[r.Intn(len(chars))]
+		}
+		return string(s)
+	}
+}
+
+// a slice from gen, sorted in increasing order
+func Sorted(gen Gen[[]int]) Gen[[]int] {
+	return func(r *rand.Rand) []int {
+		values := gen(r)
+		slices.Sort(values)
+		return values
+	}
+}
+
+// a slice from gen, in reverse order
+func Reversed[T any](gen Gen[[]T]) Gen[[]T] {
+	return func(r *rand.Rand) []T {
+		values := gen(r)
+		slices.Reverse(values)
+		return values
+	}
+}
+
+// a value from gen, changed by f
+func Map[T any, U any](gen Gen[T], f func(T) U) Gen[U] {
+	return func(r *rand.Rand) U {
+		return f(gen(r))
+	}
+}
+
+// generates test cases for a problem. inputs for solutions that take more than one argument are models.Args
+type Generator[I any, O any] struct {
+	Solution func(input I) O // works out the expected output for an input
+	Inputs   []Gen[I]        // the kinds of input to generate, used in turn
+}
+
+// makes count test cases. the same seed always gives the same cases
+func (g Generator[I, O]) Cases(seed int64, count int) []models.TestCase {
+	r := rand.New(rand.NewSource(seed))
+	testCases := make([]models.TestCase, count)
+	for i := range testCases {
+		// each input is generated twice, so the test case keeps its input even if the solution changes it
+		caseSeed := r.Int63()
+		gen := g.Inputs[i%len(g.Inputs)]
+		output := g.Solution(gen(rand.New(rand.NewSource(caseSeed))))
+		testCases[i] = models.TestCase{gen(rand.New(rand.NewSource(caseSeed))), output}
+	}
+	return testCases
+}
@@ -0,0 +1,60 @@
+package stress
+
+import (
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestGensStayInBounds(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	ints := Slice(Sizes(0, 5), Int(-3, 3))
+	for i := 0; i < 1000; i++ {
+		values := ints(r)
+		if len(values) > 5 {
+			t.Fatalf("Generated %d values; Expected at most 5", len(values))
+		}
+		for _, value := range values {
+			if value < -3 || value > 3 {
+				t.Fatalf("Generated %d; Expected a value from -3 to 3", value)
+			}
+		}
+		if s := String(Int(2, 2), "ab")(r); len(s) != 2 || s[0] != 'a' && s[0] != 'b' {
+			t.Fatalf("Generated %q; Expected 2 characters from \"ab\"", s)
+		}
+	}
+	if values := Sorted(ints)(r); !slices.IsSorted(values) {
+		t.Errorf("Generated %v; Expected it sorted", values)
+	}
+}
+
+func TestGeneratorCases(t *testing.T) {
+	generator := Generator[[]int, int]{
+		Solution: func(values []int) int {
+			// solutions that change their input shouldn't change the test case
+			slices.Sort(values)
+			return len(values)
+		},
+		Inputs: []Gen[[]int]{
+			Reversed(Slice(Int(1, 10), Int(0, 100))),
+			Const([]int{}),
+		},
+	}
+	testCases := generator.Cases(42, 100)
+	if len(testCases) != 100 {
+		t.Fatalf("Generated %d cases; Expected 100", len(testCases))
+	}
+	for i, testCase := range testCases {
+		input := testCase[0].([]int)
+		if testCase[1] != len(input) {
+			t.Errorf("case %d: Expected output [%v] for input %v", i, testCase[1], input)
+		}
+		if i%2 == 1 && len(input) != 0 {
+			t.Errorf("case %d: Expected inputs to alternate between the generators", i)
+		}
+	}
+	if !reflect.DeepEqual(testCases, generator.Cases(42, 100)) {
+		t.Error("Expected the same cases from the same seed")
+	}
+}
@@ -0,0 +1,97 @@
+package ratings
+
+import (
+	"math"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// starting values for a player's first rated game
+const (
+	defaultRating     = 1500
+	defaultRD         = 350
+	defaultVolatility = 0.06
+)
+
+const (
+	// limits how much a player's volatility can change in one game. smaller values keep ratings steadier
+	tau = 0.5
+	// converts ratings to and from the scale Glicko-2 works in
+	glickoScale = 173.7178
+	// how closely the new volatility is worked out
+	convergence = 1e-6
+)
+
+// a player's result against one opponent: 1 for a win, 0.5 for a draw and 0 for a loss
+type result struct {
+	opponent models.PlayerRating
+	score    float64
+}
+
+// the rating of a player who hasn't played a rated game yet
+func newRating(user string) models.PlayerRating {
+	return models.PlayerRating{User: user, Rating: defaultRating, RD: defaultRD, Volatility: defaultVolatility}
+}
+
+// works out a player's new rating, RD and volatility after a rating period with at least one result, following
+// Glickman's "Example of the Glicko-2 system". the other fields are left as they are
+func update(player models.PlayerRating, results []result) models.PlayerRating {
+	mu := (player.Rating - defaultRating) / glickoScale
+	phi := player.RD / glickoScale
+
+	var vInverse, improvement float64
+	for _, r := range results {
+		muJ := (r.opponent.Rating - defaultRating) / glickoScale
+		phiJ := r.opponent.RD / glickoScale
+		g := 1 / math.Sqrt(1+3*phiJ*phiJ/(math.Pi*math.Pi))
+		expected := 1 / (1 + math.Exp(-g*(mu-muJ)))
+		vInverse += g * g * expected * (1 - expected)
+		improvement += g * (r.score - expected)
+	}
+	v := 1 / vInverse
+	delta := v * improvement
+
+	sigma := newVolatility(phi, player.Volatility, v, delta)
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*improvement
+
+	player.Rating = newMu*glickoScale + defaultRating
+	player.RD = newPhi * glickoScale
+	player.Volatility = sigma
+	return player
+}
+
+// finds the new volatility with the Illinois algorithm (step 5 of the Glicko-2 example)
+func newVolatility(phi float64, sigma float64, v float64, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-d)/(2*d*d) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > convergence {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
@@ -0,0 +1,95 @@
+// Package ratings keeps players' Glicko-2 ratings up to date with the results of their games.
+//
+// each game is its own rating period. a game with more than two players is rated as if every player had played
+// every other: they beat the players who finished below them, lose to those above them, and draw with the players
+// they tied with. everyone's new rating is worked out from the ratings they all had before the game.
+package ratings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+)
+
+// updates the ratings of a finished game's players, and adds the game to their rating history. places are where
+// each player finished, by username: 1 for first, with players who did equally well sharing a place. a game needs
+// at least two players to be rated.
+//
+// returns how each player's rating changed, by username
+func RateGame(ctx context.Context, gameID string, places map[string]int) (map[string]models.RatingChange, error) {
+	if len(places) < 2 {
+		return nil, nil
+	}
+	store := storage.Get()
+	before := make(map[string]models.PlayerRating, len(places))
+	for user := range places {
+		rating, err := GetRating(ctx, user)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the rating of %s; %w", user, err)
+		}
+		before[user] = rating
+	}
+
+	// rated in a fixed order, so a failure part way through always leaves the same players unrated
+	users := make([]string, 0, len(places))
+	for user := range places {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+
+	now := time.Now()
+	changes := make(map[string]models.RatingChange, len(users))
+	for _, user := range users {
+		results := make([]result, 0, len(users)-1)
+		for _, opponent := range users {
+			if opponent != user {
+				results = append(results, result{opponent: before[opponent], score: score(places[user], places[opponent])})
+			}
+		}
+		rating := update(before[user], results)
+		rating.Games++
+		rating.UpdatedAt = now
+		change := models.RatingChange{
+			User:    user,
+			GameID:  gameID,
+			Place:   places[user],
+			Players: len(users),
+			Before:  before[user].Rating,
+			After:   rating.Rating,
+			Change:  rating.Rating - before[user].Rating,
+			RD:      rating.RD,
+			At:      now,
+		}
+		if err := store.SaveRating(ctx, rating, change); err != nil {
+			return changes, fmt.Errorf("failed to save the rating of %s; %w", user, err)
+		}
+		changes[user] = change
+	}
+	return changes, nil
+}
+
+// gets a player's current rating. players who haven't played a rated game yet have the starting rating
+func GetRating(ctx context.Context, user string) (models.PlayerRating, error) {
+	rating, err := storage.Get().GetRating(ctx, user)
+	if errors.Is(err, storage.ErrNotFound) {
+		return newRating(user), nil
+	}
+	return rating, err
+}
+
+// a player's score against an opponent, from where they each finished
+func score(place int, opponentPlace int) float64 {
+	switch {
+	case place < opponentPlace:
+		return 1
+	case place > opponentPlace:
+		return 0
+	default:
+		return 0.5
+	}
+}
@@ -0,0 +1,67 @@
+package ratings
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+)
+
+// the worked example from Glickman's "Example of the Glicko-2 system"
+func TestUpdate(t *testing.T) {
+	player := models.PlayerRating{Rating: 1500, RD: 200, Volatility: 0.06}
+	results := []result{
+		{opponent: models.PlayerRating{Rating: 1400, RD: 30}, score: 1},
+		{opponent: models.PlayerRating{Rating: 1550, RD: 100}, score: 0},
+		{opponent: models.PlayerRating{Rating: 1700, RD: 300}, score: 0},
+	}
+	got := update(player, results)
+	if math.Abs(got.Rating-1464.06) > 0.01 || math.Abs(got.RD-151.52) > 0.01 || math.Abs(got.Volatility-0.05999) > 0.00001 {
+		t.Errorf("update: %+v, expected a rating of 1464.06, RD of 151.52 and volatility of 0.05999", got)
+	}
+}
+
+func TestRateGame(t *testing.T) {
+	ctx := context.Background()
+	storage.Set(storage.NewMemoryStore())
+
+	// ann won, and bob and cat tied behind her
+	changes, err := RateGame(ctx, "room1-1", map[string]int{"ann": 1, "bob": 2, "cat": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 || changes["ann"].Change <= 0 || changes["bob"].Change >= 0 || changes["bob"].Players != 3 {
+		t.Fatalf("RateGame: %+v", changes)
+	}
+	// bob and cat started with the same rating and did the same, so they end up with the same rating
+	if changes["bob"].After != changes["cat"].After {
+		t.Errorf("tied players' ratings differ: %+v and %+v", changes["bob"], changes["cat"])
+	}
+	if changes["ann"].Before != 1500 || changes["ann"].RD >= 350 {
+		t.Errorf("a first game should start from 1500 and make the rating more certain: %+v", changes["ann"])
+	}
+
+	// the next game starts from where the last one left off
+	changes, err = RateGame(ctx, "room1-2", map[string]int{"ann": 2, "bob": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes["bob"].Before >= 1500 || changes["bob"].Change <= 0 {
+		t.Errorf("bob's second game: %+v", changes["bob"])
+	}
+	rating, err := storage.Get().GetRating(ctx, "ann")
+	if err != nil || rating.Games != 2 || rating.Rating != changes["ann"].After {
+		t.Errorf("ann's stored rating: %+v (%v)", rating, err)
+	}
+	history, _ := storage.Get().ListRatingChanges(ctx, "cat")
+	if len(history) != 1 || history[0].GameID != "room1-1" || history[0].Place != 2 {
+		t.Errorf("cat's rating history: %+v", history)
+	}
+
+	// a game on your own can't be rated
+	if changes, err := RateGame(ctx, "room2-1", map[string]int{"ann": 1}); err != nil || changes != nil {
+		t.Errorf("RateGame with one player: %+v (%v)", changes, err)
+	}
+}
@@ -0,0 +1,119 @@
+// code for handling room resources in the store
+package rooms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/webbben/code-duel/handlers/general"
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+)
+
+// create room in the store
+func CreateRoom(request *models.CreateRoomRequest, username string) (roomID string, err error) {
+	room := models.Room{
+		Owner:       username,
+		Title:       request.Title,
+		Difficulty:  request.Difficulty,
+		MaxCapacity: request.MaxCapacity,
+		Users:       []string{username},
+		Status:      "waiting",
+		ReqPassword: request.ReqPassword,
+		Password:    request.Password,
+		TimeLimit:   30,
+		ScoringMode: models.ScoringFirstToSolve,
+	}
+	err = storage.Get().CreateRoom(context.Background(), &room)
+	if err != nil {
+		return "", fmt.Errorf("Failed creating room: %w", err)
+	}
+	return room.ID, nil
+}
+
+// adds or removes a user from a room. code is combined since logic is similar
+func AddOrRemoveUser(username string, roomID string, add bool) error {
+	err := storage.Get().ModifyRoom(context.Background(), roomID, func(room *models.Room) (bool, error) {
+		if add {
+			if len(room.Users) >= room.MaxCapacity {
+				return false, errors.New("Add user: room is already full.")
+			}
+		} else {
+			// no users in room, so do nothing
+			if len(room.Users) == 0 {
+				return false, nil
+			}
+		}
+
+		// trying to add user but they're already in the room, so do nothing
+		if add && slices.Contains(room.Users, username) {
+			return false, nil
+		}
+		// trying to remove user but they're not in room, so do nothing
+		if !add && !slices.Contains(room.Users, username) {
+			return false, nil
+		}
+
+		if add {
+			room.Users = append(room.Users, username)
+			return false, nil
+		}
+		room.Users = general.RemoveElementFromArray(username, room.Users)
+		// if the room is empty now, just delete the room instead.
+		return len(room.Users) == 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to update users in room: %v", err)
+	}
+	return nil
+}
+
+func GetRoom(roomID string) (*models.Room, error) {
+	room, err := storage.Get().GetRoom(context.Background(), roomID)
+	if err != nil {
+		return nil, fmt.Errorf("GetRoom: %w", err)
+	}
+	return &room, nil
+}
+
+func GetRooms() ([]models.Room, error) {
+	rooms, err := storage.Get().ListRooms(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rooms; %w", err)
+	}
+	return rooms, nil
+}
+
+func DeleteRoom(roomID string) error {
+	return storage.Get().DeleteRoom(context.Background(), roomID)
+}
+
+// set game information in room
+func SetupGameContext(roomID string, problemID string) error {
+	return UpdateRoom(roomID, map[string]interface{}{
+		"Status":  "In game",
+		"InGame":  true,
+		"Problem": problemID,
+	})
+}
+
+// send a batch of updates to the store for a given room
+//
+// an update should be a map where the key is the "path" (the name of the property)
+// and the value is the new updated value.
+func UpdateRoom(roomID string, updates map[string]interface{}) error {
+	return storage.Get().UpdateRoom(context.Background(), roomID, updates)
+}
+
+func GetUserCount(roomID string) int {
+	room, err := GetRoom(roomID)
+	if err != nil {
+		return 0
+	}
+	if room == nil {
+		return 0
+	}
+	return len(room.Users)
+}
@@ -0,0 +1,57 @@
+package rooms
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+)
+
+func TestAddOrRemoveUser(t *testing.T) {
+	storage.Set(storage.NewMemoryStore())
+	roomID, err := CreateRoom(&models.CreateRoomRequest{Title: "duel", MaxCapacity: 3}, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := func() []string {
+		room, err := GetRoom(roomID)
+		if err != nil {
+			return nil
+		}
+		return room.Users
+	}
+
+	if err := AddOrRemoveUser("bob", roomID, true); err != nil {
+		t.Fatal(err)
+	}
+	// joining twice doesn't add them twice
+	if err := AddOrRemoveUser("bob", roomID, true); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(users(), []string{"alice", "bob"}) {
+		t.Errorf("users: %v, expected alice and bob", users())
+	}
+	if err := AddOrRemoveUser("carol", roomID, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddOrRemoveUser("dave", roomID, true); err == nil {
+		t.Error("dave shouldn't be able to join a full room")
+	}
+
+	if err := AddOrRemoveUser("alice", roomID, false); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(users(), []string{"bob", "carol"}) {
+		t.Errorf("users after alice left: %v", users())
+	}
+	// the last user leaving deletes the room
+	for _, user := range []string{"bob", "carol"} {
+		if err := AddOrRemoveUser(user, roomID, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := GetRoom(roomID); err == nil {
+		t.Error("the room should be deleted once it's empty")
+	}
+}
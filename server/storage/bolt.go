@@ -0,0 +1,396 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/webbben/code-duel/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltStore keeps everything in an embedded bolt database, in a single file, so the server can be self-hosted
+// without firestore. each kind of document has its own bucket, keyed by ID, with the documents stored as JSON
+type BoltStore struct {
+	db *bolt.DB
+}
+
+var (
+	usersBucket       = []byte("users")
+	roomsBucket       = []byte("rooms")
+	reviewsBucket     = []byte("reviews")
+	submissionsBucket = []byte("submissions")
+	credentialsBucket = []byte("credentials")
+	sessionsBucket    = []byte("sessions")
+	ratingsBucket     = []byte("ratings")
+	changesBucket     = []byte("ratingChanges")
+	resultsBucket     = []byte("gameResults")
+	solvesBucket      = []byte("solves")
+)
+
+// OpenBoltStore opens the database at path, creating it if it doesn't exist yet
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{usersBucket, roomsBucket, reviewsBucket, submissionsBucket, credentialsBucket, sessionsBucket, ratingsBucket, changesBucket, resultsBucket, solvesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets; %w", err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// stores a document as JSON
+func (s *BoltStore) put(bucket []byte, id string, document any) error {
+	data, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(id), data)
+	})
+}
+
+// reads a document into document, or returns ErrNotFound
+func (s *BoltStore) get(bucket []byte, id string, document any) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucket).Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, document)
+	})
+}
+
+// checks the username against every user in the same transaction it's stored in, so two users can't get the same
+// one. like filterSubmissions, this reads every user
+func (s *BoltStore) CreateUser(ctx context.Context, user *models.User) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+		err := bucket.ForEach(func(id []byte, data []byte) error {
+			var other models.User
+			if err := json.Unmarshal(data, &other); err != nil {
+				return fmt.Errorf("failed to read user %s; %w", id, err)
+			}
+			if strings.EqualFold(other.Username, user.Username) {
+				return ErrExists
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		user.ID = newID()
+		data, err := json.Marshal(user)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(user.ID), data)
+	})
+}
+
+func (s *BoltStore) GetUser(ctx context.Context, id string) (models.User, error) {
+	var user models.User
+	err := s.get(usersBucket, id, &user)
+	return user, err
+}
+
+// usernames are only kept in the users themselves, so deleting the user frees theirs
+func (s *BoltStore) DeleteUser(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(usersBucket).Delete([]byte(id))
+	})
+}
+
+func (s *BoltStore) CreateRoom(ctx context.Context, room *models.Room) error {
+	room.ID = newID()
+	return s.put(roomsBucket, room.ID, room)
+}
+
+func (s *BoltStore) GetRoom(ctx context.Context, id string) (models.Room, error) {
+	var room models.Room
+	err := s.get(roomsBucket, id, &room)
+	return room, err
+}
+
+func (s *BoltStore) ListRooms(ctx context.Context) ([]models.Room, error) {
+	rooms := []models.Room{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(roomsBucket).ForEach(func(id []byte, data []byte) error {
+			var room models.Room
+			if err := json.Unmarshal(data, &room); err != nil {
+				return fmt.Errorf("failed to read room %s; %w", id, err)
+			}
+			rooms = append(rooms, room)
+			return nil
+		})
+	})
+	return rooms, err
+}
+
+func (s *BoltStore) UpdateRoom(ctx context.Context, id string, updates map[string]interface{}) error {
+	return s.ModifyRoom(ctx, id, func(room *models.Room) (bool, error) {
+		return false, applyRoomUpdates(room, updates)
+	})
+}
+
+// bolt only allows one write transaction at a time, so nothing else can change the room while it's being modified
+func (s *BoltStore) ModifyRoom(ctx context.Context, id string, change func(room *models.Room) (remove bool, err error)) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(roomsBucket)
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		var room models.Room
+		if err := json.Unmarshal(data, &room); err != nil {
+			return err
+		}
+		remove, err := change(&room)
+		if err != nil {
+			return err
+		}
+		if remove {
+			return bucket.Delete([]byte(id))
+		}
+		room.ID = id
+		data, err = json.Marshal(room)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(id), data)
+	})
+}
+
+func (s *BoltStore) DeleteRoom(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(roomsBucket).Delete([]byte(id))
+	})
+}
+
+func (s *BoltStore) SaveGameReview(ctx context.Context, review models.GameReview) error {
+	return s.put(reviewsBucket, review.GameID, review)
+}
+
+func (s *BoltStore) GetGameReview(ctx context.Context, gameID string) (models.GameReview, error) {
+	var review models.GameReview
+	err := s.get(reviewsBucket, gameID, &review)
+	return review, err
+}
+
+func (s *BoltStore) SaveSubmission(ctx context.Context, submission *models.Submission) error {
+	submission.ID = newID()
+	return s.put(submissionsBucket, submission.ID, submission)
+}
+
+func (s *BoltStore) GetSubmission(ctx context.Context, id string) (models.Submission, error) {
+	var submission models.Submission
+	err := s.get(submissionsBucket, id, &submission)
+	return submission, err
+}
+
+func (s *BoltStore) ListSubmissionsByUser(ctx context.Context, user string) ([]models.Submission, error) {
+	return s.filterSubmissions(func(submission models.Submission) bool {
+		return submission.User == user
+	})
+}
+
+func (s *BoltStore) ListSubmissionsByGame(ctx context.Context, gameID string) ([]models.Submission, error) {
+	return s.filterSubmissions(func(submission models.Submission) bool {
+		return submission.GameID == gameID
+	})
+}
+
+// the submissions that match, newest first. this reads every submission, which is fine at the scale a
+// self-hosted server runs at
+func (s *BoltStore) filterSubmissions(match func(models.Submission) bool) ([]models.Submission, error) {
+	matches := []models.Submission{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(submissionsBucket).ForEach(func(id []byte, data []byte) error {
+			var submission models.Submission
+			if err := json.Unmarshal(data, &submission); err != nil {
+				return fmt.Errorf("failed to read submission %s; %w", id, err)
+			}
+			if match(submission) {
+				matches = append(matches, submission)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortNewestFirst(matches)
+	return matches, nil
+}
+
+func (s *BoltStore) CreateCredentials(ctx context.Context, credentials models.Credentials) error {
+	data, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(credentialsBucket)
+		if bucket.Get([]byte(credentials.Email)) != nil {
+			return ErrExists
+		}
+		return bucket.Put([]byte(credentials.Email), data)
+	})
+}
+
+func (s *BoltStore) GetCredentials(ctx context.Context, email string) (models.Credentials, error) {
+	var credentials models.Credentials
+	err := s.get(credentialsBucket, email, &credentials)
+	return credentials, err
+}
+
+func (s *BoltStore) SaveSession(ctx context.Context, session models.Session) error {
+	return s.put(sessionsBucket, session.ID, session)
+}
+
+func (s *BoltStore) TakeSession(ctx context.Context, id string) (models.Session, error) {
+	var session models.Session
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sessionsBucket)
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		if err := json.Unmarshal(data, &session); err != nil {
+			return err
+		}
+		return bucket.Delete([]byte(id))
+	})
+	return session, err
+}
+
+func (s *BoltStore) DeleteSession(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Delete([]byte(id))
+	})
+}
+
+func (s *BoltStore) GetRating(ctx context.Context, user string) (models.PlayerRating, error) {
+	var rating models.PlayerRating
+	err := s.get(ratingsBucket, user, &rating)
+	return rating, err
+}
+
+// rating changes are keyed by user, then game, so a user's history can be read by seeking to their prefix
+func changeKey(user string, gameID string) []byte {
+	return []byte(user + "\x00" + gameID)
+}
+
+func (s *BoltStore) SaveRating(ctx context.Context, rating models.PlayerRating, change models.RatingChange) error {
+	ratingData, err := json.Marshal(rating)
+	if err != nil {
+		return err
+	}
+	changeData, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(ratingsBucket).Put([]byte(rating.User), ratingData); err != nil {
+			return err
+		}
+		return tx.Bucket(changesBucket).Put(changeKey(change.User, change.GameID), changeData)
+	})
+}
+
+func (s *BoltStore) ListRatingChanges(ctx context.Context, user string) ([]models.RatingChange, error) {
+	changes := []models.RatingChange{}
+	prefix := changeKey(user, "")
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(changesBucket).Cursor()
+		for key, data := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, data = cursor.Next() {
+			var change models.RatingChange
+			if err := json.Unmarshal(data, &change); err != nil {
+				return fmt.Errorf("failed to read rating change %s; %w", key, err)
+			}
+			changes = append(changes, change)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortChangesNewestFirst(changes)
+	return changes, nil
+}
+
+// reads every document in a bucket, and passes each one to add
+func boltForEach[T any](s *BoltStore, bucket []byte, add func(document T)) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(id []byte, data []byte) error {
+			var document T
+			if err := json.Unmarshal(data, &document); err != nil {
+				return fmt.Errorf("failed to read %s/%s; %w", bucket, id, err)
+			}
+			add(document)
+			return nil
+		})
+	})
+}
+
+func (s *BoltStore) ListRatings(ctx context.Context) ([]models.PlayerRating, error) {
+	ratings := []models.PlayerRating{}
+	err := boltForEach(s, ratingsBucket, func(rating models.PlayerRating) {
+		ratings = append(ratings, rating)
+	})
+	return ratings, err
+}
+
+func (s *BoltStore) ListRatingChangesSince(ctx context.Context, since time.Time) ([]models.RatingChange, error) {
+	changes := []models.RatingChange{}
+	err := boltForEach(s, changesBucket, func(change models.RatingChange) {
+		if !change.At.Before(since) {
+			changes = append(changes, change)
+		}
+	})
+	return changes, err
+}
+
+func (s *BoltStore) SaveGameResult(ctx context.Context, result models.GameResult) error {
+	return s.put(resultsBucket, result.GameID, result)
+}
+
+func (s *BoltStore) ListGameResults(ctx context.Context, since time.Time) ([]models.GameResult, error) {
+	results := []models.GameResult{}
+	err := boltForEach(s, resultsBucket, func(result models.GameResult) {
+		if !result.EndedAt.Before(since) {
+			results = append(results, result)
+		}
+	})
+	return results, err
+}
+
+func (s *BoltStore) SaveSolve(ctx context.Context, solve models.Solve) error {
+	return s.put(solvesBucket, newID(), solve)
+}
+
+func (s *BoltStore) ListSolves(ctx context.Context, problemID string, since time.Time) ([]models.Solve, error) {
+	solves := []models.Solve{}
+	err := boltForEach(s, solvesBucket, func(solve models.Solve) {
+		if solve.ProblemID == problemID && !solve.SolvedAt.Before(since) {
+			solves = append(solves, solve)
+		}
+	})
+	return solves, err
+}
@@ -0,0 +1,415 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/webbben/code-duel/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreStore keeps everything in firestore, in the "users", "usernames", "rooms", "reviews", "submissions",
+// "credentials", "sessions", "ratings", "ratingChanges", "gameResults" and "solves" collections. reviews and game
+// results are keyed by game ID, credentials by email, sessions by their own ID, ratings by username and usernames
+// by the lowercased username, and the other documents get their IDs from firestore
+type FirestoreStore struct {
+	client *firestore.Client
+}
+
+func NewFirestoreStore(client *firestore.Client) *FirestoreStore {
+	return &FirestoreStore{client: client}
+}
+
+// the firestore client is shared with the rest of the server, so it's left open
+func (s *FirestoreStore) Close() error {
+	return nil
+}
+
+// reads a document into document, or returns ErrNotFound
+func (s *FirestoreStore) get(ctx context.Context, collection string, id string, document any) error {
+	snapshot, err := s.client.Collection(collection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get %s/%s; %w", collection, id, err)
+	}
+	if err := snapshot.DataTo(document); err != nil {
+		return fmt.Errorf("failed to get data from %s/%s; %w", collection, id, err)
+	}
+	return nil
+}
+
+// claims the username in the "usernames" collection in the same transaction as the user is added, so the
+// transaction fails if someone else has it
+func (s *FirestoreStore) CreateUser(ctx context.Context, user *models.User) error {
+	userRef := s.client.Collection("users").NewDoc()
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		usernameRef := s.client.Collection("usernames").Doc(strings.ToLower(user.Username))
+		if err := tx.Create(usernameRef, map[string]interface{}{"userID": userRef.ID}); err != nil {
+			return err
+		}
+		return tx.Create(userRef, map[string]interface{}{
+			"username": user.Username,
+			"email":    user.Email,
+		})
+	})
+	if status.Code(err) == codes.AlreadyExists {
+		return ErrExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed adding user; %w", err)
+	}
+	user.ID = userRef.ID
+	return nil
+}
+
+// deletes the username's claim in the same transaction as the user, so the username is free again as soon as the
+// user is gone
+func (s *FirestoreStore) DeleteUser(ctx context.Context, id string) error {
+	userRef := s.client.Collection("users").Doc(id)
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snapshot, err := tx.Get(userRef)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		username, err := snapshot.DataAt("username")
+		if err != nil {
+			return err
+		}
+		if name, ok := username.(string); ok {
+			if err := tx.Delete(s.client.Collection("usernames").Doc(strings.ToLower(name))); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(userRef)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete user %s; %w", id, err)
+	}
+	return nil
+}
+
+func (s *FirestoreStore) GetUser(ctx context.Context, id string) (models.User, error) {
+	var data struct {
+		Username string `firestore:"username"`
+		Email    string `firestore:"email"`
+	}
+	if err := s.get(ctx, "users", id, &data); err != nil {
+		return models.User{}, err
+	}
+	return models.User{ID: id, Username: data.Username, Email: data.Email}, nil
+}
+
+func (s *FirestoreStore) CreateRoom(ctx context.Context, room *models.Room) error {
+	docRef, _, err := s.client.Collection("rooms").Add(ctx, room)
+	if err != nil {
+		return fmt.Errorf("failed creating room; %w", err)
+	}
+	room.ID = docRef.ID
+	return nil
+}
+
+func (s *FirestoreStore) GetRoom(ctx context.Context, id string) (models.Room, error) {
+	var room models.Room
+	if err := s.get(ctx, "rooms", id, &room); err != nil {
+		return models.Room{}, err
+	}
+	room.ID = id
+	return room, nil
+}
+
+func (s *FirestoreStore) ListRooms(ctx context.Context) ([]models.Room, error) {
+	snapshots, err := s.client.Collection("rooms").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshots; %w", err)
+	}
+	rooms := make([]models.Room, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		var room models.Room
+		if err := snapshot.DataTo(&room); err != nil {
+			return nil, fmt.Errorf("failed to get data from snapshot; %w", err)
+		}
+		room.ID = snapshot.Ref.ID // add the document ID too
+		rooms = append(rooms, room)
+	}
+	return rooms, nil
+}
+
+func (s *FirestoreStore) UpdateRoom(ctx context.Context, id string, updates map[string]interface{}) error {
+	firestoreUpdates := make([]firestore.Update, 0, len(updates))
+	for key, value := range updates {
+		firestoreUpdates = append(firestoreUpdates, firestore.Update{Path: key, Value: value})
+	}
+	_, err := s.client.Collection("rooms").Doc(id).Update(ctx, firestoreUpdates)
+	return err
+}
+
+func (s *FirestoreStore) ModifyRoom(ctx context.Context, id string, change func(room *models.Room) (remove bool, err error)) error {
+	roomRef := s.client.Collection("rooms").Doc(id)
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(roomRef)
+		if status.Code(err) == codes.NotFound {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var room models.Room
+		if err := doc.DataTo(&room); err != nil {
+			return err
+		}
+		room.ID = id
+		remove, err := change(&room)
+		if err != nil {
+			return err
+		}
+		if remove {
+			return tx.Delete(roomRef)
+		}
+		return tx.Set(roomRef, room)
+	})
+}
+
+func (s *FirestoreStore) DeleteRoom(ctx context.Context, id string) error {
+	_, err := s.client.Collection("rooms").Doc(id).Delete(ctx)
+	return err
+}
+
+func (s *FirestoreStore) SaveGameReview(ctx context.Context, review models.GameReview) error {
+	_, err := s.client.Collection("reviews").Doc(review.GameID).Set(ctx, review)
+	if err != nil {
+		return fmt.Errorf("failed to save review of game %s; %w", review.GameID, err)
+	}
+	return nil
+}
+
+func (s *FirestoreStore) GetGameReview(ctx context.Context, gameID string) (models.GameReview, error) {
+	var review models.GameReview
+	err := s.get(ctx, "reviews", gameID, &review)
+	return review, err
+}
+
+func (s *FirestoreStore) SaveSubmission(ctx context.Context, submission *models.Submission) error {
+	docRef, _, err := s.client.Collection("submissions").Add(ctx, submission)
+	if err != nil {
+		return fmt.Errorf("failed to save submission; %w", err)
+	}
+	submission.ID = docRef.ID
+	return nil
+}
+
+func (s *FirestoreStore) GetSubmission(ctx context.Context, id string) (models.Submission, error) {
+	var submission models.Submission
+	if err := s.get(ctx, "submissions", id, &submission); err != nil {
+		return models.Submission{}, err
+	}
+	submission.ID = id
+	return submission, nil
+}
+
+func (s *FirestoreStore) ListSubmissionsByUser(ctx context.Context, user string) ([]models.Submission, error) {
+	return s.listSubmissions(ctx, s.client.Collection("submissions").Where("User", "==", user))
+}
+
+func (s *FirestoreStore) ListSubmissionsByGame(ctx context.Context, gameID string) ([]models.Submission, error) {
+	return s.listSubmissions(ctx, s.client.Collection("submissions").Where("GameID", "==", gameID))
+}
+
+// runs the query and sorts what it finds, newest first. sorting here means firestore doesn't need an index for
+// each query
+func (s *FirestoreStore) listSubmissions(ctx context.Context, query firestore.Query) ([]models.Submission, error) {
+	snapshots, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get submissions; %w", err)
+	}
+	submissions := make([]models.Submission, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		var submission models.Submission
+		if err := snapshot.DataTo(&submission); err != nil {
+			return nil, fmt.Errorf("failed to get data from submission %s; %w", snapshot.Ref.ID, err)
+		}
+		submission.ID = snapshot.Ref.ID
+		submissions = append(submissions, submission)
+	}
+	sortNewestFirst(submissions)
+	return submissions, nil
+}
+
+func (s *FirestoreStore) CreateCredentials(ctx context.Context, credentials models.Credentials) error {
+	_, err := s.client.Collection("credentials").Doc(credentials.Email).Create(ctx, credentials)
+	if status.Code(err) == codes.AlreadyExists {
+		return ErrExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save credentials; %w", err)
+	}
+	return nil
+}
+
+func (s *FirestoreStore) GetCredentials(ctx context.Context, email string) (models.Credentials, error) {
+	var credentials models.Credentials
+	err := s.get(ctx, "credentials", email, &credentials)
+	return credentials, err
+}
+
+func (s *FirestoreStore) SaveSession(ctx context.Context, session models.Session) error {
+	_, err := s.client.Collection("sessions").Doc(session.ID).Set(ctx, session)
+	if err != nil {
+		return fmt.Errorf("failed to save session; %w", err)
+	}
+	return nil
+}
+
+func (s *FirestoreStore) TakeSession(ctx context.Context, id string) (models.Session, error) {
+	var session models.Session
+	sessionRef := s.client.Collection("sessions").Doc(id)
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(sessionRef)
+		if status.Code(err) == codes.NotFound {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := doc.DataTo(&session); err != nil {
+			return err
+		}
+		return tx.Delete(sessionRef)
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return models.Session{}, fmt.Errorf("failed to take session; %w", err)
+	}
+	return session, err
+}
+
+func (s *FirestoreStore) DeleteSession(ctx context.Context, id string) error {
+	_, err := s.client.Collection("sessions").Doc(id).Delete(ctx)
+	return err
+}
+
+func (s *FirestoreStore) GetRating(ctx context.Context, user string) (models.PlayerRating, error) {
+	var rating models.PlayerRating
+	err := s.get(ctx, "ratings", user, &rating)
+	return rating, err
+}
+
+func (s *FirestoreStore) SaveRating(ctx context.Context, rating models.PlayerRating, change models.RatingChange) error {
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := tx.Set(s.client.Collection("ratings").Doc(rating.User), rating); err != nil {
+			return err
+		}
+		return tx.Create(s.client.Collection("ratingChanges").NewDoc(), change)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save rating of %s; %w", rating.User, err)
+	}
+	return nil
+}
+
+func (s *FirestoreStore) ListRatingChanges(ctx context.Context, user string) ([]models.RatingChange, error) {
+	snapshots, err := s.client.Collection("ratingChanges").Where("User", "==", user).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rating changes; %w", err)
+	}
+	changes := make([]models.RatingChange, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		var change models.RatingChange
+		if err := snapshot.DataTo(&change); err != nil {
+			return nil, fmt.Errorf("failed to get data from rating change %s; %w", snapshot.Ref.ID, err)
+		}
+		changes = append(changes, change)
+	}
+	sortChangesNewestFirst(changes)
+	return changes, nil
+}
+
+// reads every document the query finds, and passes each one to add
+func firestoreForEach[T any](ctx context.Context, query firestore.Query, add func(document T)) error {
+	snapshots, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range snapshots {
+		var document T
+		if err := snapshot.DataTo(&document); err != nil {
+			return fmt.Errorf("failed to get data from %s; %w", snapshot.Ref.Path, err)
+		}
+		add(document)
+	}
+	return nil
+}
+
+func (s *FirestoreStore) ListRatings(ctx context.Context) ([]models.PlayerRating, error) {
+	ratings := []models.PlayerRating{}
+	err := firestoreForEach(ctx, s.client.Collection("ratings").Query, func(rating models.PlayerRating) {
+		ratings = append(ratings, rating)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ratings; %w", err)
+	}
+	return ratings, nil
+}
+
+func (s *FirestoreStore) ListRatingChangesSince(ctx context.Context, since time.Time) ([]models.RatingChange, error) {
+	changes := []models.RatingChange{}
+	query := s.client.Collection("ratingChanges").Where("At", ">=", since)
+	err := firestoreForEach(ctx, query, func(change models.RatingChange) {
+		changes = append(changes, change)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rating changes; %w", err)
+	}
+	return changes, nil
+}
+
+func (s *FirestoreStore) SaveGameResult(ctx context.Context, result models.GameResult) error {
+	_, err := s.client.Collection("gameResults").Doc(result.GameID).Set(ctx, result)
+	if err != nil {
+		return fmt.Errorf("failed to save result of game %s; %w", result.GameID, err)
+	}
+	return nil
+}
+
+func (s *FirestoreStore) ListGameResults(ctx context.Context, since time.Time) ([]models.GameResult, error) {
+	results := []models.GameResult{}
+	query := s.client.Collection("gameResults").Where("EndedAt", ">=", since)
+	err := firestoreForEach(ctx, query, func(result models.GameResult) {
+		results = append(results, result)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get game results; %w", err)
+	}
+	return results, nil
+}
+
+func (s *FirestoreStore) SaveSolve(ctx context.Context, solve models.Solve) error {
+	_, _, err := s.client.Collection("solves").Add(ctx, solve)
+	if err != nil {
+		return fmt.Errorf("failed to save solve; %w", err)
+	}
+	return nil
+}
+
+// solves are only queried by problem, and filtered by time here, so firestore doesn't need a composite index
+func (s *FirestoreStore) ListSolves(ctx context.Context, problemID string, since time.Time) ([]models.Solve, error) {
+	solves := []models.Solve{}
+	query := s.client.Collection("solves").Where("ProblemID", "==", problemID)
+	err := firestoreForEach(ctx, query, func(solve models.Solve) {
+		if !solve.SolvedAt.Before(since) {
+			solves = append(solves, solve)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get solves of problem %s; %w", problemID, err)
+	}
+	return solves, nil
+}
@@ -0,0 +1,340 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// MemoryStore keeps everything in memory, so it only lasts as long as the server is running. it's meant for tests
+// and trying the server out
+type MemoryStore struct {
+	mu          sync.Mutex
+	users       map[string]models.User
+	rooms       map[string]models.Room
+	reviews     map[string]models.GameReview
+	submissions []models.Submission
+	credentials map[string]models.Credentials
+	sessions    map[string]models.Session
+	ratings     map[string]models.PlayerRating
+	changes     []models.RatingChange
+	results     map[string]models.GameResult
+	solves      []models.Solve
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:       map[string]models.User{},
+		rooms:       map[string]models.Room{},
+		reviews:     map[string]models.GameReview{},
+		credentials: map[string]models.Credentials{},
+		sessions:    map[string]models.Session{},
+		ratings:     map[string]models.PlayerRating{},
+		results:     map[string]models.GameResult{},
+	}
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func (s *MemoryStore) CreateUser(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, other := range s.users {
+		if strings.EqualFold(other.Username, user.Username) {
+			return ErrExists
+		}
+	}
+	user.ID = newID()
+	s.users[user.ID] = *user
+	return nil
+}
+
+func (s *MemoryStore) GetUser(ctx context.Context, id string) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[id]
+	if !ok {
+		return models.User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+func (s *MemoryStore) DeleteUser(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.users, id)
+	return nil
+}
+
+func (s *MemoryStore) CreateRoom(ctx context.Context, room *models.Room) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	room.ID = newID()
+	s.rooms[room.ID] = copyRoom(*room)
+	return nil
+}
+
+func (s *MemoryStore) GetRoom(ctx context.Context, id string) (models.Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	room, ok := s.rooms[id]
+	if !ok {
+		return models.Room{}, ErrNotFound
+	}
+	return copyRoom(room), nil
+}
+
+func (s *MemoryStore) ListRooms(ctx context.Context) ([]models.Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rooms := make([]models.Room, 0, len(s.rooms))
+	for _, room := range s.rooms {
+		rooms = append(rooms, copyRoom(room))
+	}
+	return rooms, nil
+}
+
+func (s *MemoryStore) UpdateRoom(ctx context.Context, id string, updates map[string]interface{}) error {
+	return s.ModifyRoom(ctx, id, func(room *models.Room) (bool, error) {
+		return false, applyRoomUpdates(room, updates)
+	})
+}
+
+func (s *MemoryStore) ModifyRoom(ctx context.Context, id string, change func(room *models.Room) (remove bool, err error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.rooms[id]
+	if !ok {
+		return ErrNotFound
+	}
+	room := copyRoom(stored)
+	remove, err := change(&room)
+	if err != nil {
+		return err
+	}
+	if remove {
+		delete(s.rooms, id)
+		return nil
+	}
+	room.ID = id
+	s.rooms[id] = room
+	return nil
+}
+
+func (s *MemoryStore) DeleteRoom(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.rooms, id)
+	return nil
+}
+
+func (s *MemoryStore) SaveGameReview(ctx context.Context, review models.GameReview) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reviews[review.GameID] = review
+	return nil
+}
+
+func (s *MemoryStore) GetGameReview(ctx context.Context, gameID string) (models.GameReview, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	review, ok := s.reviews[gameID]
+	if !ok {
+		return models.GameReview{}, ErrNotFound
+	}
+	return review, nil
+}
+
+func (s *MemoryStore) SaveSubmission(ctx context.Context, submission *models.Submission) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	submission.ID = newID()
+	s.submissions = append(s.submissions, *submission)
+	return nil
+}
+
+func (s *MemoryStore) GetSubmission(ctx context.Context, id string) (models.Submission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, submission := range s.submissions {
+		if submission.ID == id {
+			return submission, nil
+		}
+	}
+	return models.Submission{}, ErrNotFound
+}
+
+func (s *MemoryStore) ListSubmissionsByUser(ctx context.Context, user string) ([]models.Submission, error) {
+	return s.filterSubmissions(func(submission models.Submission) bool {
+		return submission.User == user
+	}), nil
+}
+
+func (s *MemoryStore) ListSubmissionsByGame(ctx context.Context, gameID string) ([]models.Submission, error) {
+	return s.filterSubmissions(func(submission models.Submission) bool {
+		return submission.GameID == gameID
+	}), nil
+}
+
+// the submissions that match, newest first
+func (s *MemoryStore) filterSubmissions(match func(models.Submission) bool) []models.Submission {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	matches := []models.Submission{}
+	for _, submission := range s.submissions {
+		if match(submission) {
+			matches = append(matches, submission)
+		}
+	}
+	sortNewestFirst(matches)
+	return matches
+}
+
+func (s *MemoryStore) CreateCredentials(ctx context.Context, credentials models.Credentials) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.credentials[credentials.Email]; ok {
+		return ErrExists
+	}
+	s.credentials[credentials.Email] = credentials
+	return nil
+}
+
+func (s *MemoryStore) GetCredentials(ctx context.Context, email string) (models.Credentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	credentials, ok := s.credentials[email]
+	if !ok {
+		return models.Credentials{}, ErrNotFound
+	}
+	return credentials, nil
+}
+
+func (s *MemoryStore) SaveSession(ctx context.Context, session models.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[session.ID] = session
+	return nil
+}
+
+func (s *MemoryStore) TakeSession(ctx context.Context, id string) (models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return models.Session{}, ErrNotFound
+	}
+	delete(s.sessions, id)
+	return session, nil
+}
+
+func (s *MemoryStore) DeleteSession(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *MemoryStore) GetRating(ctx context.Context, user string) (models.PlayerRating, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rating, ok := s.ratings[user]
+	if !ok {
+		return models.PlayerRating{}, ErrNotFound
+	}
+	return rating, nil
+}
+
+func (s *MemoryStore) SaveRating(ctx context.Context, rating models.PlayerRating, change models.RatingChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ratings[rating.User] = rating
+	s.changes = append(s.changes, change)
+	return nil
+}
+
+func (s *MemoryStore) ListRatingChanges(ctx context.Context, user string) ([]models.RatingChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := []models.RatingChange{}
+	for _, change := range s.changes {
+		if change.User == user {
+			changes = append(changes, change)
+		}
+	}
+	sortChangesNewestFirst(changes)
+	return changes, nil
+}
+
+func (s *MemoryStore) ListRatings(ctx context.Context) ([]models.PlayerRating, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ratings := make([]models.PlayerRating, 0, len(s.ratings))
+	for _, rating := range s.ratings {
+		ratings = append(ratings, rating)
+	}
+	return ratings, nil
+}
+
+func (s *MemoryStore) ListRatingChangesSince(ctx context.Context, since time.Time) ([]models.RatingChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := []models.RatingChange{}
+	for _, change := range s.changes {
+		if !change.At.Before(since) {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (s *MemoryStore) SaveGameResult(ctx context.Context, result models.GameResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result.Players = slices.Clone(result.Players)
+	s.results[result.GameID] = result
+	return nil
+}
+
+func (s *MemoryStore) ListGameResults(ctx context.Context, since time.Time) ([]models.GameResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := []models.GameResult{}
+	for _, result := range s.results {
+		if !result.EndedAt.Before(since) {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+func (s *MemoryStore) SaveSolve(ctx context.Context, solve models.Solve) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.solves = append(s.solves, solve)
+	return nil
+}
+
+func (s *MemoryStore) ListSolves(ctx context.Context, problemID string, since time.Time) ([]models.Solve, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	solves := []models.Solve{}
+	for _, solve := range s.solves {
+		if solve.ProblemID == problemID && !solve.SolvedAt.Before(since) {
+			solves = append(solves, solve)
+		}
+	}
+	return solves, nil
+}
+
+// copies a room, so changes to the copy's users don't change the stored room
+func copyRoom(room models.Room) models.Room {
+	room.Users = slices.Clone(room.Users)
+	return room
+}
@@ -0,0 +1,211 @@
+// Package storage is the repository layer for everything the server keeps: users, rooms, game reviews,
+// submissions, ratings, leaderboard results, and the logins and sessions of the local auth provider. the Store
+// interface has one implementation for each backend, and the STORE setting picks which one the server uses:
+// firestore, an in-memory store for tests, or an embedded bolt database for self-hosting.
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/webbben/code-duel/config"
+	"github.com/webbben/code-duel/firebase"
+	"github.com/webbben/code-duel/models"
+)
+
+// ErrNotFound is returned when there's nothing stored with the requested ID
+var ErrNotFound = errors.New("not found")
+
+// ErrExists is returned when something that has to be unique is already stored
+var ErrExists = errors.New("already exists")
+
+// Store keeps all of the server's data. lists of submissions are sorted newest first
+type Store interface {
+	UserStore
+	RoomStore
+	GameStore
+	SubmissionStore
+	AuthStore
+	RatingStore
+	LeaderboardStore
+	// Close releases the store's resources, such as its database file
+	Close() error
+}
+
+type UserStore interface {
+	// CreateUser stores a new user, and sets its ID, or returns ErrExists if another user has the same username.
+	// usernames are compared without case
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUser(ctx context.Context, id string) (models.User, error)
+	// DeleteUser removes a user and frees their username. deleting a user that doesn't exist isn't an error
+	DeleteUser(ctx context.Context, id string) error
+}
+
+type RoomStore interface {
+	// CreateRoom stores a new room, and sets its ID
+	CreateRoom(ctx context.Context, room *models.Room) error
+	GetRoom(ctx context.Context, id string) (models.Room, error)
+	ListRooms(ctx context.Context) ([]models.Room, error)
+	// UpdateRoom sets some of a room's fields. updates are keyed by field name (as in the Room's JSON)
+	UpdateRoom(ctx context.Context, id string, updates map[string]interface{}) error
+	// ModifyRoom reads a room and passes it to change, which can modify it, then writes it back. nothing else can
+	// change the room in between. if change returns remove, the room is deleted instead, and if it returns an
+	// error, the room is left as it was
+	ModifyRoom(ctx context.Context, id string, change func(room *models.Room) (remove bool, err error)) error
+	DeleteRoom(ctx context.Context, id string) error
+}
+
+type GameStore interface {
+	// SaveGameReview stores the review of a finished game, replacing any earlier one
+	SaveGameReview(ctx context.Context, review models.GameReview) error
+	GetGameReview(ctx context.Context, gameID string) (models.GameReview, error)
+}
+
+type SubmissionStore interface {
+	// SaveSubmission stores a new submission, and sets its ID
+	SaveSubmission(ctx context.Context, submission *models.Submission) error
+	GetSubmission(ctx context.Context, id string) (models.Submission, error)
+	ListSubmissionsByUser(ctx context.Context, user string) ([]models.Submission, error)
+	ListSubmissionsByGame(ctx context.Context, gameID string) ([]models.Submission, error)
+}
+
+// AuthStore keeps the logins and refresh sessions of the local auth provider. credentials are keyed by email,
+// which is lowercased before it gets here
+type AuthStore interface {
+	// CreateCredentials stores a user's login, or returns ErrExists if the email already has one
+	CreateCredentials(ctx context.Context, credentials models.Credentials) error
+	GetCredentials(ctx context.Context, email string) (models.Credentials, error)
+	SaveSession(ctx context.Context, session models.Session) error
+	// TakeSession removes a session and returns it, or returns ErrNotFound if it isn't there. when several callers
+	// take the same session at once, only one of them gets it
+	TakeSession(ctx context.Context, id string) (models.Session, error)
+	// DeleteSession removes a session. deleting one that isn't there isn't an error
+	DeleteSession(ctx context.Context, id string) error
+}
+
+// RatingStore keeps players' ratings and rating history, keyed by username
+type RatingStore interface {
+	// GetRating gets a player's current rating, or ErrNotFound if they haven't played a rated game
+	GetRating(ctx context.Context, user string) (models.PlayerRating, error)
+	// SaveRating stores a player's new rating, and adds the change that led to it to their history
+	SaveRating(ctx context.Context, rating models.PlayerRating, change models.RatingChange) error
+	// ListRatingChanges lists a player's rating history, newest first
+	ListRatingChanges(ctx context.Context, user string) ([]models.RatingChange, error)
+	// ListRatings lists every player's current rating, in no particular order
+	ListRatings(ctx context.Context) ([]models.PlayerRating, error)
+	// ListRatingChangesSince lists every player's rating changes made at or after since, in no particular order
+	ListRatingChangesSince(ctx context.Context, since time.Time) ([]models.RatingChange, error)
+}
+
+// LeaderboardStore keeps the results the leaderboards are worked out from. lists are in no particular order, and
+// a zero since lists everything
+type LeaderboardStore interface {
+	SaveGameResult(ctx context.Context, result models.GameResult) error
+	// ListGameResults lists the games that ended at or after since
+	ListGameResults(ctx context.Context, since time.Time) ([]models.GameResult, error)
+	SaveSolve(ctx context.Context, solve models.Solve) error
+	// ListSolves lists a problem's solves made at or after since
+	ListSolves(ctx context.Context, problemID string, since time.Time) ([]models.Solve, error)
+}
+
+var (
+	store     Store
+	storeOnce sync.Once
+)
+
+// Get returns the store the server uses, opening the configured backend on first use
+func Get() Store {
+	storeOnce.Do(func() {
+		store = open(config.Get())
+	})
+	return store
+}
+
+// Set replaces the store the server uses
+func Set(s Store) {
+	storeOnce.Do(func() {})
+	store = s
+}
+
+func open(cfg config.Config) Store {
+	switch cfg.Store {
+	case "memory":
+		return NewMemoryStore()
+	case "bolt":
+		s, err := OpenBoltStore(cfg.StorePath)
+		if err != nil {
+			log.Fatalf("failed to open the bolt database at %s: %v\n", cfg.StorePath, err)
+		}
+		return s
+	case "firestore":
+		// keeping data in memory has to be asked for, so a deploy that's missing its credentials doesn't lose it all
+		client := firebase.GetFirestoreClient()
+		if client == nil {
+			log.Fatalln("no firestore client, so the firestore store can't be used. set the FIREBASE_* environment variables, or set STORE=bolt or STORE=memory to run without firestore")
+		}
+		return NewFirestoreStore(client)
+	default:
+		log.Fatalf("unknown store %q; use firestore, bolt or memory\n", cfg.Store)
+		return nil
+	}
+}
+
+// makes a random ID for a new document, for the stores that don't make their own
+func newID() string {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to make an ID; %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// sets the room's fields that are in updates, the way firestore would
+func applyRoomUpdates(room *models.Room, updates map[string]interface{}) error {
+	// the room's JSON has the same field names as the updates, so they can be merged into it
+	data, err := json.Marshal(room)
+	if err != nil {
+		return err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for key, value := range updates {
+		if _, ok := fields[key]; !ok {
+			return fmt.Errorf("rooms have no field %s", key)
+		}
+		fields[key] = value
+	}
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	var updated models.Room
+	if err := json.Unmarshal(data, &updated); err != nil {
+		return fmt.Errorf("invalid room update; %w", err)
+	}
+	*room = updated
+	return nil
+}
+
+// sorts rating changes newest first
+func sortChangesNewestFirst(changes []models.RatingChange) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].At.After(changes[j].At)
+	})
+}
+
+// sorts submissions newest first
+func sortNewestFirst(submissions []models.Submission) {
+	sort.SliceStable(submissions, func(i, j int) bool {
+		return submissions[i].SubmittedAt.After(submissions[j].SubmittedAt)
+	})
+}
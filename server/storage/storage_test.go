@@ -0,0 +1,320 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/webbben/code-duel/models"
+)
+
+// the stores that can be tested without any outside services
+func testStores(t *testing.T) map[string]Store {
+	bolt, err := OpenBoltStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bolt.Close() })
+	return map[string]Store{
+		"memory": NewMemoryStore(),
+		"bolt":   bolt,
+	}
+}
+
+func TestUsers(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			user := models.User{Username: "alice", Email: "alice@example.com"}
+			if err := store.CreateUser(ctx, &user); err != nil || user.ID == "" {
+				t.Fatalf("CreateUser: %+v (%v)", user, err)
+			}
+			got, err := store.GetUser(ctx, user.ID)
+			if err != nil || got != user {
+				t.Errorf("GetUser: %+v (%v), expected %+v", got, err, user)
+			}
+			if _, err := store.GetUser(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetUser of a missing user: %v, expected ErrNotFound", err)
+			}
+			taken := models.User{Username: "Alice", Email: "other@example.com"}
+			if err := store.CreateUser(ctx, &taken); !errors.Is(err, ErrExists) {
+				t.Errorf("CreateUser with a username that's taken: %v, expected ErrExists", err)
+			}
+			if err := store.DeleteUser(ctx, user.ID); err != nil {
+				t.Fatalf("DeleteUser: %v", err)
+			}
+			if _, err := store.GetUser(ctx, user.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetUser of a deleted user: %v, expected ErrNotFound", err)
+			}
+			if err := store.CreateUser(ctx, &taken); err != nil {
+				t.Errorf("CreateUser with a deleted user's username: %v", err)
+			}
+			if err := store.DeleteUser(ctx, "missing"); err != nil {
+				t.Errorf("DeleteUser of a missing user: %v", err)
+			}
+		})
+	}
+}
+
+func TestRooms(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			room := models.Room{Owner: "alice", Title: "duel", MaxCapacity: 2, Users: []string{"alice"}, TimeLimit: 30}
+			if err := store.CreateRoom(ctx, &room); err != nil || room.ID == "" {
+				t.Fatalf("CreateRoom: %+v (%v)", room, err)
+			}
+
+			// updates come from JSON, so numbers are floats
+			err := store.UpdateRoom(ctx, room.ID, map[string]interface{}{"Difficulty": 2.0, "ScoringMode": models.ScoringRuntime})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.UpdateRoom(ctx, room.ID, map[string]interface{}{"Nonsense": 1}); err == nil {
+				t.Error("UpdateRoom should reject fields rooms don't have")
+			}
+			got, err := store.GetRoom(ctx, room.ID)
+			if err != nil || got.ID != room.ID || got.Difficulty != 2 || got.ScoringMode != models.ScoringRuntime || got.TimeLimit != 30 {
+				t.Errorf("GetRoom after update: %+v (%v)", got, err)
+			}
+
+			err = store.ModifyRoom(ctx, room.ID, func(room *models.Room) (bool, error) {
+				room.Users = append(room.Users, "bob")
+				return false, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			failed := errors.New("room is full")
+			err = store.ModifyRoom(ctx, room.ID, func(room *models.Room) (bool, error) {
+				room.Users = append(room.Users, "carol")
+				return false, failed
+			})
+			if !errors.Is(err, failed) {
+				t.Errorf("ModifyRoom should return change's error, got %v", err)
+			}
+			rooms, err := store.ListRooms(ctx)
+			if err != nil || len(rooms) != 1 || len(rooms[0].Users) != 2 || rooms[0].Users[1] != "bob" {
+				t.Errorf("ListRooms: %+v (%v), expected alice and bob in the room", rooms, err)
+			}
+
+			err = store.ModifyRoom(ctx, room.ID, func(room *models.Room) (bool, error) {
+				return true, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.GetRoom(ctx, room.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetRoom of a removed room: %v, expected ErrNotFound", err)
+			}
+			if err := store.ModifyRoom(ctx, room.ID, func(*models.Room) (bool, error) { return false, nil }); !errors.Is(err, ErrNotFound) {
+				t.Errorf("ModifyRoom of a removed room: %v, expected ErrNotFound", err)
+			}
+
+			other := models.Room{Title: "other"}
+			store.CreateRoom(ctx, &other)
+			if err := store.DeleteRoom(ctx, other.ID); err != nil {
+				t.Fatal(err)
+			}
+			if rooms, _ := store.ListRooms(ctx); len(rooms) != 0 {
+				t.Errorf("rooms left after deleting them all: %+v", rooms)
+			}
+		})
+	}
+}
+
+func TestSubmissions(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			sent := []models.Submission{
+				{User: "alice", GameID: "room1-1", ProblemID: "problem01", SubmittedAt: start},
+				{User: "bob", GameID: "room1-1", ProblemID: "problem01", SubmittedAt: start.Add(time.Minute)},
+				{User: "alice", ProblemID: "problem02", SubmittedAt: start.Add(2 * time.Minute),
+					Cases: []models.SubmissionCase{{Case: 0, Verdict: "AC", Runtime: 5}}},
+			}
+			for i := range sent {
+				if err := store.SaveSubmission(ctx, &sent[i]); err != nil || sent[i].ID == "" {
+					t.Fatalf("SaveSubmission %d: %v", i, err)
+				}
+			}
+
+			got, err := store.GetSubmission(ctx, sent[2].ID)
+			if err != nil || got.User != "alice" || !got.SubmittedAt.Equal(sent[2].SubmittedAt) || len(got.Cases) != 1 || got.Cases[0].Verdict != "AC" {
+				t.Errorf("GetSubmission: %+v (%v)", got, err)
+			}
+			if _, err := store.GetSubmission(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetSubmission of a missing submission: %v, expected ErrNotFound", err)
+			}
+
+			byUser, err := store.ListSubmissionsByUser(ctx, "alice")
+			if err != nil || len(byUser) != 2 || byUser[0].ID != sent[2].ID || byUser[1].ID != sent[0].ID {
+				t.Errorf("ListSubmissionsByUser should list alice's submissions newest first, got %+v (%v)", byUser, err)
+			}
+			byGame, err := store.ListSubmissionsByGame(ctx, "room1-1")
+			if err != nil || len(byGame) != 2 || byGame[0].User != "bob" || byGame[1].User != "alice" {
+				t.Errorf("ListSubmissionsByGame should list the game's submissions newest first, got %+v (%v)", byGame, err)
+			}
+			if none, _ := store.ListSubmissionsByGame(ctx, "room2-1"); len(none) != 0 {
+				t.Errorf("ListSubmissionsByGame of a game without submissions: %+v", none)
+			}
+		})
+	}
+}
+
+func TestGameReviews(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			review := models.GameReview{GameID: "room1-1", RoomID: "room1", Winner: "alice",
+				Players: []models.PlayerReview{{User: "alice", Solved: true, SolveTime: 1000}}}
+			if err := store.SaveGameReview(ctx, review); err != nil {
+				t.Fatal(err)
+			}
+			review.Winner = "bob"
+			if err := store.SaveGameReview(ctx, review); err != nil {
+				t.Fatal(err)
+			}
+			got, err := store.GetGameReview(ctx, "room1-1")
+			if err != nil || got.Winner != "bob" || len(got.Players) != 1 || got.Players[0].SolveTime != 1000 {
+				t.Errorf("GetGameReview should get the latest review: %+v (%v)", got, err)
+			}
+			if _, err := store.GetGameReview(ctx, "room2-1"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetGameReview of a game without a review: %v, expected ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestCredentialsAndSessions(t *testing.T) {
+	ctx := context.Background()
+	expires := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			credentials := models.Credentials{UserID: "user1", Email: "alice@example.com", PasswordHash: "hash"}
+			if err := store.CreateCredentials(ctx, credentials); err != nil {
+				t.Fatal(err)
+			}
+			taken := models.Credentials{UserID: "user2", Email: "alice@example.com", PasswordHash: "other"}
+			if err := store.CreateCredentials(ctx, taken); !errors.Is(err, ErrExists) {
+				t.Errorf("CreateCredentials for an email that's taken: %v, expected ErrExists", err)
+			}
+			got, err := store.GetCredentials(ctx, "alice@example.com")
+			if err != nil || got != credentials {
+				t.Errorf("GetCredentials: %+v (%v), expected %+v", got, err, credentials)
+			}
+			if _, err := store.GetCredentials(ctx, "bob@example.com"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetCredentials of a missing email: %v, expected ErrNotFound", err)
+			}
+
+			session := models.Session{ID: "session1", UserID: "user1", ExpiresAt: expires}
+			if err := store.SaveSession(ctx, session); err != nil {
+				t.Fatal(err)
+			}
+			gotSession, err := store.TakeSession(ctx, "session1")
+			if err != nil || gotSession.UserID != "user1" || !gotSession.ExpiresAt.Equal(expires) {
+				t.Errorf("TakeSession: %+v (%v)", gotSession, err)
+			}
+			if _, err := store.TakeSession(ctx, "session1"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("TakeSession after it was taken: %v, expected ErrNotFound", err)
+			}
+
+			store.SaveSession(ctx, models.Session{ID: "session2", UserID: "user1", ExpiresAt: expires})
+			if err := store.DeleteSession(ctx, "session2"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.TakeSession(ctx, "session2"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("TakeSession after deleting it: %v, expected ErrNotFound", err)
+			}
+			if err := store.DeleteSession(ctx, "session2"); err != nil {
+				t.Errorf("deleting a missing session: %v", err)
+			}
+		})
+	}
+}
+
+func TestRatings(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.GetRating(ctx, "alice"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetRating of a player without a rating: %v, expected ErrNotFound", err)
+			}
+			games := []struct {
+				user   string
+				gameID string
+				after  float64
+			}{
+				{user: "alice", gameID: "room1-1", after: 1600},
+				{user: "al", gameID: "room1-1", after: 1400},
+				{user: "alice", gameID: "room1-2", after: 1650},
+			}
+			for i, game := range games {
+				rating := models.PlayerRating{User: game.user, Rating: game.after, RD: 200, Volatility: 0.06, Games: i + 1}
+				change := models.RatingChange{User: game.user, GameID: game.gameID, After: game.after, At: start.Add(time.Duration(i) * time.Minute)}
+				if err := store.SaveRating(ctx, rating, change); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			rating, err := store.GetRating(ctx, "alice")
+			if err != nil || rating.Rating != 1650 || rating.Games != 3 {
+				t.Errorf("GetRating should get the latest rating: %+v (%v)", rating, err)
+			}
+			// al's history shouldn't be mixed up with alice's
+			history, err := store.ListRatingChanges(ctx, "alice")
+			if err != nil || len(history) != 2 || history[0].GameID != "room1-2" || history[1].GameID != "room1-1" {
+				t.Errorf("ListRatingChanges should list alice's changes newest first, got %+v (%v)", history, err)
+			}
+			if none, _ := store.ListRatingChanges(ctx, "bob"); len(none) != 0 {
+				t.Errorf("ListRatingChanges of a player without a history: %+v", none)
+			}
+		})
+	}
+}
+
+func TestLeaderboardResults(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, gameID := range []string{"room1-1", "room1-2"} {
+				result := models.GameResult{GameID: gameID, Winner: "alice", Players: []string{"alice", "bob"}, EndedAt: start.Add(time.Duration(i) * time.Hour)}
+				if err := store.SaveGameResult(ctx, result); err != nil {
+					t.Fatal(err)
+				}
+				solve := models.Solve{User: "alice", ProblemID: "problem01", GameID: gameID, SolveTime: 1000, SolvedAt: result.EndedAt}
+				if err := store.SaveSolve(ctx, solve); err != nil {
+					t.Fatal(err)
+				}
+			}
+			store.SaveSolve(ctx, models.Solve{User: "bob", ProblemID: "problem02", SolvedAt: start})
+
+			if results, err := store.ListGameResults(ctx, time.Time{}); err != nil || len(results) != 2 || len(results[0].Players) != 2 {
+				t.Errorf("ListGameResults: %+v (%v)", results, err)
+			}
+			if results, err := store.ListGameResults(ctx, start.Add(time.Minute)); err != nil || len(results) != 1 || results[0].GameID != "room1-2" {
+				t.Errorf("ListGameResults since the first game: %+v (%v)", results, err)
+			}
+			if solves, err := store.ListSolves(ctx, "problem01", time.Time{}); err != nil || len(solves) != 2 {
+				t.Errorf("ListSolves: %+v (%v)", solves, err)
+			}
+			if solves, err := store.ListSolves(ctx, "problem01", start.Add(time.Minute)); err != nil || len(solves) != 1 || solves[0].GameID != "room1-2" {
+				t.Errorf("ListSolves since the first game: %+v (%v)", solves, err)
+			}
+
+			store.SaveRating(ctx, models.PlayerRating{User: "alice", Rating: 1600}, models.RatingChange{User: "alice", GameID: "room1-1", At: start})
+			store.SaveRating(ctx, models.PlayerRating{User: "bob", Rating: 1400}, models.RatingChange{User: "bob", GameID: "room1-2", At: start.Add(time.Hour)})
+			if ratings, err := store.ListRatings(ctx); err != nil || len(ratings) != 2 {
+				t.Errorf("ListRatings: %+v (%v)", ratings, err)
+			}
+			if changes, err := store.ListRatingChangesSince(ctx, start.Add(time.Minute)); err != nil || len(changes) != 1 || changes[0].User != "bob" {
+				t.Errorf("ListRatingChangesSince: %+v (%v)", changes, err)
+			}
+		})
+	}
+}
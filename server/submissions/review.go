@@ -0,0 +1,91 @@
+// Package submissions works out what the submissions sent during a game add up to, such as the game's review.
+package submissions
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/webbben/code-duel/diff"
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+)
+
+// fills in the players of a finished game's review, from what they submitted during it. the review's game ID and
+// start time must be set already. references are the problem's reference solutions, by language.
+//
+// a player's final submission is the last full submission they sent, or their last test if they never submitted.
+// players are listed with those who solved it first, fastest first, then by how many tests they passed
+func BuildReview(ctx context.Context, review models.GameReview, players []string, references map[string]string) (models.GameReview, error) {
+	sent, err := storage.Get().ListSubmissionsByGame(ctx, review.GameID)
+	if err != nil {
+		return review, err
+	}
+	players = slices.Clone(players)
+	for _, submission := range sent {
+		if !slices.Contains(players, submission.User) {
+			players = append(players, submission.User)
+		}
+	}
+
+	review.Players = make([]models.PlayerReview, 0, len(players))
+	for _, player := range players {
+		playerReview := models.PlayerReview{User: player, Cases: []models.SubmissionCase{}}
+		var final *models.Submission
+		// sent is newest first, so the last passing submission found is the first one that passed
+		for i := range sent {
+			submission := &sent[i]
+			if submission.User != player {
+				continue
+			}
+			if final == nil || (submission.FullTest && !final.FullTest) {
+				final = submission
+			}
+			if submission.FullTest && submission.TestCount > 0 && submission.PassCount == submission.TestCount {
+				playerReview.Solved = true
+				playerReview.SolveTime = submission.SolveTime(review.StartedAt)
+			}
+		}
+		if final != nil {
+			playerReview.SubmissionID = final.ID
+			playerReview.Lang = final.Lang
+			playerReview.Code = final.Code
+			playerReview.PassCount = final.PassCount
+			playerReview.TestCount = final.TestCount
+			playerReview.Cases = final.Cases
+			if reference, ok := references[final.Lang]; ok {
+				playerReview.ReferenceDiff = diff.Lines(reference, final.Code)
+			}
+		}
+		review.Players = append(review.Players, playerReview)
+	}
+
+	sort.SliceStable(review.Players, func(i, j int) bool {
+		a, b := review.Players[i], review.Players[j]
+		if a.Solved != b.Solved {
+			return a.Solved
+		}
+		if a.Solved && a.SolveTime != b.SolveTime {
+			return a.SolveTime < b.SolveTime
+		}
+		return a.PassCount > b.PassCount
+	})
+	return review, nil
+}
+
+// the review as a user sees it. the reference diffs give away most of the reference solutions, so they're only
+// left in for a user who solved the problem in the game themselves
+func ReviewFor(review models.GameReview, viewer string) models.GameReview {
+	for _, player := range review.Players {
+		if player.User == viewer && player.Solved {
+			return review
+		}
+	}
+	players := make([]models.PlayerReview, len(review.Players))
+	for i, player := range review.Players {
+		player.ReferenceDiff = ""
+		players[i] = player
+	}
+	review.Players = players
+	return review
+}
@@ -0,0 +1,76 @@
+package submissions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/webbben/code-duel/models"
+	"github.com/webbben/code-duel/storage"
+)
+
+func TestBuildReview(t *testing.T) {
+	ctx := context.Background()
+	storage.Set(storage.NewMemoryStore())
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+	sent := []models.Submission{
+		// alice solves it after 3 minutes, then sends another test
+		{User: "alice", Lang: "python", Code: "wrong", FullTest: true, PassCount: 1, TestCount: 4, SubmittedAt: at(1), FinishedAt: at(1)},
+		{User: "alice", Lang: "python", Code: "right", FullTest: true, PassCount: 4, TestCount: 4, SubmittedAt: at(3), FinishedAt: at(3)},
+		{User: "alice", Lang: "python", Code: "testing", PassCount: 2, TestCount: 2, SubmittedAt: at(4), FinishedAt: at(4)},
+		// bob solves it after 2 minutes; his solve time counts from when he sent it, not from when its tests finished
+		{User: "bob", Lang: "go", Code: "fast", FullTest: true, PassCount: 4, TestCount: 4, SubmittedAt: at(2), FinishedAt: at(4)},
+		// carol only tests her code
+		{User: "carol", Lang: "python", Code: "partial", PassCount: 1, TestCount: 2, SubmittedAt: at(5), FinishedAt: at(5)},
+		// from another game
+		{User: "dave", Lang: "python", Code: "other", FullTest: true, PassCount: 4, TestCount: 4, SubmittedAt: at(1), FinishedAt: at(1)},
+	}
+	for i := range sent {
+		sent[i].GameID = "room1-1"
+		if sent[i].User == "dave" {
+			sent[i].GameID = "room2-1"
+		}
+		if err := storage.Get().SaveSubmission(ctx, &sent[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	review, err := BuildReview(ctx, models.GameReview{GameID: "room1-1", StartedAt: start}, []string{"alice", "carol", "erin"}, map[string]string{"python": "right"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var users []string
+	for _, player := range review.Players {
+		users = append(users, player.User)
+	}
+	if len(users) != 4 || users[0] != "bob" || users[1] != "alice" || users[2] != "carol" || users[3] != "erin" {
+		t.Fatalf("players should be bob, alice, carol and erin, got %v", users)
+	}
+	bob, alice, carol, erin := review.Players[0], review.Players[1], review.Players[2], review.Players[3]
+	if !bob.Solved || bob.SolveTime != 2*60*1000 || bob.ReferenceDiff != "" {
+		t.Errorf("bob: %+v", bob)
+	}
+	if !alice.Solved || alice.SolveTime != 3*60*1000 || alice.Code != "right" || alice.SubmissionID != sent[1].ID || alice.ReferenceDiff != "  right\n" {
+		t.Errorf("alice's final submission should be her last full submission: %+v", alice)
+	}
+	if carol.Solved || carol.Code != "partial" || carol.ReferenceDiff != "- right\n+ partial\n" {
+		t.Errorf("carol: %+v", carol)
+	}
+	if erin.Solved || erin.Code != "" || erin.SubmissionID != "" {
+		t.Errorf("erin never sent anything: %+v", erin)
+	}
+
+	// carol didn't solve it, so the diffs would give her the reference solution
+	if hidden := ReviewFor(review, "carol"); hidden.Players[1].ReferenceDiff != "" || hidden.Players[2].ReferenceDiff != "" {
+		t.Errorf("carol shouldn't see the reference diffs: %+v", hidden.Players)
+	}
+	if review.Players[2].ReferenceDiff == "" {
+		t.Error("ReviewFor shouldn't change the review it's given")
+	}
+	if shown := ReviewFor(review, "alice"); shown.Players[2].ReferenceDiff != "- right\n+ partial\n" {
+		t.Errorf("alice solved it, so she should see the reference diffs: %+v", shown.Players)
+	}
+}